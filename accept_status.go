@@ -0,0 +1,54 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithAcceptStatus returns a RequestOption which causes responses with any of
+// the given status codes to be treated as successful, for endpoints where e.g.
+// 409 Conflict is a meaningful outcome rather than an error. The body of such
+// a response is decoded normally instead of being returned in an HTTPError.
+// Responses with those status codes are never cached.
+func WithAcceptStatus(codes ...int) RequestOption {
+	return func(opts *requestOptions) {
+		opts.acceptStatus = append(opts.acceptStatus, codes...)
+	}
+}
+
+// acceptStatusKey is the context key for the status codes given by
+// WithAcceptStatus.
+type acceptStatusKey struct{}
+
+// withAcceptStatus returns a copy of ctx which carries codes.
+func withAcceptStatus(ctx context.Context, codes []int) context.Context {
+	return context.WithValue(ctx, acceptStatusKey{}, codes)
+}
+
+// successful returns true iff the status code of res is 2xx or was accepted
+// with WithAcceptStatus.
+func successful(res *http.Response) bool {
+	if res.StatusCode/100 == 2 {
+		return true
+	}
+	return acceptsStatus(res.Request, res.StatusCode)
+}
+
+// acceptsStatus returns true iff statusCode was accepted for req with
+// WithAcceptStatus.
+func acceptsStatus(req *http.Request, statusCode int) bool {
+	if req == nil {
+		return false
+	}
+	codes, _ := req.Context().Value(acceptStatusKey{}).([]int)
+	for _, code := range codes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
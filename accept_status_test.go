@@ -0,0 +1,56 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWithAcceptStatus(t *testing.T) {
+	newEchoServer(t, http.StatusConflict, `{"Id": "7", "Title": "existing"}`)
+	client := NewClient()
+	reports := 0
+	client.OnError = func(ErrorReport) { reports++ }
+	todo := &testTodo{Title: "a"}
+	if err := client.Create(todo, WithAcceptStatus(http.StatusNotFound, http.StatusConflict)); err != nil {
+		t.Fatalf("Expected the accepted status to be treated as success but got %v", err)
+	}
+	if todo.Id != "7" || todo.Title != "existing" {
+		t.Errorf("Expected the body to be decoded but got %+v", todo)
+	}
+	if reports != 0 {
+		t.Errorf("Expected the accepted status not to be reported to OnError but got %d reports", reports)
+	}
+
+	err := client.Create(&testTodo{Title: "a"}, WithAcceptStatus(http.StatusNotFound))
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusConflict {
+		t.Errorf("Expected an HTTPError for a status which was not accepted but got %v", err)
+	}
+	if reports != 1 {
+		t.Errorf("Expected the error to be reported but got %d reports", reports)
+	}
+}
+
+func TestWithAcceptStatusNotCached(t *testing.T) {
+	server := newEchoServer(t, http.StatusNotFound, `{"Id": "1", "Title": "placeholder"}`)
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	for i := 0; i < 2; i++ {
+		todo := &testTodo{}
+		if err := client.Read("1", todo, WithAcceptStatus(http.StatusNotFound)); err != nil {
+			t.Fatal(err)
+		}
+		if todo.Title != "placeholder" {
+			t.Errorf("Expected the body to be decoded but got %+v", todo)
+		}
+	}
+	if n := len(server.all()); n != 2 {
+		t.Errorf("Expected responses with an accepted status not to be cached but got %d requests", n)
+	}
+	if client.Cache.(*MemoryCache).Len() != 0 {
+		t.Error("Expected the cache to be empty")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Accepted describes a 202 Accepted response, which servers use to indicate
+// that a request has been accepted for processing but has not completed yet.
+type Accepted struct {
+	// Location is the absolute url of the resource which can be polled to find
+	// out the status of the operation, taken from the Location header (or the
+	// Content-Location header if there is no Location). It is empty if the
+	// server did not send either header.
+	Location string
+	// RetryAfter is how long the server asked the client to wait before
+	// polling, taken from the Retry-After header. It is zero if the server did
+	// not send one.
+	RetryAfter time.Duration
+}
+
+// WithAccepted returns a RequestOption which causes accepted to be filled in if
+// the server responds with 202 Accepted. A 202 response is treated as a
+// success, so without this option there is no way to tell it apart from any
+// other successful response. If the response has no body, the model passed
+// to the method is left unchanged.
+func WithAccepted(accepted *Accepted) RequestOption {
+	return func(opts *requestOptions) {
+		opts.accepted = accepted
+	}
+}
+
+// isEmptyBody returns true if body contains nothing but whitespace, which is
+// the case for e.g. 204 No Content responses.
+func isEmptyBody(body []byte) bool {
+	return len(strings.TrimSpace(string(body))) == 0
+}
+
+// hasNoContent returns true if the status code of res means that the response
+// never has a body.
+func hasNoContent(res *http.Response) bool {
+	return res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusResetContent
+}
+
+// recordAccepted fills in the Accepted given by reqOpts if res has the status
+// code 202 Accepted. reqOpts may be nil.
+func recordAccepted(res *http.Response, reqOpts *requestOptions) {
+	if reqOpts == nil || reqOpts.accepted == nil || res.StatusCode != http.StatusAccepted {
+		return
+	}
+	*reqOpts.accepted = newAccepted(res)
+}
+
+// newAccepted returns the Accepted corresponding to res.
+func newAccepted(res *http.Response) Accepted {
+	accepted := Accepted{}
+	if location, err := res.Location(); err == nil {
+		accepted.Location = location.String()
+	} else if contentLocation := res.Header.Get("Content-Location"); contentLocation != "" {
+		if location, err := res.Request.URL.Parse(contentLocation); err == nil {
+			accepted.Location = location.String()
+		}
+	}
+	accepted.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+	return accepted
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an http date. It returns zero if value is empty or
+// invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := t.Sub(time.Now()); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNoContent(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusResetContent, http.StatusOK} {
+		newEchoServer(t, status, "")
+		todo := &testTodo{DefaultId: DefaultId{Id: "1"}, Title: "a"}
+		if err := NewClient().Update(todo); err != nil {
+			t.Errorf("Unexpected error for status %d: %s", status, err)
+		}
+		if todo.Title != "a" {
+			t.Errorf("Expected the model to be unchanged for status %d but got %+v", status, todo)
+		}
+	}
+}
+
+func TestWithAccepted(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/jobs/7")
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	todo := &testTodo{Title: "a"}
+	accepted := Accepted{}
+	if err := NewClient().Create(todo, WithAccepted(&accepted)); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.Location != testRootURL+"/jobs/7" || accepted.RetryAfter != 3*time.Second {
+		t.Errorf("Unexpected Accepted: %+v", accepted)
+	}
+	if todo.Title != "a" || todo.Id != "" {
+		t.Errorf("Expected the model to be unchanged but got %+v", todo)
+	}
+}
+
+func TestAcceptedContentLocation(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Location", "status")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	accepted := Accepted{}
+	if err := NewClient().Delete(&testTodo{DefaultId: DefaultId{Id: "1"}}, WithAccepted(&accepted)); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.Location != testRootURL+"/todos/status" {
+		t.Errorf("Expected the Content-Location to be resolved against the request url but got %q", accepted.Location)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                              0,
+		"10":                            10 * time.Second,
+		"-1":                            0,
+		"invalid":                       0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0,
+	}
+	for value, expected := range tests {
+		if got := parseRetryAfter(value); got != expected {
+			t.Errorf("Expected %s for %q but got %s", expected, value, got)
+		}
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 58*time.Minute || got > time.Hour {
+		t.Errorf("Expected about an hour for %q but got %s", future, got)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PollPolicy determines how AwaitOperation polls the status of an operation.
+// The zero value is a reasonable default.
+type PollPolicy struct {
+	// Interval is how long to wait before polling for the first time. The wait
+	// doubles after each poll, up to MaxInterval. If the server sends a
+	// Retry-After header, it takes precedence. The default is one second.
+	Interval time.Duration
+	// MaxInterval is the maximum time to wait between polls. The default is
+	// 30 seconds.
+	MaxInterval time.Duration
+	// Timeout, if not zero, is the maximum total time to wait for the
+	// operation to complete. The context passed to AwaitOperation is
+	// respected either way.
+	Timeout time.Duration
+	// StatusField is the key of the field in the status resource which holds
+	// the status of the operation. The default is "status".
+	StatusField string
+	// DoneStatuses are the values of the status field which mean that the
+	// operation has completed successfully. The default is "succeeded",
+	// "success", "completed", and "done".
+	DoneStatuses []string
+	// FailedStatuses are the values of the status field which mean that the
+	// operation has failed. The default is "failed", "error", "canceled", and
+	// "cancelled".
+	FailedStatuses []string
+	// ResultField, if not empty, is the key of the field in the status
+	// resource which holds the result of a completed operation. If it is
+	// empty, the whole status resource is decoded into the result, unless the
+	// completed status resource has a Location header, in which case the
+	// resource at that location is.
+	ResultField string
+}
+
+var (
+	defaultDoneStatuses   = []string{"succeeded", "success", "completed", "done"}
+	defaultFailedStatuses = []string{"failed", "error", "canceled", "cancelled"}
+)
+
+// OperationError is returned by AwaitOperation when the status of an operation
+// indicates that it failed.
+type OperationError struct {
+	// URL is the url of the status resource
+	URL string
+	// Status is the value of the status field
+	Status string
+	// Body is the body of the status resource
+	Body []byte
+}
+
+// Error satisfies the error interface
+func (e OperationError) Error() string {
+	return fmt.Sprintf("rest: operation at %s failed with status %q", e.URL, e.Status)
+}
+
+// AwaitOperation polls the status resource at location until the operation it
+// describes completes, and then decodes the final resource into result.
+// location is typically the Location of an Accepted (see WithAccepted).
+//
+// The operation is considered complete when polling results in a redirect
+// (usually 303 See Other) to the final resource, or when the status resource
+// has a status field with one of the poll.DoneStatuses, or no status field at
+// all. While the server responds with 202 Accepted or an unfinished status,
+// AwaitOperation keeps polling. If the status is one of poll.FailedStatuses,
+// an OperationError is returned. If ctx is canceled or poll.Timeout passes
+// first, the error from the context is returned.
+func (c *Client) AwaitOperation(ctx context.Context, location string, result interface{}, poll PollPolicy) error {
+	if poll.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, poll.Timeout)
+		defer cancel()
+	}
+	wait := poll.Interval
+	if wait <= 0 {
+		wait = time.Second
+	}
+	maxWait := poll.MaxInterval
+	if maxWait <= 0 {
+		maxWait = 30 * time.Second
+	}
+	for {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		done, retryAfter, err := c.pollOperation(ctx, location, result, poll)
+		if err != nil || done {
+			return err
+		}
+		if wait *= 2; wait > maxWait {
+			wait = maxWait
+		}
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+	}
+}
+
+// pollOperation polls the status resource at location once. It returns true
+// if the operation has completed, in which case the final resource has been
+// decoded into result. Otherwise it returns how long the server asked the
+// client to wait before polling again, if at all.
+func (c *Client) pollOperation(ctx context.Context, location string, result interface{}, poll PollPolicy) (bool, time.Duration, error) {
+	res, body, err := c.getWithContext(ctx, location)
+	if err != nil {
+		return false, 0, err
+	}
+	switch {
+	case res.StatusCode == http.StatusAccepted:
+		return false, parseRetryAfter(res.Header.Get("Retry-After")), nil
+	case res.StatusCode/100 == 3:
+		// The http.Client did not follow the redirect (e.g. because of its
+		// CheckRedirect function), so follow it ourselves.
+		final, err := res.Location()
+		if err != nil {
+			return false, 0, fmt.Errorf("rest: operation at %s redirected without a valid Location: %s", location, err)
+		}
+		return true, 0, c.getInto(ctx, final.String(), result)
+	case res.Request != nil && res.Request.Response != nil:
+		// The http.Client followed a redirect to the final resource. We can't
+		// compare the url of the request to location instead, since round
+		// trippers like LoadBalancer and FailoverPolicy may have changed it.
+		return true, 0, c.unmarshal(body, result)
+	}
+	status, found, err := operationStatus(body, poll)
+	if err != nil {
+		return false, 0, err
+	}
+	switch {
+	case !found || containsFold(doneStatuses(poll), status):
+		return true, 0, c.decodeOperationResult(ctx, res, body, result, poll)
+	case containsFold(failedStatuses(poll), status):
+		return false, 0, OperationError{URL: location, Status: status, Body: body}
+	}
+	return false, parseRetryAfter(res.Header.Get("Retry-After")), nil
+}
+
+// decodeOperationResult decodes the result of a completed operation, given the
+// final response from its status resource, into result.
+func (c *Client) decodeOperationResult(ctx context.Context, res *http.Response, body []byte, result interface{}, poll PollPolicy) error {
+	if poll.ResultField != "" {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return err
+		}
+		return c.unmarshal(fields[poll.ResultField], result)
+	}
+	if final, err := res.Location(); err == nil {
+		return c.getInto(ctx, final.String(), result)
+	}
+	return c.unmarshal(body, result)
+}
+
+// getWithContext sends a GET request to url and returns the response and its
+// body. 2xx and 3xx responses are returned as is and any other status code
+// results in an HTTPError.
+func (c *Client) getWithContext(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return c.getAccepting(ctx, url, "application/json")
+}
+
+// getAccepting is like getWithContext but sends the given Accept header.
+func (c *Client) getAccepting(ctx context.Context, url string, accept string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Something went wrong building GET request to %s: %s", url, err.Error())
+	}
+	req.Header.Set("Accept", accept)
+	res, err := c.do(req.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 == 3 {
+		ioutil.ReadAll(res.Body)
+		return res, nil, nil
+	}
+	body, err := c.readResponse(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, body, nil
+}
+
+// getInto sends a GET request to url and decodes the response into v.
+func (c *Client) getInto(ctx context.Context, url string, v interface{}) error {
+	return c.NewRequestBuilder("GET", url).Into(v).Execute(ctx)
+}
+
+// operationStatus returns the value of the status field in body. The second
+// return value is false if body has no status field.
+func operationStatus(body []byte, poll PollPolicy) (string, bool, error) {
+	if isEmptyBody(body) {
+		return "", false, nil
+	}
+	field := poll.StatusField
+	if field == "" {
+		field = "status"
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		// Not an object, so it must be the final resource
+		return "", false, nil
+	}
+	status, found := fields[field]
+	if !found {
+		return "", false, nil
+	}
+	return fmt.Sprint(status), true, nil
+}
+
+func doneStatuses(poll PollPolicy) []string {
+	if len(poll.DoneStatuses) == 0 {
+		return defaultDoneStatuses
+	}
+	return poll.DoneStatuses
+}
+
+func failedStatuses(poll PollPolicy) []string {
+	if len(poll.FailedStatuses) == 0 {
+		return defaultFailedStatuses
+	}
+	return poll.FailedStatuses
+}
+
+// containsFold returns true if list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// operationServer serves a status resource at /operations/1 which reports
+// the operation as running for the given number of polls, and then either
+// redirects to /todos/1 or reports it as done.
+func operationServer(polls int, redirect bool) http.HandlerFunc {
+	mut := sync.Mutex{}
+	count := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		switch r.URL.Path {
+		case "/operations/1":
+			count++
+			switch {
+			case count <= polls:
+				w.Write([]byte(`{"status": "running"}`))
+			case redirect:
+				http.Redirect(w, r, "/todos/1", http.StatusSeeOther)
+			default:
+				w.Write([]byte(`{"status": "done", "result": {"Id": "1", "Title": "imported"}}`))
+			}
+		case "/todos/1":
+			w.Write([]byte(`{"Id": "1", "Title": "imported"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestAwaitOperation(t *testing.T) {
+	for _, redirect := range []bool{false, true} {
+		server := newTestServer(t, operationServer(2, redirect))
+		client := NewClient()
+		todo := &testTodo{}
+		poll := PollPolicy{Interval: time.Millisecond}
+		if !redirect {
+			poll.ResultField = "result"
+		}
+		if err := client.AwaitOperation(context.Background(), server.URL+"/operations/1", todo, poll); err != nil {
+			t.Fatal(err)
+		}
+		if todo.Title != "imported" {
+			t.Errorf("Expected the result of the operation (redirect: %v) but got %+v", redirect, todo)
+		}
+	}
+}
+
+func TestAwaitOperationFailed(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "failed"}`))
+	})
+	err := NewClient().AwaitOperation(context.Background(), server.URL, &testTodo{}, PollPolicy{Interval: time.Millisecond})
+	if opErr, ok := err.(OperationError); !ok || opErr.Status != "failed" {
+		t.Errorf("Expected an OperationError but got %v", err)
+	}
+}
+
+func TestAwaitOperationBehindLoadBalancer(t *testing.T) {
+	primary := httptest.NewServer(operationServer(2, false))
+	defer primary.Close()
+	replica := httptest.NewServer(operationServer(2, false))
+	defer replica.Close()
+	client := FromRoundTripper(&LoadBalancer{Endpoints: []string{primary.URL, replica.URL}})
+	result := map[string]interface{}{}
+	poll := PollPolicy{Interval: time.Millisecond, ResultField: "result"}
+	if err := client.AwaitOperation(context.Background(), primary.URL+"/operations/1", &result, poll); err != nil {
+		t.Fatal(err)
+	}
+	if result["Title"] != "imported" {
+		t.Errorf("Expected the result of the operation but got %v", result)
+	}
+}
+
+func TestAwaitOperationTimeout(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	poll := PollPolicy{Interval: time.Millisecond, Timeout: 20 * time.Millisecond}
+	if err := NewClient().AwaitOperation(context.Background(), server.URL, &testTodo{}, poll); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded but got %v", err)
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+// package benchmarks measures the performance of the rest package: encoding
+// request bodies (url-encoded and JSON), decoding responses into slices of
+// various sizes, and complete round trips against an in-process server. The
+// results are printed in the same format as go test -bench, so they can be
+// compared with benchstat:
+//
+//	go run github.com/go-humble/rest/benchmarks/restbench -count 10 > old.txt
+//	# make some changes
+//	go run github.com/go-humble/rest/benchmarks/restbench -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// restbench can also act as a regression gate in CI by comparing against a
+// baseline with the -baseline and -threshold flags. The benchmarks can be run
+// with go test too, from a test file:
+//
+//	func BenchmarkRest(b *testing.B) {
+//		for _, bm := range benchmarks.All {
+//			b.Run(bm.Name, bm.F)
+//		}
+//	}
+package benchmarks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-humble/rest"
+	"github.com/go-humble/rest/resthandler"
+)
+
+// Benchmark is a named benchmark function.
+type Benchmark struct {
+	// Name is the name of the benchmark, without the "Benchmark" prefix
+	Name string
+	// F is the benchmark function
+	F func(b *testing.B)
+}
+
+// All holds every benchmark in the package.
+var All = []Benchmark{
+	{"EncodeURL", benchmarkEncode(rest.ContentURLEncoded)},
+	{"EncodeJSON", benchmarkEncode(rest.ContentJSON)},
+	{"Decode/10", benchmarkDecode(10, false)},
+	{"Decode/100", benchmarkDecode(100, false)},
+	{"Decode/1000", benchmarkDecode(1000, false)},
+	{"Decode/10000", benchmarkDecode(10000, false)},
+	{"DecodeReuse/100", benchmarkDecode(100, true)},
+	{"DecodeReuse/1000", benchmarkDecode(1000, true)},
+	{"EndToEnd/Loopback/Read", benchmarkEndToEndRead(loopbackClient)},
+	{"EndToEnd/Loopback/ReadAll100", benchmarkEndToEndReadAll(loopbackClient, 100)},
+	{"EndToEnd/Loopback/Create", benchmarkEndToEndCreate(loopbackClient)},
+	{"EndToEnd/HTTP/Read", benchmarkEndToEndRead(httpClient)},
+	{"EndToEnd/HTTP/ReadAll100", benchmarkEndToEndReadAll(httpClient, 100)},
+	{"EndToEnd/HTTP/Create", benchmarkEndToEndCreate(httpClient)},
+}
+
+// Run runs each benchmark in All whose name matches pattern (all of them if
+// pattern is empty) count times, and writes the results to w in the format of
+// go test -bench, which benchstat understands.
+func Run(w io.Writer, pattern string, count int) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "goos: %s\ngoarch: %s\npkg: github.com/go-humble/rest\n", runtime.GOOS, runtime.GOARCH)
+	suffix := ""
+	if procs := runtime.GOMAXPROCS(0); procs > 1 {
+		suffix = "-" + strconv.Itoa(procs)
+	}
+	for _, bm := range All {
+		if !re.MatchString(bm.Name) {
+			continue
+		}
+		for i := 0; i < count; i++ {
+			result := testing.Benchmark(bm.F)
+			fmt.Fprintf(w, "Benchmark%s%s\t%s\t%s\n", bm.Name, suffix, result.String(), result.MemString())
+		}
+	}
+	return nil
+}
+
+// ParseResults reads benchmark results in the format of go test -bench and
+// returns the ns/op of each run, by benchmark name (including the GOMAXPROCS
+// suffix). Lines which are not results are ignored.
+func ParseResults(r io.Reader) (map[string][]float64, error) {
+	results := map[string][]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			if fields[i+1] != "ns/op" {
+				continue
+			}
+			nsPerOp, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchmarks: invalid ns/op in %q: %s", scanner.Text(), err)
+			}
+			results[fields[0]] = append(results[fields[0]], nsPerOp)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Regression describes a benchmark which got slower.
+type Regression struct {
+	// Name is the name of the benchmark
+	Name string
+	// Baseline is the median ns/op of the baseline runs
+	Baseline float64
+	// Current is the median ns/op of the current runs
+	Current float64
+}
+
+// String returns a human-readable description of the regression.
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %.0f ns/op -> %.0f ns/op (%+.1f%%)", r.Name, r.Baseline, r.Current, 100*(r.Current/r.Baseline-1))
+}
+
+// Regressions compares the median ns/op of each benchmark in current to the
+// same benchmark in baseline, and returns those which are slower by more than
+// threshold (e.g. 0.1 for 10%). Benchmarks which are missing from either set
+// of results are ignored.
+func Regressions(baseline map[string][]float64, current map[string][]float64, threshold float64) []Regression {
+	regressions := []Regression{}
+	names := []string{}
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(baseline[name]) == 0 || len(current[name]) == 0 {
+			continue
+		}
+		old, cur := median(baseline[name]), median(current[name])
+		if cur > old*(1+threshold) {
+			regressions = append(regressions, Regression{Name: name, Baseline: old, Current: cur})
+		}
+	}
+	return regressions
+}
+
+// median returns the median of values, which must not be empty.
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// todo is the model used by the benchmarks.
+type todo struct {
+	rest.DefaultId
+	Title       string
+	IsCompleted bool
+	Priority    int
+	Estimate    float64
+}
+
+// RootURL satisfies rest.Model.
+func (*todo) RootURL() string {
+	return "http://bench.local/todos"
+}
+
+// newTodo returns a todo with typical values.
+func newTodo(i int) *todo {
+	return &todo{
+		DefaultId:   rest.DefaultId{Id: strconv.Itoa(i)},
+		Title:       "Todo " + strconv.Itoa(i),
+		IsCompleted: i%2 == 0,
+		Priority:    i % 5,
+		Estimate:    float64(i) / 4,
+	}
+}
+
+// cannedTransport is an http.RoundTripper which responds to every request
+// with the same body, so that benchmarks measure the client alone.
+type cannedTransport []byte
+
+// RoundTrip satisfies http.RoundTripper.
+func (body cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// benchmarkEncode returns a benchmark which creates a model with the given
+// content type against a canned response.
+func benchmarkEncode(contentType rest.ContentType) func(b *testing.B) {
+	return func(b *testing.B) {
+		body, _ := json.Marshal(newTodo(1))
+		client := rest.FromRoundTripper(cannedTransport(body))
+		client.ContentType = contentType
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := client.Create(newTodo(1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkDecode returns a benchmark which reads n models from a canned
+// response. If reuse is true, the models are read into the same slice each
+// time with the WithReuse option. Otherwise they are read into a new slice.
+func benchmarkDecode(n int, reuse bool) func(b *testing.B) {
+	return func(b *testing.B) {
+		todos := make([]*todo, n)
+		for i := range todos {
+			todos[i] = newTodo(i)
+		}
+		body, _ := json.Marshal(todos)
+		client := rest.FromRoundTripper(cannedTransport(body))
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		var result []*todo
+		opts := []rest.RequestOption{}
+		if reuse {
+			opts = append(opts, rest.WithReuse())
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if !reuse {
+				result = nil
+			}
+			if err := client.ReadAll(&result, opts...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkEndToEndRead returns a benchmark which reads a single model from a
+// server created by newClient.
+func benchmarkEndToEndRead(newClient func(n int) (*rest.Client, func())) func(b *testing.B) {
+	return func(b *testing.B) {
+		client, done := newClient(1)
+		defer done()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := client.Read("0", &todo{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkEndToEndReadAll returns a benchmark which reads n models from a
+// server created by newClient.
+func benchmarkEndToEndReadAll(newClient func(n int) (*rest.Client, func()), n int) func(b *testing.B) {
+	return func(b *testing.B) {
+		client, done := newClient(n)
+		defer done()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var result []*todo
+			if err := client.ReadAll(&result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkEndToEndCreate returns a benchmark which creates models on a server
+// created by newClient.
+func benchmarkEndToEndCreate(newClient func(n int) (*rest.Client, func())) func(b *testing.B) {
+	return func(b *testing.B) {
+		client, done := newClient(0)
+		defer done()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			model := newTodo(i)
+			model.Id = ""
+			if err := client.Create(model); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// loopbackClient returns a client which sends requests to a resthandler
+// holding n todos through rest.HandlerTransport, without any network.
+func loopbackClient(n int) (*rest.Client, func()) {
+	return rest.FromRoundTripper(rest.HandlerTransport(newHandler(n))), func() {}
+}
+
+// httpClient returns a client which sends requests to a resthandler holding n
+// todos over a loopback network connection. The returned function shuts the
+// server down.
+func httpClient(n int) (*rest.Client, func()) {
+	server := httptest.NewServer(newHandler(n))
+	// Send the requests to the test server instead of the root url of todo
+	transport := &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial("tcp", server.Listener.Addr().String())
+		},
+	}
+	return rest.FromRoundTripper(transport), server.Close
+}
+
+// newHandler returns an http.Handler serving n todos at /todos.
+func newHandler(n int) http.Handler {
+	storage := &memoryStorage{models: map[string]rest.Model{}}
+	for i := 0; i < n; i++ {
+		storage.models[strconv.Itoa(i)] = newTodo(i)
+	}
+	storage.nextId = n
+	mux := http.NewServeMux()
+	mux.Handle("/todos/", http.StripPrefix("/todos", resthandler.New(func() rest.Model { return &todo{} }, storage)))
+	mux.Handle("/todos", http.StripPrefix("/todos", resthandler.New(func() rest.Model { return &todo{} }, storage)))
+	return mux
+}
+
+// memoryStorage is a resthandler.Storage which holds models in memory.
+type memoryStorage struct {
+	models map[string]rest.Model
+	nextId int
+	mut    sync.Mutex
+}
+
+// All satisfies resthandler.Storage.
+func (s *memoryStorage) All() ([]rest.Model, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	models := make([]rest.Model, 0, len(s.models))
+	for _, model := range s.models {
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// Get satisfies resthandler.Storage.
+func (s *memoryStorage) Get(id string) (rest.Model, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	model, found := s.models[id]
+	if !found {
+		return nil, resthandler.ErrNotFound
+	}
+	return model, nil
+}
+
+// Create satisfies resthandler.Storage.
+func (s *memoryStorage) Create(model rest.Model) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	id := strconv.Itoa(s.nextId)
+	s.nextId++
+	model.(*todo).Id = id
+	s.models[id] = model
+	return nil
+}
+
+// Save satisfies resthandler.Storage.
+func (s *memoryStorage) Save(model rest.Model) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.models[model.ModelId()] = model
+	return nil
+}
+
+// Delete satisfies resthandler.Storage.
+func (s *memoryStorage) Delete(id string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, found := s.models[id]; !found {
+		return resthandler.ErrNotFound
+	}
+	delete(s.models, id)
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package benchmarks
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func BenchmarkRest(b *testing.B) {
+	for _, bm := range All {
+		b.Run(bm.Name, bm.F)
+	}
+}
+
+// runOnce causes testing.Benchmark to run each benchmark with b.N = 1 for the
+// rest of the test, so that the benchmarks can be smoke tested quickly.
+func runOnce(t *testing.T) {
+	benchtime := flag.Lookup("test.benchtime")
+	previous := benchtime.Value.String()
+	if err := benchtime.Value.Set("1x"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { benchtime.Value.Set(previous) })
+}
+
+func TestBenchmarksSmoke(t *testing.T) {
+	runOnce(t)
+	for _, bm := range All {
+		if result := testing.Benchmark(bm.F); result.N != 1 {
+			t.Errorf("Expected %s to run once but got N = %d", bm.Name, result.N)
+		}
+	}
+}
+
+func TestRunAndParseResults(t *testing.T) {
+	runOnce(t)
+	var buf bytes.Buffer
+	if err := Run(&buf, "^Encode", 2); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "goos: ") {
+		t.Errorf("Expected a header in the format of go test -bench but got:\n%s", buf.String())
+	}
+	results, err := ParseResults(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected results for the 2 encode benchmarks but got %v", results)
+	}
+	for name, runs := range results {
+		if !strings.HasPrefix(name, "BenchmarkEncode") || len(runs) != 2 {
+			t.Errorf("Expected 2 runs of an encode benchmark but got %d runs of %s", len(runs), name)
+		}
+	}
+	if err := Run(&buf, "(", 1); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	results, err := ParseResults(strings.NewReader(`goos: linux
+BenchmarkA-8	100	1000 ns/op	10 B/op	1 allocs/op
+BenchmarkA-8	100	1200 ns/op	10 B/op	1 allocs/op
+BenchmarkB-8	100	500 ns/op
+PASS
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := map[string][]float64{"BenchmarkA-8": {1000}, "BenchmarkB-8": {500, 400, 600}, "BenchmarkC-8": {1}}
+	if !reflect.DeepEqual(results, map[string][]float64{"BenchmarkA-8": {1000, 1200}, "BenchmarkB-8": {500}}) {
+		t.Fatalf("Unexpected results %v", results)
+	}
+	regressions := Regressions(baseline, results, 0.05)
+	expected := []Regression{{Name: "BenchmarkA-8", Baseline: 1000, Current: 1100}}
+	if !reflect.DeepEqual(regressions, expected) {
+		t.Errorf("Expected %v but got %v", expected, regressions)
+	}
+	if got := regressions[0].String(); got != "BenchmarkA-8: 1000 ns/op -> 1100 ns/op (+10.0%)" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	if regressions := Regressions(baseline, results, 0.2); len(regressions) != 0 {
+		t.Errorf("Expected no regressions above the threshold but got %v", regressions)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+// Command restbench runs the benchmarks of the rest package and prints the
+// results in the format of go test -bench, which benchstat understands. If
+// -baseline is given, it exits with status 1 if any benchmark got slower than
+// in the baseline by more than -threshold.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-humble/rest/benchmarks"
+)
+
+var (
+	bench     = flag.String("bench", "", "run only the benchmarks matching this regular expression")
+	count     = flag.Int("count", 1, "run each benchmark this many times")
+	baseline  = flag.String("baseline", "", "file with earlier results to compare against")
+	threshold = flag.Float64("threshold", 0.1, "fraction by which a benchmark may get slower than the baseline")
+)
+
+func main() {
+	flag.Parse()
+	var output bytes.Buffer
+	if err := benchmarks.Run(io.MultiWriter(os.Stdout, &output), *bench, *count); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *baseline == "" {
+		return
+	}
+	f, err := os.Open(*baseline)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer f.Close()
+	old, err := benchmarks.ParseResults(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	current, err := benchmarks.ParseResults(&output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	regressions := benchmarks.Regressions(old, current, *threshold)
+	for _, regression := range regressions {
+		fmt.Fprintln(os.Stderr, "regression:", regression)
+	}
+	if len(regressions) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,454 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore is a key-value store which the client uses to cache the responses
+// to GET requests. Keys are urls, followed by a hash of the values of the
+// request headers listed in the client's CacheVary if there are any, and values
+// are opaque encoded cache entries.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value for key and true, or nil and false if there is no
+	// value for key.
+	Get(key string) ([]byte, bool)
+	// Set sets the value for key, replacing any existing value.
+	Set(key string, value []byte)
+	// Delete removes the value for key, if any.
+	Delete(key string)
+}
+
+// Compacter is implemented by CacheStores which can remove expired entries
+// and evict entries to stay within their limits on demand.
+type Compacter interface {
+	Compact()
+}
+
+// EvictionPolicy determines which entries a MemoryCache evicts when it is
+// full.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used entry first.
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU evicts the least frequently used entry first. Ties are broken
+	// by evicting the least recently used of them.
+	EvictLFU
+)
+
+// CacheLimits bounds the size of a MemoryCache. A zero limit means there is no
+// limit.
+type CacheLimits struct {
+	// MaxEntries is the maximum number of entries.
+	MaxEntries int
+	// MaxBytes is the maximum total size of the keys and values of all the
+	// entries.
+	MaxBytes int
+	// Eviction determines which entries are evicted when a limit is
+	// exceeded. The default is EvictLRU.
+	Eviction EvictionPolicy
+}
+
+// MemoryCache is a CacheStore which keeps entries in memory. By default it
+// grows without bound; use NewBoundedMemoryCache to limit its size.
+type MemoryCache struct {
+	entries map[string]*memoryEntry
+	limits  CacheLimits
+	size    int
+	// clock is incremented each time an entry is used, to order entries by
+	// recency without calling time.Now
+	clock uint64
+	mut   sync.Mutex
+}
+
+// memoryEntry is a value stored in a MemoryCache along with how recently and
+// how often it has been used.
+type memoryEntry struct {
+	value    []byte
+	lastUsed uint64
+	uses     uint64
+}
+
+// NewMemoryCache returns a new, empty MemoryCache without any limits.
+func NewMemoryCache() *MemoryCache {
+	return NewBoundedMemoryCache(CacheLimits{})
+}
+
+// NewBoundedMemoryCache returns a new, empty MemoryCache which evicts entries
+// according to limits.Eviction whenever it holds more than limits.MaxEntries
+// entries or limits.MaxBytes bytes, so that long-running applications do not
+// use more and more memory.
+func NewBoundedMemoryCache(limits CacheLimits) *MemoryCache {
+	return &MemoryCache{
+		entries: map[string]*memoryEntry{},
+		limits:  limits,
+	}
+}
+
+// Get satisfies the Get method of CacheStore.
+func (mc *MemoryCache) Get(key string) ([]byte, bool) {
+	mc.mut.Lock()
+	defer mc.mut.Unlock()
+	entry, found := mc.entries[key]
+	if !found {
+		return nil, false
+	}
+	mc.touch(entry)
+	return entry.value, true
+}
+
+// Set satisfies the Set method of CacheStore.
+func (mc *MemoryCache) Set(key string, value []byte) {
+	mc.mut.Lock()
+	defer mc.mut.Unlock()
+	if entry, found := mc.entries[key]; found {
+		mc.size += len(value) - len(entry.value)
+		entry.value = value
+		mc.touch(entry)
+	} else {
+		entry := &memoryEntry{value: value}
+		mc.entries[key] = entry
+		mc.size += len(key) + len(value)
+		mc.touch(entry)
+	}
+	mc.evict()
+}
+
+// Delete satisfies the Delete method of CacheStore.
+func (mc *MemoryCache) Delete(key string) {
+	mc.mut.Lock()
+	defer mc.mut.Unlock()
+	mc.remove(key)
+}
+
+// Len returns the number of entries in the cache.
+func (mc *MemoryCache) Len() int {
+	mc.mut.Lock()
+	defer mc.mut.Unlock()
+	return len(mc.entries)
+}
+
+// Size returns the total size of the keys and values of all the entries in
+// the cache.
+func (mc *MemoryCache) Size() int {
+	mc.mut.Lock()
+	defer mc.mut.Unlock()
+	return mc.size
+}
+
+// Compact satisfies the Compacter interface. It removes the entries which
+// hold expired responses and then evicts entries until the cache is within
+// its limits. Entries which cannot be decoded (e.g. because they are
+// encrypted or compressed by a wrapping CacheStore) are only removed by
+// eviction.
+func (mc *MemoryCache) Compact() {
+	mc.mut.Lock()
+	defer mc.mut.Unlock()
+	for key, entry := range mc.entries {
+		decoded := cacheEntry{}
+		if err := json.Unmarshal(entry.value, &decoded); err == nil && !decoded.Expires.IsZero() && !decoded.fresh() {
+			mc.remove(key)
+		}
+	}
+	mc.evict()
+}
+
+// touch records that entry was just used.
+func (mc *MemoryCache) touch(entry *memoryEntry) {
+	mc.clock++
+	entry.lastUsed = mc.clock
+	entry.uses++
+}
+
+// remove removes the entry for key, if any.
+func (mc *MemoryCache) remove(key string) {
+	if entry, found := mc.entries[key]; found {
+		mc.size -= len(key) + len(entry.value)
+		delete(mc.entries, key)
+	}
+}
+
+// evict removes entries according to the eviction policy until the cache is
+// within its limits.
+func (mc *MemoryCache) evict() {
+	for mc.overLimit() {
+		victim, victimEntry := "", (*memoryEntry)(nil)
+		for key, entry := range mc.entries {
+			if victimEntry == nil || mc.evictBefore(entry, victimEntry) {
+				victim, victimEntry = key, entry
+			}
+		}
+		mc.remove(victim)
+	}
+}
+
+// overLimit returns true iff the cache exceeds one of its limits.
+func (mc *MemoryCache) overLimit() bool {
+	if len(mc.entries) == 0 {
+		return false
+	}
+	return (mc.limits.MaxEntries > 0 && len(mc.entries) > mc.limits.MaxEntries) ||
+		(mc.limits.MaxBytes > 0 && mc.size > mc.limits.MaxBytes)
+}
+
+// evictBefore returns true iff a should be evicted before b.
+func (mc *MemoryCache) evictBefore(a, b *memoryEntry) bool {
+	if mc.limits.Eviction == EvictLFU && a.uses != b.uses {
+		return a.uses < b.uses
+	}
+	return a.lastUsed < b.lastUsed
+}
+
+// CachePolicy determines how a single request uses the cache of the client.
+type CachePolicy int
+
+const (
+	// CacheDefault uses a fresh cached response if there is one, and
+	// otherwise sends a request and caches the response.
+	CacheDefault CachePolicy = iota
+	// NoCache always sends a request, ignoring any cached response. The
+	// response is still cached for subsequent requests.
+	NoCache
+	// CacheOnly never sends a request. If there is no fresh cached response,
+	// ErrNotCached is returned.
+	CacheOnly
+)
+
+// ErrNotCached is returned for requests with the CacheOnly policy when there is
+// no fresh cached response.
+var ErrNotCached = errors.New("rest: no cached response available")
+
+// WithCachePolicy returns a RequestOption which causes the request to use the
+// cache according to policy. It only affects GET requests.
+func WithCachePolicy(policy CachePolicy) RequestOption {
+	return func(opts *requestOptions) {
+		opts.cachePolicy = policy
+	}
+}
+
+// cacheEntry is a cached response body along with the time it expires and a
+// checksum of the body. It is encoded as JSON before being stored in a
+// CacheStore.
+type cacheEntry struct {
+	Body     json.RawMessage
+	Expires  time.Time
+	Checksum string
+}
+
+// bodyChecksum returns the checksum stored in a cacheEntry for body.
+func bodyChecksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// intact returns true iff the checksum of the entry matches its body.
+func (entry cacheEntry) intact() bool {
+	return entry.Checksum != "" && entry.Checksum == bodyChecksum(entry.Body)
+}
+
+// fresh returns true iff the entry has not expired. Entries with a zero
+// Expires time never expire.
+func (entry cacheEntry) fresh() bool {
+	return entry.Expires.IsZero() || time.Now().Before(entry.Expires)
+}
+
+// cacheKey returns the key under which the response to a GET request for url
+// is cached. Template variables in url are expanded first, so that e.g.
+// responses for different tenants are cached separately. If c.CacheVary is not
+// empty, a hash of the values of those headers is appended, so that e.g.
+// responses for different users or locales are cached separately as well.
+// header holds the headers of the request, and policy, which may be nil, is
+// the Policy of the model the request is for.
+func (c *Client) cacheKey(url string, header http.Header, policy *Policy) string {
+	key := c.expandVars(url)
+	if len(c.CacheVary) == 0 {
+		return key
+	}
+	effective := http.Header{}
+	for name, values := range header {
+		effective[name] = values
+	}
+	if u, err := neturl.Parse(key); err == nil {
+		c.addDefaultHeaders(effective, u, policy)
+	}
+	hash := sha256.New()
+	for _, name := range c.CacheVary {
+		name = http.CanonicalHeaderKey(name)
+		values := make([]string, len(effective[name]))
+		for i, value := range effective[name] {
+			values[i] = c.expandVars(value)
+		}
+		fmt.Fprintf(hash, "%s: %q\n", name, values)
+	}
+	return key + "#vary=" + hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// cachedBody returns the cached response body for url if there is a fresh
+// cache entry for it.
+func (c *Client) cachedBody(url string) ([]byte, bool) {
+	entry, found := c.cachedEntry(url, nil, nil)
+	return entry.Body, found
+}
+
+// cachedEntry is like cachedBody but returns the whole cache entry. header and
+// policy are used to determine the cache key. See cacheKey.
+func (c *Client) cachedEntry(url string, header http.Header, policy *Policy) (cacheEntry, bool) {
+	if c.Cache == nil {
+		return cacheEntry{}, false
+	}
+	key := c.cacheKey(url, header, policy)
+	data, found := c.Cache.Get(key)
+	if !found {
+		return cacheEntry{}, false
+	}
+	entry := cacheEntry{}
+	if err := json.Unmarshal(data, &entry); err != nil || !entry.intact() {
+		// The entry has been corrupted or tampered with, so it can't be
+		// trusted. Evict it so that it is replaced by a fresh response.
+		c.Cache.Delete(key)
+		return cacheEntry{}, false
+	}
+	if !entry.fresh() {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// storeCache stores body as the cached response for url. It does nothing if
+// the client does not have a Cache or if body is not valid JSON.
+func (c *Client) storeCache(url string, body []byte) {
+	c.storeCacheWithPolicy(url, nil, body, nil)
+}
+
+// storeCacheWithPolicy is like storeCache but respects the NoCache and
+// CacheTTL settings of policy, which may be nil. header and policy are used
+// to determine the cache key. See cacheKey.
+func (c *Client) storeCacheWithPolicy(url string, header http.Header, body []byte, policy *Policy) {
+	if c.Cache == nil || !json.Valid(body) || (policy != nil && policy.NoCache) {
+		return
+	}
+	// Compact the body, since that is what json.Marshal does to a
+	// json.RawMessage and the checksum must match what is stored
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, body); err != nil {
+		return
+	}
+	body = compacted.Bytes()
+	entry := cacheEntry{
+		Body:     body,
+		Checksum: bodyChecksum(body),
+	}
+	ttl := c.cacheTTLFor(url)
+	if policy != nil && policy.CacheTTL != 0 {
+		ttl = policy.CacheTTL
+	}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	key := c.cacheKey(url, header, policy)
+	c.Cache.Set(key, data)
+	c.trackCacheKey(url, key)
+}
+
+// trackCacheKey records that the cache entry with the given key holds the
+// response for url.
+func (c *Client) trackCacheKey(url string, key string) {
+	path := withoutQuery(c.expandVars(url))
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.cacheKeys == nil {
+		c.cacheKeys = map[string]map[string]bool{}
+	}
+	if c.cacheKeys[path] == nil {
+		c.cacheKeys[path] = map[string]bool{}
+	}
+	c.cacheKeys[path][key] = true
+}
+
+// untrackCacheKeys returns the keys of the cache entries stored by the client
+// for url with any query and forgets them.
+func (c *Client) untrackCacheKeys(url string) map[string]bool {
+	path := withoutQuery(c.expandVars(url))
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	keys := c.cacheKeys[path]
+	delete(c.cacheKeys, path)
+	return keys
+}
+
+// withoutQuery returns url without its query and fragment.
+func withoutQuery(url string) string {
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		return url[:i]
+	}
+	return url
+}
+
+// cacheTTLFor returns how long the response for url should be cached: the
+// entry in c.CacheTTLs with the longest root url that url starts with, or
+// c.CacheTTL if there is none.
+func (c *Client) cacheTTLFor(url string) time.Duration {
+	url = c.expandVars(url)
+	ttl, longest := c.CacheTTL, -1
+	for rootURL, rootTTL := range c.CacheTTLs {
+		rootURL = strings.TrimSuffix(c.expandVars(rootURL), "/")
+		if len(rootURL) > longest && hasURLPrefix(url, rootURL) {
+			ttl, longest = rootTTL, len(rootURL)
+		}
+	}
+	return ttl
+}
+
+// invalidateCache removes the cache entries for model and for the collection
+// it belongs to, since either may have changed. This includes the entries for
+// every query (e.g. from WithQuery, WithFields, or WithInclude) and, for
+// entries stored by c, every variant of the headers in c.CacheVary.
+func (c *Client) invalidateCache(model Model) {
+	if c.Cache == nil {
+		return
+	}
+	rootURL, err := c.routeRootURL(model, ActionRead)
+	if err != nil {
+		return
+	}
+	if rootURL, err = normalizeRootURL(rootURL); err != nil {
+		return
+	}
+	urls := []string{rootURL}
+	if _, ok := model.(CompositeModel); ok || model.ModelId() != "" {
+		if modelURL, err := c.urlForModelAt(rootURL, model); err == nil {
+			urls = append(urls, modelURL)
+		}
+	}
+	policy := policyOf(model)
+	for _, url := range urls {
+		for key := range c.untrackCacheKeys(url) {
+			c.Cache.Delete(key)
+		}
+		c.Cache.Delete(c.expandVars(url))
+		if len(c.CacheVary) > 0 {
+			// Only the variant for the default headers of the client can be
+			// found, since the headers of other requests are unknown
+			c.Cache.Delete(c.cacheKey(url, nil, policy))
+		}
+	}
+}
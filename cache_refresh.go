@@ -0,0 +1,113 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// flight is a request which is in progress. Its result is shared by every
+// caller which needs the response to the same url at the same time.
+type flight struct {
+	done chan struct{}
+	res  *http.Response
+	body []byte
+	err  error
+}
+
+// fetchShared calls fetch and returns the result, unless a call for the same
+// key is already in progress, in which case it waits for that call to finish
+// and returns its result instead. This prevents a stampede of identical
+// requests when a popular cache entry expires.
+func (c *Client) fetchShared(ctx context.Context, key string, fetch func() (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	c.mut.Lock()
+	if f, found := c.flights[key]; found {
+		c.mut.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if (f.err == context.Canceled || f.err == context.DeadlineExceeded) && ctx.Err() == nil {
+			// The caller which sent the request gave up on it, but we
+			// haven't.
+			return fetch()
+		}
+		return f.res, f.body, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	if c.flights == nil {
+		c.flights = map[string]*flight{}
+	}
+	c.flights[key] = f
+	c.mut.Unlock()
+	f.res, f.body, f.err = fetch()
+	c.mut.Lock()
+	delete(c.flights, key)
+	c.mut.Unlock()
+	close(f.done)
+	return f.res, f.body, f.err
+}
+
+// inFlight returns true if a request for key is in progress.
+func (c *Client) inFlight(key string) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	_, found := c.flights[key]
+	return found
+}
+
+// dueForRefresh returns true if entry should be refreshed in the background
+// because it expires within c.CacheRefreshAhead.
+func (c *Client) dueForRefresh(entry cacheEntry) bool {
+	if c.CacheRefreshAhead <= 0 || entry.Expires.IsZero() {
+		return false
+	}
+	return time.Until(entry.Expires) < c.CacheRefreshAhead
+}
+
+// flightKey returns the key under which the request for fullURL can share its
+// response with identical requests which are in progress. The second return
+// value is false if the request should not be shared, because options like
+// WithAcceptStatus, WithTimeout, or WithTiming make the outcome or its side
+// effects specific to this request, or because the request is passed to
+// Authorize, which may authorize it on behalf of a user given by its context.
+// The type of the target is part of the key since it determines how errors
+// are masked.
+func (rb *RequestBuilder) flightKey(fullURL string, reqOpts *requestOptions) (string, bool) {
+	if len(reqOpts.acceptStatus) > 0 || reqOpts.timeout != nil || reqOpts.timing != nil {
+		return "", false
+	}
+	if reqOpts.policy != nil && len(reqOpts.policy.Scopes) > 0 {
+		return "", false
+	}
+	key := rb.client.cacheKey(fullURL, rb.header, reqOpts.policy)
+	return fmt.Sprintf("%s %T", key, rb.target), true
+}
+
+// refresh sends the request in the background to refresh the cached response
+// for fullURL, unless a request with the same key is already in progress. key
+// and shared are the return values of flightKey. ctx is the context of the
+// request which found the response in the cache. Its values (including the
+// Policy of the model) carry over to the refresh, but its cancelation does
+// not.
+func (rb *RequestBuilder) refresh(ctx context.Context, key string, shared bool, fullURL string, data []byte, contentType ContentType) {
+	c := rb.client
+	ctx = detach(ctx)
+	fetch := func() (*http.Response, []byte, error) {
+		return rb.fetch(ctx, fullURL, data, contentType)
+	}
+	if !shared {
+		go fetch()
+		return
+	}
+	if c.inFlight(key) {
+		return
+	}
+	go c.fetchShared(ctx, key, fetch)
+}
@@ -0,0 +1,186 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingServer responds to every request with status once Release is
+// called, and counts the requests it receives.
+type blockingServer struct {
+	status   int
+	release  chan struct{}
+	once     sync.Once
+	requests int
+	mut      sync.Mutex
+}
+
+// newBlockingServer starts a blockingServer and points testTodo at it. The
+// server is released when the test ends, so that a failing test does not
+// hang.
+func newBlockingServer(t *testing.T, status int) *blockingServer {
+	s := &blockingServer{status: status, release: make(chan struct{})}
+	newTestServer(t, s.ServeHTTP)
+	t.Cleanup(s.Release)
+	return s
+}
+
+// Release lets the server respond to the requests it received.
+func (s *blockingServer) Release() {
+	s.once.Do(func() {
+		close(s.release)
+	})
+}
+
+func (s *blockingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	s.requests++
+	s.mut.Unlock()
+	<-s.release
+	w.WriteHeader(s.status)
+	w.Write([]byte(`{"Id": "1", "Title": "a"}`))
+}
+
+// waitForRequests waits until the server has received n requests.
+func (s *blockingServer) waitForRequests(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		s.mut.Lock()
+		requests := s.requests
+		s.mut.Unlock()
+		if requests >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d requests; got %d", n, requests)
+		}
+	}
+}
+
+func TestConcurrentReadsShareRequest(t *testing.T) {
+	server := newBlockingServer(t, http.StatusOK)
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	errs := make(chan error)
+	todos := make([]*testTodo, 5)
+	for i := range todos {
+		todos[i] = &testTodo{}
+		go func(todo *testTodo) {
+			errs <- client.Read("1", todo)
+		}(todos[i])
+	}
+	server.waitForRequests(t, 1)
+	// Give the other reads time to join the request in progress
+	time.Sleep(20 * time.Millisecond)
+	server.Release()
+	for range todos {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	for _, todo := range todos {
+		if todo.Title != "a" {
+			t.Errorf("Expected every read to decode the shared response but got %+v", todo)
+		}
+	}
+	if server.requests != 1 {
+		t.Errorf("Expected 1 request but got %d", server.requests)
+	}
+}
+
+func TestConcurrentReadsWithAcceptStatusNotShared(t *testing.T) {
+	server := newBlockingServer(t, http.StatusNotFound)
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	accepting := make(chan error)
+	go func() {
+		accepting <- client.Read("1", &testTodo{}, WithAcceptStatus(http.StatusNotFound))
+	}()
+	server.waitForRequests(t, 1)
+	plain := make(chan error)
+	go func() {
+		plain <- client.Read("1", &testTodo{})
+	}()
+	server.waitForRequests(t, 2)
+	server.Release()
+	if err := <-accepting; err != nil {
+		t.Errorf("Expected the accepted 404 to succeed but got %v", err)
+	}
+	if _, ok := (<-plain).(HTTPError); !ok {
+		t.Error("Expected the read without WithAcceptStatus to return an HTTPError")
+	}
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	client.CacheTTL = 200 * time.Millisecond
+	client.CacheRefreshAhead = 150 * time.Millisecond
+	read := func() *testTodo {
+		todo := &testTodo{}
+		if err := client.Read("1", todo); err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	read()
+	read()
+	if got := server.count("GET"); got != 1 {
+		t.Fatalf("Expected a fresh entry not to be refreshed but got %v", server.Requests())
+	}
+	server.mut.Lock()
+	server.todos[0].Title = "b"
+	server.mut.Unlock()
+	time.Sleep(80 * time.Millisecond)
+	if todo := read(); todo.Title != "a" {
+		t.Errorf("Expected the cached response while refreshing but got %+v", todo)
+	}
+	for deadline := time.Now().Add(time.Second); server.count("GET") < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the refresh; got %v", server.Requests())
+		}
+	}
+	// Wait for the refreshed response to be stored
+	for deadline := time.Now().Add(time.Second); read().Title != "b"; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the refreshed response to be cached")
+		}
+	}
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected a single refresh request but got %v", server.Requests())
+	}
+}
+
+func TestConcurrentAuthorizedReadsNotShared(t *testing.T) {
+	server := newBlockingServer(t, http.StatusOK)
+	setPolicy(t, Policy{Scopes: []string{"todos:read"}})
+	type userKey struct{}
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	client.Authorize = func(req *http.Request, scopes []string) error {
+		req.Header.Set("Authorization", "Bearer "+req.Context().Value(userKey{}).(string))
+		return nil
+	}
+	errs := make(chan error)
+	for i, user := range []string{"alice", "bob"} {
+		ctx := context.WithValue(context.Background(), userKey{}, user)
+		go func() {
+			errs <- client.Read("1", &policyTodo{}, WithContext(ctx))
+		}()
+		server.waitForRequests(t, i+1)
+	}
+	server.Release()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
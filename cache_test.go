@@ -0,0 +1,375 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheServesRepeatedReads(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	for i := 0; i < 3; i++ {
+		todos := []*testTodo{}
+		if err := client.ReadAll(&todos); err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 2 {
+			t.Fatalf("Expected 2 todos but got %d", len(todos))
+		}
+		todo := &testTodo{}
+		if err := client.Read("1", todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected 2 GET requests but got %d: %v", got, server.Requests())
+	}
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	readAll := func(opts ...RequestOption) []*testTodo {
+		todos := []*testTodo{}
+		if err := client.ReadAll(&todos, opts...); err != nil {
+			t.Fatal(err)
+		}
+		return todos
+	}
+	filter := WithQuery(Query{"IsCompleted": {"false"}})
+	// Fill the cache with the plain and the filtered collection
+	readAll()
+	readAll(filter)
+	todo := &testTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+
+	created := &testTodo{Title: "b"}
+	if err := client.Create(created); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(readAll()); got != 2 {
+		t.Errorf("Expected the collection to be reread after Create, but got %d todos", got)
+	}
+	if got := len(readAll(filter)); got != 2 {
+		t.Errorf("Expected the filtered collection to be reread after Create, but got %d todos", got)
+	}
+
+	todo.Title = "changed"
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	reread := &testTodo{}
+	if err := client.Read("1", reread); err != nil {
+		t.Fatal(err)
+	}
+	if reread.Title != "changed" {
+		t.Errorf("Expected the model to be reread after Update, but got %q", reread.Title)
+	}
+
+	if err := client.Delete(created); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(readAll(filter)); got != 1 {
+		t.Errorf("Expected the filtered collection to be reread after Delete, but got %d todos", got)
+	}
+	if server.count("GET") != 7 {
+		t.Errorf("Expected 7 GET requests but got %v", server.Requests())
+	}
+}
+
+func TestCacheOnlyAndNoCache(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	todo := &testTodo{}
+	if err := client.Read("1", todo, WithCachePolicy(CacheOnly)); err == nil {
+		t.Error("Expected CacheOnly to fail when nothing is cached")
+	}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Read("1", todo, WithCachePolicy(CacheOnly)); err != nil {
+		t.Errorf("Expected CacheOnly to succeed once the model is cached, but got %v", err)
+	}
+	if err := client.Read("1", todo, WithCachePolicy(NoCache)); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected 2 GET requests but got %v", server.Requests())
+	}
+}
+
+func TestCacheTTLs(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	client.CacheTTL = time.Hour
+	client.CacheTTLs = map[string]time.Duration{testRootURL + "/todos": 20 * time.Millisecond}
+	read := func() {
+		if err := client.Read("1", &testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read()
+	read()
+	if got := server.count("GET"); got != 1 {
+		t.Fatalf("Expected the second read to be served from the cache but got %v", server.Requests())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := client.Read("1", &testTodo{}, WithCachePolicy(CacheOnly)); err != ErrNotCached {
+		t.Errorf("Expected ErrNotCached for an expired entry but got %v", err)
+	}
+	read()
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected the expired entry to be fetched again but got %v", server.Requests())
+	}
+}
+
+func TestCacheTTLFor(t *testing.T) {
+	client := NewClient()
+	client.CacheTTL = time.Minute
+	client.CacheTTLs = map[string]time.Duration{
+		"http://api.example.com":              time.Second,
+		"http://api.example.com/users/":       10 * time.Minute,
+		"http://api.example.com/users/admins": time.Hour,
+	}
+	tests := map[string]time.Duration{
+		"http://other.example.com/todos":          time.Minute,
+		"http://api.example.com/todos?page=2":     time.Second,
+		"http://api.example.com/users/1":          10 * time.Minute,
+		"http://api.example.com/users/admins/1":   time.Hour,
+		"http://api.example.com/users/adminsonly": 10 * time.Minute,
+	}
+	for url, expected := range tests {
+		if ttl := client.cacheTTLFor(url); ttl != expected {
+			t.Errorf("Expected a TTL of %s for %s but got %s", expected, url, ttl)
+		}
+	}
+}
+
+func TestCacheChecksum(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	cache := NewMemoryCache()
+	client.Cache = cache
+	key := testRootURL + "/todos/1"
+	read := func() *testTodo {
+		todo := &testTodo{}
+		if err := client.Read("1", todo); err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	read()
+	data, found := cache.Get(key)
+	if !found {
+		t.Fatalf("Expected the response to be cached under %s", key)
+	}
+	// Change the body without updating the checksum
+	tampered := bytes.Replace(data, []byte(`"Title":"a"`), []byte(`"Title":"x"`), 1)
+	if bytes.Equal(tampered, data) {
+		t.Fatalf("Expected the cached entry to contain the title but got %s", data)
+	}
+	cache.Set(key, tampered)
+	if todo := read(); todo.Title != "a" {
+		t.Errorf("Expected a tampered entry to be ignored but got %+v", todo)
+	}
+	cache.Set(key, []byte("garbage"))
+	if todo := read(); todo.Title != "a" {
+		t.Errorf("Expected a corrupted entry to be ignored but got %+v", todo)
+	}
+	if got := server.count("GET"); got != 3 {
+		t.Errorf("Expected each bad entry to be fetched again but got %v", server.Requests())
+	}
+	if data, _ := cache.Get(key); !json.Valid(data) {
+		t.Errorf("Expected the bad entry to be replaced but got %q", data)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	cache := NewMemoryCache()
+	client.Cache = cache
+	client.Prefetch(context.Background(), &[]*testTodo{}, &testTodo{DefaultId: DefaultId{Id: "2"}})
+	// Prefetch runs in the background, so wait for both responses to be cached
+	for deadline := time.Now().Add(time.Second); cache.Len() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for prefetch; got %v", server.Requests())
+		}
+	}
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	todo := &testTodo{}
+	if err := client.Read("2", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "b" || len(todos) != 2 {
+		t.Errorf("Expected the prefetched responses but got %+v and %d todos", todo, len(todos))
+	}
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected only the 2 prefetch requests but got %v", server.Requests())
+	}
+}
+
+func TestMemoryCacheLimits(t *testing.T) {
+	cache := NewBoundedMemoryCache(CacheLimits{MaxEntries: 2})
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	cache.Get("a")
+	cache.Set("c", []byte("3"))
+	if _, found := cache.Get("b"); found {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if _, found := cache.Get("a"); !found {
+		t.Error("Expected the recently used entry to be kept")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries but got %d", cache.Len())
+	}
+}
+
+func TestMemoryCacheLFU(t *testing.T) {
+	cache := NewBoundedMemoryCache(CacheLimits{MaxEntries: 2, Eviction: EvictLFU})
+	cache.Set("a", []byte("1"))
+	cache.Get("a")
+	cache.Set("b", []byte("2"))
+	cache.Set("c", []byte("3"))
+	if _, found := cache.Get("a"); !found {
+		t.Error("Expected the most frequently used entry to be kept")
+	}
+	if _, found := cache.Get("b"); found {
+		t.Error("Expected the least frequently used entry to be evicted")
+	}
+}
+
+func TestMemoryCacheMaxBytes(t *testing.T) {
+	cache := NewBoundedMemoryCache(CacheLimits{MaxBytes: 10})
+	cache.Set("a", []byte("1234"))
+	cache.Set("b", []byte("1234"))
+	if cache.Size() != 10 || cache.Len() != 2 {
+		t.Errorf("Expected 2 entries with 10 bytes but got %d with %d bytes", cache.Len(), cache.Size())
+	}
+	cache.Set("b", []byte("12345"))
+	if _, found := cache.Get("a"); found || cache.Size() != 6 {
+		t.Errorf("Expected the oldest entry to be evicted when a value grows but got %d bytes", cache.Size())
+	}
+	cache.Set("c", []byte("12345678901"))
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Errorf("Expected an entry larger than the limit not to be kept but got %d entries", cache.Len())
+	}
+	cache.Set("d", []byte("1"))
+	cache.Delete("d")
+	if cache.Size() != 0 {
+		t.Errorf("Expected Delete to reduce the size but got %d", cache.Size())
+	}
+}
+
+func TestMemoryCacheCompact(t *testing.T) {
+	newTodoServer(t, "a", "b")
+	memory := NewMemoryCache()
+	client := NewClient()
+	client.Cache = memory
+	read := func(id string, ttl time.Duration) {
+		client.CacheTTL = ttl
+		if err := client.Read(id, &testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read("1", time.Millisecond)
+	read("2", time.Hour)
+	if err := client.ReadAll(&[]*testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	memory.Compact()
+	if _, found := memory.Get(testRootURL + "/todos/1"); found {
+		t.Error("Expected Compact to remove the expired entry")
+	}
+	if _, found := memory.Get(testRootURL + "/todos/2"); !found {
+		t.Error("Expected Compact to keep the fresh entry")
+	}
+
+	// Entries which cannot be decoded are left alone
+	memory = NewMemoryCache()
+	var store CacheStore = NewCompressedCache(memory, GzipCodec{}, 0)
+	client.Cache = store
+	read("1", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	store.(Compacter).Compact()
+	if memory.Len() != 1 {
+		t.Errorf("Expected the compressed entry to be kept but got %d entries", memory.Len())
+	}
+}
+
+// keyRecorder is a CacheStore which records the keys it is given.
+type keyRecorder struct {
+	*MemoryCache
+	keys []string
+}
+
+func (r *keyRecorder) Set(key string, value []byte) {
+	r.keys = append(r.keys, key)
+	r.MemoryCache.Set(key, value)
+}
+
+func TestCacheVary(t *testing.T) {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		json.NewEncoder(w).Encode(testTodo{DefaultId: DefaultId{Id: "1"}, Title: r.Header.Get("Accept-Language")})
+	})
+	client := NewClient()
+	cache := &keyRecorder{MemoryCache: NewMemoryCache()}
+	client.Cache = cache
+	client.CacheVary = []string{"accept-language", "Authorization"}
+	client.Header = http.Header{"Authorization": {"Bearer secret"}}
+	read := func(lang string) string {
+		client.Header.Set("Accept-Language", lang)
+		todo := &testTodo{}
+		if err := client.Read("1", todo); err != nil {
+			t.Fatal(err)
+		}
+		return todo.Title
+	}
+	for _, lang := range []string{"en", "de", "en", "de"} {
+		if title := read(lang); title != lang {
+			t.Errorf("Expected the variant for %s but got %s", lang, title)
+		}
+	}
+	if len(requests.all()) != 2 {
+		t.Errorf("Expected one request per variant but got %d", len(requests.all()))
+	}
+	if len(cache.keys) != 2 || cache.keys[0] == cache.keys[1] {
+		t.Errorf("Expected a cache key per variant but got %v", cache.keys)
+	}
+	for _, key := range cache.keys {
+		if strings.Contains(key, "secret") {
+			t.Errorf("Expected the header values to be hashed but got the key %s", key)
+		}
+	}
+
+	// Invalidation removes the variant for the current headers
+	if err := client.Update(&testTodo{DefaultId: DefaultId{Id: "1"}, Title: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	read("de")
+	if len(requests.all()) != 4 {
+		t.Errorf("Expected the current variant to be invalidated by Update but got %d requests", len(requests.all()))
+	}
+}
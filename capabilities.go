@@ -0,0 +1,148 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Capabilities describes what the server allows for a resource, as discovered
+// by Client.Capabilities. It can be used to toggle UI affordances, e.g. to hide
+// a delete button when DELETE is not allowed.
+type Capabilities struct {
+	// Methods are the http methods listed in the Allow header of the
+	// response, in upper case.
+	Methods []string
+	// Features are the values listed in the client's CapabilitiesHeader
+	// (X-Capabilities by default), e.g. "export" or "bulk-delete".
+	Features []string
+	// Header holds all the headers of the response, for APIs which describe
+	// their capabilities in other headers (e.g. Accept-Patch).
+	Header http.Header
+	// expires is when the capabilities should be discovered again. It is zero
+	// if they never expire.
+	expires time.Time
+}
+
+// Allows returns true iff method is one of the allowed methods.
+func (caps Capabilities) Allows(method string) bool {
+	for _, allowed := range caps.Methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports returns true iff feature is one of the features listed by the
+// server. Features are compared case-insensitively.
+func (caps Capabilities) Supports(feature string) bool {
+	for _, supported := range caps.Features {
+		if strings.EqualFold(supported, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities discovers what the server allows for the resource of model by
+// sending an OPTIONS request to model.RootURL() and parsing the Allow header
+// and the client's CapabilitiesHeader of the response. The result is cached
+// per root url for CapabilitiesTTL (forever if it is zero), so it is cheap to
+// call whenever a view is rendered. Use ForgetCapabilities to discover them
+// again.
+func (c *Client) Capabilities(model Model, opts ...RequestOption) (Capabilities, error) {
+	rootURL, err := normalizeRootURL(model.RootURL())
+	if err != nil {
+		return Capabilities{}, err
+	}
+	key := c.expandVars(rootURL)
+	c.mut.RLock()
+	caps, found := c.capabilities[key]
+	c.mut.RUnlock()
+	if found && (caps.expires.IsZero() || time.Now().Before(caps.expires)) {
+		return caps, nil
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	req, err := http.NewRequest("OPTIONS", rootURL, nil)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("Something went wrong building OPTIONS request to %s: %s", rootURL, err.Error())
+	}
+	res, err := c.do(req.WithContext(reqOpts.context()))
+	if err != nil {
+		return Capabilities{}, err
+	}
+	defer res.Body.Close()
+	if !successful(res) {
+		return Capabilities{}, newHTTPError(res)
+	}
+	ioutil.ReadAll(res.Body)
+	caps = Capabilities{
+		Methods:  headerList(res.Header, "Allow"),
+		Features: headerList(res.Header, c.capabilitiesHeader()),
+		Header:   res.Header,
+	}
+	for i, method := range caps.Methods {
+		caps.Methods[i] = strings.ToUpper(method)
+	}
+	if c.CapabilitiesTTL > 0 {
+		caps.expires = time.Now().Add(c.CapabilitiesTTL)
+	}
+	c.mut.Lock()
+	if c.capabilities == nil {
+		c.capabilities = map[string]Capabilities{}
+	}
+	c.capabilities[key] = caps
+	c.mut.Unlock()
+	return caps, nil
+}
+
+// ForgetCapabilities removes the cached capabilities of the given models, or
+// of all resources if no models are given, so that they are discovered again
+// the next time Capabilities is called.
+func (c *Client) ForgetCapabilities(models ...Model) {
+	keys := []string{}
+	for _, model := range models {
+		if rootURL, err := normalizeRootURL(model.RootURL()); err == nil {
+			keys = append(keys, c.expandVars(rootURL))
+		}
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if len(models) == 0 {
+		c.capabilities = nil
+		return
+	}
+	for _, key := range keys {
+		delete(c.capabilities, key)
+	}
+}
+
+// capabilitiesHeader returns the name of the header which lists the features
+// of a resource.
+func (c *Client) capabilitiesHeader() string {
+	if c.CapabilitiesHeader == "" {
+		return "X-Capabilities"
+	}
+	return c.CapabilitiesHeader
+}
+
+// headerList returns the comma-separated values of the header with the given
+// name, which may be repeated.
+func headerList(header http.Header, name string) []string {
+	list := []string{}
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,88 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newCapabilitiesServer(t *testing.T) *requestLog {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		if r.URL.Path != "/todos" {
+			http.Error(w, "not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Allow", "get, POST,  ,PATCH")
+		w.Header().Add("Allow", "OPTIONS")
+		w.Header().Set("X-Features", "export, Bulk-Delete")
+		w.Header().Set("Accept-Patch", "application/merge-patch+json")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return requests
+}
+
+func TestCapabilities(t *testing.T) {
+	requests := newCapabilitiesServer(t)
+	client := NewClient()
+	client.CapabilitiesHeader = "X-Features"
+	caps, err := client.Capabilities(&testTodo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := requests.last(); req.Method != "OPTIONS" || req.URL.Path != "/todos" {
+		t.Errorf("Expected OPTIONS /todos but got %s %s", req.Method, req.URL.Path)
+	}
+	if !reflect.DeepEqual(caps.Methods, []string{"GET", "POST", "PATCH", "OPTIONS"}) {
+		t.Errorf("Expected the methods in the Allow headers but got %v", caps.Methods)
+	}
+	if !caps.Allows("patch") || caps.Allows("DELETE") {
+		t.Errorf("Expected PATCH to be allowed and DELETE not to be allowed by %v", caps.Methods)
+	}
+	if !caps.Supports("bulk-delete") || caps.Supports("import") {
+		t.Errorf("Expected bulk-delete to be supported and import not to be supported by %v", caps.Features)
+	}
+	if caps.Header.Get("Accept-Patch") != "application/merge-patch+json" {
+		t.Errorf("Expected the headers of the response to be kept but got %v", caps.Header)
+	}
+
+	client.Capabilities(&testTodo{})
+	if n := len(requests.all()); n != 1 {
+		t.Errorf("Expected the capabilities to be cached but got %d requests", n)
+	}
+	client.ForgetCapabilities(&testTodo{})
+	client.Capabilities(&testTodo{})
+	client.ForgetCapabilities()
+	client.Capabilities(&testTodo{})
+	if n := len(requests.all()); n != 3 {
+		t.Errorf("Expected ForgetCapabilities to cause new requests but got %d requests", n)
+	}
+
+	client.CapabilitiesTTL = time.Millisecond
+	client.ForgetCapabilities()
+	client.Capabilities(&testTodo{})
+	time.Sleep(5 * time.Millisecond)
+	client.Capabilities(&testTodo{})
+	if n := len(requests.all()); n != 5 {
+		t.Errorf("Expected the capabilities to expire after CapabilitiesTTL but got %d requests", n)
+	}
+}
+
+func TestCapabilitiesError(t *testing.T) {
+	requests := newCapabilitiesServer(t)
+	client := NewClient()
+	_, err := client.Capabilities(&tenantTodo{})
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected an HTTPError with status 405 but got %v", err)
+	}
+	client.Capabilities(&tenantTodo{})
+	if n := len(requests.all()); n != 2 {
+		t.Errorf("Expected errors not to be cached but got %d requests", n)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+)
+
+// ClientOption configures a client constructed with NewClient. Client options
+// make it possible to construct a fully configured client in a single call:
+//
+//	client := rest.NewClient(
+//		rest.WithBaseURL("https://api.example.com"),
+//		rest.WithContentType(rest.ContentJSON),
+//	)
+//
+// Setting the exported fields of Client directly works just as well.
+type ClientOption func(*Client)
+
+// WithContentType returns a ClientOption which sets the ContentType of the
+// client.
+func WithContentType(contentType ContentType) ClientOption {
+	return func(c *Client) {
+		c.ContentType = contentType
+	}
+}
+
+// WithBaseURL returns a ClientOption which sets the BaseURL of the client.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithHTTPClient returns a ClientOption which sets the http.Client used to
+// send requests.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithHeader returns a ClientOption which adds a header to every request sent
+// by the client. See Client.Header.
+func WithHeader(key string, value string) ClientOption {
+	return func(c *Client) {
+		if c.Header == nil {
+			c.Header = http.Header{}
+		}
+		c.Header.Add(key, value)
+	}
+}
+
+// WithVar returns a ClientOption which sets a template variable. See
+// Client.SetVar.
+func WithVar(name string, value string) ClientOption {
+	return func(c *Client) {
+		c.SetVar(name, value)
+	}
+}
+
+// WithRetry returns a ClientOption which sets the RetryPolicy of the client.
+func WithRetry(policy *RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithTransport returns a ClientOption which sets the Transport used to send
+// requests.
+func WithTransport(transport Transport) ClientOption {
+	return func(c *Client) {
+		c.Transport = transport
+	}
+}
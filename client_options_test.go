@@ -0,0 +1,67 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	transportUsed := false
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			transportUsed = true
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	retry := &RetryPolicy{MaxAttempts: 3}
+	client := NewClient(
+		WithBaseURL(testRootURL+"/{version}"),
+		WithContentType(ContentJSON),
+		WithHTTPClient(httpClient),
+		WithHeader("X-App", "test"),
+		WithHeader("X-App", "other"),
+		WithVar("version", "v1"),
+		WithRetry(retry),
+	)
+	if client.Retry != retry || client.HTTPClient != httpClient {
+		t.Errorf("Expected the options to set Retry and HTTPClient but got %v and %v", client.Retry, client.HTTPClient)
+	}
+
+	if err := client.Create(&relativeTodo{Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	req := server.last()
+	if req.URL.Path != "/v1/todos" {
+		t.Errorf("Expected the request to be sent to /v1/todos but got %s", req.URL.Path)
+	}
+	if got := req.Header["X-App"]; len(got) != 2 || got[0] != "test" || got[1] != "other" {
+		t.Errorf("Expected both X-App headers to be sent but got %v", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != string(ContentJSON) {
+		t.Errorf("Expected a Content-Type of %s but got %s", ContentJSON, got)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(server.lastBody()), &fields); err != nil || fields["Title"] != "a" {
+		t.Errorf("Expected a JSON body but got %s", server.lastBody())
+	}
+	if !transportUsed {
+		t.Error("Expected the request to be sent with the given http.Client")
+	}
+}
+
+func TestNewClientOptionsOrder(t *testing.T) {
+	client := NewClient(WithContentType(ContentJSON), WithContentType(ContentURLEncoded))
+	if client.ContentType != ContentURLEncoded {
+		t.Errorf("Expected the last option to win but got %s", client.ContentType)
+	}
+	defaults := NewClient()
+	if defaults.ContentType != ContentURLEncoded || defaults.Header != nil {
+		t.Errorf("Expected NewClient without options to use the defaults but got %s and %v", defaults.ContentType, defaults.Header)
+	}
+}
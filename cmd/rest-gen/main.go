@@ -0,0 +1,365 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+// Command rest-gen generates rest.FastCodec implementations, which url-encode
+// and decode models without reflection, for flat struct types. It is meant to
+// be used with go generate:
+//
+//	//go:generate rest-gen -type Todo,User
+//
+// The generated file registers a codec for a pointer to each type. Supported
+// fields are exported fields of type string, bool, or any of the built-in
+// integer and float types, and embedded rest.DefaultId or rest.DefaultIntId.
+// Fields tagged with `json:"-"` are not decoded and fields tagged with
+// `rest:"-"` are not url-encoded, just like with the reflection-based path.
+// Types which cannot be handled are reported as errors, so that it is never
+// necessary to check the generated code by hand.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const restPath = "github.com/go-humble/rest"
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; required")
+	output    = flag.String("output", "rest_gen.go", "output file name, relative to the package directory")
+)
+
+// field is a field of a model which the generated codec handles.
+type field struct {
+	// goPath is the selector for the field, e.g. "Title" or "DefaultId.Id".
+	goPath string
+	// urlName is the key used in url-encoded data, or "" if the field is not
+	// url-encoded.
+	urlName string
+	// jsonName is the key used in JSON data, or "" if the field is not
+	// decoded.
+	jsonName string
+	// kind is the name of the built-in type of the field, e.g. "int32".
+	kind string
+	// goType is the type of the field as written in the generated code.
+	goType string
+}
+
+func main() {
+	log := func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "rest-gen: "+format+"\n", args...)
+		os.Exit(1)
+	}
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	src, err := generate(dir, strings.Split(*typeNames, ","))
+	if err != nil {
+		log("%s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, *output), src, 0644); err != nil {
+		log("%s", err)
+	}
+}
+
+// generate returns the source of a file which registers a FastCodec for each
+// of the named types in the package in dir.
+func generate(dir string, names []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != *output
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package in %s but found %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rest-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg.Name)
+	fmt.Fprintf(&buf, "import \"github.com/go-humble/rest\"\n\n")
+	fmt.Fprintf(&buf, "func init() {\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\trest.RegisterFastCodec(&%s{}, %s{})\n", name, codecName(name))
+	}
+	fmt.Fprintf(&buf, "}\n")
+	for _, name := range names {
+		spec, file := findType(pkg, name)
+		if spec == nil {
+			return nil, fmt.Errorf("type %s not found in package %s", name, pkg.Name)
+		}
+		structType, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		fields, err := structFields(name, structType, restImportName(file))
+		if err != nil {
+			return nil, err
+		}
+		writeCodec(&buf, name, fields)
+	}
+	return format.Source(buf.Bytes())
+}
+
+// findType returns the declaration of the type with the given name in pkg,
+// and the file which contains it.
+func findType(pkg *ast.Package, name string) (*ast.TypeSpec, *ast.File) {
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec := spec.(*ast.TypeSpec); typeSpec.Name.Name == name {
+					return typeSpec, file
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// restImportName returns the name under which file imports the rest package,
+// or "" if it does not import it.
+func restImportName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == restPath {
+			if spec.Name != nil {
+				return spec.Name.Name
+			}
+			return "rest"
+		}
+	}
+	return ""
+}
+
+// structFields returns the fields of the struct type with the given name, in
+// the order of their declaration, or an error if any of them cannot be
+// handled by a generated codec.
+func structFields(name string, structType *ast.StructType, restName string) ([]field, error) {
+	var fields []field
+	seen := map[string]bool{}
+	for _, f := range structType.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			unquoted, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(unquoted)
+		}
+		if len(f.Names) == 0 {
+			embedded, err := embeddedFields(name, f.Type, restName)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		for _, fieldName := range f.Names {
+			if !ast.IsExported(fieldName.Name) {
+				// Unexported fields are neither encoded nor decoded
+				continue
+			}
+			ident, ok := f.Type.(*ast.Ident)
+			if !ok || !isBasic(ident.Name) {
+				return nil, fmt.Errorf("field %s.%s has unsupported type %s", name, fieldName.Name, exprString(f.Type))
+			}
+			fld := field{goPath: fieldName.Name, kind: ident.Name, goType: ident.Name}
+			if restName, _ := splitTag(tag.Get("rest")); restName != "-" {
+				fld.urlName = fieldName.Name
+			}
+			jsonName, opts := splitTag(tag.Get("json"))
+			if opts != "" && hasOption(opts, "string") {
+				return nil, fmt.Errorf("field %s.%s uses the unsupported json option \"string\"", name, fieldName.Name)
+			}
+			switch {
+			case jsonName == "-" && opts == "":
+			case jsonName == "":
+				fld.jsonName = fieldName.Name
+			default:
+				fld.jsonName = jsonName
+			}
+			fields = append(fields, fld)
+		}
+	}
+	for _, fld := range fields {
+		for _, key := range []string{"url:" + fld.urlName, "json:" + fld.jsonName} {
+			if strings.HasSuffix(key, ":") {
+				continue
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("type %s has more than one field named %s", name, key[strings.Index(key, ":")+1:])
+			}
+			seen[key] = true
+		}
+	}
+	return fields, nil
+}
+
+// embeddedFields returns the fields of the embedded field of the given type,
+// which must be rest.DefaultId or rest.DefaultIntId.
+func embeddedFields(name string, typ ast.Expr, restName string) ([]field, error) {
+	if sel, ok := typ.(*ast.SelectorExpr); ok && restName != "" {
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == restName {
+			switch sel.Sel.Name {
+			case "DefaultId":
+				return []field{{goPath: "DefaultId.Id", urlName: "Id", jsonName: "Id", kind: "string", goType: "string"}}, nil
+			case "DefaultIntId":
+				return []field{{goPath: "DefaultIntId.Id", urlName: "Id", jsonName: "Id", kind: "int64", goType: "int64"}}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("type %s embeds the unsupported type %s", name, exprString(typ))
+}
+
+// writeCodec writes the codec type for the named model type to buf.
+func writeCodec(buf *bytes.Buffer, name string, fields []field) {
+	codec := codecName(name)
+	var jsonFields []field
+	for _, fld := range fields {
+		if fld.jsonName != "" {
+			jsonFields = append(jsonFields, fld)
+		}
+	}
+	fmt.Fprintf(buf, "\n// %s is the rest.FastCodec for *%s.\ntype %s struct{}\n\n", codec, name, codec)
+	fmt.Fprintf(buf, "var %sJSONKeys = []string{", codec)
+	for i, fld := range jsonFields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "%q", fld.jsonName)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+
+	fmt.Fprintf(buf, "func (%s) AppendURLEncoded(dst []byte, model rest.Model) ([]byte, error) {\n", codec)
+	// url.Values.Encode sorts the fields by name, and so must the codec
+	urlFields := append([]field(nil), fields...)
+	sort.SliceStable(urlFields, func(i, j int) bool {
+		return urlFields[i].urlName < urlFields[j].urlName
+	})
+	fmt.Fprintf(buf, "\tm := model.(*%s)\n", name)
+	for _, fld := range urlFields {
+		if fld.urlName != "" && strings.HasPrefix(fld.kind, "float") {
+			fmt.Fprintf(buf, "\tvar err error\n")
+			break
+		}
+	}
+	for _, fld := range urlFields {
+		if fld.urlName == "" {
+			continue
+		}
+		switch {
+		case fld.kind == "string":
+			fmt.Fprintf(buf, "\tdst = rest.AppendURLString(dst, %q, string(m.%s))\n", fld.urlName, fld.goPath)
+		case fld.kind == "bool":
+			fmt.Fprintf(buf, "\tdst = rest.AppendURLBool(dst, %q, bool(m.%s))\n", fld.urlName, fld.goPath)
+		case strings.HasPrefix(fld.kind, "float"):
+			fmt.Fprintf(buf, "\tif dst, err = rest.AppendURLFloat(dst, %q, float64(m.%s), %d); err != nil {\n\t\treturn dst, err\n\t}\n", fld.urlName, fld.goPath, bits(fld.kind))
+		case strings.HasPrefix(fld.kind, "uint"):
+			fmt.Fprintf(buf, "\tdst = rest.AppendURLUint(dst, %q, uint64(m.%s))\n", fld.urlName, fld.goPath)
+		default:
+			fmt.Fprintf(buf, "\tdst = rest.AppendURLInt(dst, %q, int64(m.%s))\n", fld.urlName, fld.goPath)
+		}
+	}
+	fmt.Fprintf(buf, "\treturn dst, nil\n}\n\n")
+
+	fmt.Fprintf(buf, "func (%s) DecodeJSON(data []byte, model rest.Model) error {\n", codec)
+	if len(jsonFields) == 0 {
+		fmt.Fprintf(buf, "\treturn rest.ScanJSONObject(data, func(key, value []byte) error { return nil })\n}\n")
+		return
+	}
+	fmt.Fprintf(buf, "\tm := model.(*%s)\n", name)
+	fmt.Fprintf(buf, "\treturn rest.ScanJSONObject(data, func(key, value []byte) error {\n")
+	fmt.Fprintf(buf, "\t\tif rest.IsJSONNull(value) {\n\t\t\treturn nil\n\t\t}\n")
+	fmt.Fprintf(buf, "\t\tswitch rest.FoldJSONKey(key, %sJSONKeys) {\n", codec)
+	for i, fld := range jsonFields {
+		fmt.Fprintf(buf, "\t\tcase %d:\n", i)
+		switch {
+		case fld.kind == "string":
+			fmt.Fprintf(buf, "\t\t\tv, err := rest.DecodeJSONString(value)\n")
+		case fld.kind == "bool":
+			fmt.Fprintf(buf, "\t\t\tv, err := rest.DecodeJSONBool(value)\n")
+		case strings.HasPrefix(fld.kind, "float"):
+			fmt.Fprintf(buf, "\t\t\tv, err := rest.DecodeJSONFloat(value, %d)\n", bits(fld.kind))
+		case strings.HasPrefix(fld.kind, "uint"):
+			fmt.Fprintf(buf, "\t\t\tv, err := rest.DecodeJSONUint(value, %d)\n", bits(fld.kind))
+		default:
+			fmt.Fprintf(buf, "\t\t\tv, err := rest.DecodeJSONInt(value, %d)\n", bits(fld.kind))
+		}
+		fmt.Fprintf(buf, "\t\t\tif err != nil {\n\t\t\t\treturn err\n\t\t\t}\n")
+		fmt.Fprintf(buf, "\t\t\tm.%s = %s(v)\n", fld.goPath, fld.goType)
+	}
+	fmt.Fprintf(buf, "\t\t}\n\t\treturn nil\n\t})\n}\n")
+}
+
+// codecName returns the name of the codec type for the named model type.
+func codecName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes) + "Codec"
+}
+
+// isBasic returns true iff name is a built-in type supported by the codecs.
+func isBasic(name string) bool {
+	switch name {
+	case "string", "bool", "float32", "float64",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+// bits returns the size in bits of the built-in numeric type with the given
+// name, or 0 for int and uint.
+func bits(kind string) int {
+	n, _ := strconv.Atoi(strings.TrimLeft(kind, "aflintou"))
+	return n
+}
+
+// splitTag splits a struct tag value into its name and options.
+func splitTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// hasOption returns true iff the comma-separated opts contain name.
+func hasOption(opts string, name string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// exprString returns the source of a type expression for error messages.
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
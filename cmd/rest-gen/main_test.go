@@ -0,0 +1,92 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePackage writes a package with a single file holding src to a new
+// temporary directory and returns the directory.
+func writePackage(t *testing.T, src string) string {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writePackage(t, `package app
+
+import r "github.com/go-humble/rest"
+
+type Todo struct {
+	r.DefaultId
+	Title    string
+	Done     bool `+"`json:\"done\"`"+`
+	Priority int8
+	Estimate float32
+	Secret   string `+"`rest:\"-\"`"+`
+	Ignored  string `+"`json:\"-\"`"+`
+	internal string
+}
+
+type User struct {
+	r.DefaultIntId
+	Name string
+}
+`)
+	src, err := generate(dir, []string{"Todo", "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "rest_gen.go", src, 0); err != nil {
+		t.Fatalf("Expected valid Go code but got %v:\n%s", err, src)
+	}
+	code := string(src)
+	for _, expected := range []string{
+		"package app\n",
+		"rest.RegisterFastCodec(&Todo{}, todoCodec{})",
+		"rest.RegisterFastCodec(&User{}, userCodec{})",
+		`var todoCodecJSONKeys = []string{"Id", "Title", "done", "Priority", "Estimate", "Secret"}`,
+		`rest.AppendURLFloat(dst, "Estimate", float64(m.Estimate), 32)`,
+		`rest.DecodeJSONInt(value, 8)`,
+		`m.DefaultId.Id = string(v)`,
+		`m.DefaultIntId.Id = int64(v)`,
+		// Like the reflective path, json:"-" only affects decoding
+		`rest.AppendURLString(dst, "Ignored", string(m.Ignored))`,
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected the generated code to contain %q:\n%s", expected, code)
+		}
+	}
+	for _, unexpected := range []string{`"Secret", string(m.Secret)`, "m.Ignored = ", "m.internal"} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("Expected the generated code not to contain %q", unexpected)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := map[string]string{
+		"unsupported type []string": "type Todo struct {\n\tTags []string\n}\n",
+		"unsupported json option":   "type Todo struct {\n\tN int `json:\",string\"`\n}\n",
+		"embeds the unsupported":    "type Base struct{}\n\ntype Todo struct {\n\tBase\n}\n",
+		"is not a struct":           "type Todo string\n",
+		"not found":                 "type User struct{}\n",
+	}
+	for expected, src := range tests {
+		dir := writePackage(t, "package app\n\n"+src)
+		if _, err := generate(dir, []string{"Todo"}); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %q for\n%s\nbut got %v", expected, src, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DiffKind is the kind of a Difference found by Compare.
+type DiffKind string
+
+const (
+	// DiffRequests means the operation sent a different number of requests.
+	// A and B are the numbers of requests.
+	DiffRequests DiffKind = "requests"
+	// DiffError means the operation failed for only one of the clients. A and
+	// B are the error messages ("" for none).
+	DiffError DiffKind = "error"
+	// DiffStatus means the responses have different status codes.
+	DiffStatus DiffKind = "status"
+	// DiffHeader means the responses have different values for the header
+	// named by Path. A and B are the values of the header.
+	DiffHeader DiffKind = "header"
+	// DiffBody means the decoded bodies of the responses differ at the JSON
+	// pointer given by Path (e.g. "/todos/0/title"). A and B are the values
+	// at that location, or nil if there is none.
+	DiffBody DiffKind = "body"
+)
+
+// Difference is a single difference between the results of running an
+// operation against two clients.
+type Difference struct {
+	// Kind is the kind of the difference
+	Kind DiffKind
+	// Request is the index of the request (within the requests sent by the
+	// operation) whose responses differ. It is -1 for DiffRequests and
+	// DiffError.
+	Request int
+	// Path is the name of the header for DiffHeader or the JSON pointer to the
+	// differing value for DiffBody. It is empty otherwise.
+	Path string
+	// A is the value for the first client
+	A interface{}
+	// B is the value for the second client
+	B interface{}
+}
+
+// String returns a human-readable description of the difference.
+func (d Difference) String() string {
+	if d.Path != "" {
+		return fmt.Sprintf("%s %s of request %d: %v != %v", d.Kind, d.Path, d.Request, d.A, d.B)
+	}
+	if d.Request >= 0 {
+		return fmt.Sprintf("%s of request %d: %v != %v", d.Kind, d.Request, d.A, d.B)
+	}
+	return fmt.Sprintf("%s: %v != %v", d.Kind, d.A, d.B)
+}
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	// Differences holds every difference that was found, in order.
+	Differences []Difference
+	// ErrA is the error returned by the operation for the first client
+	ErrA error
+	// ErrB is the error returned by the operation for the second client
+	ErrB error
+}
+
+// Equal returns true iff no differences were found.
+func (cmp Comparison) Equal() bool {
+	return len(cmp.Differences) == 0
+}
+
+// DefaultIgnoredHeaders are the headers Compare ignores unless a Comparer
+// specifies others. They usually differ between any two responses.
+var DefaultIgnoredHeaders = []string{"Age", "Content-Length", "Date", "Server", "Set-Cookie", "Via", "X-Request-Id"}
+
+// Comparer configures how the responses to an operation are compared. The zero
+// value is ready to use.
+type Comparer struct {
+	// IgnoreHeaders are the names of the headers which are not compared. If it
+	// is nil, DefaultIgnoredHeaders is used.
+	IgnoreHeaders []string
+	// IgnorePaths are JSON pointers (e.g. "/updatedAt" or "/0/id") to values
+	// in the bodies which are not compared, along with everything beneath
+	// them.
+	IgnorePaths []string
+}
+
+// Compare runs op against a and b and returns the differences between the
+// responses to the requests it sent. It is shorthand for Comparer{}.Compare.
+func Compare(a *Client, b *Client, op func(c *Client) error) Comparison {
+	return Comparer{}.Compare(a, b, op)
+}
+
+// Compare runs op once with a child of a and once with a child of b (see
+// Client.Scope), and returns the differences between the status codes,
+// headers, and JSON bodies of the responses to the requests op sent. The
+// requests are matched in the order they were sent. It can be used to verify
+// that a rewritten API (or a client configured for a different dialect)
+// behaves the same as the original one:
+//
+//	cmp := rest.Compare(oldClient, newClient, func(c *rest.Client) error {
+//		return c.ReadAll(&[]*Todo{})
+//	})
+//	if !cmp.Equal() {
+//		log.Println(cmp.Differences)
+//	}
+//
+// Note that responses served from the cache of a client are not compared.
+func (comparer Comparer) Compare(a *Client, b *Client, op func(c *Client) error) Comparison {
+	exchangesA, errA := recordExchanges(a, op)
+	exchangesB, errB := recordExchanges(b, op)
+	cmp := Comparison{ErrA: errA, ErrB: errB}
+	if (errA == nil) != (errB == nil) {
+		cmp.add(Difference{Kind: DiffError, Request: -1, A: errorMessage(errA), B: errorMessage(errB)})
+	}
+	if len(exchangesA) != len(exchangesB) {
+		cmp.add(Difference{Kind: DiffRequests, Request: -1, A: len(exchangesA), B: len(exchangesB)})
+	}
+	for i := 0; i < len(exchangesA) && i < len(exchangesB); i++ {
+		comparer.compareExchange(&cmp, i, exchangesA[i], exchangesB[i])
+	}
+	return cmp
+}
+
+// add appends d to the differences of cmp.
+func (cmp *Comparison) add(d Difference) {
+	cmp.Differences = append(cmp.Differences, d)
+}
+
+// compareExchange adds the differences between the responses a and b to the
+// request with the given index to cmp.
+func (comparer Comparer) compareExchange(cmp *Comparison, request int, a exchange, b exchange) {
+	if a.statusCode != b.statusCode {
+		cmp.add(Difference{Kind: DiffStatus, Request: request, A: a.statusCode, B: b.statusCode})
+	}
+	ignored := comparer.IgnoreHeaders
+	if ignored == nil {
+		ignored = DefaultIgnoredHeaders
+	}
+	names := []string{}
+	for name := range a.header {
+		names = append(names, name)
+	}
+	for name := range b.header {
+		if _, found := a.header[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if containsFold(ignored, name) {
+			continue
+		}
+		valueA, valueB := strings.Join(a.header[name], ", "), strings.Join(b.header[name], ", ")
+		if valueA != valueB {
+			cmp.add(Difference{Kind: DiffHeader, Request: request, Path: name, A: valueA, B: valueB})
+		}
+	}
+	var bodyA, bodyB interface{}
+	if json.Unmarshal(a.body, &bodyA) != nil || json.Unmarshal(b.body, &bodyB) != nil {
+		if !bytes.Equal(bytes.TrimSpace(a.body), bytes.TrimSpace(b.body)) {
+			cmp.add(Difference{Kind: DiffBody, Request: request, Path: "", A: string(a.body), B: string(b.body)})
+		}
+		return
+	}
+	comparer.compareValues(cmp, request, "", bodyA, bodyB)
+}
+
+// compareValues adds the differences between the decoded JSON values a and b,
+// found at the JSON pointer path, to cmp.
+func (comparer Comparer) compareValues(cmp *Comparison, request int, path string, a interface{}, b interface{}) {
+	for _, ignored := range comparer.IgnorePaths {
+		if path == ignored {
+			return
+		}
+	}
+	switch valueA := a.(type) {
+	case map[string]interface{}:
+		if valueB, ok := b.(map[string]interface{}); ok {
+			keys := []string{}
+			for key := range valueA {
+				keys = append(keys, key)
+			}
+			for key := range valueB {
+				if _, found := valueA[key]; !found {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				comparer.compareValues(cmp, request, path+"/"+escapePointer(key), valueA[key], valueB[key])
+			}
+			return
+		}
+	case []interface{}:
+		if valueB, ok := b.([]interface{}); ok {
+			for i := 0; i < len(valueA) || i < len(valueB); i++ {
+				var elemA, elemB interface{}
+				if i < len(valueA) {
+					elemA = valueA[i]
+				}
+				if i < len(valueB) {
+					elemB = valueB[i]
+				}
+				comparer.compareValues(cmp, request, fmt.Sprintf("%s/%d", path, i), elemA, elemB)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		cmp.add(Difference{Kind: DiffBody, Request: request, Path: path, A: a, B: b})
+	}
+}
+
+// escapePointer escapes key for use as a reference token in a JSON pointer.
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// exchange is a response recorded by recordExchanges.
+type exchange struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// recordExchanges runs op with a child of c and returns the responses to the
+// requests it sent, in order, along with the error returned by op.
+func recordExchanges(c *Client, op func(c *Client) error) ([]exchange, error) {
+	var mut sync.Mutex
+	exchanges := []exchange{}
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				return res, err
+			}
+			body, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			res.Body = ioutil.NopCloser(bytes.NewReader(body))
+			mut.Lock()
+			exchanges = append(exchanges, exchange{
+				statusCode: res.StatusCode,
+				header:     res.Header.Clone(),
+				body:       body,
+			})
+			mut.Unlock()
+			return res, nil
+		})
+	}
+	err := op(c.Scope(ScopeMiddleware(record)))
+	mut.Lock()
+	defer mut.Unlock()
+	return exchanges, err
+}
+
+// roundTripperFunc is an adapter to allow the use of ordinary functions as
+// http.RoundTrippers.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip satisfies http.RoundTripper.
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// errorMessage returns the message of err, or "" if err is nil.
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,104 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// newComparedClients starts a server which acts as two backends, selected by
+// the X-Backend header, and returns a client for each of them. The backends
+// agree about the todo with id 1 apart from the ignored headers, and disagree
+// about the todo with id 2 and the collection.
+func newComparedClients(t *testing.T) (*Client, *Client) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		backend := r.Header.Get("X-Backend")
+		w.Header().Set("X-Request-Id", backend)
+		switch r.URL.Path {
+		case "/todos/1":
+			w.Write([]byte(`{"Id": "1", "Title": "a", "Tags": ["x"]}`))
+		case "/todos/2":
+			w.Header().Set("X-Version", backend)
+			if backend == "a" {
+				w.Write([]byte(`{"Id": "2", "Title": "b", "Tags": ["x", "y"]}`))
+			} else {
+				w.Write([]byte(`{"Id": "2", "Title": "B", "Tags": ["x"], "Extra/Field": 1}`))
+			}
+		case "/todos":
+			if backend == "a" {
+				w.Write([]byte(`[]`))
+			} else {
+				http.Error(w, "not implemented", http.StatusNotImplemented)
+			}
+		}
+	})
+	a, b := NewClient(), NewClient()
+	a.Header = http.Header{"X-Backend": {"a"}}
+	b.Header = http.Header{"X-Backend": {"b"}}
+	return a, b
+}
+
+func TestCompare(t *testing.T) {
+	a, b := newComparedClients(t)
+	cmp := Compare(a, b, func(c *Client) error {
+		return c.Read("1", &testTodo{})
+	})
+	if !cmp.Equal() || cmp.ErrA != nil || cmp.ErrB != nil {
+		t.Errorf("Expected no differences but got %v", cmp.Differences)
+	}
+
+	cmp = Compare(a, b, func(c *Client) error {
+		if err := c.Read("1", &testTodo{}); err != nil {
+			return err
+		}
+		return c.Read("2", &testTodo{})
+	})
+	expected := []Difference{
+		{Kind: DiffHeader, Request: 1, Path: "X-Version", A: "a", B: "b"},
+		{Kind: DiffBody, Request: 1, Path: "/Extra~1Field", A: nil, B: 1.0},
+		{Kind: DiffBody, Request: 1, Path: "/Tags/1", A: "y", B: nil},
+		{Kind: DiffBody, Request: 1, Path: "/Title", A: "b", B: "B"},
+	}
+	if !reflect.DeepEqual(cmp.Differences, expected) {
+		t.Errorf("Expected %v but got %v", expected, cmp.Differences)
+	}
+	if got := expected[3].String(); got != "body /Title of request 1: b != B" {
+		t.Errorf("Unexpected description %q", got)
+	}
+
+	comparer := Comparer{IgnoreHeaders: []string{"x-version", "X-Request-Id", "Date", "Content-Length", "Content-Type"}, IgnorePaths: []string{"/Tags", "/Extra~1Field"}}
+	cmp = comparer.Compare(a, b, func(c *Client) error {
+		return c.Read("2", &testTodo{})
+	})
+	if len(cmp.Differences) != 1 || cmp.Differences[0].Path != "/Title" {
+		t.Errorf("Expected only the title to differ but got %v", cmp.Differences)
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	a, b := newComparedClients(t)
+	cmp := Compare(a, b, func(c *Client) error {
+		return c.ReadAll(&[]*testTodo{})
+	})
+	if cmp.ErrA != nil || cmp.ErrB == nil {
+		t.Fatalf("Expected only the second client to fail but got %v and %v", cmp.ErrA, cmp.ErrB)
+	}
+	if len(cmp.Differences) < 2 || cmp.Differences[0].Kind != DiffError || cmp.Differences[1].Kind != DiffStatus {
+		t.Errorf("Expected an error and a status difference but got %v", cmp.Differences)
+	}
+
+	cmp = Compare(a, b, func(c *Client) error {
+		if c.Header.Get("X-Backend") == "a" {
+			return c.Read("1", &testTodo{})
+		}
+		return nil
+	})
+	expected := Difference{Kind: DiffRequests, Request: -1, A: 1, B: 0}
+	if len(cmp.Differences) != 1 || cmp.Differences[0] != expected {
+		t.Errorf("Expected a difference in the number of requests but got %v", cmp.Differences)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"sync"
+)
+
+// Codec compresses and decompresses cache entries for CompressedCache.
+type Codec interface {
+	// Compress returns the compressed form of data.
+	Compress(data []byte) ([]byte, error)
+	// Decompress reverses Compress.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCodec is a Codec which uses gzip. The zero value uses the default
+// compression level.
+type GzipCodec struct {
+	// Level is the gzip compression level. Zero means gzip.DefaultCompression.
+	Level int
+}
+
+// Compress satisfies the Compress method of Codec.
+func (codec GzipCodec) Compress(data []byte) ([]byte, error) {
+	level := codec.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	buf := &bytes.Buffer{}
+	w, err := gzip.NewWriterLevel(buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress satisfies the Decompress method of Codec.
+func (codec GzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// CompressionStats holds statistics about the values stored by a
+// CompressedCache.
+type CompressionStats struct {
+	// Stored is the number of values stored.
+	Stored int64
+	// Compressed is the number of values which were stored compressed.
+	Compressed int64
+	// OriginalBytes is the total size of the values before compression.
+	OriginalBytes int64
+	// StoredBytes is the total size of the values as stored.
+	StoredBytes int64
+}
+
+// Saved returns the number of bytes saved by compression.
+func (stats CompressionStats) Saved() int64 {
+	return stats.OriginalBytes - stats.StoredBytes
+}
+
+// Markers which are prepended to each value stored by a CompressedCache.
+const (
+	uncompressedValue byte = 0
+	compressedValue   byte = 1
+)
+
+// CompressedCache is a CacheStore which compresses values larger than a
+// threshold before passing them to another CacheStore. It is transparent to
+// readers: Get always returns the original value. Values which fail to
+// decompress are deleted and treated as missing. To combine compression with
+// EncryptedCache, the CompressedCache must wrap the EncryptedCache, since
+// encrypted data does not compress:
+//
+//	cache := rest.NewCompressedCache(rest.NewEncryptedCache(store, keys), rest.GzipCodec{}, 1024)
+type CompressedCache struct {
+	store     CacheStore
+	codec     Codec
+	threshold int
+	stats     CompressionStats
+	mut       sync.Mutex
+}
+
+// NewCompressedCache returns a CompressedCache which stores values in store,
+// compressing any value of at least threshold bytes with codec. Compressed
+// values are only stored if they are smaller than the original.
+func NewCompressedCache(store CacheStore, codec Codec, threshold int) *CompressedCache {
+	return &CompressedCache{
+		store:     store,
+		codec:     codec,
+		threshold: threshold,
+	}
+}
+
+// Compact satisfies the Compacter interface by compacting the underlying
+// store, if it is a Compacter.
+func (cc *CompressedCache) Compact() {
+	if compacter, ok := cc.store.(Compacter); ok {
+		compacter.Compact()
+	}
+}
+
+// Get satisfies the Get method of CacheStore.
+func (cc *CompressedCache) Get(key string) ([]byte, bool) {
+	data, found := cc.store.Get(key)
+	if !found || len(data) == 0 {
+		return nil, false
+	}
+	switch data[0] {
+	case uncompressedValue:
+		return data[1:], true
+	case compressedValue:
+		if value, err := cc.codec.Decompress(data[1:]); err == nil {
+			return value, true
+		}
+	}
+	cc.store.Delete(key)
+	return nil, false
+}
+
+// Set satisfies the Set method of CacheStore.
+func (cc *CompressedCache) Set(key string, value []byte) {
+	data := append([]byte{uncompressedValue}, value...)
+	if len(value) >= cc.threshold {
+		if compressed, err := cc.codec.Compress(value); err == nil && len(compressed) < len(value) {
+			data = append([]byte{compressedValue}, compressed...)
+		}
+	}
+	cc.mut.Lock()
+	cc.stats.Stored++
+	if data[0] == compressedValue {
+		cc.stats.Compressed++
+	}
+	cc.stats.OriginalBytes += int64(len(value))
+	cc.stats.StoredBytes += int64(len(data))
+	cc.mut.Unlock()
+	cc.store.Set(key, data)
+}
+
+// Delete satisfies the Delete method of CacheStore.
+func (cc *CompressedCache) Delete(key string) {
+	cc.store.Delete(key)
+}
+
+// Stats returns statistics about all the values stored by cc so far.
+func (cc *CompressedCache) Stats() CompressionStats {
+	cc.mut.Lock()
+	defer cc.mut.Unlock()
+	return cc.stats
+}
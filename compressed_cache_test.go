@@ -0,0 +1,78 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestCompressedCache(t *testing.T) {
+	store := NewMemoryCache()
+	cache := NewCompressedCache(store, GzipCodec{}, 100)
+	large := strings.Repeat(`{"Title": "a"}`, 100)
+	cache.Set("/large", []byte(large))
+	cache.Set("/small", []byte(`{}`))
+	if raw, _ := store.Get("/large"); len(raw) >= len(large) || raw[0] != compressedValue {
+		t.Errorf("Expected the large value to be stored compressed but got %d bytes", len(raw))
+	}
+	if raw, _ := store.Get("/small"); string(raw) != "\x00{}" {
+		t.Errorf("Expected the small value to be stored uncompressed but got %q", raw)
+	}
+	for key, expected := range map[string]string{"/large": large, "/small": "{}"} {
+		if value, found := cache.Get(key); !found || string(value) != expected {
+			t.Errorf("Expected the original value for %s but got %q", key, value)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Stored != 2 || stats.Compressed != 1 || stats.OriginalBytes != int64(len(large)+2) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.Saved() <= 0 || stats.Saved() != stats.OriginalBytes-stats.StoredBytes {
+		t.Errorf("Expected compression to save some bytes but got %d", stats.Saved())
+	}
+}
+
+func TestCompressedCacheIncompressible(t *testing.T) {
+	store := NewMemoryCache()
+	cache := NewCompressedCache(store, GzipCodec{Level: gzip.BestSpeed}, 0)
+	cache.Set("/todos/1", []byte("x"))
+	if raw, _ := store.Get("/todos/1"); raw[0] != uncompressedValue {
+		t.Error("Expected a value which does not shrink to be stored uncompressed")
+	}
+}
+
+func TestCompressedCacheCorrupt(t *testing.T) {
+	store := NewMemoryCache()
+	cache := NewCompressedCache(store, GzipCodec{}, 0)
+	for key, raw := range map[string]string{"/bad": "\x01not gzip", "/unknown": "\x07{}", "/empty": ""} {
+		store.Set(key, []byte(raw))
+		if _, found := cache.Get(key); found {
+			t.Errorf("Expected a corrupt value for %s to be treated as missing", key)
+		}
+	}
+	if _, found := store.Get("/bad"); found {
+		t.Error("Expected a value which fails to decompress to be deleted")
+	}
+}
+
+func TestCompressedCacheClient(t *testing.T) {
+	server := newTodoServer(t, strings.Repeat("a", 500))
+	client := NewClient()
+	client.Cache = NewCompressedCache(NewMemoryCache(), GzipCodec{}, 100)
+	for i := 0; i < 2; i++ {
+		todo := &testTodo{}
+		if err := client.Read("1", todo); err != nil {
+			t.Fatal(err)
+		}
+		if len(todo.Title) != 500 {
+			t.Fatalf("Expected the title to survive compression but got %d bytes", len(todo.Title))
+		}
+	}
+	if got := server.count("GET"); got != 1 {
+		t.Errorf("Expected the second read to be served from the cache but got %d GET requests", got)
+	}
+}
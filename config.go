@@ -0,0 +1,380 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration which can be unmarshaled from a string like
+// "30s" or "1m30s" (or from a number of nanoseconds) in config files.
+type Duration time.Duration
+
+// UnmarshalJSON satisfies json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		return d.UnmarshalText([]byte(str))
+	}
+	var nanos int64
+	if err := json.Unmarshal(data, &nanos); err != nil {
+		return fmt.Errorf("rest: invalid duration %s", string(data))
+	}
+	*d = Duration(nanos)
+	return nil
+}
+
+// UnmarshalText satisfies encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("rest: invalid duration %q: %s", string(text), err.Error())
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText satisfies encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config holds all the settings needed to construct a Client. It can be
+// filled in directly, loaded from a JSON file with LoadConfig, or loaded from
+// environment variables with ConfigFromEnv. The struct tags also make it
+// suitable for use with most YAML libraries, so YAML files can be loaded with
+// LoadConfigWith.
+type Config struct {
+	// BaseURL is prepended to relative urls. See Client.BaseURL.
+	BaseURL string `json:"baseURL" yaml:"baseURL"`
+	// ContentType is the ContentType of the client. The default is
+	// ContentURLEncoded.
+	ContentType ContentType `json:"contentType" yaml:"contentType"`
+	// Headers are added to every request. See Client.Header.
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	// Vars are the initial template variables. See Client.SetVar.
+	Vars map[string]string `json:"vars" yaml:"vars"`
+	// AllowedMethods restricts the http methods the client may use. See
+	// Client.AllowedMethods.
+	AllowedMethods []string `json:"allowedMethods" yaml:"allowedMethods"`
+	// BearerToken, if not empty, is sent in an Authorization header of the
+	// form "Bearer <token>".
+	BearerToken string `json:"bearerToken" yaml:"bearerToken"`
+	// Username and Password, if Username is not empty, are sent in an
+	// Authorization header using basic authentication.
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	// Cache causes the client to cache responses in memory. See Client.Cache.
+	Cache bool `json:"cache" yaml:"cache"`
+	// CacheMaxEntries and CacheMaxBytes limit the size of the in-memory
+	// cache. Least recently used entries are evicted first. See
+	// NewBoundedMemoryCache.
+	CacheMaxEntries int `json:"cacheMaxEntries" yaml:"cacheMaxEntries"`
+	CacheMaxBytes   int `json:"cacheMaxBytes" yaml:"cacheMaxBytes"`
+	// CacheTTL is how long cached responses remain valid. See
+	// Client.CacheTTL.
+	CacheTTL Duration `json:"cacheTTL" yaml:"cacheTTL"`
+	// CacheTTLs overrides CacheTTL for particular root urls. See
+	// Client.CacheTTLs.
+	CacheTTLs map[string]Duration `json:"cacheTTLs" yaml:"cacheTTLs"`
+	// ResourceHeaders contains headers which are only added to requests for
+	// particular root urls. See Client.ResourceHeaders.
+	ResourceHeaders map[string]map[string]string `json:"resourceHeaders" yaml:"resourceHeaders"`
+	// CacheVary lists request headers whose values are part of the cache
+	// key. See Client.CacheVary.
+	CacheVary []string `json:"cacheVary" yaml:"cacheVary"`
+	// UseNumber causes numbers to be decoded as json.Number. See
+	// Client.UseNumber.
+	UseNumber bool `json:"useNumber" yaml:"useNumber"`
+	// RetryAttempts, if greater than 1, is the maximum number of times each
+	// request is attempted. See Client.Retry.
+	RetryAttempts int `json:"retryAttempts" yaml:"retryAttempts"`
+	// RetryBackoff is how long to wait before the first retry.
+	RetryBackoff Duration `json:"retryBackoff" yaml:"retryBackoff"`
+	// RetryStatusCodes are the response status codes which cause a request to
+	// be retried.
+	RetryStatusCodes []int `json:"retryStatusCodes" yaml:"retryStatusCodes"`
+	// Timeout is the maximum time a request may take. See Client.Timeout.
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+}
+
+// ClientFromConfig returns a new client configured according to cfg. It
+// returns an error if cfg is invalid.
+func ClientFromConfig(cfg Config) (*Client, error) {
+	c := NewClient()
+	if cfg.BaseURL != "" {
+		if base, err := url.Parse(cfg.BaseURL); err != nil || !base.IsAbs() || base.Host == "" {
+			return nil, fmt.Errorf("rest: invalid config: BaseURL %q is not an absolute url", cfg.BaseURL)
+		}
+		c.BaseURL = cfg.BaseURL
+	}
+	switch cfg.ContentType {
+	case "":
+	case ContentJSON, ContentURLEncoded:
+		c.ContentType = cfg.ContentType
+	default:
+		return nil, fmt.Errorf("rest: invalid config: unsupported ContentType %q", cfg.ContentType)
+	}
+	if cfg.BearerToken != "" && cfg.Username != "" {
+		return nil, fmt.Errorf("rest: invalid config: BearerToken and Username cannot both be set")
+	}
+	if len(cfg.Headers) > 0 || cfg.BearerToken != "" || cfg.Username != "" {
+		c.Header = http.Header{}
+		for key, value := range cfg.Headers {
+			c.Header.Set(key, value)
+		}
+		if cfg.BearerToken != "" {
+			c.Header.Set("Authorization", "Bearer "+cfg.BearerToken)
+		}
+		if cfg.Username != "" {
+			credentials := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+			c.Header.Set("Authorization", "Basic "+credentials)
+		}
+	}
+	for name, value := range cfg.Vars {
+		c.SetVar(name, value)
+	}
+	c.AllowedMethods = cfg.AllowedMethods
+	if cfg.Cache {
+		c.Cache = NewBoundedMemoryCache(CacheLimits{
+			MaxEntries: cfg.CacheMaxEntries,
+			MaxBytes:   cfg.CacheMaxBytes,
+		})
+	}
+	c.CacheTTL = time.Duration(cfg.CacheTTL)
+	if len(cfg.CacheTTLs) > 0 {
+		c.CacheTTLs = map[string]time.Duration{}
+		for rootURL, ttl := range cfg.CacheTTLs {
+			c.CacheTTLs[rootURL] = time.Duration(ttl)
+		}
+	}
+	c.CacheVary = cfg.CacheVary
+	if len(cfg.ResourceHeaders) > 0 {
+		c.ResourceHeaders = map[string]http.Header{}
+		for rootURL, headers := range cfg.ResourceHeaders {
+			c.ResourceHeaders[rootURL] = http.Header{}
+			for key, value := range headers {
+				c.ResourceHeaders[rootURL].Set(key, value)
+			}
+		}
+	}
+	c.UseNumber = cfg.UseNumber
+	if cfg.RetryAttempts > 1 {
+		c.Retry = &RetryPolicy{
+			MaxAttempts: cfg.RetryAttempts,
+			Backoff:     time.Duration(cfg.RetryBackoff),
+			StatusCodes: cfg.RetryStatusCodes,
+		}
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("rest: invalid config: Timeout cannot be negative")
+	}
+	c.Timeout = time.Duration(cfg.Timeout)
+	return c, nil
+}
+
+// LoadConfig reads a JSON config file from path. See Config for the available
+// settings.
+func LoadConfig(path string) (Config, error) {
+	return LoadConfigWith(path, json.Unmarshal)
+}
+
+// LoadConfigWith reads a config file from path and decodes it with unmarshal.
+// It can be used to load config files in formats other than JSON without
+// adding a dependency to this package. For example, to load a YAML file with
+// gopkg.in/yaml.v2:
+//
+//	cfg, err := rest.LoadConfigWith("config.yaml", yaml.Unmarshal)
+func LoadConfigWith(path string, unmarshal func(data []byte, v interface{}) error) (Config, error) {
+	cfg := Config{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("rest: error reading config file: %s", err.Error())
+	}
+	if err := unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("rest: error parsing config file %s: %s", path, err.Error())
+	}
+	return cfg, nil
+}
+
+// ConfigFromEnv returns a Config based on environment variables. Each setting
+// is read from a variable named prefix followed by the name of the setting in
+// upper snake case, e.g. with a prefix of "API_", ContentType is read from
+// API_CONTENT_TYPE and BearerToken from API_BEARER_TOKEN. Map settings
+// (Headers, Vars, and CacheTTLs) use a comma-separated list of key=value
+// pairs, and list settings (AllowedMethods, CacheVary, and RetryStatusCodes)
+// use a comma-separated list of values. ResourceHeaders uses a
+// comma-separated list of entries of the form "<root url> <key>=<value>",
+// e.g. "https://example.com/todos X-Team=a". Variables which are not set are
+// left at their zero value.
+func ConfigFromEnv(prefix string) (Config, error) {
+	cfg := Config{
+		BaseURL:     os.Getenv(prefix + "BASE_URL"),
+		ContentType: ContentType(os.Getenv(prefix + "CONTENT_TYPE")),
+		BearerToken: os.Getenv(prefix + "BEARER_TOKEN"),
+		Username:    os.Getenv(prefix + "USERNAME"),
+		Password:    os.Getenv(prefix + "PASSWORD"),
+	}
+	var err error
+	if cfg.Headers, err = envMap(prefix + "HEADERS"); err != nil {
+		return cfg, err
+	}
+	if cfg.Vars, err = envMap(prefix + "VARS"); err != nil {
+		return cfg, err
+	}
+	if methods := os.Getenv(prefix + "ALLOWED_METHODS"); methods != "" {
+		for _, method := range strings.Split(methods, ",") {
+			cfg.AllowedMethods = append(cfg.AllowedMethods, strings.TrimSpace(method))
+		}
+	}
+	if cfg.Cache, err = envBool(prefix + "CACHE"); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheMaxEntries, err = envInt(prefix + "CACHE_MAX_ENTRIES"); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheMaxBytes, err = envInt(prefix + "CACHE_MAX_BYTES"); err != nil {
+		return cfg, err
+	}
+	if ttl := os.Getenv(prefix + "CACHE_TTL"); ttl != "" {
+		if err := cfg.CacheTTL.UnmarshalText([]byte(ttl)); err != nil {
+			return cfg, err
+		}
+	}
+	ttls, err := envMap(prefix + "CACHE_TTLS")
+	if err != nil {
+		return cfg, err
+	}
+	for rootURL, ttl := range ttls {
+		if cfg.CacheTTLs == nil {
+			cfg.CacheTTLs = map[string]Duration{}
+		}
+		var d Duration
+		if err := d.UnmarshalText([]byte(ttl)); err != nil {
+			return cfg, err
+		}
+		cfg.CacheTTLs[rootURL] = d
+	}
+	if vary := os.Getenv(prefix + "CACHE_VARY"); vary != "" {
+		for _, header := range strings.Split(vary, ",") {
+			cfg.CacheVary = append(cfg.CacheVary, strings.TrimSpace(header))
+		}
+	}
+	if cfg.ResourceHeaders, err = envResourceHeaders(prefix + "RESOURCE_HEADERS"); err != nil {
+		return cfg, err
+	}
+	if cfg.UseNumber, err = envBool(prefix + "USE_NUMBER"); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryAttempts, err = envInt(prefix + "RETRY_ATTEMPTS"); err != nil {
+		return cfg, err
+	}
+	if backoff := os.Getenv(prefix + "RETRY_BACKOFF"); backoff != "" {
+		if err := cfg.RetryBackoff.UnmarshalText([]byte(backoff)); err != nil {
+			return cfg, err
+		}
+	}
+	if codes := os.Getenv(prefix + "RETRY_STATUS_CODES"); codes != "" {
+		for _, code := range strings.Split(codes, ",") {
+			statusCode, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return cfg, fmt.Errorf("rest: invalid value for %sRETRY_STATUS_CODES: %q is not an int", prefix, code)
+			}
+			cfg.RetryStatusCodes = append(cfg.RetryStatusCodes, statusCode)
+		}
+	}
+	if timeout := os.Getenv(prefix + "TIMEOUT"); timeout != "" {
+		if err := cfg.Timeout.UnmarshalText([]byte(timeout)); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// envBool returns the value of the environment variable with the given name
+// parsed as a bool. It returns false if the variable is not set.
+func envBool(name string) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("rest: invalid value for %s: %q is not a bool", name, value)
+	}
+	return b, nil
+}
+
+// envInt returns the value of the environment variable with the given name
+// parsed as an int. It returns 0 if the variable is not set.
+func envInt(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("rest: invalid value for %s: %q is not an int", name, value)
+	}
+	return i, nil
+}
+
+// envMap returns the value of the environment variable with the given name
+// parsed as a comma-separated list of key=value pairs. It returns nil if the
+// variable is not set.
+func envMap(name string) (map[string]string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+	result := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		i := strings.Index(pair, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("rest: invalid value for %s: %q is not of the form key=value", name, pair)
+		}
+		result[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return result, nil
+}
+
+// envResourceHeaders returns the value of the environment variable with the
+// given name parsed as a comma-separated list of entries of the form
+// "<root url> <key>=<value>". It returns nil if the variable is not set.
+func envResourceHeaders(name string) (map[string]map[string]string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+	result := map[string]map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		fields := strings.Fields(entry)
+		i := -1
+		if len(fields) == 2 {
+			i = strings.Index(fields[1], "=")
+		}
+		if i == -1 {
+			return nil, fmt.Errorf("rest: invalid value for %s: %q is not of the form <root url> key=value", name, entry)
+		}
+		rootURL, pair := fields[0], fields[1]
+		if result[rootURL] == nil {
+			result[rootURL] = map[string]string{}
+		}
+		result[rootURL][pair[:i]] = pair[i+1:]
+	}
+	return result, nil
+}
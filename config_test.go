@@ -0,0 +1,150 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"API_BASE_URL":           "https://example.com/api",
+		"API_CONTENT_TYPE":       string(ContentJSON),
+		"API_HEADERS":            "X-App=todos, X-Version=2",
+		"API_BEARER_TOKEN":       "secret",
+		"API_CACHE":              "true",
+		"API_CACHE_MAX_ENTRIES":  "10",
+		"API_CACHE_MAX_BYTES":    "1024",
+		"API_CACHE_TTL":          "1m",
+		"API_CACHE_TTLS":         "https://example.com/api/todos=5s",
+		"API_CACHE_VARY":         "Accept-Language, X-Tenant",
+		"API_RESOURCE_HEADERS":   "https://example.com/api/todos X-Team=a, https://example.com/api/todos X-Owner=b",
+		"API_RETRY_ATTEMPTS":     "3",
+		"API_RETRY_STATUS_CODES": "502, 503",
+		"API_TIMEOUT":            "10s",
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	cfg, err := ConfigFromEnv("API_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Config{
+		BaseURL:          "https://example.com/api",
+		ContentType:      ContentJSON,
+		Headers:          map[string]string{"X-App": "todos", "X-Version": "2"},
+		BearerToken:      "secret",
+		Cache:            true,
+		CacheMaxEntries:  10,
+		CacheMaxBytes:    1024,
+		CacheTTL:         Duration(time.Minute),
+		CacheTTLs:        map[string]Duration{"https://example.com/api/todos": Duration(5 * time.Second)},
+		CacheVary:        []string{"Accept-Language", "X-Tenant"},
+		ResourceHeaders:  map[string]map[string]string{"https://example.com/api/todos": {"X-Team": "a", "X-Owner": "b"}},
+		RetryAttempts:    3,
+		RetryStatusCodes: []int{502, 503},
+		Timeout:          Duration(10 * time.Second),
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, cfg)
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	invalid := map[string]string{
+		"API_CACHE":            "maybe",
+		"API_CACHE_MAX_BYTES":  "lots",
+		"API_CACHE_TTLS":       "https://example.com/todos=soon",
+		"API_RESOURCE_HEADERS": "X-Team=a",
+		"API_RETRY_ATTEMPTS":   "three",
+		"API_HEADERS":          "X-App",
+	}
+	for key, value := range invalid {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := ConfigFromEnv("API_"); err == nil {
+				t.Errorf("Expected an error for %s=%q", key, value)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"baseURL": "https://example.com", "cacheTTL": "30s", "retryAttempts": 2}`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "https://example.com" || cfg.CacheTTL != Duration(30*time.Second) || cfg.RetryAttempts != 2 {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestLoadConfigWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.custom")
+	if err := ioutil.WriteFile(path, []byte("anything"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unmarshal := func(data []byte, v interface{}) error {
+		return json.Unmarshal([]byte(`{"bearerToken": "token"}`), v)
+	}
+	cfg, err := LoadConfigWith(path, unmarshal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BearerToken != "token" {
+		t.Errorf("Expected the config decoded by unmarshal but got %+v", cfg)
+	}
+}
+
+func TestClientFromConfig(t *testing.T) {
+	c, err := ClientFromConfig(Config{
+		BaseURL:         "https://example.com",
+		ContentType:     ContentJSON,
+		Username:        "user",
+		Password:        "pass",
+		Cache:           true,
+		CacheTTLs:       map[string]Duration{"https://example.com/todos": Duration(time.Second)},
+		ResourceHeaders: map[string]map[string]string{"https://example.com/todos": {"X-Team": "a"}},
+		RetryAttempts:   2,
+		Timeout:         Duration(time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BaseURL != "https://example.com" || c.ContentType != ContentJSON || c.Cache == nil || c.Retry == nil || c.Timeout != time.Second {
+		t.Errorf("Unexpected client: %+v", c)
+	}
+	if got := c.Header.Get("Authorization"); got != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Expected basic auth but got %q", got)
+	}
+	if c.CacheTTLs["https://example.com/todos"] != time.Second || c.ResourceHeaders["https://example.com/todos"].Get("X-Team") != "a" {
+		t.Errorf("Expected per-resource settings to be copied but got %v and %v", c.CacheTTLs, c.ResourceHeaders)
+	}
+	invalid := []Config{
+		{BaseURL: "/relative"},
+		{ContentType: "text/xml"},
+		{BearerToken: "token", Username: "user"},
+		{Timeout: Duration(-time.Second)},
+	}
+	for _, cfg := range invalid {
+		if _, err := ClientFromConfig(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ContentCSV is the media type of CSV exports.
+const ContentCSV ContentType = "text/csv"
+
+// acceptCSV is the Accept header of requests for CSV exports, which may also
+// be delivered as ZIP archives of CSV files.
+const acceptCSV = string(ContentCSV) + ", application/zip;q=0.9"
+
+// WithCSV returns a RequestOption which causes ReadAll to request the
+// collection as CSV and decode it with DecodeCSV. Responses are never cached.
+// WithCSV cannot be combined with WithMerge, WithReuse, or WithMeta.
+func WithCSV() RequestOption {
+	return func(opts *requestOptions) {
+		opts.csv = true
+	}
+}
+
+// DecodeCSV is a BodyDecoder which decodes a CSV document, or a ZIP archive
+// of CSV documents, into v, which must be a pointer to a slice of models.
+// The first line of each document is the header, which names the columns.
+// Each column is decoded into the field of the model with the same name in
+// its `csv` struct tag, or else in its `json` struct tag, or else the same
+// field name, compared case-insensitively:
+//
+//	type Todo struct {
+//		rest.DefaultId
+//		Title       string `csv:"title"`
+//		IsCompleted bool   `csv:"completed"`
+//		Notes       string `csv:"-"`
+//	}
+//
+// Columns without a field are ignored and empty values leave the field at its
+// zero value. Fields may be strings, bools, numbers, pointers to them, or
+// implement encoding.TextUnmarshaler (e.g. time.Time). DecodeCSV can be passed
+// to RequestBuilder.Decoder.
+func DecodeCSV(data []byte, v interface{}) error {
+	if err := checkModelsType(v, false); err != nil {
+		return err
+	}
+	documents, err := csvDocuments(data)
+	if err != nil {
+		return err
+	}
+	sliceType := reflect.TypeOf(v).Elem()
+	elemType := sliceType.Elem()
+	models := reflect.MakeSlice(sliceType, 0, 0)
+	for _, document := range documents {
+		reader := csv.NewReader(bytes.NewReader(document))
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("rest: error decoding CSV: %s", err.Error())
+		}
+		fields := csvFieldsFor(elemType, header)
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("rest: error decoding CSV: %s", err.Error())
+			}
+			model := newModelOfType(elemType)
+			if err := decodeCSVRecord(model, header, fields, record); err != nil {
+				return err
+			}
+			models = reflect.Append(models, model)
+		}
+	}
+	reflect.ValueOf(v).Elem().Set(models)
+	return nil
+}
+
+// ExportCSV writes every record of a collection to w as CSV, which is
+// requested from the server with an Accept header of text/csv. Like Export,
+// it uses models only as a prototype to determine the collection, streams the
+// records page by page following the Link header of each response, and
+// applies the WithQuery option to the first page only. ZIP archives of CSV
+// files, as sent by some APIs for bulk exports, are decompressed. The header
+// line is written once, and the header lines of the following pages and files
+// are skipped. ExportCSV returns the number of records written, not counting
+// the header.
+func (c *Client) ExportCSV(models interface{}, w io.Writer, opts ...RequestOption) (int, error) {
+	if err := checkModelsType(models, false); err != nil {
+		return 0, err
+	}
+	reqOpts := newRequestOptions(opts).forModel(models)
+	rootURL, err := c.routeModels(models)
+	if err != nil {
+		return 0, err
+	}
+	query, err := toQuery(reqOpts.query)
+	if err != nil {
+		return 0, err
+	}
+	writer := csv.NewWriter(w)
+	var header []string
+	written := 0
+	for url := appendQuery(rootURL, query); url != ""; {
+		res, body, err := c.getAccepting(reqOpts.context(), url, acceptCSV)
+		if err != nil {
+			return written, err
+		}
+		documents, err := csvDocuments(body)
+		if err != nil {
+			return written, err
+		}
+		for _, document := range documents {
+			reader := csv.NewReader(bytes.NewReader(document))
+			reader.FieldsPerRecord = -1
+			for line := 0; ; line++ {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return written, fmt.Errorf("rest: error reading CSV from %s: %s", url, err.Error())
+				}
+				if line == 0 {
+					if header != nil {
+						continue
+					}
+					header = record
+				} else {
+					written++
+				}
+				if err := writer.Write(record); err != nil {
+					return written, err
+				}
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return written, err
+		}
+		url = ""
+		if next, found := parseLinks(res.Header["Link"])["next"]; found {
+			if nextURL, err := res.Request.URL.Parse(next); err == nil {
+				url = nextURL.String()
+			}
+		}
+	}
+	return written, nil
+}
+
+// readAllCSV is the implementation of ReadAll for the WithCSV option. It
+// bypasses the cache, which holds the JSON representation of collections.
+func (c *Client) readAllCSV(models interface{}, query Query, reqOpts *requestOptions) error {
+	rootURL, err := c.routeModels(models)
+	if err != nil {
+		return err
+	}
+	url := appendQuery(rootURL, query)
+	_, body, err := c.getAccepting(reqOpts.context(), url, acceptCSV)
+	if err != nil {
+		return err
+	}
+	if err := DecodeCSV(body, models); err != nil {
+		c.reportError(ErrorReport{
+			Kind:    ErrorDecode,
+			Method:  "GET",
+			URL:     c.expandVars(url),
+			Err:     err,
+			Context: reqOpts.context(),
+		})
+		return err
+	}
+	return nil
+}
+
+// csvDocuments returns the CSV documents in data, which is either a single
+// CSV document or a ZIP archive of them. Files in the archive whose names end
+// in ".csv" are returned in order; if there are none, all files are.
+func csvDocuments(data []byte) ([][]byte, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return [][]byte{data}, nil
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("rest: error reading ZIP archive: %s", err.Error())
+	}
+	var files []*zip.File
+	for _, file := range archive.File {
+		if strings.HasSuffix(strings.ToLower(file.Name), ".csv") {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		for _, file := range archive.File {
+			if !file.FileInfo().IsDir() {
+				files = append(files, file)
+			}
+		}
+	}
+	documents := make([][]byte, 0, len(files))
+	for _, file := range files {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("rest: error reading %s from ZIP archive: %s", file.Name, err.Error())
+		}
+		document, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("rest: error reading %s from ZIP archive: %s", file.Name, err.Error())
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// csvFieldsFor returns the index of the field of the model type modelType
+// for each column in header, or nil for columns without a field.
+func csvFieldsFor(modelType reflect.Type, header []string) [][]int {
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	names := map[string][]int{}
+	collectCSVFields(modelType, nil, names)
+	fields := make([][]int, len(header))
+	for i, column := range header {
+		fields[i] = names[strings.ToLower(strings.TrimSpace(column))]
+	}
+	return fields
+}
+
+// collectCSVFields adds the lower-cased column name of each field of
+// structType to names, along with its index. The fields of embedded structs
+// are included, unless a field of the outer struct has the same name.
+func collectCSVFields(structType reflect.Type, index []int, names map[string][]int) {
+	var embedded []reflect.StructField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		field.Index = append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("csv") == "" {
+			embedded = append(embedded, field)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("csv")
+		if name == "" {
+			name, _ = splitTag(field.Tag.Get("json"))
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[strings.ToLower(name)] = field.Index
+	}
+	for _, field := range embedded {
+		inner := map[string][]int{}
+		collectCSVFields(field.Type, field.Index, inner)
+		for name, index := range inner {
+			if _, found := names[name]; !found {
+				names[name] = index
+			}
+		}
+	}
+}
+
+// decodeCSVRecord sets the fields of model to the values of record.
+func decodeCSVRecord(model reflect.Value, header []string, fields [][]int, record []string) error {
+	structVal := model
+	for structVal.Kind() == reflect.Ptr {
+		structVal = structVal.Elem()
+	}
+	for i, value := range record {
+		if i >= len(fields) || fields[i] == nil || value == "" {
+			continue
+		}
+		field := structVal.FieldByIndex(fields[i])
+		if err := setCSVValue(field, value); err != nil {
+			return fmt.Errorf("rest: cannot decode CSV column %q into field of type %s: %s", header[i], field.Type(), err.Error())
+		}
+	}
+	return nil
+}
+
+// setCSVValue sets field, which must be settable, to value.
+func setCSVValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setCSVValue(field.Elem(), value)
+	}
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported kind %s", field.Kind())
+	}
+	return nil
+}
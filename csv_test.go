@@ -0,0 +1,204 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// csvTodo is a model whose fields are decoded from CSV columns.
+type csvTodo struct {
+	DefaultId
+	Title       string `csv:"title"`
+	IsCompleted bool   `csv:"completed"`
+	Priority    *int   `json:"priority"`
+	Estimate    float64
+	Due         time.Time `csv:"due"`
+	Notes       string    `csv:"-"`
+	Internal    string    `json:"-"`
+}
+
+func (*csvTodo) RootURL() string { return testRootURL + "/todos" }
+
+// zipCSV returns a ZIP archive holding the given files.
+func zipCSV(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(file[1]))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeCSV(t *testing.T) {
+	data := "Id, Title ,COMPLETED,priority,Estimate,due,notes,internal,extra\n" +
+		"1,\"Write a book, finally\",true,3,2.5,2015-06-01T00:00:00Z,secret,secret,x\n" +
+		"2,Take out the trash,false,,,,,,\n"
+	todos := []*csvTodo{}
+	if err := DecodeCSV([]byte(data), &todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 {
+		t.Fatalf("Expected 2 todos but got %d", len(todos))
+	}
+	first := todos[0]
+	if first.Id != "1" || first.Title != "Write a book, finally" || !first.IsCompleted {
+		t.Errorf("Expected the columns to be decoded into the fields but got %+v", first)
+	}
+	if first.Priority == nil || *first.Priority != 3 {
+		t.Errorf("Expected Priority to be decoded by its json name but got %v", first.Priority)
+	}
+	if first.Estimate != 2.5 {
+		t.Errorf("Expected Estimate to be decoded by its field name but got %v", first.Estimate)
+	}
+	if !first.Due.Equal(time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Due to be decoded with UnmarshalText but got %s", first.Due)
+	}
+	if first.Notes != "" || first.Internal != "" {
+		t.Errorf("Expected fields tagged with \"-\" to be ignored but got %q and %q", first.Notes, first.Internal)
+	}
+	if second := todos[1]; second.Priority != nil || second.IsCompleted || !second.Due.IsZero() {
+		t.Errorf("Expected empty values to leave the fields at their zero value but got %+v", second)
+	}
+}
+
+func TestDecodeCSVErrors(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		v    interface{}
+	}{
+		"invalid bool":     {"completed\nmaybe\n", &[]*csvTodo{}},
+		"invalid number":   {"priority\nhigh\n", &[]*csvTodo{}},
+		"malformed CSV":    {"title\n\"unterminated\n", &[]*csvTodo{}},
+		"not a slice":      {"title\na\n", &csvTodo{}},
+		"invalid ZIP file": {"PK\x03\x04 truncated", &[]*csvTodo{}},
+	}
+	for name, test := range tests {
+		if err := DecodeCSV([]byte(test.data), test.v); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestDecodeCSVZip(t *testing.T) {
+	data := zipCSV(t,
+		[2]string{"readme.txt", "not a CSV file"},
+		[2]string{"part1.csv", "Id,title\n1,a\n"},
+		[2]string{"part2.CSV", "title,Id\nb,2\n"},
+	)
+	todos := []*csvTodo{}
+	if err := DecodeCSV(data, &todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].Id != "1" || todos[0].Title != "a" || todos[1].Id != "2" || todos[1].Title != "b" {
+		t.Errorf("Expected the todos of both CSV files in order but got %+v", todos)
+	}
+}
+
+func TestReadAllWithCSV(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Header().Set("Content-Type", string(ContentCSV))
+		if r.URL.Query().Get("broken") != "" {
+			w.Write([]byte("priority\nhigh\n"))
+			return
+		}
+		w.Write([]byte("Id,title\n1,a\n2,b\n"))
+	})
+	client := NewClient()
+	var reports []ErrorReport
+	client.OnError = func(report ErrorReport) {
+		reports = append(reports, report)
+	}
+	todos := []*csvTodo{}
+	if err := client.ReadAll(&todos, WithCSV(), WithQuery(Query{"IsCompleted": {"true"}})); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[1].Title != "b" {
+		t.Errorf("Expected the todos to be decoded from CSV but got %+v", todos)
+	}
+	req := log.last()
+	if got := req.Header.Get("Accept"); got != acceptCSV {
+		t.Errorf("Expected an Accept header of %q but got %q", acceptCSV, got)
+	}
+	if got := req.URL.RawQuery; got != "IsCompleted=true" {
+		t.Errorf("Expected the query to be sent but got %q", got)
+	}
+
+	if err := client.ReadAll(&todos, WithCSV(), WithQuery(Query{"broken": {"1"}})); err == nil {
+		t.Error("Expected an error for a CSV document which cannot be decoded")
+	}
+	if len(reports) != 1 || reports[0].Kind != ErrorDecode {
+		t.Errorf("Expected the decode error to be reported but got %+v", reports)
+	}
+
+	for name, opt := range map[string]RequestOption{"WithMerge": WithMerge(false), "WithReuse": WithReuse(), "WithMeta": WithMeta(&todoListMeta{})} {
+		if err := client.ReadAll(&todos, WithCSV(), opt); err == nil {
+			t.Errorf("Expected an error combining WithCSV with %s", name)
+		}
+	}
+	if n := len(log.all()); n != 2 {
+		t.Errorf("Expected 2 requests but got %d", n)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	lastPage := zipCSV(t, [2]string{"4.csv", "Id,title\n4,e\n"}, [2]string{"5.csv", "Id,title\n5,f\n"})
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</todos?page=2>; rel="next"`)
+			w.Write([]byte("Id,title\n1,a\n2,b\n"))
+		case "2":
+			w.Header().Set("Link", `</todos?page=3>; rel="next"`)
+			w.Write([]byte("Id,title\n3,\"c, d\"\n"))
+		case "3":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write(lastPage)
+		}
+	})
+	var buf bytes.Buffer
+	n, err := NewClient().ExportCSV(&[]*csvTodo{}, &buf, WithQuery(Query{"Title": {"x"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 records to be written but got %d", n)
+	}
+	expected := "Id,title\n1,a\n2,b\n3,\"c, d\"\n4,e\n5,f\n"
+	if buf.String() != expected {
+		t.Errorf("Expected the export to be:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+	requests := log.all()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests but got %d", len(requests))
+	}
+	if got := requests[0].URL.RawQuery; got != "Title=x" {
+		t.Errorf("Expected the query to be sent with the first page but got %q", got)
+	}
+	if got := requests[1].URL.RawQuery; got != "page=2" {
+		t.Errorf("Expected the query not to be sent with the following pages but got %q", got)
+	}
+	for _, req := range requests {
+		if got := req.Header.Get("Accept"); got != acceptCSV {
+			t.Errorf("Expected an Accept header of %q but got %q", acceptCSV, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// debugEnabled is true iff requests and responses should be dumped. It
+	// starts out true in builds with the restdebug build tag.
+	debugEnabled = debugDefault
+	// debugOutput is where requests and responses are dumped.
+	debugOutput io.Writer = os.Stderr
+	// debugMu protects debugEnabled and debugOutput, and serializes dumps so
+	// that those of concurrent requests do not interleave.
+	debugMu sync.Mutex
+)
+
+// Debug enables or disables debug mode for all clients. In debug mode every
+// attempt to send a request is dumped in wire format, along with the
+// response, to the writer set with SetDebugOutput (os.Stderr by default).
+// Credentials are redacted: the values of the Authorization, Cookie, and
+// Set-Cookie headers and of any header whose name contains "token",
+// "secret", or "key" are replaced, and the fields of the PII policy of the
+// client are masked in bodies. Debug mode can also be enabled at build time,
+// without changing any code, with the restdebug build tag:
+//
+//	go run -tags restdebug .
+//
+// Dumping reads the whole body of each response into memory, so debug mode is
+// not meant for production.
+func Debug(enabled bool) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugEnabled = enabled
+}
+
+// SetDebugOutput sets the writer requests and responses are dumped to in
+// debug mode.
+func SetDebugOutput(w io.Writer) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugOutput = w
+}
+
+// debugging returns true iff debug mode is enabled.
+func debugging() bool {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	return debugEnabled
+}
+
+// dumpRequest writes req to the debug output. The body of req is left
+// intact.
+func (c *Client) dumpRequest(req *http.Request) {
+	dumped := *req
+	dumped.Header = redactHeader(req.Header)
+	dumped.Body = nil
+	withBody := req.Body == nil || req.GetBody != nil
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			withBody = false
+		} else {
+			dumped.Body = body
+		}
+	}
+	dump, err := httputil.DumpRequest(&dumped, withBody)
+	if err != nil {
+		c.writeDebug("rest: could not dump %s request to %s: %s\n", req.Method, req.URL, err)
+		return
+	}
+	if !withBody {
+		dump = append(dump, "[body not shown]\n"...)
+	}
+	c.writeDebug("---> %s %s\n%s\n", req.Method, req.URL, c.maskDump(dump))
+}
+
+// dumpResponse writes res, which was received in response to req after
+// latency, to the debug output. The body of res is read into memory so that
+// it can still be read afterwards.
+func (c *Client) dumpResponse(req *http.Request, res *http.Response, latency time.Duration) {
+	header := res.Header
+	res.Header = redactHeader(header)
+	dump, err := httputil.DumpResponse(res, true)
+	res.Header = header
+	if err != nil {
+		c.writeDebug("rest: could not dump response to %s request to %s: %s\n", req.Method, req.URL, err)
+		return
+	}
+	c.writeDebug("<--- %s %s (%s)\n%s\n", req.Method, req.URL, latency, c.maskDump(dump))
+}
+
+// dumpError writes err, which was returned while sending req, to the debug
+// output.
+func (c *Client) dumpError(req *http.Request, err error) {
+	c.writeDebug("<--- %s %s failed: %s\n\n", req.Method, req.URL, err)
+}
+
+// writeDebug writes a formatted message to the debug output.
+func (c *Client) writeDebug(format string, args ...interface{}) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	fmt.Fprintf(debugOutput, format, args...)
+}
+
+// maskDump masks the PII in the body of dump, a dumped request or response,
+// if c has a PIIPolicy.
+func (c *Client) maskDump(dump []byte) []byte {
+	if c.PII == nil {
+		return dump
+	}
+	i := bytes.Index(dump, []byte("\r\n\r\n"))
+	if i == -1 {
+		return dump
+	}
+	head, body := dump[:i+4], dump[i+4:]
+	return append(append([]byte{}, head...), maskPII(body, c.PII.Fields, c.PII.mask())...)
+}
+
+// redactHeader returns a copy of header in which the values of headers which
+// may hold credentials are replaced.
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name, values := range redacted {
+		if !isSecretHeader(name) {
+			continue
+		}
+		for i := range values {
+			values[i] = "[REDACTED]"
+		}
+	}
+	return redacted
+}
+
+// isSecretHeader returns true iff the header with the given name may hold
+// credentials.
+func isSecretHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+		return true
+	}
+	name = strings.ToLower(name)
+	return strings.Contains(name, "token") || strings.Contains(name, "secret") || strings.Contains(name, "key")
+}
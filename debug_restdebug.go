@@ -0,0 +1,12 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+//go:build restdebug
+// +build restdebug
+
+package rest
+
+// debugDefault is whether debug mode is enabled before Debug is called. The
+// restdebug build tag enables it.
+const debugDefault = true
@@ -0,0 +1,133 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer which is safe for concurrent use.
+type syncBuffer struct {
+	buf bytes.Buffer
+	mut sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.String()
+}
+
+// enableDebug enables debug mode for the rest of the test and returns the
+// buffer requests and responses are dumped to.
+func enableDebug(t *testing.T) *syncBuffer {
+	output := &syncBuffer{}
+	debugMu.Lock()
+	previousEnabled, previousOutput := debugEnabled, debugOutput
+	debugMu.Unlock()
+	Debug(true)
+	SetDebugOutput(output)
+	t.Cleanup(func() {
+		Debug(previousEnabled)
+		SetDebugOutput(previousOutput)
+	})
+	return output
+}
+
+func TestDebug(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+		w.Write([]byte(`{"Id": "1", "Title": "Write a book"}`))
+	})
+	output := enableDebug(t)
+	client := NewClient()
+	client.Header = http.Header{
+		"Authorization": {"Bearer abc123"},
+		"X-Api-Key":     {"k3y"},
+		"X-App":         {"test"},
+	}
+	todo := &testTodo{Title: "Write a book"}
+	todo.Id = "1"
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "Write a book" {
+		t.Errorf("Expected the response to still be decoded but Title was %q", todo.Title)
+	}
+
+	dump := output.String()
+	for _, expected := range []string{
+		"---> PATCH " + testRootURL + "/todos/1",
+		"Title=Write+a+book",
+		"X-App: test",
+		"<--- PATCH " + testRootURL + "/todos/1",
+		"200 OK",
+		`{"Id": "1", "Title": "Write a book"}`,
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("Expected the dump to contain %q but got:\n%s", expected, dump)
+		}
+	}
+	for _, secret := range []string{"abc123", "k3y", "s3cr3t"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("Expected %q to be redacted but got:\n%s", secret, dump)
+		}
+	}
+}
+
+func TestDebugMasksPII(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"1","Title":"Call alice@example.com"}`))
+	})
+	output := enableDebug(t)
+	client := NewClient()
+	client.PII = &PIIPolicy{Fields: []string{"Title"}}
+	todo := &testTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), "alice@example.com") {
+		t.Errorf("Expected PII to be masked in the dump but got:\n%s", output)
+	}
+	if todo.Title != "Call alice@example.com" {
+		t.Errorf("Expected the model to be decoded from the unmasked response but Title was %q", todo.Title)
+	}
+}
+
+func TestDebugError(t *testing.T) {
+	output := enableDebug(t)
+	// Close the server right away so that the request fails
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	srv.Close()
+
+	if err := NewClient().Read("1", &testTodo{}); err == nil {
+		t.Fatal("Expected an error reading from a closed server")
+	}
+	if expected := "<--- GET " + srv.URL + "/todos/1 failed:"; !strings.Contains(output.String(), expected) {
+		t.Errorf("Expected the dump to contain %q but got:\n%s", expected, output)
+	}
+}
+
+func TestDebugDisabled(t *testing.T) {
+	newTodoServer(t, "Write a book")
+	output := enableDebug(t)
+	Debug(false)
+	if err := NewClient().Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if dump := output.String(); dump != "" {
+		t.Errorf("Expected nothing to be dumped when debug mode is disabled but got:\n%s", dump)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeInto maps top-level keys of a JSON response to the targets they should
+// be decoded into. It is useful for endpoints which return a record alongside
+// metadata, e.g.:
+//
+//	{"todo": {...}, "permissions": [...]}
+//
+// A DecodeInto can be passed to RequestBuilder.Into, or to the methods of
+// Client via WithDecodeInto. Each target must be a pointer (or a Model). Keys
+// in the response which have no target are ignored, and targets whose key is
+// missing from the response are left unchanged.
+type DecodeInto map[string]interface{}
+
+// WithDecodeInto returns a RequestOption which causes the response to be
+// decoded into targets instead of the model(s) passed to the method. To also
+// fill in the model, include it in targets under the appropriate key:
+//
+//	client.Read("1", todo, rest.WithDecodeInto(rest.DecodeInto{
+//		"todo":        todo,
+//		"permissions": &perms,
+//	}))
+func WithDecodeInto(targets DecodeInto) RequestOption {
+	return func(opts *requestOptions) {
+		opts.decodeInto = targets
+	}
+}
+
+// decodeInto decodes the JSON object in data into targets.
+func (c *Client) decodeInto(data []byte, targets DecodeInto) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("rest: could not decode response into multiple targets: %s", err)
+	}
+	for key, target := range targets {
+		raw, found := fields[key]
+		if !found {
+			continue
+		}
+		if err := c.unmarshal(raw, target); err != nil {
+			return fmt.Errorf("rest: could not decode %q: %s", key, err)
+		}
+	}
+	return nil
+}
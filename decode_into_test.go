@@ -0,0 +1,64 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestWithDecodeInto(t *testing.T) {
+	newEchoServer(t, http.StatusOK, `{"todo": {"Id": "1", "Title": "a"}, "permissions": ["read"], "extra": 1}`)
+	todo := &testTodo{}
+	perms := []string{}
+	count := 7
+	err := NewClient().Read("1", todo, WithDecodeInto(DecodeInto{
+		"todo":        todo,
+		"permissions": &perms,
+		"count":       &count,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "1" || todo.Title != "a" {
+		t.Errorf("Expected the todo to be decoded but got %+v", todo)
+	}
+	if !reflect.DeepEqual(perms, []string{"read"}) {
+		t.Errorf("Expected the permissions to be decoded but got %v", perms)
+	}
+	if count != 7 {
+		t.Errorf("Expected a target whose key is missing to be unchanged but got %d", count)
+	}
+}
+
+func TestRequestBuilderDecodeInto(t *testing.T) {
+	newEchoServer(t, http.StatusOK, `{"total": 3, "todos": "not a list"}`)
+	client := NewClient()
+	total := 0
+	err := client.NewRequestBuilder("GET", testRootURL+"/todos/stats").
+		Into(DecodeInto{"total": &total}).
+		Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Errorf("Expected the total to be decoded but got %d", total)
+	}
+	todos := []*testTodo{}
+	err = client.NewRequestBuilder("GET", testRootURL+"/todos/stats").
+		Into(DecodeInto{"todos": &todos}).
+		Execute(context.Background())
+	if err == nil {
+		t.Error("Expected an error for a key with the wrong type")
+	}
+}
+
+func TestDecodeIntoNonObject(t *testing.T) {
+	if err := NewClient().decodeInto([]byte("[1, 2]"), DecodeInto{}); err == nil {
+		t.Error("Expected an error for a response which is not an object")
+	}
+}
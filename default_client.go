@@ -0,0 +1,84 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"sync"
+)
+
+// defaultClient is the client used by the package-level functions.
+var (
+	defaultClient     = NewClient()
+	defaultClientUsed bool
+	defaultClientMu   sync.RWMutex
+)
+
+// DefaultClient returns the client used by the package-level functions such
+// as Create and Read. Unless it was replaced with SetDefaultClient, it has the
+// settings of NewClient. Like any client, it may be configured before it is
+// first used, but not while requests are in progress.
+func DefaultClient() *Client {
+	defaultClientMu.RLock()
+	defer defaultClientMu.RUnlock()
+	return defaultClient
+}
+
+// SetDefaultClient replaces the client used by the package-level functions.
+// It is meant to be called during initialization, e.g. in an init function or
+// at the start of main, and panics if c is nil or if a package-level function
+// has already sent a request with the previous default client, since requests
+// would otherwise silently be sent with different settings depending on
+// timing.
+func SetDefaultClient(c *Client) {
+	if c == nil {
+		panic("rest: SetDefaultClient called with a nil client")
+	}
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	if defaultClientUsed {
+		panic("rest: SetDefaultClient called after the default client was used")
+	}
+	defaultClient = c
+}
+
+// useDefaultClient returns the default client and records that it was used.
+func useDefaultClient() *Client {
+	defaultClientMu.RLock()
+	used := defaultClientUsed
+	c := defaultClient
+	defaultClientMu.RUnlock()
+	if !used {
+		defaultClientMu.Lock()
+		defaultClientUsed = true
+		c = defaultClient
+		defaultClientMu.Unlock()
+	}
+	return c
+}
+
+// Create is like Client.Create but uses the default client.
+func Create(model Model, opts ...RequestOption) error {
+	return useDefaultClient().Create(model, opts...)
+}
+
+// Read is like Client.Read but uses the default client.
+func Read(id string, model Model, opts ...RequestOption) error {
+	return useDefaultClient().Read(id, model, opts...)
+}
+
+// ReadAll is like Client.ReadAll but uses the default client.
+func ReadAll(models interface{}, opts ...RequestOption) error {
+	return useDefaultClient().ReadAll(models, opts...)
+}
+
+// Update is like Client.Update but uses the default client.
+func Update(model Model, opts ...RequestOption) error {
+	return useDefaultClient().Update(model, opts...)
+}
+
+// Delete is like Client.Delete but uses the default client.
+func Delete(model Model, opts ...RequestOption) error {
+	return useDefaultClient().Delete(model, opts...)
+}
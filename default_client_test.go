@@ -0,0 +1,129 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// resetDefaultClient replaces the default client with a fresh, unused one for
+// the rest of the test.
+func resetDefaultClient(t *testing.T) {
+	defaultClientMu.Lock()
+	previous, previousUsed := defaultClient, defaultClientUsed
+	defaultClient, defaultClientUsed = NewClient(), false
+	defaultClientMu.Unlock()
+	t.Cleanup(func() {
+		defaultClientMu.Lock()
+		defaultClient, defaultClientUsed = previous, previousUsed
+		defaultClientMu.Unlock()
+	})
+}
+
+func TestPackageLevelFunctions(t *testing.T) {
+	resetDefaultClient(t)
+	server := newTodoServer(t, "Write a book")
+
+	todo := &testTodo{Title: "Take out the trash"}
+	if err := Create(todo); err != nil {
+		t.Fatalf("Unexpected error in Create: %s", err)
+	}
+	if todo.Id != "2" {
+		t.Errorf("Expected the created todo to have id 2 but got %q", todo.Id)
+	}
+	todo.IsCompleted = true
+	if err := Update(todo); err != nil {
+		t.Fatalf("Unexpected error in Update: %s", err)
+	}
+	got := &testTodo{}
+	if err := Read("2", got); err != nil {
+		t.Fatalf("Unexpected error in Read: %s", err)
+	}
+	if got.Title != "Take out the trash" || !got.IsCompleted {
+		t.Errorf("Read returned the wrong todo: %+v", got)
+	}
+	if err := Delete(todo); err != nil {
+		t.Fatalf("Unexpected error in Delete: %s", err)
+	}
+	todos := []*testTodo{}
+	if err := ReadAll(&todos); err != nil {
+		t.Fatalf("Unexpected error in ReadAll: %s", err)
+	}
+	if len(todos) != 1 || todos[0].Title != "Write a book" {
+		t.Errorf("Expected only the first todo to remain but got %+v", todos)
+	}
+	if n := len(server.Requests()); n != 5 {
+		t.Errorf("Expected 5 requests but got %d: %v", n, server.Requests())
+	}
+}
+
+func TestSetDefaultClient(t *testing.T) {
+	resetDefaultClient(t)
+	var header string
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Client")
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+
+	c := NewClient()
+	c.Header = http.Header{"X-Client": {"custom"}}
+	SetDefaultClient(c)
+	if DefaultClient() != c {
+		t.Fatal("Expected DefaultClient to return the client passed to SetDefaultClient")
+	}
+	if err := Read("1", &testTodo{}); err != nil {
+		t.Fatalf("Unexpected error in Read: %s", err)
+	}
+	if header != "custom" {
+		t.Errorf("Expected the request to be sent by the new default client but X-Client was %q", header)
+	}
+}
+
+func TestSetDefaultClientPanics(t *testing.T) {
+	resetDefaultClient(t)
+	expectPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected SetDefaultClient to panic %s", name)
+			}
+		}()
+		f()
+	}
+	expectPanic("with a nil client", func() { SetDefaultClient(nil) })
+
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	if err := Read("1", &testTodo{}); err != nil {
+		t.Fatalf("Unexpected error in Read: %s", err)
+	}
+	expectPanic("after the default client was used", func() { SetDefaultClient(NewClient()) })
+}
+
+func TestDefaultClientConcurrentUse(t *testing.T) {
+	resetDefaultClient(t)
+	server := newTodoServer(t, "Write a book")
+	want := DefaultClient()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if DefaultClient() != want {
+				t.Error("Expected DefaultClient to return the same client")
+			}
+			if err := Read("1", &testTodo{}); err != nil {
+				t.Errorf("Unexpected error in Read: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := server.count("GET"); n != 10 {
+		t.Errorf("Expected 10 requests but got %d", n)
+	}
+}
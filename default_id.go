@@ -4,6 +4,11 @@
 
 package rest
 
+import (
+	"fmt"
+	"strconv"
+)
+
 // DefaultId is a struct with an Id property and a getter
 // called ModelId. You can embed it to satisfy the ModelId
 // method of rest.Model.
@@ -15,3 +20,69 @@ type DefaultId struct {
 func (d DefaultId) ModelId() string {
 	return d.Id
 }
+
+// SetModelId sets the Id property to id.
+func (d *DefaultId) SetModelId(id string) error {
+	d.Id = id
+	return nil
+}
+
+// DefaultIntId is like DefaultId, but for servers which use
+// integer ids. The zero id means the model has not been
+// created yet, so ModelId returns an empty string for it.
+type DefaultIntId struct {
+	Id int64
+}
+
+// ModelId satisfies the ModelId method of rest.Model.
+func (d DefaultIntId) ModelId() string {
+	if d.Id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(d.Id, 10)
+}
+
+// SetModelId parses id and sets the Id property to the
+// result. An empty id sets it to 0.
+func (d *DefaultIntId) SetModelId(id string) error {
+	if id == "" {
+		d.Id = 0
+		return nil
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("rest: invalid integer id %q", id)
+	}
+	d.Id = n
+	return nil
+}
+
+// DefaultUUID is like DefaultId, but for servers which use
+// UUIDs as ids. The zero UUID means the model has not been
+// created yet, so ModelId returns an empty string for it.
+type DefaultUUID struct {
+	Id UUID
+}
+
+// ModelId satisfies the ModelId method of rest.Model.
+func (d DefaultUUID) ModelId() string {
+	if d.Id.IsZero() {
+		return ""
+	}
+	return d.Id.String()
+}
+
+// SetModelId parses id and sets the Id property to the
+// result. An empty id sets it to the zero UUID.
+func (d *DefaultUUID) SetModelId(id string) error {
+	if id == "" {
+		d.Id = UUID{}
+		return nil
+	}
+	uuid, err := ParseUUID(id)
+	if err != nil {
+		return err
+	}
+	d.Id = uuid
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type intTodo struct {
+	DefaultIntId
+	Title string
+}
+
+func (*intTodo) RootURL() string { return testRootURL + "/todos" }
+
+type uuidTodo struct {
+	DefaultUUID
+	Title string
+}
+
+func (*uuidTodo) RootURL() string { return testRootURL + "/todos" }
+
+const testUUID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+func TestDefaultIntId(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": 42, "Title": "a"}`)
+	client := NewClient()
+	todo := &intTodo{Title: "a"}
+	if todo.ModelId() != "" {
+		t.Errorf("Expected an empty id for a new model but got %q", todo.ModelId())
+	}
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != 42 || todo.ModelId() != "42" {
+		t.Fatalf("Expected the id to be decoded but got %+v", todo)
+	}
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if path := server.last().URL.Path; path != "/todos/42" {
+		t.Errorf("Expected the int id in the url but got %s", path)
+	}
+	read := &intTodo{}
+	if err := client.Read("42", read); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := todo.SetModelId("7"); err != nil || todo.Id != 7 {
+		t.Errorf("Expected SetModelId to parse the id but got %d, %v", todo.Id, err)
+	}
+	if err := todo.SetModelId(""); err != nil || todo.Id != 0 {
+		t.Errorf("Expected an empty id to reset the id but got %d, %v", todo.Id, err)
+	}
+	if err := todo.SetModelId("seven"); err == nil {
+		t.Error("Expected an error for an id which is not an integer")
+	}
+}
+
+func TestDefaultUUID(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "`+testUUID+`", "Title": "a"}`)
+	client := NewClient()
+	todo := &uuidTodo{}
+	if todo.ModelId() != "" {
+		t.Errorf("Expected an empty id for a new model but got %q", todo.ModelId())
+	}
+	if err := client.Read(testUUID, todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.ModelId() != testUUID {
+		t.Fatalf("Expected the UUID to be decoded but got %q", todo.ModelId())
+	}
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); !strings.Contains(body, "Id="+testUUID) {
+		t.Errorf("Expected the UUID to be url encoded in its canonical form but got %s", body)
+	}
+	if err := todo.SetModelId(strings.ToUpper("{" + testUUID + "}")); err != nil || todo.ModelId() != testUUID {
+		t.Errorf("Expected SetModelId to accept braces and upper case but got %q, %v", todo.ModelId(), err)
+	}
+	if err := todo.SetModelId(""); err != nil || !todo.Id.IsZero() {
+		t.Errorf("Expected an empty id to reset the id but got %v, %v", todo.Id, err)
+	}
+	for _, id := range []string{"6ba7b810", "6ba7b810-9dad-11d1-80b4-00c04fd430cx", "6ba7b8109dad-11d1-80b4-00c04fd430c8a"} {
+		if err := todo.SetModelId(id); err == nil {
+			t.Errorf("Expected an error for %q", id)
+		}
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	a, err := NewUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewUUID()
+	if a == b || a.IsZero() {
+		t.Errorf("Expected distinct random UUIDs but got %s and %s", a, b)
+	}
+	if str := a.String(); str[14] != '4' || !strings.ContainsAny(str[19:20], "89ab") {
+		t.Errorf("Expected a version 4 UUID but got %s", str)
+	}
+	parsed, err := ParseUUID(a.String())
+	if err != nil || parsed != a {
+		t.Errorf("Expected the UUID to round trip but got %s, %v", parsed, err)
+	}
+}
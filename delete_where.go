@@ -0,0 +1,98 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WithDeleteViaPost returns a RequestOption which causes DeleteWhere to send a
+// POST request to model.RootURL() + path (e.g. "/delete") with the query in
+// the body, instead of a DELETE request with the query in the url. It is
+// useful for servers which do not accept DELETE requests for collections, or
+// when the query is too large to fit in a url.
+func WithDeleteViaPost(path string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.deleteViaPost = path
+	}
+}
+
+// DeleteWhere deletes all the models of the same type as model which match
+// query. It sends a DELETE request to model.RootURL() with the query encoded
+// in the url (or a POST request if the WithDeleteViaPost option is provided).
+// model is only used to determine the url and is not mutated.
+//
+// DeleteWhere returns the number of models that were deleted, which it reads
+// from the response. The response should be either a number or a JSON object
+// with a "count", "deleted", or "deletedCount" field. If the response is
+// empty, DeleteWhere returns -1 to indicate that the count is unknown.
+func (c *Client) DeleteWhere(model Model, query Query, opts ...RequestOption) (int, error) {
+	reqOpts := newRequestOptions(opts)
+	var rb *RequestBuilder
+	if reqOpts.deleteViaPost != "" {
+		contentType := c.contentTypeFor(model)
+		rb = c.NewRequestBuilder("POST", model.RootURL()+reqOpts.deleteViaPost).ContentType(contentType)
+		if contentType == ContentURLEncoded {
+			rb.Body(query)
+		} else {
+			rb.Body(flattenQuery(query))
+		}
+	} else {
+		rb = c.NewRequestBuilder("DELETE", model.RootURL()).Query(query)
+	}
+	var body json.RawMessage
+	if err := rb.Decoder(rawDecoder).Into(&body).execute(reqOpts); err != nil {
+		return 0, err
+	}
+	c.invalidateCache(model)
+	return parseDeleteCount(body)
+}
+
+// rawDecoder is a BodyDecoder which stores the response as is in a
+// *json.RawMessage, without trying to parse it.
+func rawDecoder(data []byte, v interface{}) error {
+	*(v.(*json.RawMessage)) = append(json.RawMessage{}, data...)
+	return nil
+}
+
+// flattenQuery converts query into a map suitable for encoding as JSON, where
+// keys with a single value map to that value instead of a list.
+func flattenQuery(query Query) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, values := range query {
+		if len(values) == 1 {
+			result[key] = values[0]
+		} else {
+			result[key] = values
+		}
+	}
+	return result
+}
+
+// parseDeleteCount returns the count of deleted models from the response to a
+// DeleteWhere request.
+func parseDeleteCount(body json.RawMessage) (int, error) {
+	if len(body) == 0 {
+		return -1, nil
+	}
+	var count int
+	if err := json.Unmarshal(body, &count); err == nil {
+		return count, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return 0, fmt.Errorf("rest: could not read count of deleted models from response: %s", err.Error())
+	}
+	for _, name := range []string{"count", "deleted", "deletedCount"} {
+		if raw, found := fields[name]; found {
+			if err := json.Unmarshal(raw, &count); err != nil {
+				return 0, fmt.Errorf("rest: could not read count of deleted models from response: %s", err.Error())
+			}
+			return count, nil
+		}
+	}
+	return 0, fmt.Errorf("rest: could not read count of deleted models from response: no count field in %s", string(body))
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDeleteWhere(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"deleted": 2}`)
+	count, err := NewClient().DeleteWhere(&testTodo{}, Query{"IsCompleted": {"true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected a count of 2 but got %d", count)
+	}
+	req := server.last()
+	if req.Method != "DELETE" || req.URL.Path != "/todos" || req.URL.Query().Get("IsCompleted") != "true" {
+		t.Errorf("Unexpected request: %s %s", req.Method, req.URL)
+	}
+}
+
+func TestDeleteWhereViaPost(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, "3")
+	client := NewClient()
+	query := Query{"IsCompleted": {"true"}, "Title": {"a", "b"}}
+	if _, err := client.DeleteWhere(&testTodo{}, query, WithDeleteViaPost("/delete")); err != nil {
+		t.Fatal(err)
+	}
+	req := server.last()
+	if req.Method != "POST" || req.URL.Path != "/todos/delete" {
+		t.Errorf("Unexpected request: %s %s", req.Method, req.URL)
+	}
+	if body := server.lastBody(); body != "IsCompleted=true&Title=a&Title=b" {
+		t.Errorf("Expected a url encoded body but got %q", body)
+	}
+	client.ContentType = ContentJSON
+	if _, err := client.DeleteWhere(&testTodo{}, query, WithDeleteViaPost("/delete")); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != `{"IsCompleted":"true","Title":["a","b"]}` {
+		t.Errorf("Expected a JSON body but got %q", body)
+	}
+}
+
+func TestDeleteWhereInvalidatesCache(t *testing.T) {
+	gets := 0
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			gets++
+			w.Write([]byte("[]"))
+			return
+		}
+		w.Write([]byte("1"))
+	})
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	for i := 0; i < 2; i++ {
+		if err := client.ReadAll(&[]*testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.DeleteWhere(&testTodo{}, Query{"IsCompleted": {"true"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gets != 2 {
+		t.Errorf("Expected DeleteWhere to invalidate the cached collection but got %d GET requests", gets)
+	}
+}
+
+func TestParseDeleteCount(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected int
+	}{
+		{body: "", expected: -1},
+		{body: "4", expected: 4},
+		{body: `{"count": 5}`, expected: 5},
+		{body: `{"deletedCount": 6, "ok": true}`, expected: 6},
+	}
+	for _, test := range tests {
+		count, err := parseDeleteCount([]byte(test.body))
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", test.body, err)
+		} else if count != test.expected {
+			t.Errorf("Expected a count of %d for %q but got %d", test.expected, test.body, count)
+		}
+	}
+	for _, body := range []string{`{"ok": true}`, `{"count": "many"}`, "[1]"} {
+		if _, err := parseDeleteCount([]byte(body)); err == nil {
+			t.Errorf("Expected an error for %q", body)
+		}
+	}
+}
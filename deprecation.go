@@ -0,0 +1,107 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeprecationInfo describes the deprecation-related headers sent by the server
+// in response to a request.
+type DeprecationInfo struct {
+	// Method is the http method of the request
+	Method string
+	// URL is the url that the request was sent to
+	URL string
+	// Deprecated is true if the server sent a Deprecation header
+	Deprecated bool
+	// DeprecatedAt is the date the endpoint was (or will be) deprecated, if the
+	// Deprecation header contained one. Otherwise it is the zero time.
+	DeprecatedAt time.Time
+	// Sunset is the date after which the endpoint will stop working, from the
+	// Sunset header. It is the zero time if the header was not sent or could
+	// not be parsed.
+	Sunset time.Time
+	// Warnings holds the values of any Warning headers
+	Warnings []string
+	// Links holds the values of any Link headers, which may point to
+	// documentation about the deprecation.
+	Links []string
+}
+
+// deprecationInfo returns information about the deprecation headers in res and
+// true, or false if res does not contain any.
+func deprecationInfo(res *http.Response) (DeprecationInfo, bool) {
+	deprecation := res.Header.Get("Deprecation")
+	sunset := res.Header.Get("Sunset")
+	warnings := res.Header["Warning"]
+	if deprecation == "" && sunset == "" && len(warnings) == 0 {
+		return DeprecationInfo{}, false
+	}
+	info := DeprecationInfo{
+		Method:     res.Request.Method,
+		URL:        res.Request.URL.String(),
+		Deprecated: deprecation != "" && deprecation != "false",
+		Warnings:   warnings,
+		Links:      res.Header["Link"],
+	}
+	if deprecation != "" {
+		info.DeprecatedAt = parseHeaderDate(deprecation)
+	}
+	if sunset != "" {
+		info.Sunset = parseHeaderDate(sunset)
+	}
+	return info, true
+}
+
+// parseHeaderDate parses a date from a header value, which may be either an
+// HTTP-date (e.g. "Sat, 01 Jul 2023 23:59:59 GMT") or a structured field date
+// (e.g. "@1688169599"). It returns the zero time if value is neither.
+func parseHeaderDate(value string) time.Time {
+	if strings.HasPrefix(value, "@") {
+		if seconds, err := strconv.ParseInt(value[1:], 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+		return time.Time{}
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// checkDeprecation reports any deprecation headers in res by calling
+// c.OnDeprecation and logging a warning if c.LogDeprecations is true.
+func (c *Client) checkDeprecation(res *http.Response) {
+	if c.OnDeprecation == nil && !c.LogDeprecations {
+		return
+	}
+	info, found := deprecationInfo(res)
+	if !found {
+		return
+	}
+	if c.LogDeprecations {
+		msg := "rest: warning: " + info.Method + " " + info.URL
+		if info.Deprecated {
+			msg += " is deprecated"
+		} else {
+			msg += " returned a warning"
+		}
+		if !info.Sunset.IsZero() {
+			msg += " and will stop working after " + info.Sunset.Format(time.RFC1123)
+		}
+		if len(info.Warnings) > 0 {
+			msg += ": " + strings.Join(info.Warnings, "; ")
+		}
+		log.Println(msg)
+	}
+	if c.OnDeprecation != nil {
+		c.OnDeprecation(info)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnDeprecation(t *testing.T) {
+	header := http.Header{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	client := NewClient()
+	infos := []DeprecationInfo{}
+	client.OnDeprecation = func(info DeprecationInfo) {
+		infos = append(infos, info)
+	}
+
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("Expected no deprecation info without the headers but got %v", infos)
+	}
+
+	header.Set("Deprecation", "@1688255999")
+	header.Set("Sunset", "Sat, 01 Jul 2023 23:59:59 GMT")
+	header.Add("Warning", `299 - "Use /v2/todos"`)
+	header.Add("Link", `<https://example.com/docs>; rel="deprecation"`)
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("Expected one deprecation info but got %v", infos)
+	}
+	info := infos[0]
+	sunset := time.Date(2023, 7, 1, 23, 59, 59, 0, time.UTC)
+	if info.Method != "GET" || info.URL != testRootURL+"/todos/1" || !info.Deprecated {
+		t.Errorf("Unexpected deprecation info: %+v", info)
+	}
+	if !info.DeprecatedAt.Equal(sunset) || !info.Sunset.Equal(sunset) {
+		t.Errorf("Expected both dates to be %s but got %s and %s", sunset, info.DeprecatedAt, info.Sunset)
+	}
+	if len(info.Warnings) != 1 || len(info.Links) != 1 {
+		t.Errorf("Expected the warning and link but got %v and %v", info.Warnings, info.Links)
+	}
+
+	header = http.Header{"Deprecation": {"true"}, "Sunset": {"soon"}}
+	infos = infos[:0]
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || !infos[0].Deprecated || !infos[0].DeprecatedAt.IsZero() || !infos[0].Sunset.IsZero() {
+		t.Errorf("Expected a deprecation without dates but got %+v", infos)
+	}
+}
+
+func TestLogDeprecations(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", `299 - "going away"`)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	client := NewClient()
+	client.LogDeprecations = true
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "/todos/1 is deprecated: 299 - \"going away\"") {
+		t.Errorf("Expected a logged warning but got %q", buf.String())
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext carries the values of the context of a request, e.g. the
+// user id or trace info set by the caller, into work which outlives the
+// request, such as background cache refreshes and mirrored requests. Unlike
+// its parent it is never canceled and has no deadline. The Timing of the
+// request is not carried over, since it belongs to the request alone.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context which carries the values of ctx but is never
+// canceled.
+func detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return detachedContext{parent: ctx}
+}
+
+// Deadline satisfies the context.Context interface. A detachedContext has no
+// deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done satisfies the context.Context interface. A detachedContext is never
+// done.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err satisfies the context.Context interface.
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value satisfies the context.Context interface.
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	if _, ok := key.(timingKey); ok {
+		return nil
+	}
+	return ctx.parent.Value(key)
+}
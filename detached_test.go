@@ -0,0 +1,141 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// userKey is the context key used by the tests to pass a user id through a
+// request.
+type userKey struct{}
+
+// userFrom returns the user id carried by ctx, or "" if there is none.
+func userFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+func TestContextValuesInAuthorize(t *testing.T) {
+	var impersonated string
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		impersonated = r.Header.Get("X-Impersonate")
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	setPolicy(t, Policy{Scopes: []string{"todos:read"}})
+	client := NewClient()
+	client.Authorize = func(req *http.Request, scopes []string) error {
+		req.Header.Set("X-Impersonate", userFrom(req.Context()))
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	if err := client.Read("1", &policyTodo{}, WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	if impersonated != "alice" {
+		t.Errorf("Expected Authorize to see the user from the context but X-Impersonate was %q", impersonated)
+	}
+}
+
+func TestContextValuesInErrorReports(t *testing.T) {
+	newTodoServer(t)
+	client := NewClient()
+	var reports []ErrorReport
+	client.OnError = func(report ErrorReport) {
+		reports = append(reports, report)
+	}
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	if err := client.Read("42", &testTodo{}, WithContext(ctx)); err == nil {
+		t.Fatal("Expected an error reading a todo which does not exist")
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 error report but got %d", len(reports))
+	}
+	if got := userFrom(reports[0].Context); got != "alice" {
+		t.Errorf("Expected the error report to carry the user from the context but got %q", got)
+	}
+}
+
+func TestContextValuesInEvents(t *testing.T) {
+	newTodoServer(t)
+	client := NewClient()
+	users := map[EventType]string{}
+	client.Events().Subscribe(func(event Event) {
+		users[event.Type] = userFrom(event.Context)
+	}, ModelCreated, RequestFailed)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	if err := client.Create(&testTodo{Title: "a"}, WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	client.Read("42", &testTodo{}, WithContext(ctx))
+	for _, typ := range []EventType{ModelCreated, RequestFailed} {
+		if users[typ] != "alice" {
+			t.Errorf("Expected the %s event to carry the user from the context but got %q", typ, users[typ])
+		}
+	}
+}
+
+func TestContextValuesInMirroredRequests(t *testing.T) {
+	_, _, secondaryURL := newMirrorServers(t)
+	results := make(chan MirrorResult, 1)
+	mirrorUsers := make(chan string, 1)
+	client := NewClient()
+	client.HTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(testRootURL, "http://"+req.URL.Host) {
+				mirrorUsers <- userFrom(req.Context())
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	client.Mirror = &MirrorPolicy{
+		BaseURL:  secondaryURL,
+		Percent:  100,
+		OnResult: func(result MirrorResult) { results <- result },
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), userKey{}, "alice"))
+	if err := client.Read("1", &testTodo{}, WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if result := waitForMirror(t, results); result.Err != nil {
+		t.Errorf("Expected the mirrored request not to be canceled along with the request but got %s", result.Err)
+	}
+	if got := <-mirrorUsers; got != "alice" {
+		t.Errorf("Expected the mirrored request to carry the user from the context but got %q", got)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	ctx = withTiming(ctx, &Timing{})
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	detached := detach(ctx)
+	<-ctx.Done()
+
+	if detached.Err() != nil || detached.Done() != nil {
+		t.Error("Expected the detached context not to be canceled along with its parent")
+	}
+	if _, hasDeadline := detached.Deadline(); hasDeadline {
+		t.Error("Expected the detached context to have no deadline")
+	}
+	if got := userFrom(detached); got != "alice" {
+		t.Errorf("Expected the detached context to carry the values of its parent but got %q", got)
+	}
+	if timingFrom(detached) != nil {
+		t.Error("Expected the detached context not to carry the Timing of the request")
+	}
+	if detach(nil) == nil {
+		t.Error("Expected detach(nil) to return a usable context")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// FieldAdded means the field is present in the new model but not the old.
+	FieldAdded ChangeKind = "added"
+	// FieldRemoved means the field is present in the old model but not the new.
+	FieldRemoved ChangeKind = "removed"
+	// FieldModified means the field has a different value in the new model.
+	FieldModified ChangeKind = "modified"
+)
+
+// Change is a single difference between two versions of a model.
+type Change struct {
+	// Kind is the kind of change
+	Kind ChangeKind
+	// Path is the list of JSON keys leading to the field which changed. For a
+	// top-level field it has a single element. Nested objects are compared
+	// field by field, so e.g. a change to the Name of an Author has the path
+	// ["Author", "Name"].
+	Path []string
+	// Old is the old value of the field as decoded from JSON (e.g. float64 for
+	// numbers), or nil if the field was added.
+	Old interface{}
+	// New is the new value of the field as decoded from JSON, or nil if the
+	// field was removed.
+	New interface{}
+}
+
+// Pointer returns the path of the change as a JSON Pointer (RFC 6901), e.g.
+// "/Author/Name".
+func (change Change) Pointer() string {
+	pointer := ""
+	for _, key := range change.Path {
+		pointer += "/" + escapeJSONPointer(key)
+	}
+	return pointer
+}
+
+// Diff returns the changes needed to turn old into new, sorted by path. The
+// models are compared using their JSON representations, so field names are
+// JSON keys and only fields which would be sent to the server are compared.
+// Diff returns an empty slice if the models are equivalent. It can be used to
+// tell whether a model has unsaved changes, to build audit entries, or to
+// generate patches (see PatchMode).
+func Diff(old, new Model) ([]Change, error) {
+	before, err := toJSONObject(old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := toJSONObject(new)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	diffObjects(&changes, nil, before, after)
+	return changes, nil
+}
+
+// diffObjects appends the changes needed to turn the JSON object before into
+// after to changes. path is the path to the objects being compared.
+func diffObjects(changes *[]Change, path []string, before, after map[string]interface{}) {
+	for _, key := range sortedKeys(before, after) {
+		keyPath := append(append([]string{}, path...), key)
+		oldValue, inBefore := before[key]
+		newValue, inAfter := after[key]
+		switch {
+		case !inAfter:
+			*changes = append(*changes, Change{Kind: FieldRemoved, Path: keyPath, Old: oldValue})
+		case !inBefore:
+			*changes = append(*changes, Change{Kind: FieldAdded, Path: keyPath, New: newValue})
+		case reflect.DeepEqual(oldValue, newValue):
+		default:
+			oldObj, oldIsObj := oldValue.(map[string]interface{})
+			newObj, newIsObj := newValue.(map[string]interface{})
+			if oldIsObj && newIsObj {
+				diffObjects(changes, keyPath, oldObj, newObj)
+			} else {
+				*changes = append(*changes, Change{Kind: FieldModified, Path: keyPath, Old: oldValue, New: newValue})
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of the given objects, sorted and without
+// duplicates, so that diffs are deterministic.
+func sortedKeys(objs ...map[string]interface{}) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, obj := range objs {
+		for key := range obj {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeJSONPointer escapes a key for use in a JSON Pointer (RFC 6901).
+func escapeJSONPointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
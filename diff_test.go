@@ -0,0 +1,65 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// authoredTodo is a todo with a nested object, used to test nested diffs.
+type authoredTodo struct {
+	testTodo
+	Author struct {
+		Name  string
+		Email string `json:",omitempty"`
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &authoredTodo{}
+	old.Id = "1"
+	old.Title = "a"
+	old.Author.Name = "alex"
+	old.Author.Email = "alex@example.com"
+	new := &authoredTodo{}
+	*new = *old
+	if changes, err := Diff(old, new); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 0 {
+		t.Errorf("Expected no changes for equivalent models but got %v", changes)
+	}
+
+	new.Title = "b"
+	new.Author.Name = "bob"
+	new.Author.Email = ""
+	changes, err := Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{Kind: FieldRemoved, Path: []string{"Author", "Email"}, Old: "alex@example.com"},
+		{Kind: FieldModified, Path: []string{"Author", "Name"}, Old: "alex", New: "bob"},
+		{Kind: FieldModified, Path: []string{"Title"}, Old: "a", New: "b"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %v but got %v", expected, changes)
+	}
+
+	changes, err = Diff(new, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[0].Kind != FieldAdded || changes[0].New != "alex@example.com" {
+		t.Errorf("Expected the email to be added in the reverse diff but got %v", changes)
+	}
+}
+
+func TestChangePointer(t *testing.T) {
+	change := Change{Path: []string{"Author", "a/b~c"}}
+	if pointer := change.Pointer(); pointer != "/Author/a~1b~0c" {
+		t.Errorf("Expected the path to be escaped but got %s", pointer)
+	}
+}
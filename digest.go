@@ -0,0 +1,135 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// DigestAlgorithm is an algorithm used to compute a checksum over the body of
+// a request or response.
+type DigestAlgorithm string
+
+const (
+	// DigestMD5 causes the client to send a Content-MD5 header containing
+	// the base64-encoded MD5 sum of the request body.
+	DigestMD5 DigestAlgorithm = "MD5"
+	// DigestSHA256 causes the client to send a Digest header (or the header
+	// named by Client.DigestHeader) of the form "SHA-256=<base64 sum>".
+	DigestSHA256 DigestAlgorithm = "SHA-256"
+)
+
+// DigestError is returned when the body of a response does not match the
+// digest sent by the server.
+type DigestError struct {
+	// URL is the url that the request was sent to
+	URL string
+	// Algorithm is the algorithm that was used to compute the digest
+	Algorithm DigestAlgorithm
+	// Expected is the base64-encoded digest sent by the server
+	Expected string
+	// Actual is the base64-encoded digest of the body that was received
+	Actual string
+}
+
+// Error satisfies the error interface
+func (e DigestError) Error() string {
+	return fmt.Sprintf("rest: %s digest of response from %s did not match: expected %s but got %s", e.Algorithm, e.URL, e.Expected, e.Actual)
+}
+
+// newHash returns a new hash.Hash for the algorithm, or nil if the algorithm
+// is not supported.
+func (alg DigestAlgorithm) newHash() hash.Hash {
+	switch alg {
+	case DigestMD5:
+		return md5.New()
+	case DigestSHA256:
+		return sha256.New()
+	default:
+		return nil
+	}
+}
+
+// sum returns the base64-encoded checksum of data.
+func (alg DigestAlgorithm) sum(data []byte) string {
+	h := alg.newHash()
+	h.Write(data)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// digestHeader returns the name of the header used for DigestSHA256.
+func (c *Client) digestHeader() string {
+	if c.DigestHeader != "" {
+		return c.DigestHeader
+	}
+	return "Digest"
+}
+
+// setBodyDigest sets the checksum header for the request body data according
+// to c.BodyDigest.
+func (c *Client) setBodyDigest(req *http.Request, data []byte) error {
+	switch c.BodyDigest {
+	case "":
+		return nil
+	case DigestMD5:
+		req.Header.Set("Content-MD5", DigestMD5.sum(data))
+	case DigestSHA256:
+		req.Header.Set(c.digestHeader(), string(DigestSHA256)+"="+DigestSHA256.sum(data))
+	default:
+		return fmt.Errorf("rest: unsupported DigestAlgorithm: %s", c.BodyDigest)
+	}
+	return nil
+}
+
+// verifyResponseDigest checks body against any Content-MD5 or Digest headers
+// in res. It returns a DigestError if any of them do not match. Algorithms
+// which are not supported are ignored. It does nothing unless
+// c.VerifyResponseDigest is true.
+func (c *Client) verifyResponseDigest(res *http.Response, body []byte) error {
+	if !c.VerifyResponseDigest {
+		return nil
+	}
+	if expected := res.Header.Get("Content-MD5"); expected != "" {
+		if err := checkDigest(res, DigestMD5, expected, body); err != nil {
+			return err
+		}
+	}
+	// The Digest header may contain several comma-separated digests, e.g.
+	// "SHA-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=, MD5=...".
+	for _, digest := range strings.Split(res.Header.Get(c.digestHeader()), ",") {
+		i := strings.Index(digest, "=")
+		if i == -1 {
+			continue
+		}
+		alg := DigestAlgorithm(strings.ToUpper(strings.TrimSpace(digest[:i])))
+		if alg.newHash() == nil {
+			continue
+		}
+		if err := checkDigest(res, alg, strings.TrimSpace(digest[i+1:]), body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDigest returns a DigestError if the alg checksum of body is not equal
+// to expected.
+func checkDigest(res *http.Response, alg DigestAlgorithm, expected string, body []byte) error {
+	if actual := alg.sum(body); actual != expected {
+		return DigestError{
+			URL:       res.Request.URL.String(),
+			Algorithm: alg,
+			Expected:  expected,
+			Actual:    actual,
+		}
+	}
+	return nil
+}
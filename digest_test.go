@@ -0,0 +1,83 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestBodyDigest(t *testing.T) {
+	var header http.Header
+	var body []byte
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	client := NewClient()
+
+	client.BodyDigest = DigestMD5
+	if err := client.Create(&testTodo{Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	md5Sum := md5.Sum(body)
+	if got, expected := header.Get("Content-MD5"), base64.StdEncoding.EncodeToString(md5Sum[:]); got != expected {
+		t.Errorf("Expected Content-MD5 %q but got %q", expected, got)
+	}
+
+	client.BodyDigest = DigestSHA256
+	client.DigestHeader = "Repr-Digest"
+	if err := client.Create(&testTodo{Title: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	shaSum := sha256.Sum256(body)
+	if got, expected := header.Get("Repr-Digest"), "SHA-256="+base64.StdEncoding.EncodeToString(shaSum[:]); got != expected {
+		t.Errorf("Expected Repr-Digest %q but got %q", expected, got)
+	}
+
+	client.BodyDigest = "CRC32"
+	if err := client.Create(&testTodo{Title: "c"}); err == nil {
+		t.Error("Expected an error for an unsupported DigestAlgorithm")
+	}
+}
+
+func TestVerifyResponseDigest(t *testing.T) {
+	const body = `{"Id": "1", "Title": "a"}`
+	digest := ""
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Digest", digest)
+		w.Write([]byte(body))
+	})
+	client := NewClient()
+	client.VerifyResponseDigest = true
+	shaSum := sha256.Sum256([]byte(body))
+	valid := base64.StdEncoding.EncodeToString(shaSum[:])
+
+	for _, digest = range []string{"", "SHA-256=" + valid, "unknown=abc, sha-256=" + valid} {
+		if err := client.Read("1", &testTodo{}); err != nil {
+			t.Errorf("Expected digest %q to be accepted but got %v", digest, err)
+		}
+	}
+
+	digest = "SHA-256=" + DigestSHA256.sum([]byte("tampered"))
+	err := client.Read("1", &testTodo{})
+	digestErr, ok := err.(DigestError)
+	if !ok {
+		t.Fatalf("Expected a DigestError but got %v", err)
+	}
+	if digestErr.Algorithm != DigestSHA256 || digestErr.Actual != valid {
+		t.Errorf("Unexpected DigestError: %+v", digestErr)
+	}
+
+	client.VerifyResponseDigest = false
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Errorf("Expected digests to be ignored unless VerifyResponseDigest is set, but got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestDoRequest(t *testing.T) {
+	server := newEchoServer(t, http.StatusTeapot, "data: 1\n\n")
+	client := NewClient()
+	client.Header = http.Header{"X-Client": {"test"}}
+	client.Cache = NewMemoryCache()
+	failures := 0
+	client.Events().Subscribe(func(Event) { failures++ }, RequestFailed)
+	rb := client.NewRequestBuilder("GET", testRootURL+"/events").
+		Query(Query{"since": {"1"}}).
+		Header("Accept", "text/event-stream")
+	for i := 0; i < 2; i++ {
+		res, err := client.DoRequest(context.Background(), rb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusTeapot || string(body) != "data: 1\n\n" {
+			t.Errorf("Expected the response to be returned as is but got %d %q", res.StatusCode, body)
+		}
+	}
+	if len(server.all()) != 2 {
+		t.Errorf("Expected DoRequest not to use the cache but got %d requests", len(server.all()))
+	}
+	req := server.last()
+	if req.URL.Query().Get("since") != "1" || req.Header.Get("Accept") != "text/event-stream" || req.Header.Get("X-Client") != "test" {
+		t.Errorf("Unexpected request: %s %v", req.URL, req.Header)
+	}
+	if failures != 2 {
+		t.Errorf("Expected a RequestFailed event for each 418 response but got %d", failures)
+	}
+}
+
+func TestDoRequestInvalid(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, "")
+	client := NewClient()
+	rb := client.NewRequestBuilder("GET", testRootURL).Query(42)
+	if _, err := client.DoRequest(context.Background(), rb); err == nil {
+		t.Error("Expected an error for an invalid query")
+	}
+	if len(server.all()) != 0 {
+		t.Error("Expected the invalid request not to be sent")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrResourceChanged is the Err of a DownloadError when a download could not
+// be resumed because the resource changed since the download started, and w
+// could not be truncated to start over. The Offset of the DownloadError is 0,
+// since the download has to start over with an empty writer.
+var ErrResourceChanged = errors.New("rest: resource changed since the download started")
+
+// DownloadError is returned by Download when a download fails part-way
+// through. It can be used to resume the download with the WithResumeFrom and
+// WithIfRange options.
+type DownloadError struct {
+	// URL is the url that the download was sent to
+	URL string
+	// Offset is the number of bytes which were successfully written to w
+	// before the error occurred, counting from the beginning of the resource.
+	Offset int64
+	// ETag is the strong ETag of the resource, or an empty string if the
+	// server did not send one.
+	ETag string
+	// Err is the error that caused the download to fail
+	Err error
+}
+
+// Error satisfies the error interface
+func (e DownloadError) Error() string {
+	return fmt.Sprintf("rest: download of %s failed at offset %d: %s", e.URL, e.Offset, e.Err.Error())
+}
+
+// Download sends a GET request to url and copies the body of the response to
+// w as it is received. Unlike the other methods of Client, Download does not
+// expect a JSON response, so it can be used for resources like reports,
+// exports, and images. The WithProgress option can be used to track the
+// progress of the download. Download returns an HTTPError if the response has
+// a non-2xx status code, in which case nothing is written to w.
+//
+// If the download fails part-way through, Download returns a DownloadError.
+// The download can be resumed where it left off by calling Download again
+// with the WithResumeFrom and WithIfRange options, which cause it to send a
+// Range request for the rest of the resource, as long as it still has the
+// given ETag. If w is an io.Seeker, it is first moved to the offset. If the
+// resource changed in the meantime, or if no strong ETag is given with
+// WithIfRange, so that there is no way to tell whether it did, the whole
+// resource is sent again and the download starts over: w is truncated first if
+// it has a Truncate method (as *os.File does), and otherwise Download returns
+// a DownloadError with ErrResourceChanged without writing anything.
+//
+//	err := client.Download(url, f)
+//	if dlErr, ok := err.(rest.DownloadError); ok {
+//		err = client.Download(url, f, rest.WithResumeFrom(dlErr.Offset), rest.WithIfRange(dlErr.ETag))
+//	}
+func (c *Client) Download(url string, w io.Writer, opts ...RequestOption) error {
+	reqOpts := newRequestOptions(opts)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("Something went wrong building GET request to %s: %s", url, err.Error())
+	}
+	offset := reqOpts.resumeFrom
+	// Without a validator, the rest of the resource might belong to a
+	// different version than what w already has, so it is not requested
+	canResume := isStrongETag(reqOpts.ifRange)
+	if offset > 0 && canResume {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", reqOpts.ifRange)
+	}
+	res, err := c.do(req.WithContext(reqOpts.context()))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if offset > 0 && canResume && res.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		if _, total, ok := parseContentRange(res.Header.Get("Content-Range")); ok && total == offset {
+			// The previous attempt got everything but failed to notice
+			return nil
+		}
+	}
+	if res.StatusCode/100 != 2 {
+		return newHTTPError(res)
+	}
+	etag := res.Header.Get("ETag")
+	if !isStrongETag(etag) {
+		etag = ""
+	}
+	total := res.ContentLength
+	if offset > 0 {
+		start, size, ok := parseContentRange(res.Header.Get("Content-Range"))
+		resumed := canResume && res.StatusCode == http.StatusPartialContent && ok && start == offset &&
+			(etag == "" || etag == reqOpts.ifRange)
+		if resumed {
+			total = size
+		} else {
+			if res.StatusCode == http.StatusPartialContent {
+				// A range we did not ask for, or a different version of the
+				// resource, which cannot be appended to what w already has
+				return DownloadError{URL: url, Offset: 0, ETag: etag, Err: ErrResourceChanged}
+			}
+			// The server sent the whole resource, so start over
+			if err := restartWriter(w); err != nil {
+				return DownloadError{URL: url, Offset: 0, ETag: etag, Err: err}
+			}
+			offset = 0
+		}
+	}
+	if offset > 0 {
+		if seeker, ok := w.(io.Seeker); ok {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return DownloadError{URL: url, Offset: offset, ETag: etag, Err: err}
+			}
+		}
+	}
+	counter := &progressWriter{w: w, written: offset, total: total, progress: reqOpts.progress}
+	if _, err := io.Copy(counter, res.Body); err != nil {
+		return DownloadError{URL: url, Offset: counter.written, ETag: etag, Err: err}
+	}
+	return nil
+}
+
+// restartWriter prepares w to receive a download from the beginning, or
+// returns ErrResourceChanged if that is not possible.
+func restartWriter(w io.Writer) error {
+	truncater, ok := w.(interface {
+		Truncate(size int64) error
+	})
+	if !ok {
+		return ErrResourceChanged
+	}
+	if err := truncater.Truncate(0); err != nil {
+		return err
+	}
+	if seeker, ok := w.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isStrongETag returns true iff etag is a strong entity tag, which is the
+// only kind that may be used in an If-Range header.
+func isStrongETag(etag string) bool {
+	return len(etag) >= 2 && etag[0] == '"' && etag[len(etag)-1] == '"'
+}
+
+// parseContentRange parses a Content-Range header of the form
+// "bytes start-end/total" or "bytes */total". The total is -1 if it is
+// unknown ("*").
+func parseContentRange(header string) (start int64, total int64, ok bool) {
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, false
+	}
+	spec := strings.TrimPrefix(header, "bytes ")
+	slash := strings.Index(spec, "/")
+	if slash == -1 {
+		return 0, 0, false
+	}
+	total = -1
+	if spec[slash+1:] != "*" {
+		var err error
+		if total, err = strconv.ParseInt(spec[slash+1:], 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if spec[:slash] == "*" {
+		return 0, total, true
+	}
+	dash := strings.Index(spec[:slash], "-")
+	if dash == -1 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(spec[:dash], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// DownloadModelAttachment downloads the attachment belonging to model and
+// writes it to w. field is the name of a string field of model which holds the
+// url of the attachment (e.g. "AvatarURL"). See Download for more details.
+func (c *Client) DownloadModelAttachment(model Model, field string, w io.Writer, opts ...RequestOption) error {
+	modelVal := reflect.ValueOf(model)
+	for modelVal.Kind() == reflect.Ptr {
+		if modelVal.IsNil() {
+			return fmt.Errorf("rest: cannot download attachment of nil model %T", model)
+		}
+		modelVal = modelVal.Elem()
+	}
+	if modelVal.Kind() != reflect.Struct {
+		return fmt.Errorf("rest: cannot download attachment: model must be a struct or a pointer to a struct.")
+	}
+	fieldVal := modelVal.FieldByName(field)
+	if !fieldVal.IsValid() {
+		return fmt.Errorf("rest: cannot download attachment: %T has no field named %s", model, field)
+	}
+	if fieldVal.Kind() != reflect.String {
+		return fmt.Errorf("rest: cannot download attachment: %T.%s must be a string but has type %s", model, field, fieldVal.Type().String())
+	}
+	if fieldVal.String() == "" {
+		return fmt.Errorf("rest: cannot download attachment: %T.%s is empty", model, field)
+	}
+	return c.Download(fieldVal.String(), w, opts...)
+}
+
+// progressWriter is an io.Writer which counts the bytes written to w and
+// calls progress, if it is not nil, after each write.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.written += int64(n)
+	if pw.progress != nil {
+		pw.progress(pw.written, pw.total)
+	}
+	return n, err
+}
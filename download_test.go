@@ -0,0 +1,261 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportContent is the resource served by newReportServer.
+var reportContent = strings.Repeat("0123456789", 1000)
+
+// newReportServer starts a server which serves reportContent at /report with
+// support for Range and If-Range requests, and responds with 404 otherwise.
+func newReportServer(t *testing.T, etag string) *requestLog {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		if r.URL.Path != "/report" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "report.txt", time.Time{}, strings.NewReader(reportContent))
+	})
+	return requests
+}
+
+func TestDownload(t *testing.T) {
+	newReportServer(t, `"v1"`)
+	client := NewClient()
+	var buf bytes.Buffer
+	var lastWritten, lastTotal int64
+	err := client.Download(testRootURL+"/report", &buf, WithProgress(func(written, total int64) {
+		lastWritten, lastTotal = written, total
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != reportContent {
+		t.Errorf("Expected the whole report but got %d bytes", buf.Len())
+	}
+	if lastWritten != int64(len(reportContent)) || lastTotal != int64(len(reportContent)) {
+		t.Errorf("Expected progress to reach %d of %d but got %d of %d", len(reportContent), len(reportContent), lastWritten, lastTotal)
+	}
+
+	buf.Reset()
+	err = client.Download(testRootURL+"/missing", &buf)
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected an HTTPError with status 404 but got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be written for an error response but got %q", buf.String())
+	}
+}
+
+// attachment is a model with the url of an attachment.
+type attachment struct {
+	DefaultId
+	FileURL string
+	Size    int
+}
+
+func (*attachment) RootURL() string { return testRootURL + "/attachments" }
+
+func TestDownloadModelAttachment(t *testing.T) {
+	newReportServer(t, `"v1"`)
+	client := NewClient()
+	var buf bytes.Buffer
+	if err := client.DownloadModelAttachment(&attachment{FileURL: testRootURL + "/report"}, "FileURL", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != reportContent {
+		t.Errorf("Expected the attachment but got %d bytes", buf.Len())
+	}
+	var nilAttachment *attachment
+	invalid := map[string]struct {
+		model Model
+		field string
+	}{
+		"nil model":     {nilAttachment, "FileURL"},
+		"missing field": {&attachment{}, "URL"},
+		"not a string":  {&attachment{}, "Size"},
+		"empty url":     {&attachment{}, "FileURL"},
+	}
+	for name, test := range invalid {
+		if err := client.DownloadModelAttachment(test.model, test.field, &buf); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+// newInterruptedReportServer starts a server which serves reportContent at
+// /report like newReportServer, except that requests without a Range header
+// are cut off after the first cutoff bytes.
+func newInterruptedReportServer(t *testing.T, etag string, cutoff int) *requestLog {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("Range") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(reportContent)))
+			w.Write([]byte(reportContent[:cutoff]))
+			return
+		}
+		http.ServeContent(w, r, "report.txt", time.Time{}, strings.NewReader(reportContent))
+	})
+	return requests
+}
+
+func TestDownloadResume(t *testing.T) {
+	requests := newInterruptedReportServer(t, `"v1"`, 4000)
+	client := NewClient()
+	var buf bytes.Buffer
+	err := client.Download(testRootURL+"/report", &buf)
+	dlErr, ok := err.(DownloadError)
+	if !ok {
+		t.Fatalf("Expected a DownloadError but got %T: %v", err, err)
+	}
+	if dlErr.Offset != 4000 || dlErr.ETag != `"v1"` || dlErr.URL != testRootURL+"/report" {
+		t.Errorf("Expected a DownloadError at offset 4000 with ETag \"v1\" but got %+v", dlErr)
+	}
+	if buf.Len() != 4000 {
+		t.Fatalf("Expected the first 4000 bytes to be written but got %d", buf.Len())
+	}
+
+	var lastWritten, lastTotal int64
+	err = client.Download(testRootURL+"/report", &buf, WithResumeFrom(dlErr.Offset), WithIfRange(dlErr.ETag), WithProgress(func(written, total int64) {
+		lastWritten, lastTotal = written, total
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != reportContent {
+		t.Errorf("Expected the resumed download to complete the report but got %d bytes", buf.Len())
+	}
+	req := requests.last()
+	if got := req.Header.Get("Range"); got != "bytes=4000-" {
+		t.Errorf("Expected a Range header of bytes=4000- but got %q", got)
+	}
+	if got := req.Header.Get("If-Range"); got != `"v1"` {
+		t.Errorf("Expected an If-Range header of \"v1\" but got %q", got)
+	}
+	if size := int64(len(reportContent)); lastWritten != size || lastTotal != size {
+		t.Errorf("Expected progress to count from the start of the resource and reach %d of %d but got %d of %d", size, size, lastWritten, lastTotal)
+	}
+}
+
+func TestDownloadResumeChangedResource(t *testing.T) {
+	newReportServer(t, `"v2"`)
+	client := NewClient()
+	partial := reportContent[:4000]
+
+	// A writer which cannot be truncated cannot start over
+	buf := bytes.NewBufferString(partial)
+	err := client.Download(testRootURL+"/report", buf, WithResumeFrom(4000), WithIfRange(`"v1"`))
+	if dlErr, ok := err.(DownloadError); !ok || dlErr.Err != ErrResourceChanged || dlErr.Offset != 0 || dlErr.ETag != `"v2"` {
+		t.Errorf("Expected a DownloadError with ErrResourceChanged but got %v", err)
+	}
+	if buf.String() != partial {
+		t.Errorf("Expected nothing to be written but got %d bytes", buf.Len())
+	}
+
+	// A file is truncated and the download starts over
+	f, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("stale content"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Download(testRootURL+"/report", f, WithResumeFrom(13), WithIfRange(`"v1"`)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != reportContent {
+		t.Errorf("Expected the file to hold the whole report but got %d bytes", len(got))
+	}
+}
+
+func TestDownloadResumeWithoutETag(t *testing.T) {
+	requests := newReportServer(t, `"v2"`)
+	client := NewClient()
+
+	// Without an ETag to compare with, the partial download might belong to
+	// a different version, so it cannot be resumed
+	buf := bytes.NewBufferString(reportContent[:4000])
+	err := client.Download(testRootURL+"/report", buf, WithResumeFrom(4000))
+	if dlErr, ok := err.(DownloadError); !ok || dlErr.Err != ErrResourceChanged || dlErr.Offset != 0 {
+		t.Errorf("Expected a DownloadError with ErrResourceChanged but got %v", err)
+	}
+	if got := requests.last().Header.Get("Range"); got != "" {
+		t.Errorf("Expected no Range header without an ETag but got %q", got)
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("stale content"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Download(testRootURL+"/report", f, WithResumeFrom(13)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != reportContent {
+		t.Errorf("Expected the file to hold the whole report but got %d bytes", len(got))
+	}
+}
+
+func TestDownloadResumeComplete(t *testing.T) {
+	newReportServer(t, `"v1"`)
+	buf := bytes.NewBufferString(reportContent)
+	if err := NewClient().Download(testRootURL+"/report", buf, WithResumeFrom(int64(len(reportContent))), WithIfRange(`"v1"`)); err != nil {
+		t.Errorf("Expected resuming a complete download to succeed but got %s", err)
+	}
+	if buf.String() != reportContent {
+		t.Errorf("Expected nothing more to be written but got %d bytes", buf.Len())
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header       string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 100-199/*", 100, -1, true},
+		{"bytes */1000", 0, 1000, true},
+		{"bytes 100/1000", 0, 0, false},
+		{"bytes x-199/1000", 0, 0, false},
+		{"bytes 100-199/x", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, test := range tests {
+		start, total, ok := parseContentRange(test.header)
+		if start != test.start || total != test.total || ok != test.ok {
+			t.Errorf("parseContentRange(%q): expected %d, %d, %t but got %d, %d, %t", test.header, test.start, test.total, test.ok, start, total, ok)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// session has no fields which are sent to the server. Everything about it is
+// generated by the server.
+type session struct {
+	Token string `rest:"-"`
+}
+
+func (s *session) ModelId() string { return s.Token }
+func (*session) RootURL() string   { return testRootURL + "/sessions" }
+
+func TestCreateWithEmptyBody(t *testing.T) {
+	server := newEchoServer(t, http.StatusCreated, `{"Id": "1", "Title": "generated"}`)
+	todo := &testTodo{Title: "a"}
+	if err := NewClient().Create(todo, WithEmptyBody()); err != nil {
+		t.Fatal(err)
+	}
+	req := server.last()
+	if body := server.lastBody(); body != "" || req.ContentLength != 0 {
+		t.Errorf("Expected no body but got %q", body)
+	}
+	if _, found := req.Header["Content-Type"]; found {
+		t.Errorf("Expected no Content-Type header but got %s", req.Header.Get("Content-Type"))
+	}
+	if todo.Id != "1" || todo.Title != "generated" {
+		t.Errorf("Expected the response to be used to set the fields but got %+v", todo)
+	}
+
+	if err := NewClient().Create(&testTodo{Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body == "" {
+		t.Errorf("Expected a body without WithEmptyBody but got %q", body)
+	}
+}
+
+func TestCreateModelWithoutFields(t *testing.T) {
+	server := newEchoServer(t, http.StatusCreated, `{"Token": "abc"}`)
+	s := &session{}
+	if err := NewClient().Create(s); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != "" {
+		t.Errorf("Expected no body for a model without encodable fields but got %q", body)
+	}
+	if _, found := server.last().Header["Content-Type"]; found {
+		t.Error("Expected no Content-Type header for a model without encodable fields")
+	}
+	if s.Token != "abc" {
+		t.Errorf("Expected the token to be set from the response but got %q", s.Token)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"mime"
+	"sync"
+)
+
+// Encoder encodes the fields of a model into the body of a request.
+type Encoder interface {
+	Encode(model Model) ([]byte, error)
+}
+
+// EncoderFunc is an adapter which allows an ordinary function to be used as an
+// Encoder.
+type EncoderFunc func(model Model) ([]byte, error)
+
+// Encode satisfies the Encoder interface by calling f.
+func (f EncoderFunc) Encode(model Model) ([]byte, error) {
+	return f(model)
+}
+
+var (
+	encoders = map[ContentType]Encoder{
+		ContentURLEncoded: EncoderFunc(func(model Model) ([]byte, error) {
+			data, err := urlEncodeFields(model)
+			return []byte(data), err
+		}),
+		ContentJSON: jsonEncoder{},
+	}
+	encodersMu sync.RWMutex
+)
+
+// jsonEncoder is the built-in Encoder for ContentJSON. Clients replace it with
+// their JSONBackend.
+type jsonEncoder struct{}
+
+// Encode satisfies the Encoder interface by calling json.Marshal.
+func (jsonEncoder) Encode(model Model) ([]byte, error) {
+	return json.Marshal(model)
+}
+
+// RegisterEncoder registers encoder as the Encoder for the given ContentType,
+// which makes it possible to send models in formats the rest package does not
+// know about (e.g. multipart forms, msgpack, or a vendor-specific media type)
+// by setting the client's ContentType or implementing ContentTyper.
+// Registering an encoder for a ContentType that already has one replaces the
+// old encoder, including the built-in encoders for ContentURLEncoded and
+// ContentJSON.
+func RegisterEncoder(contentType ContentType, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[contentType] = encoder
+}
+
+// LookupEncoder returns the Encoder registered for contentType. If there is
+// no encoder for contentType itself, the encoder for its media type without
+// parameters (e.g. "application/json" for "application/json; charset=utf-8")
+// is returned. The second return value is false if neither is registered.
+func LookupEncoder(contentType ContentType) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	if encoder, found := encoders[contentType]; found {
+		return encoder, true
+	}
+	if mediaType, _, err := mime.ParseMediaType(string(contentType)); err == nil {
+		encoder, found := encoders[ContentType(mediaType)]
+		return encoder, found
+	}
+	return nil, false
+}
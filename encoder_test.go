@@ -0,0 +1,85 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const contentVendor ContentType = "application/vnd.test.todo"
+
+// vendorTodo is sent with contentVendor.
+type vendorTodo struct {
+	testTodo
+}
+
+func (*vendorTodo) ContentType() ContentType { return contentVendor }
+
+// registerEncoder registers encoder for contentType until the end of the
+// test, after which the previous encoder, if any, is restored.
+func registerEncoder(t *testing.T, contentType ContentType, encoder Encoder) {
+	encodersMu.RLock()
+	previous, found := encoders[contentType]
+	encodersMu.RUnlock()
+	RegisterEncoder(contentType, encoder)
+	t.Cleanup(func() {
+		encodersMu.Lock()
+		defer encodersMu.Unlock()
+		if found {
+			encoders[contentType] = previous
+		} else {
+			delete(encoders, contentType)
+		}
+	})
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	server := newEchoServer(t, http.StatusCreated, `{"Id": "1"}`)
+	registerEncoder(t, contentVendor, EncoderFunc(func(model Model) ([]byte, error) {
+		return []byte("title:" + model.(*vendorTodo).Title), nil
+	}))
+	todo := &vendorTodo{testTodo{Title: "a"}}
+	if err := NewClient().Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.last().Header.Get("Content-Type"); got != string(contentVendor) {
+		t.Errorf("Expected Content-Type %s but got %s", contentVendor, got)
+	}
+	if body := server.lastBody(); body != "title:a" {
+		t.Errorf("Expected the body from the registered encoder but got %q", body)
+	}
+}
+
+func TestLookupEncoderParameters(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	client := NewClient()
+	client.ContentType = ContentJSON + "; charset=utf-8"
+	if err := client.Update(&testTodo{DefaultId: DefaultId{Id: "1"}, Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); !strings.Contains(body, `"Title":"a"`) {
+		t.Errorf("Expected the JSON encoder to be used for a media type with parameters but got %s", body)
+	}
+	if got := server.last().Header.Get("Content-Type"); got != string(client.ContentType) {
+		t.Errorf("Expected the parameters to be kept in the Content-Type but got %s", got)
+	}
+}
+
+func TestUnknownContentType(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{}`)
+	client := NewClient()
+	client.ContentType = "application/x-unknown"
+	if err := client.Create(&testTodo{Title: "a"}); err == nil {
+		t.Error("Expected an error for a ContentType without an encoder")
+	}
+	if len(server.all()) != 0 {
+		t.Error("Expected no request to be sent")
+	}
+	if _, found := LookupEncoder("application/x-unknown"); found {
+		t.Error("Expected LookupEncoder to report that there is no encoder")
+	}
+}
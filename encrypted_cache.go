@@ -0,0 +1,131 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// KeyProvider supplies the key used by EncryptedCache. The key must be 16, 24,
+// or 32 bytes long, selecting AES-128, AES-192, or AES-256. Key is called for
+// every operation, so implementations can rotate keys or fetch them lazily
+// (e.g. after the user logs in), but should cache the result if it is
+// expensive to compute.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyProviderFunc is an adapter which allows an ordinary function to be used
+// as a KeyProvider.
+type KeyProviderFunc func() ([]byte, error)
+
+// Key satisfies the KeyProvider interface by calling f.
+func (f KeyProviderFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// EncryptedCache is a CacheStore which encrypts values with AES-GCM before
+// passing them to another CacheStore, so that cached responses are never
+// persisted in plain text (e.g. in localStorage). Keys (i.e. urls) are stored
+// as is. Values which cannot be decrypted, because they were encrypted with a
+// different key or have been tampered with, are deleted and treated as
+// missing. If the KeyProvider returns an error, nothing is stored.
+type EncryptedCache struct {
+	store CacheStore
+	keys  KeyProvider
+}
+
+// NewEncryptedCache returns an EncryptedCache which stores encrypted values in
+// store, using the key supplied by keys.
+func NewEncryptedCache(store CacheStore, keys KeyProvider) *EncryptedCache {
+	return &EncryptedCache{
+		store: store,
+		keys:  keys,
+	}
+}
+
+// Compact satisfies the Compacter interface by compacting the underlying
+// store, if it is a Compacter.
+func (ec *EncryptedCache) Compact() {
+	if compacter, ok := ec.store.(Compacter); ok {
+		compacter.Compact()
+	}
+}
+
+// Get satisfies the Get method of CacheStore.
+func (ec *EncryptedCache) Get(key string) ([]byte, bool) {
+	data, found := ec.store.Get(key)
+	if !found {
+		return nil, false
+	}
+	value, err := ec.decrypt(key, data)
+	if err != nil {
+		ec.store.Delete(key)
+		return nil, false
+	}
+	return value, true
+}
+
+// Set satisfies the Set method of CacheStore.
+func (ec *EncryptedCache) Set(key string, value []byte) {
+	data, err := ec.encrypt(key, value)
+	if err != nil {
+		// Never fall back to storing the value unencrypted. Remove any stale
+		// value instead.
+		ec.store.Delete(key)
+		return
+	}
+	ec.store.Set(key, data)
+}
+
+// Delete satisfies the Delete method of CacheStore.
+func (ec *EncryptedCache) Delete(key string) {
+	ec.store.Delete(key)
+}
+
+// aead returns the AES-GCM cipher for the current key.
+func (ec *EncryptedCache) aead() (cipher.AEAD, error) {
+	key, err := ec.keys.Key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts value with a random nonce, which is prepended to the
+// result. The cache key is used as additional authenticated data, so that an
+// encrypted value cannot be moved to a different key.
+func (ec *EncryptedCache) encrypt(key string, value []byte) ([]byte, error) {
+	aead, err := ec.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, value, []byte(key)), nil
+}
+
+// decrypt reverses encrypt.
+func (ec *EncryptedCache) decrypt(key string, data []byte) ([]byte, error) {
+	aead, err := ec.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("rest: encrypted cache value is too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(key))
+}
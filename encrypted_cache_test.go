@@ -0,0 +1,85 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// staticKey returns a KeyProvider which always returns key.
+func staticKey(key string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		return []byte(key), nil
+	})
+}
+
+func TestEncryptedCache(t *testing.T) {
+	store := NewMemoryCache()
+	cache := NewEncryptedCache(store, staticKey("0123456789abcdef"))
+	cache.Set("/todos", []byte(`[{"Title": "secret"}]`))
+	raw, found := store.Get("/todos")
+	if !found || bytes.Contains(raw, []byte("secret")) {
+		t.Fatalf("Expected the value to be stored encrypted but got %q", raw)
+	}
+	value, found := cache.Get("/todos")
+	if !found || string(value) != `[{"Title": "secret"}]` {
+		t.Errorf("Expected the value to be decrypted but got %q", value)
+	}
+
+	// A value moved to a different key fails authentication
+	store.Set("/todos/1", raw)
+	if _, found := cache.Get("/todos/1"); found {
+		t.Error("Expected a value moved to a different key to be treated as missing")
+	}
+	if _, found := store.Get("/todos/1"); found {
+		t.Error("Expected a value which cannot be decrypted to be deleted")
+	}
+
+	rotated := NewEncryptedCache(store, staticKey("fedcba9876543210"))
+	if _, found := rotated.Get("/todos"); found {
+		t.Error("Expected a value encrypted with a different key to be treated as missing")
+	}
+	cache.Delete("/todos")
+	if _, found := cache.Get("/todos"); found {
+		t.Error("Expected the value to be deleted")
+	}
+}
+
+func TestEncryptedCacheKeyError(t *testing.T) {
+	store := NewMemoryCache()
+	store.Set("/todos", []byte("stale"))
+	cache := NewEncryptedCache(store, KeyProviderFunc(func() ([]byte, error) {
+		return nil, errors.New("logged out")
+	}))
+	cache.Set("/todos", []byte("fresh"))
+	if _, found := store.Get("/todos"); found {
+		t.Error("Expected nothing to be stored without a key")
+	}
+	cache = NewEncryptedCache(store, staticKey("too short"))
+	cache.Set("/todos", []byte("fresh"))
+	if _, found := store.Get("/todos"); found {
+		t.Error("Expected nothing to be stored with an invalid key")
+	}
+}
+
+func TestEncryptedCacheClient(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewEncryptedCache(NewMemoryCache(), staticKey("0123456789abcdef0123456789abcdef"))
+	for i := 0; i < 2; i++ {
+		todos := []*testTodo{}
+		if err := client.ReadAll(&todos); err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 1 || todos[0].Title != "a" {
+			t.Fatalf("Unexpected todos: %v", todos)
+		}
+	}
+	if got := server.count("GET"); got != 1 {
+		t.Errorf("Expected the second read to be served from the cache but got %d GET requests", got)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// EndpointStats holds the exponential moving averages of the latency and
+// error rate of the requests for one RootURL. Recent requests weigh more than
+// older ones (see Client.LatencySmoothing), so the averages follow changes in
+// the behavior of the server quickly without jumping at every outlier.
+type EndpointStats struct {
+	// Requests is the number of requests sent for the RootURL
+	Requests int
+	// Latency is the moving average of the time it took to receive the
+	// response headers, including any retries
+	Latency time.Duration
+	// ErrorRate is the moving average of the fraction of requests which
+	// failed, i.e. which could not be sent or got a 5xx response
+	ErrorRate float64
+	// Degraded is true iff Latency or ErrorRate exceeds the SlowLatency or
+	// SlowErrorRate of the client
+	Degraded bool
+}
+
+// rootURLKey is the context key for the RootURL of the model a request is
+// for.
+type rootURLKey struct{}
+
+// withRootURL returns a copy of ctx which carries rootURL.
+func withRootURL(ctx context.Context, rootURL string) context.Context {
+	return context.WithValue(ctx, rootURLKey{}, rootURL)
+}
+
+// rootURLFrom returns the RootURL carried by ctx, or "" if there is none.
+func rootURLFrom(ctx context.Context) string {
+	rootURL, _ := ctx.Value(rootURLKey{}).(string)
+	return rootURL
+}
+
+// rootURLOf returns the RootURL of v, which may be a model or a pointer to a
+// slice of models, or "" if it has none.
+func rootURLOf(v interface{}) string {
+	if model, ok := v.(Model); ok {
+		if isNilPointer(model) {
+			return ""
+		}
+		rootURL, err := normalizeRootURL(model.RootURL())
+		if err != nil {
+			return model.RootURL()
+		}
+		return rootURL
+	}
+	if checkModelsType(v, false) != nil {
+		return ""
+	}
+	rootURL, _ := getURLFromModels(v)
+	return rootURL
+}
+
+// latencySmoothing returns the weight of the latest request in the moving
+// averages of EndpointStats.
+func (c *Client) latencySmoothing() float64 {
+	if c.LatencySmoothing <= 0 || c.LatencySmoothing > 1 {
+		return 0.2
+	}
+	return c.LatencySmoothing
+}
+
+// recordEndpoint updates the EndpointStats for the RootURL of req, if it has
+// one, with the outcome of the request, which took latency, and publishes an
+// EndpointDegraded or EndpointRecovered event if the endpoint changed state.
+func (c *Client) recordEndpoint(req *http.Request, res *http.Response, err error, latency time.Duration) {
+	rootURL := rootURLFrom(req.Context())
+	if rootURL == "" {
+		return
+	}
+	failed := 0.0
+	if err != nil || res.StatusCode >= 500 {
+		failed = 1
+	}
+	alpha := c.latencySmoothing()
+	c.mut.Lock()
+	if c.endpoints == nil {
+		c.endpoints = map[string]EndpointStats{}
+	}
+	stats, found := c.endpoints[rootURL]
+	if found {
+		stats.Latency += time.Duration(alpha * float64(latency-stats.Latency))
+		stats.ErrorRate += alpha * (failed - stats.ErrorRate)
+	} else {
+		stats.Latency = latency
+		stats.ErrorRate = failed
+	}
+	stats.Requests++
+	wasDegraded := stats.Degraded
+	stats.Degraded = (c.SlowLatency > 0 && stats.Latency > c.SlowLatency) ||
+		(c.SlowErrorRate > 0 && stats.ErrorRate > c.SlowErrorRate)
+	c.endpoints[rootURL] = stats
+	c.mut.Unlock()
+	if stats.Degraded == wasDegraded {
+		return
+	}
+	typ := EndpointRecovered
+	if stats.Degraded {
+		typ = EndpointDegraded
+	}
+	c.publish(Event{
+		Type:    typ,
+		Method:  req.Method,
+		URL:     rootURL,
+		Context: req.Context(),
+	})
+}
+
+// endpointStats returns a copy of the EndpointStats of c.
+func (c *Client) endpointStats() map[string]EndpointStats {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	endpoints := make(map[string]EndpointStats, len(c.endpoints))
+	for rootURL, stats := range c.endpoints {
+		endpoints[rootURL] = stats
+	}
+	return endpoints
+}
@@ -0,0 +1,129 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStatsServer starts a server for testTodo which responds with the status
+// code stored in status, after sleeping for the duration stored in delay.
+func newStatsServer(t *testing.T) (status *int32, delay *int64) {
+	status, delay = new(int32), new(int64)
+	*status = http.StatusOK
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(atomic.LoadInt64(delay)))
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+		w.Write([]byte(`[]`))
+	})
+	return status, delay
+}
+
+func TestEndpointStats(t *testing.T) {
+	status, _ := newStatsServer(t)
+	client := NewClient()
+	client.LatencySmoothing = 0.5
+	atomic.StoreInt32(status, http.StatusInternalServerError)
+	client.ReadAll(&[]*testTodo{})
+	atomic.StoreInt32(status, http.StatusOK)
+	for i := 0; i < 2; i++ {
+		if err := client.ReadAll(&[]*testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, found := client.Stats().Endpoints[testRootURL+"/todos"]
+	if !found {
+		t.Fatalf("Expected stats for %s but got %v", testRootURL+"/todos", client.Stats().Endpoints)
+	}
+	if stats.Requests != 3 {
+		t.Errorf("Expected 3 requests but got %d", stats.Requests)
+	}
+	if math.Abs(stats.ErrorRate-0.25) > 1e-9 {
+		t.Errorf("Expected an error rate of 0.25 but got %v", stats.ErrorRate)
+	}
+	if stats.Latency <= 0 {
+		t.Errorf("Expected a positive latency but got %s", stats.Latency)
+	}
+	if stats.Degraded {
+		t.Error("Expected the endpoint not to be degraded without SlowLatency or SlowErrorRate")
+	}
+}
+
+func TestEndpointStatsIgnoresRequestsWithoutModel(t *testing.T) {
+	newStatsServer(t)
+	client := NewClient()
+	err := client.NewRequestBuilder("GET", testRootURL+"/todos").Into(&[]*testTodo{}).Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoints := client.Stats().Endpoints; len(endpoints) != 0 {
+		t.Errorf("Expected no endpoint stats for requests without a model but got %v", endpoints)
+	}
+}
+
+func TestEndpointDegradedByErrorRate(t *testing.T) {
+	status, _ := newStatsServer(t)
+	client := NewClient()
+	client.SlowErrorRate = 0.5
+	var events []EventType
+	client.Events().Subscribe(func(event Event) {
+		if event.URL != testRootURL+"/todos" {
+			t.Errorf("Expected the event to be for %s but got %s", testRootURL+"/todos", event.URL)
+		}
+		events = append(events, event.Type)
+	}, EndpointDegraded, EndpointRecovered)
+
+	atomic.StoreInt32(status, http.StatusServiceUnavailable)
+	client.ReadAll(&[]*testTodo{})
+	if len(events) != 1 || events[0] != EndpointDegraded {
+		t.Fatalf("Expected an EndpointDegraded event but got %v", events)
+	}
+	if !client.Stats().Endpoints[testRootURL+"/todos"].Degraded {
+		t.Error("Expected the endpoint to be degraded")
+	}
+
+	// With the default smoothing of 0.2 the error rate drops to 0.8, 0.64,
+	// 0.512, and then 0.4096
+	atomic.StoreInt32(status, http.StatusOK)
+	for i := 0; i < 4; i++ {
+		if len(events) != 1 {
+			t.Fatalf("Expected the endpoint to recover after 4 successful requests but it recovered after %d", i)
+		}
+		if err := client.ReadAll(&[]*testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != 2 || events[1] != EndpointRecovered {
+		t.Errorf("Expected an EndpointRecovered event but got %v", events)
+	}
+}
+
+func TestEndpointDegradedByLatency(t *testing.T) {
+	_, delay := newStatsServer(t)
+	client := NewClient()
+	client.SlowLatency = 10 * time.Millisecond
+	degraded := 0
+	client.Events().Subscribe(func(Event) { degraded++ }, EndpointDegraded)
+
+	if err := client.ReadAll(&[]*testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if degraded != 0 {
+		t.Fatal("Expected a fast endpoint not to be degraded")
+	}
+	atomic.StoreInt64(delay, int64(200*time.Millisecond))
+	if err := client.ReadAll(&[]*testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if degraded != 1 {
+		t.Errorf("Expected a slow response to degrade the endpoint")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ErrorKind classifies the failures reported to Client.OnError.
+type ErrorKind string
+
+const (
+	// ErrorNetwork means the request could not be sent or no response was
+	// received, e.g. because the server could not be reached.
+	ErrorNetwork ErrorKind = "network"
+	// ErrorTimeout means the request timed out or its context deadline was
+	// exceeded.
+	ErrorTimeout ErrorKind = "timeout"
+	// ErrorClient means the server responded with a 4xx status code.
+	ErrorClient ErrorKind = "4xx"
+	// ErrorServer means the server responded with a 5xx status code.
+	ErrorServer ErrorKind = "5xx"
+	// ErrorDecode means the response could not be decoded.
+	ErrorDecode ErrorKind = "decode"
+)
+
+// ErrorReport describes a failed request. It is passed to Client.OnError.
+type ErrorReport struct {
+	// Kind classifies the failure
+	Kind ErrorKind
+	// Method is the http method of the request
+	Method string
+	// URL is the url of the request
+	URL string
+	// StatusCode is the status code of the response, or 0 if there was none
+	StatusCode int
+	// Err is the underlying error. For ErrorClient and ErrorServer it is an
+	// HTTPError without a Body.
+	Err error
+	// Context is the context of the request, which carries any values set
+	// by the caller with WithContext (e.g. a user id or trace info).
+	Context context.Context
+}
+
+// reportError passes report to c.OnError, if any.
+func (c *Client) reportError(report ErrorReport) {
+	if c.OnError != nil {
+		c.OnError(report)
+	}
+}
+
+// reportSendError reports err, which was returned while sending req, to
+// c.OnError. Requests which were canceled by the caller are not reported,
+// since they did not fail.
+func (c *Client) reportSendError(req *http.Request, err error) {
+	if c.OnError == nil || err == context.Canceled {
+		return
+	}
+	kind := ErrorNetwork
+	if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || err == context.DeadlineExceeded {
+		kind = ErrorTimeout
+	}
+	c.reportError(ErrorReport{Kind: kind, Method: req.Method, URL: req.URL.String(), Err: err, Context: req.Context()})
+}
+
+// unwrapURLError returns the underlying error of err if it is a *url.Error, as
+// returned by http.Client, so that it can be classified.
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		if urlErr.Timeout() {
+			return urlErr
+		}
+		return urlErr.Err
+	}
+	return err
+}
+
+// reportStatus reports a response to req with a 4xx or 5xx status code to
+// c.OnError.
+func (c *Client) reportStatus(req *http.Request, statusCode int) {
+	if c.OnError == nil || statusCode < 400 {
+		return
+	}
+	kind := ErrorClient
+	if statusCode >= 500 {
+		kind = ErrorServer
+	}
+	url := req.URL.String()
+	c.reportError(ErrorReport{
+		Kind:       kind,
+		Method:     req.Method,
+		URL:        url,
+		StatusCode: statusCode,
+		Err:        HTTPError{URL: url, StatusCode: statusCode},
+		Context:    req.Context(),
+	})
+}
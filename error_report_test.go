@@ -0,0 +1,118 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnError(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/todos/missing":
+			http.Error(w, "not found", http.StatusNotFound)
+		case "/todos/broken":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		case "/todos/garbled":
+			w.Write([]byte("not json"))
+		case "/todos/slow":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		default:
+			w.Write([]byte(`{"Id": "1"}`))
+		}
+	})
+	client := NewClient()
+	var mut sync.Mutex
+	var reports []ErrorReport
+	client.OnError = func(report ErrorReport) {
+		mut.Lock()
+		defer mut.Unlock()
+		reports = append(reports, report)
+	}
+	lastReport := func() *ErrorReport {
+		mut.Lock()
+		defer mut.Unlock()
+		if len(reports) == 0 {
+			return nil
+		}
+		report := reports[len(reports)-1]
+		reports = nil
+		return &report
+	}
+
+	expected := []struct {
+		id         string
+		kind       ErrorKind
+		statusCode int
+	}{
+		{"missing", ErrorClient, http.StatusNotFound},
+		{"broken", ErrorServer, http.StatusInternalServerError},
+		{"garbled", ErrorDecode, 0},
+	}
+	for _, e := range expected {
+		if err := client.Read(e.id, &testTodo{}); err == nil {
+			t.Errorf("Expected reading %s to fail", e.id)
+		}
+		report := lastReport()
+		if report == nil {
+			t.Errorf("Expected a report for %s", e.id)
+			continue
+		}
+		if report.Kind != e.kind || report.StatusCode != e.statusCode || report.Method != "GET" || !strings.HasSuffix(report.URL, "/todos/"+e.id) || report.Err == nil {
+			t.Errorf("Expected a %s report with status %d for %s but got %+v", e.kind, e.statusCode, e.id, report)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	client.Read("slow", &testTodo{}, WithContext(ctx))
+	if report := lastReport(); report == nil || report.Kind != ErrorTimeout {
+		t.Errorf("Expected a timeout report but got %+v", report)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	client.Read("slow", &testTodo{}, WithContext(ctx))
+	if report := lastReport(); report != nil {
+		t.Errorf("Expected requests canceled by the caller not to be reported but got %+v", report)
+	}
+
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if report := lastReport(); report != nil {
+		t.Errorf("Expected successful requests not to be reported but got %+v", report)
+	}
+}
+
+func TestOnErrorNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	previous := testRootURL
+	testRootURL = srv.URL
+	defer func() { testRootURL = previous }()
+	client := NewClient()
+	var reports []ErrorReport
+	client.OnError = func(report ErrorReport) {
+		reports = append(reports, report)
+	}
+	if err := client.Read("1", &testTodo{}); err == nil {
+		t.Fatal("Expected an error for a server which is not running")
+	}
+	if len(reports) != 1 || reports[0].Kind != ErrorNetwork || reports[0].StatusCode != 0 {
+		t.Errorf("Expected a network report but got %+v", reports)
+	}
+}
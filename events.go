@@ -0,0 +1,174 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"sync"
+)
+
+// EventType is the type of an Event.
+type EventType string
+
+const (
+	// ModelCreated is published after a model is successfully created.
+	ModelCreated EventType = "ModelCreated"
+	// ModelUpdated is published after a model is successfully updated with
+	// Update or Put.
+	ModelUpdated EventType = "ModelUpdated"
+	// ModelDeleted is published after a model is successfully deleted.
+	ModelDeleted EventType = "ModelDeleted"
+	// RequestFailed is published whenever a request could not be sent or the
+	// server responded with a 4xx or 5xx status code.
+	RequestFailed EventType = "RequestFailed"
+	// EndpointChanged is published when the active endpoint of the client's
+	// FailoverPolicy changes. URL is the new active endpoint.
+	EndpointChanged EventType = "EndpointChanged"
+	// HostHealthy is published when a url monitored with MonitorHealth
+	// becomes healthy again.
+	HostHealthy EventType = "HostHealthy"
+	// HostUnhealthy is published when a health check of a url monitored with
+	// MonitorHealth fails. Err is the reason.
+	HostUnhealthy EventType = "HostUnhealthy"
+	// EndpointDegraded is published when the average latency or error rate
+	// of the requests for a RootURL exceeds the SlowLatency or SlowErrorRate
+	// of the client. URL is the RootURL. Applications can use it to switch
+	// to a degraded mode, e.g. to disable autosave while the API is slow.
+	EndpointDegraded EventType = "EndpointDegraded"
+	// EndpointRecovered is published when a degraded RootURL is no longer
+	// slow. URL is the RootURL.
+	EndpointRecovered EventType = "EndpointRecovered"
+)
+
+// Event is published by a client's EventBus to notify subscribers about
+// something that happened.
+type Event struct {
+	// Type is the type of the event
+	Type EventType
+	// Model is the model that was created, updated, or deleted. It is nil for
+	// other events.
+	Model Model
+	// Method is the http method of the request that caused the event
+	Method string
+	// URL is the url of the request that caused the event
+	URL string
+	// StatusCode is the status code of the response, or 0 if the request
+	// could not be sent.
+	StatusCode int
+	// Err is the error that caused a RequestFailed or HostUnhealthy event.
+	Err error
+	// Context is the context of the request that caused the event, which
+	// carries any values set by the caller with WithContext. It is nil for
+	// events which were not caused by a request, e.g. those of MonitorHealth.
+	Context context.Context
+}
+
+// EventBus publishes Events to its subscribers. Handlers are called
+// synchronously, in the order they subscribed, from the goroutine that sent
+// the request which caused the event. It is safe for concurrent use.
+type EventBus struct {
+	handlers map[int]eventHandler
+	order    []int
+	nextID   int
+	mut      sync.RWMutex
+}
+
+// eventHandler is a subscribed handler along with the event types it is
+// interested in.
+type eventHandler struct {
+	handle func(Event)
+	types  []EventType
+}
+
+// Events returns the EventBus for the client, which can be used to subscribe
+// to events about the requests sent by the client.
+func (c *Client) Events() *EventBus {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.events == nil {
+		c.events = &EventBus{
+			handlers: map[int]eventHandler{},
+		}
+	}
+	return c.events
+}
+
+// Subscribe causes handle to be called for each event with one of the given
+// types, or for every event if no types are given. It returns a function which
+// can be called to unsubscribe.
+func (bus *EventBus) Subscribe(handle func(Event), types ...EventType) (unsubscribe func()) {
+	bus.mut.Lock()
+	defer bus.mut.Unlock()
+	id := bus.nextID
+	bus.nextID++
+	bus.handlers[id] = eventHandler{
+		handle: handle,
+		types:  types,
+	}
+	bus.order = append(bus.order, id)
+	return func() {
+		bus.mut.Lock()
+		defer bus.mut.Unlock()
+		delete(bus.handlers, id)
+		for i, other := range bus.order {
+			if other == id {
+				bus.order = append(bus.order[:i], bus.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish sends event to all of the interested subscribers.
+func (bus *EventBus) Publish(event Event) {
+	bus.mut.RLock()
+	handlers := make([]eventHandler, 0, len(bus.order))
+	for _, id := range bus.order {
+		handlers = append(handlers, bus.handlers[id])
+	}
+	bus.mut.RUnlock()
+	for _, handler := range handlers {
+		if handler.wants(event.Type) {
+			handler.handle(event)
+		}
+	}
+}
+
+// wants returns true iff the handler is interested in events of type typ.
+func (handler eventHandler) wants(typ EventType) bool {
+	if len(handler.types) == 0 {
+		return true
+	}
+	for _, other := range handler.types {
+		if other == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// publish publishes event on the client's EventBus, if it has one. The bus is
+// only created when Events is first called, so there is no overhead for
+// clients without subscribers.
+func (c *Client) publish(event Event) {
+	c.mut.RLock()
+	bus := c.events
+	c.mut.RUnlock()
+	if bus != nil {
+		bus.Publish(event)
+	}
+}
+
+// publishModelEvent publishes an event of the given type for model. ctx is
+// the context of the request which caused it.
+func (c *Client) publishModelEvent(ctx context.Context, typ EventType, method string, url string, model Model) {
+	c.publish(Event{
+		Type:    typ,
+		Model:   model,
+		Method:  method,
+		URL:     url,
+		Context: ctx,
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type eventsKey struct{}
+
+func TestEventsModelLifecycle(t *testing.T) {
+	newTodoServer(t)
+	client := NewClient()
+	events := []Event{}
+	client.Events().Subscribe(func(event Event) {
+		events = append(events, event)
+	}, ModelCreated, ModelUpdated, ModelDeleted)
+	ctx := context.WithValue(context.Background(), eventsKey{}, "value")
+	todo := &testTodo{Title: "a"}
+	if err := client.Create(todo, WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	todo.Title = "b"
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Read(todo.Id, todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete(todo); err != nil {
+		t.Fatal(err)
+	}
+	got := []EventType{}
+	for _, event := range events {
+		got = append(got, event.Type)
+		if event.Model != todo {
+			t.Errorf("Expected the %s event to carry the model but got %v", event.Type, event.Model)
+		}
+	}
+	expected := []EventType{ModelCreated, ModelUpdated, ModelUpdated, ModelDeleted}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected events %v but got %v", expected, got)
+	}
+	if events[0].Method != "POST" || events[0].URL != testRootURL+"/todos" {
+		t.Errorf("Unexpected request for the created event: %s %s", events[0].Method, events[0].URL)
+	}
+	if events[0].Context == nil || events[0].Context.Value(eventsKey{}) != "value" {
+		t.Error("Expected the created event to carry the context of the request")
+	}
+}
+
+func TestEventsRequestFailed(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client := NewClient()
+	failures := []Event{}
+	client.Events().Subscribe(func(event Event) {
+		failures = append(failures, event)
+	}, RequestFailed)
+	if err := client.Read("1", &testTodo{}); err == nil {
+		t.Fatal("Expected an error for a 500 response")
+	}
+	if len(failures) != 1 {
+		t.Fatalf("Expected one RequestFailed event but got %d", len(failures))
+	}
+	failure := failures[0]
+	if failure.StatusCode != http.StatusInternalServerError || failure.Method != "GET" {
+		t.Errorf("Unexpected RequestFailed event: %+v", failure)
+	}
+	if httpErr, ok := failure.Err.(HTTPError); !ok || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected an HTTPError but got %v", failure.Err)
+	}
+
+	testRootURL = "http://127.0.0.1:1"
+	if err := client.Read("1", &testTodo{}); err == nil {
+		t.Fatal("Expected an error for an unreachable server")
+	}
+	if len(failures) != 2 || failures[1].StatusCode != 0 || failures[1].Err == nil {
+		t.Errorf("Expected a RequestFailed event without a status code but got %+v", failures)
+	}
+}
+
+func TestEventBusSubscribe(t *testing.T) {
+	bus := NewClient().Events()
+	calls := []string{}
+	unsubscribeFirst := bus.Subscribe(func(Event) { calls = append(calls, "first") })
+	bus.Subscribe(func(Event) { calls = append(calls, "second") }, ModelDeleted)
+	bus.Subscribe(func(Event) { calls = append(calls, "third") })
+	bus.Publish(Event{Type: ModelCreated})
+	bus.Publish(Event{Type: ModelDeleted})
+	unsubscribeFirst()
+	unsubscribeFirst()
+	bus.Publish(Event{Type: ModelDeleted})
+	expected := []string{"first", "third", "first", "second", "third", "second", "third"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %v but got %v", expected, calls)
+	}
+}
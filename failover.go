@@ -0,0 +1,216 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverPolicy configures a client to send requests to alternative
+// endpoints when the primary one is unreachable or failing.
+type FailoverPolicy struct {
+	// Endpoints are the base urls (e.g. "https://api.example.com/v1") of
+	// equivalent deployments of the API, in order of preference. The first one
+	// is the primary. Requests to a url which starts with any of the endpoints
+	// are sent to the currently active endpoint instead.
+	Endpoints []string
+	// FailureThreshold is the number of consecutive failed requests after
+	// which the active endpoint is considered down and the next endpoint
+	// becomes active. A request has failed if it could not be sent or the
+	// server responded with a 5xx status code. The default is 3.
+	FailureThreshold int
+	// RecoveryInterval is how long to wait after failing over before trying
+	// the primary endpoint again. If a request to the primary succeeds, it
+	// becomes active again; otherwise the client waits for another interval.
+	// The default is 30 seconds.
+	RecoveryInterval time.Duration
+}
+
+// failover holds the state of a client's FailoverPolicy.
+type failover struct {
+	policy *FailoverPolicy
+	// active is the index of the active endpoint
+	active int
+	// failures is the number of consecutive failures of the active endpoint
+	failures int
+	// switchedAt is when the active endpoint last changed, or when the last
+	// attempt to recover the primary failed
+	switchedAt time.Time
+	mut        sync.Mutex
+}
+
+// getFailover returns the failover state for c.Failover, or nil if there is no
+// FailoverPolicy.
+func (c *Client) getFailover() *failover {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.Failover == nil || len(c.Failover.Endpoints) == 0 {
+		return nil
+	}
+	if c.failover == nil || c.failover.policy != c.Failover {
+		c.failover = &failover{policy: c.Failover}
+	}
+	return c.failover
+}
+
+// ActiveEndpoint returns the endpoint of c.Failover which requests are
+// currently sent to, or an empty string if there is no FailoverPolicy.
+func (c *Client) ActiveEndpoint() string {
+	f := c.getFailover()
+	if f == nil {
+		return ""
+	}
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.policy.Endpoints[f.active]
+}
+
+// route returns the url req should be sent to and the index of the endpoint it
+// belongs to. The index is -1 if the url of req does not start with any of the
+// endpoints, in which case it is returned unchanged.
+func (f *failover) route(u *url.URL) (*url.URL, int) {
+	rawURL := u.String()
+	for _, endpoint := range f.policy.Endpoints {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+		if !hasURLPrefix(rawURL, endpoint) {
+			continue
+		}
+		f.mut.Lock()
+		index := f.active
+		if index != 0 && time.Since(f.switchedAt) >= f.recoveryInterval() {
+			// Try to recover the primary
+			index = 0
+		}
+		f.mut.Unlock()
+		target := strings.TrimSuffix(f.policy.Endpoints[index], "/") + rawURL[len(endpoint):]
+		routed, err := url.Parse(target)
+		if err != nil {
+			return u, -1
+		}
+		return routed, index
+	}
+	return u, -1
+}
+
+// record records the outcome of a request sent to the endpoint with the given
+// index. It returns the new active endpoint if it changed, or an empty string
+// otherwise.
+func (f *failover) record(index int, failed bool) string {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	switch {
+	case index == 0 && f.active != 0:
+		// This was an attempt to recover the primary
+		if failed {
+			f.switchedAt = time.Now()
+			return ""
+		}
+		f.active, f.failures, f.switchedAt = 0, 0, time.Now()
+		return f.policy.Endpoints[0]
+	case index != f.active:
+		// A request that was routed before the active endpoint changed
+		return ""
+	case !failed:
+		f.failures = 0
+		return ""
+	}
+	f.failures++
+	if f.failures < f.failureThreshold() {
+		return ""
+	}
+	f.active = (f.active + 1) % len(f.policy.Endpoints)
+	f.failures, f.switchedAt = 0, time.Now()
+	return f.policy.Endpoints[f.active]
+}
+
+// recordHealth records the result of a health check of url. An unhealthy
+// active endpoint is failed over immediately and a healthy primary becomes
+// active again. It returns the new active endpoint if it changed, or an empty
+// string otherwise.
+func (f *failover) recordHealth(url string, healthy bool) string {
+	for index, endpoint := range f.policy.Endpoints {
+		if !hasURLPrefix(url, strings.TrimSuffix(endpoint, "/")) {
+			continue
+		}
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		switch {
+		case healthy && index == 0 && f.active != 0:
+			f.active = 0
+		case !healthy && index == f.active:
+			f.active = (f.active + 1) % len(f.policy.Endpoints)
+		default:
+			return ""
+		}
+		f.failures, f.switchedAt = 0, time.Now()
+		return f.policy.Endpoints[f.active]
+	}
+	return ""
+}
+
+func (f *failover) failureThreshold() int {
+	if f.policy.FailureThreshold <= 0 {
+		return 3
+	}
+	return f.policy.FailureThreshold
+}
+
+func (f *failover) recoveryInterval() time.Duration {
+	if f.policy.RecoveryInterval <= 0 {
+		return 30 * time.Second
+	}
+	return f.policy.RecoveryInterval
+}
+
+// sendWithFailover sends req with send, routing it to the active endpoint of
+// c.Failover (if any) and recording the outcome.
+func (c *Client) sendWithFailover(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	f := c.getFailover()
+	if f == nil {
+		return send(req)
+	}
+	routed, index := f.route(req.URL)
+	if index == -1 {
+		return send(req)
+	}
+	routedReq := new(http.Request)
+	*routedReq = *req
+	routedReq.URL = routed
+	routedReq.Host = ""
+	res, err := send(routedReq)
+	if err != nil && req.Context().Err() != nil {
+		// The request was abandoned, which says nothing about the endpoint
+		return res, err
+	}
+	failed := err != nil || res.StatusCode >= 500
+	if endpoint := f.record(index, failed); endpoint != "" {
+		c.publish(Event{
+			Type:    EndpointChanged,
+			Method:  req.Method,
+			URL:     endpoint,
+			Context: req.Context(),
+		})
+	}
+	return res, err
+}
+
+// hasURLPrefix returns true if rawURL starts with prefix at a path boundary.
+func hasURLPrefix(rawURL string, prefix string) bool {
+	if !strings.HasPrefix(rawURL, prefix) {
+		return false
+	}
+	if len(rawURL) == len(prefix) {
+		return true
+	}
+	switch rawURL[len(prefix)] {
+	case '/', '?', '#':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	newTodoServer(t, "a")
+	secondary := testRootURL
+	var primaryDown int32 = 1
+	var primaryRequests int32
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryRequests, 1)
+		if atomic.LoadInt32(&primaryDown) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"Id": "1", "Title": "primary"}`))
+	})
+	primary := testRootURL
+	client := NewClient()
+	client.Failover = &FailoverPolicy{
+		Endpoints:        []string{primary, secondary + "/"},
+		FailureThreshold: 2,
+		RecoveryInterval: 50 * time.Millisecond,
+	}
+	changes := []string{}
+	client.Events().Subscribe(func(event Event) {
+		changes = append(changes, event.URL)
+	}, EndpointChanged)
+	if client.ActiveEndpoint() != primary {
+		t.Fatalf("Expected the primary to be active but got %s", client.ActiveEndpoint())
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Read("1", &testTodo{}); err == nil {
+			t.Fatal("Expected an error while the primary is down")
+		}
+	}
+	if client.ActiveEndpoint() != secondary+"/" || len(changes) != 1 {
+		t.Fatalf("Expected to fail over to the secondary but got %s, %v", client.ActiveEndpoint(), changes)
+	}
+	todo := &testTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "a" || atomic.LoadInt32(&primaryRequests) != 2 {
+		t.Errorf("Expected the request to be sent to the secondary but got %+v", todo)
+	}
+
+	atomic.StoreInt32(&primaryDown, 0)
+	time.Sleep(60 * time.Millisecond)
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "primary" || client.ActiveEndpoint() != primary || len(changes) != 2 {
+		t.Errorf("Expected the primary to recover but got %+v, %s, %v", todo, client.ActiveEndpoint(), changes)
+	}
+}
+
+func TestFailoverRecoveryFails(t *testing.T) {
+	f := &failover{policy: &FailoverPolicy{Endpoints: []string{"http://a", "http://b"}, FailureThreshold: 1}}
+	if endpoint := f.record(0, true); endpoint != "http://b" {
+		t.Fatalf("Expected to fail over to http://b but got %q", endpoint)
+	}
+	switchedAt := f.switchedAt
+	if endpoint := f.record(0, true); endpoint != "" || f.active != 1 || f.switchedAt.Before(switchedAt) {
+		t.Errorf("Expected a failed recovery to keep the secondary and restart the interval")
+	}
+	if endpoint := f.record(1, false); endpoint != "" || f.failures != 0 {
+		t.Errorf("Expected a success to reset the failures")
+	}
+}
+
+func TestHasURLPrefix(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "http://api.example.com/v1", expected: true},
+		{url: "http://api.example.com/v1/todos", expected: true},
+		{url: "http://api.example.com/v1?a=b", expected: true},
+		{url: "http://api.example.com/v10/todos", expected: false},
+		{url: "http://other.example.com/v1", expected: false},
+	}
+	for _, test := range tests {
+		if got := hasURLPrefix(test.url, "http://api.example.com/v1"); got != test.expected {
+			t.Errorf("Expected %v for %s but got %v", test.expected, test.url, got)
+		}
+	}
+}
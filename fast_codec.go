@@ -0,0 +1,432 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// FastCodec encodes and decodes models of a single type without reflection.
+// Implementations are usually generated by the rest-gen command for flat
+// structs, i.e. structs whose fields are all strings, bools, or numbers
+// (optionally embedding DefaultId or DefaultIntId):
+//
+//	//go:generate rest-gen -type Todo,User
+//
+// A FastCodec must behave exactly like the reflection-based encoders and
+// decoders, which are used for any type without a registered FastCodec.
+type FastCodec interface {
+	// AppendURLEncoded appends the url-encoded fields of model to dst, in
+	// the order of their names, and returns the extended buffer.
+	AppendURLEncoded(dst []byte, model Model) ([]byte, error)
+	// DecodeJSON decodes the JSON object in data into model.
+	DecodeJSON(data []byte, model Model) error
+}
+
+var (
+	fastCodecs   = map[reflect.Type]FastCodec{}
+	fastCodecsMu sync.RWMutex
+)
+
+// RegisterFastCodec registers codec as the FastCodec for the type of model,
+// which is usually a pointer to a struct. The codec is used whenever models
+// of exactly that type are url-encoded or decoded from JSON by a client which
+// uses MatchExact (the default).
+func RegisterFastCodec(model Model, codec FastCodec) {
+	fastCodecsMu.Lock()
+	defer fastCodecsMu.Unlock()
+	fastCodecs[reflect.TypeOf(model)] = codec
+}
+
+// lookupFastCodec returns the FastCodec registered for typ, if any.
+func lookupFastCodec(typ reflect.Type) (FastCodec, bool) {
+	fastCodecsMu.RLock()
+	defer fastCodecsMu.RUnlock()
+	codec, found := fastCodecs[typ]
+	return codec, found
+}
+
+// isNilPointer returns true iff model is a nil pointer. FastCodecs are never
+// used for nil pointers, so that the usual errors are returned for them.
+func isNilPointer(model interface{}) bool {
+	modelVal := reflect.ValueOf(model)
+	return modelVal.Kind() == reflect.Ptr && modelVal.IsNil()
+}
+
+// unmarshalFast decodes data into v with a FastCodec if v is a model with a
+// registered FastCodec or a pointer to a slice of such models. The first
+// return value is false if no FastCodec applies. If a FastCodec returns an
+// error, the caller should decode data again with the reflective path, which
+// returns the same errors as encoding/json.
+func unmarshalFast(data []byte, v interface{}) (bool, error) {
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Kind() != reflect.Ptr || isNilPointer(v) {
+		return false, nil
+	}
+	if !json.Valid(data) {
+		// Like encoding/json, leave v untouched if data is malformed
+		return false, nil
+	}
+	if codec, found := lookupFastCodec(typ); found {
+		return true, codec.DecodeJSON(data, v.(Model))
+	}
+	if typ.Elem().Kind() != reflect.Slice {
+		return false, nil
+	}
+	elemType := typ.Elem().Elem()
+	ptrType := elemType
+	if elemType.Kind() != reflect.Ptr {
+		ptrType = reflect.PtrTo(elemType)
+	}
+	codec, found := lookupFastCodec(ptrType)
+	if !found {
+		return false, nil
+	}
+	if isJSONNull(data) {
+		return true, nil
+	}
+	slice := reflect.ValueOf(v).Elem()
+	slice.SetLen(0)
+	err := ScanJSONArray(data, func(elem []byte) error {
+		ptr := reflect.New(ptrType.Elem())
+		if err := codec.DecodeJSON(elem, ptr.Interface().(Model)); err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, ptr))
+		} else {
+			slice.Set(reflect.Append(slice, ptr.Elem()))
+		}
+		return nil
+	})
+	return true, err
+}
+
+// The rest of this file holds the building blocks of the code generated by
+// rest-gen. They are exported so that generated code in other packages can use
+// them, but are rarely useful otherwise.
+
+// ScanJSONObject calls fn with the key (still quoted and escaped) and the raw
+// value of each member of the JSON object in data, in order. If data is null,
+// fn is never called. It returns the first error returned by fn, or an error
+// if data is not a valid JSON object.
+func ScanJSONObject(data []byte, fn func(key []byte, value []byte) error) error {
+	i := skipSpace(data, 0)
+	if isJSONNull(data[i:]) {
+		return nil
+	}
+	if i == len(data) || data[i] != '{' {
+		return fmt.Errorf("rest: expected a JSON object but got %s", truncateJSON(data[i:]))
+	}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return checkJSONEnd(data, i+1)
+	}
+	for {
+		keyEnd, err := skipJSONValue(data, i)
+		if err != nil {
+			return err
+		}
+		if data[i] != '"' {
+			return fmt.Errorf("rest: expected a JSON object key but got %s", truncateJSON(data[i:]))
+		}
+		key := data[i:keyEnd]
+		i = skipSpace(data, keyEnd)
+		if i == len(data) || data[i] != ':' {
+			return fmt.Errorf("rest: expected ':' after JSON object key %s", key)
+		}
+		i = skipSpace(data, i+1)
+		valueEnd, err := skipJSONValue(data, i)
+		if err != nil {
+			return err
+		}
+		if err := fn(key, data[i:valueEnd]); err != nil {
+			return err
+		}
+		i = skipSpace(data, valueEnd)
+		if i < len(data) && data[i] == ',' {
+			i = skipSpace(data, i+1)
+			continue
+		}
+		if i < len(data) && data[i] == '}' {
+			return checkJSONEnd(data, i+1)
+		}
+		return fmt.Errorf("rest: unterminated JSON object")
+	}
+}
+
+// ScanJSONArray calls fn with each element of the JSON array in data, in
+// order. If data is null, fn is never called. It returns the first error
+// returned by fn, or an error if data is not a valid JSON array.
+func ScanJSONArray(data []byte, fn func(elem []byte) error) error {
+	i := skipSpace(data, 0)
+	if isJSONNull(data[i:]) {
+		return nil
+	}
+	if i == len(data) || data[i] != '[' {
+		return fmt.Errorf("rest: expected a JSON array but got %s", truncateJSON(data[i:]))
+	}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == ']' {
+		return checkJSONEnd(data, i+1)
+	}
+	for {
+		end, err := skipJSONValue(data, i)
+		if err != nil {
+			return err
+		}
+		if err := fn(data[i:end]); err != nil {
+			return err
+		}
+		i = skipSpace(data, end)
+		if i < len(data) && data[i] == ',' {
+			i = skipSpace(data, i+1)
+			continue
+		}
+		if i < len(data) && data[i] == ']' {
+			return checkJSONEnd(data, i+1)
+		}
+		return fmt.Errorf("rest: unterminated JSON array")
+	}
+}
+
+// FoldJSONKey returns the index of the name in names which matches key, the
+// quoted key of a JSON object member, the same way encoding/json matches keys
+// to fields: an exact match is preferred, and otherwise the first name which
+// matches case-insensitively is used. It returns -1 if there is no match.
+func FoldJSONKey(key []byte, names []string) int {
+	if len(key) >= 2 {
+		key = key[1 : len(key)-1]
+	}
+	if bytes.IndexByte(key, '\\') != -1 {
+		var unquoted string
+		if err := json.Unmarshal(append(append([]byte{'"'}, key...), '"'), &unquoted); err == nil {
+			key = []byte(unquoted)
+		}
+	}
+	for i, name := range names {
+		if string(key) == name {
+			return i
+		}
+	}
+	for i, name := range names {
+		if bytes.EqualFold(key, []byte(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsJSONNull returns true iff value is the JSON literal null. Like
+// encoding/json, generated decoders leave fields unchanged for null values.
+func IsJSONNull(value []byte) bool {
+	return isJSONNull(value)
+}
+
+// DecodeJSONString decodes the JSON string in value.
+func DecodeJSONString(value []byte) (string, error) {
+	if len(value) >= 2 && value[0] == '"' && bytes.IndexByte(value, '\\') == -1 {
+		return string(value[1 : len(value)-1]), nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// DecodeJSONInt decodes the JSON number in value as a signed integer of the
+// given size in bits, where 0 means the size of an int.
+func DecodeJSONInt(value []byte, bits int) (int64, error) {
+	n, err := strconv.ParseInt(string(value), 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("rest: cannot decode JSON %s into an integer", truncateJSON(value))
+	}
+	return n, nil
+}
+
+// DecodeJSONUint decodes the JSON number in value as an unsigned integer of
+// the given size in bits, where 0 means the size of a uint.
+func DecodeJSONUint(value []byte, bits int) (uint64, error) {
+	n, err := strconv.ParseUint(string(value), 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("rest: cannot decode JSON %s into an unsigned integer", truncateJSON(value))
+	}
+	return n, nil
+}
+
+// DecodeJSONFloat decodes the JSON number in value as a float of the given
+// size in bits.
+func DecodeJSONFloat(value []byte, bits int) (float64, error) {
+	if len(value) == 0 || value[0] == '"' || value[0] == 'N' || value[0] == 'I' || value[0] == '+' {
+		return 0, fmt.Errorf("rest: cannot decode JSON %s into a float", truncateJSON(value))
+	}
+	f, err := strconv.ParseFloat(string(value), bits)
+	if err != nil {
+		return 0, fmt.Errorf("rest: cannot decode JSON %s into a float", truncateJSON(value))
+	}
+	return f, nil
+}
+
+// DecodeJSONBool decodes the JSON boolean in value.
+func DecodeJSONBool(value []byte) (bool, error) {
+	switch string(value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("rest: cannot decode JSON %s into a bool", truncateJSON(value))
+}
+
+// AppendURLString appends the url-encoded field with the given name and value
+// to dst, preceded by "&" unless dst is empty.
+func AppendURLString(dst []byte, name string, value string) []byte {
+	if len(dst) > 0 {
+		dst = append(dst, '&')
+	}
+	dst = appendQueryEscaped(dst, name)
+	dst = append(dst, '=')
+	return appendQueryEscaped(dst, value)
+}
+
+// AppendURLInt is like AppendURLString for a signed integer.
+func AppendURLInt(dst []byte, name string, value int64) []byte {
+	dst = AppendURLString(dst, name, "")
+	return strconv.AppendInt(dst, value, 10)
+}
+
+// AppendURLUint is like AppendURLString for an unsigned integer.
+func AppendURLUint(dst []byte, name string, value uint64) []byte {
+	dst = AppendURLString(dst, name, "")
+	return strconv.AppendUint(dst, value, 10)
+}
+
+// AppendURLFloat is like AppendURLString for a float of the given size in
+// bits. Like the reflection-based encoder, it returns an error if value is
+// NaN or infinite.
+func AppendURLFloat(dst []byte, name string, value float64, bits int) ([]byte, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return dst, fmt.Errorf("rest: cannot url-encode the float %v", value)
+	}
+	var buf [32]byte
+	return AppendURLString(dst, name, string(strconv.AppendFloat(buf[:0], value, 'g', -1, bits))), nil
+}
+
+// AppendURLBool is like AppendURLString for a bool.
+func AppendURLBool(dst []byte, name string, value bool) []byte {
+	dst = AppendURLString(dst, name, "")
+	return strconv.AppendBool(dst, value)
+}
+
+// appendQueryEscaped appends s to dst, escaped the same way as by
+// url.QueryEscape.
+func appendQueryEscaped(dst []byte, s string) []byte {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			dst = append(dst, c)
+		case c == ' ':
+			dst = append(dst, '+')
+		default:
+			dst = append(dst, '%', hex[c>>4], hex[c&15])
+		}
+	}
+	return dst
+}
+
+// isJSONNull returns true iff data, ignoring surrounding whitespace, is null.
+func isJSONNull(data []byte) bool {
+	return string(bytes.TrimSpace(data)) == "null"
+}
+
+// skipSpace returns the index of the first byte of data at or after i which
+// is not JSON whitespace.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// checkJSONEnd returns an error if anything but whitespace follows index i of
+// data.
+func checkJSONEnd(data []byte, i int) error {
+	if i = skipSpace(data, i); i != len(data) {
+		return fmt.Errorf("rest: unexpected %s after JSON value", truncateJSON(data[i:]))
+	}
+	return nil
+}
+
+// skipJSONValue returns the index just after the JSON value which starts at
+// index i of data. Nested objects and arrays are skipped by matching brackets
+// outside of strings; scalars are checked when they are decoded.
+func skipJSONValue(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, fmt.Errorf("rest: unexpected end of JSON input")
+	}
+	depth := 0
+	for i < len(data) {
+		switch c := data[i]; c {
+		case '"':
+			i++
+			for i < len(data) && data[i] != '"' {
+				if data[i] == '\\' {
+					i++
+				} else if data[i] < 0x20 {
+					return 0, fmt.Errorf("rest: invalid character in JSON string")
+				}
+				i++
+			}
+			if i >= len(data) {
+				return 0, fmt.Errorf("rest: unexpected end of JSON input")
+			}
+			i++
+		case '{', '[':
+			depth++
+			i++
+		case '}', ']':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+			i++
+		case ',', ':', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i, nil
+			}
+			i++
+		default:
+			i++
+		}
+		if depth == 0 && i > 0 && (data[i-1] == '"' || data[i-1] == '}' || data[i-1] == ']') {
+			return i, nil
+		}
+	}
+	if depth != 0 {
+		return 0, fmt.Errorf("rest: unexpected end of JSON input")
+	}
+	return i, nil
+}
+
+// truncateJSON returns the beginning of data for use in error messages.
+func truncateJSON(data []byte) string {
+	if len(data) > 20 {
+		return string(data[:20]) + "..."
+	}
+	if len(data) == 0 {
+		return "end of input"
+	}
+	return string(data)
+}
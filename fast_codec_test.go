@@ -0,0 +1,220 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"math"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// fastTodo has a FastCodec. slowTodo has the same fields, but no FastCodec, so
+// that the two paths can be compared.
+type fastTodo struct {
+	DefaultId
+	Title    string
+	Done     bool `json:"done"`
+	Priority int8
+	Estimate float32
+	Secret   string `rest:"-"`
+}
+
+type slowTodo fastTodo
+
+func (*fastTodo) RootURL() string { return testRootURL + "/todos" }
+func (*slowTodo) RootURL() string { return testRootURL + "/todos" }
+
+func init() {
+	RegisterFastCodec(&fastTodo{}, fastTodoCodec{})
+}
+
+// fastTodoCalls counts the calls to fastTodoCodec.
+var fastTodoCalls int32
+
+// fastTodoCodec is the FastCodec rest-gen generates for *fastTodo.
+type fastTodoCodec struct{}
+
+var fastTodoCodecJSONKeys = []string{"Id", "Title", "done", "Priority", "Estimate", "Secret"}
+
+func (fastTodoCodec) AppendURLEncoded(dst []byte, model Model) ([]byte, error) {
+	atomic.AddInt32(&fastTodoCalls, 1)
+	m := model.(*fastTodo)
+	var err error
+	dst = AppendURLBool(dst, "Done", bool(m.Done))
+	if dst, err = AppendURLFloat(dst, "Estimate", float64(m.Estimate), 32); err != nil {
+		return dst, err
+	}
+	dst = AppendURLString(dst, "Id", string(m.DefaultId.Id))
+	dst = AppendURLInt(dst, "Priority", int64(m.Priority))
+	dst = AppendURLString(dst, "Title", string(m.Title))
+	return dst, nil
+}
+
+func (fastTodoCodec) DecodeJSON(data []byte, model Model) error {
+	atomic.AddInt32(&fastTodoCalls, 1)
+	m := model.(*fastTodo)
+	return ScanJSONObject(data, func(key, value []byte) error {
+		if IsJSONNull(value) {
+			return nil
+		}
+		switch FoldJSONKey(key, fastTodoCodecJSONKeys) {
+		case 0:
+			v, err := DecodeJSONString(value)
+			if err != nil {
+				return err
+			}
+			m.DefaultId.Id = string(v)
+		case 1:
+			v, err := DecodeJSONString(value)
+			if err != nil {
+				return err
+			}
+			m.Title = string(v)
+		case 2:
+			v, err := DecodeJSONBool(value)
+			if err != nil {
+				return err
+			}
+			m.Done = bool(v)
+		case 3:
+			v, err := DecodeJSONInt(value, 8)
+			if err != nil {
+				return err
+			}
+			m.Priority = int8(v)
+		case 4:
+			v, err := DecodeJSONFloat(value, 32)
+			if err != nil {
+				return err
+			}
+			m.Estimate = float32(v)
+		case 5:
+			v, err := DecodeJSONString(value)
+			if err != nil {
+				return err
+			}
+			m.Secret = string(v)
+		}
+		return nil
+	})
+}
+
+func TestFastCodecMatchesReflection(t *testing.T) {
+	client := NewClient()
+	inputs := []string{
+		`{"Id": "1", "Title": "a \"b\"\n", "done": true, "Priority": -3, "Estimate": 1.5, "Secret": "s"}`,
+		`{"id": "2", "TITLE": "x", "Done": false, "unknown": {"nested": [1, {"a": "]"}]}}`,
+		`{"Title": null, "Priority": 127}`,
+		`{"Title": "é😀", "Title": "escaped key"}`,
+		`{}`,
+		`null`,
+		` [ ] `,
+		`{"Priority": 128}`,
+		`{"Priority": 1.5}`,
+		`{"done": "true"}`,
+		`{"Estimate": "1"}`,
+		`{"Title": 1}`,
+		`{"Title": "a",}`,
+		`{"Title": "a"} x`,
+		`{"Title": "a"`,
+	}
+	for _, input := range inputs {
+		fast := fastTodo{Title: "before"}
+		slow := slowTodo{Title: "before"}
+		fastErr := client.unmarshal([]byte(input), &fast)
+		slowErr := client.unmarshal([]byte(input), &slow)
+		if (fastErr == nil) != (slowErr == nil) {
+			t.Errorf("Expected the same outcome for %s but got %v and %v", input, fastErr, slowErr)
+		} else if fastErr == nil && fast != fastTodo(slow) {
+			t.Errorf("Expected the same model for %s but got %+v and %+v", input, fast, slow)
+		}
+	}
+
+	models := []fastTodo{
+		{DefaultId: DefaultId{Id: "1"}, Title: "a&b=c d/é", Done: true, Priority: -128, Estimate: 0.1, Secret: "s"},
+		{Estimate: float32(math.Inf(1))},
+		{},
+	}
+	for _, model := range models {
+		model := model
+		fast, fastErr := urlEncodeFields(&model)
+		slow, slowErr := urlEncodeFields((*slowTodo)(&model))
+		if (fastErr == nil) != (slowErr == nil) {
+			t.Errorf("Expected the same outcome for %+v but got %v and %v", model, fastErr, slowErr)
+			continue
+		}
+		fastValues, _ := url.ParseQuery(fast)
+		slowValues, _ := url.ParseQuery(slow)
+		if fastErr == nil && !reflect.DeepEqual(fastValues, slowValues) {
+			t.Errorf("Expected the same encoding for %+v but got %s and %s", model, fast, slow)
+		}
+	}
+}
+
+func TestFastCodecUsedByClient(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	before := atomic.LoadInt32(&fastTodoCalls)
+	todos := []*fastTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Create(&fastTodo{Title: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[1].Title != "b" {
+		t.Errorf("Expected the todos to be decoded but got %v", todos)
+	}
+	// Two elements for ReadAll, and encoding and decoding for Create
+	if calls := atomic.LoadInt32(&fastTodoCalls) - before; calls != 4 {
+		t.Errorf("Expected the FastCodec to be used 4 times but got %d", calls)
+	}
+	if server.todos[2].Title != "c" {
+		t.Errorf("Expected the todo to be created but got %+v", server.todos[2])
+	}
+
+	// The reflective path is used when fields are matched tolerantly
+	before = atomic.LoadInt32(&fastTodoCalls)
+	client.FieldMatching = MatchTolerant
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt32(&fastTodoCalls) - before; calls != 0 {
+		t.Errorf("Expected the FastCodec not to be used with MatchTolerant but got %d calls", calls)
+	}
+}
+
+func TestScanJSON(t *testing.T) {
+	keys := []string{}
+	err := ScanJSONObject([]byte(` { "a" : [1, "]"], "b\"c": {"d": null} } `), func(key, value []byte) error {
+		keys = append(keys, string(key)+"="+string(value))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(keys, []string{`"a"=[1, "]"]`, `"b\"c"={"d": null}`}) {
+		t.Errorf("Unexpected members %v, %v", keys, err)
+	}
+	elems := []string{}
+	err = ScanJSONArray([]byte(`[1,"a",{"b":[]},null]`), func(elem []byte) error {
+		elems = append(elems, string(elem))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(elems, []string{`1`, `"a"`, `{"b":[]}`, `null`}) {
+		t.Errorf("Unexpected elements %v, %v", elems, err)
+	}
+	for _, invalid := range []string{`[`, `[1 2]`, `{"a"}`, `{1: 2}`, `"a"`, `[1]]`} {
+		noop := func([]byte) error { return nil }
+		if ScanJSONArray([]byte(invalid), noop) == nil && ScanJSONObject([]byte(invalid), func(k, v []byte) error { return nil }) == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+	if FoldJSONKey([]byte(`"title"`), []string{"Title", "title"}) != 1 || FoldJSONKey([]byte(`"TITLE"`), []string{"Id", "Title"}) != 1 || FoldJSONKey([]byte(`"x"`), []string{"Id"}) != -1 {
+		t.Error("Expected FoldJSONKey to prefer exact matches and fall back to case-insensitive matches")
+	}
+	if got := string(AppendURLString([]byte("a=1"), "b c", "d&e~é")); got != "a=1&b+c="+url.QueryEscape("d&e~é") {
+		t.Errorf("Expected AppendURLString to escape like url.QueryEscape but got %s", got)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldMatching determines how keys in a JSON response are matched to the
+// fields of the struct they are decoded into.
+type FieldMatching int
+
+const (
+	// MatchExact is the default. Keys are matched to fields the same way as
+	// the encoding/json package, i.e. by json tag or case-insensitively by
+	// field name. A key like "is_completed" does not match a field named
+	// IsCompleted and is silently dropped.
+	MatchExact FieldMatching = iota
+	// MatchTolerant additionally matches keys which differ from a field name
+	// only by case and by underscores or hyphens, so "is_completed",
+	// "is-completed", and "isCompleted" all match IsCompleted. If more than one
+	// key in the response matches the same field, or a key matches more than
+	// one field, a warning is logged and the ambiguous key is ignored.
+	MatchTolerant
+	// MatchStrict is like MatchTolerant, except that ambiguous keys cause an
+	// AmbiguousFieldError to be returned instead of a warning.
+	MatchStrict
+)
+
+// AmbiguousFieldError is returned when decoding with MatchStrict and some key
+// in a response cannot be matched to a single field.
+type AmbiguousFieldError struct {
+	// Type is the struct type being decoded into.
+	Type reflect.Type
+	// Keys are the keys in the response which are involved.
+	Keys []string
+	// Fields are the json names of the fields which are involved.
+	Fields []string
+}
+
+// Error satisfies the error interface
+func (e AmbiguousFieldError) Error() string {
+	return fmt.Sprintf("rest: ambiguous match between keys %s and fields %s of %s",
+		strings.Join(e.Keys, ", "), strings.Join(e.Fields, ", "), e.Type.String())
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// matchFields rewrites the keys of the JSON objects in data so that they
+// match the json names of the fields of typ, according to c.FieldMatching.
+func (c *Client) matchFields(data []byte, typ reflect.Type) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Preserve numbers exactly when the data is re-encoded
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		// Let the real decode report the error
+		return data, nil
+	}
+	value, err := renameKeys(value, typ, c.FieldMatching == MatchStrict)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// renameKeys returns a copy of value, the generic representation of some JSON,
+// in which the keys of objects which will be decoded into structs have been
+// renamed to match the fields of the struct.
+func renameKeys(value interface{}, typ reflect.Type, strict bool) (interface{}, error) {
+	typ = derefType(typ)
+	if typ.Implements(jsonUnmarshalerType) || reflect.PtrTo(typ).Implements(jsonUnmarshalerType) {
+		return value, nil
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		if obj, ok := value.(map[string]interface{}); ok {
+			return renameObjectKeys(obj, typ, strict)
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := value.([]interface{}); ok {
+			for i, elem := range arr {
+				renamed, err := renameKeys(elem, typ.Elem(), strict)
+				if err != nil {
+					return nil, err
+				}
+				arr[i] = renamed
+			}
+		}
+	case reflect.Map:
+		if obj, ok := value.(map[string]interface{}); ok {
+			for key, elem := range obj {
+				renamed, err := renameKeys(elem, typ.Elem(), strict)
+				if err != nil {
+					return nil, err
+				}
+				obj[key] = renamed
+			}
+		}
+	}
+	return value, nil
+}
+
+// renameObjectKeys renames the keys of obj to match the fields of the struct
+// type typ, and recursively renames the keys of the values.
+func renameObjectKeys(obj map[string]interface{}, typ reflect.Type, strict bool) (interface{}, error) {
+	fields := jsonFields(typ)
+	byNormalizedName := map[string][]string{}
+	for name := range fields {
+		normalized := normalizeFieldName(name)
+		byNormalizedName[normalized] = append(byNormalizedName[normalized], name)
+	}
+	// Find the field each key matches, grouping keys by field so we can detect
+	// keys which collide
+	keysByField := map[string][]string{}
+	result := map[string]interface{}{}
+	for _, key := range sortedKeys(obj) {
+		if _, found := fields[key]; found {
+			keysByField[key] = append(keysByField[key], key)
+			continue
+		}
+		candidates := byNormalizedName[normalizeFieldName(key)]
+		switch len(candidates) {
+		case 0:
+			// Unknown key. Keep it so that encoding/json behaves as usual.
+			result[key] = obj[key]
+		case 1:
+			keysByField[candidates[0]] = append(keysByField[candidates[0]], key)
+		default:
+			sort.Strings(candidates)
+			if err := ambiguousField(typ, []string{key}, candidates, strict); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name, keys := range keysByField {
+		key := keys[0]
+		if len(keys) > 1 {
+			if err := ambiguousField(typ, keys, []string{name}, strict); err != nil {
+				return nil, err
+			}
+			// Prefer a key which matches the field name exactly
+			if _, found := obj[name]; !found {
+				continue
+			}
+			key = name
+		}
+		renamed, err := renameKeys(obj[key], fields[name], strict)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = renamed
+	}
+	return result, nil
+}
+
+// ambiguousField returns an AmbiguousFieldError if strict is true. Otherwise
+// it logs a warning and returns nil.
+func ambiguousField(typ reflect.Type, keys []string, fields []string, strict bool) error {
+	err := AmbiguousFieldError{Type: typ, Keys: keys, Fields: fields}
+	if strict {
+		return err
+	}
+	log.Printf("rest: warning: %s", strings.TrimPrefix(err.Error(), "rest: "))
+	return nil
+}
+
+// jsonFields returns the json names of the fields of the struct type typ,
+// including fields promoted from embedded structs, mapped to their types.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	return embeddedJSONFields(typ, map[reflect.Type]bool{})
+}
+
+// embeddedJSONFields implements jsonFields. visited holds the struct types
+// which are already being inspected, so that types which embed a pointer to
+// themselves do not cause infinite recursion.
+func embeddedJSONFields(typ reflect.Type, visited map[reflect.Type]bool) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	if visited[typ] {
+		return fields
+	}
+	visited[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fieldType := derefType(field.Type)
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range embeddedJSONFields(fieldType, visited) {
+				if _, found := fields[embeddedName]; !found {
+					fields[embeddedName] = embeddedType
+				}
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// Unexported
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// normalizeFieldName converts name to lower case and removes any underscores
+// and hyphens, so that names in snake case, kebab case, and camel case can be
+// compared.
+func normalizeFieldName(name string) string {
+	name = strings.Replace(name, "_", "", -1)
+	name = strings.Replace(name, "-", "", -1)
+	return strings.ToLower(name)
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFieldMatchingTolerant(t *testing.T) {
+	newEchoServer(t, http.StatusOK, `[{"id": "1", "title": "a", "is_completed": true}, {"Id": "2", "is-completed": true}]`)
+	client := NewClient()
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if todos[0].IsCompleted || todos[1].IsCompleted {
+		t.Error("Expected MatchExact to drop keys in snake and kebab case")
+	}
+	client.FieldMatching = MatchTolerant
+	todos = []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if todos[0].Id != "1" || todos[0].Title != "a" || !todos[0].IsCompleted || !todos[1].IsCompleted {
+		t.Errorf("Expected MatchTolerant to match the keys but got %+v, %+v", todos[0], todos[1])
+	}
+}
+
+func TestFieldMatchingAmbiguous(t *testing.T) {
+	newEchoServer(t, http.StatusOK, `{"Id": "1", "is_completed": true, "isCompleted": true}`)
+	client := NewClient()
+	client.FieldMatching = MatchStrict
+	err := client.Read("1", &testTodo{})
+	ambiguous, ok := err.(AmbiguousFieldError)
+	if !ok {
+		t.Fatalf("Expected an AmbiguousFieldError but got %v", err)
+	}
+	if strings.Join(ambiguous.Keys, ",") != "isCompleted,is_completed" || strings.Join(ambiguous.Fields, ",") != "IsCompleted" {
+		t.Errorf("Unexpected keys and fields: %v, %v", ambiguous.Keys, ambiguous.Fields)
+	}
+
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+	client.FieldMatching = MatchTolerant
+	todo := &testTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "1" || todo.IsCompleted {
+		t.Errorf("Expected the ambiguous keys to be ignored but got %+v", todo)
+	}
+	if !strings.Contains(buf.String(), "rest: warning: ambiguous match") {
+		t.Errorf("Expected a warning to be logged but got %q", buf.String())
+	}
+}
+
+func TestJSONFieldsEmbedded(t *testing.T) {
+	type tagged struct {
+		testTodo
+		Hidden string `json:"-"`
+		Due    string `json:"due_date,omitempty"`
+		secret string
+	}
+	fields := jsonFields(reflect.TypeOf(tagged{}))
+	for _, name := range []string{"Id", "Title", "IsCompleted", "due_date"} {
+		if _, found := fields[name]; !found {
+			t.Errorf("Expected field %s in %v", name, fields)
+		}
+	}
+	if len(fields) != 4 {
+		t.Errorf("Expected 4 fields but got %v", fields)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"reflect"
+)
+
+// FirstOrCreate searches for an existing model which matches query by sending
+// a GET request to model.RootURL() with the query encoded in the url. The url
+// is resolved the same way as for Create, so the search and the creation are
+// sent to the same server. If the server responds with at least one model,
+// FirstOrCreate sets model to the first one, which is decoded just as by
+// ReadAll. Otherwise it creates model on the
+// server by calling Create. Since model may be mutated, it should be a pointer.
+//
+// If the WithUpsert option is provided and model has a non-empty id,
+// FirstOrCreate does not search at all. Instead it calls Put, which the server
+// should treat as "create or replace". Note that the query is not used in this
+// case.
+func (c *Client) FirstOrCreate(model Model, query Query, opts ...RequestOption) error {
+	if err := checkModelPointer("FirstOrCreate", model); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	if reqOpts.upsert && model.ModelId() != "" {
+		return c.Put(model, opts...)
+	}
+	rootURL, err := c.routeRootURL(model, ActionRead)
+	if err != nil {
+		return err
+	}
+	if rootURL, err = normalizeRootURL(rootURL); err != nil {
+		return err
+	}
+	found := reflect.New(reflect.SliceOf(reflect.TypeOf(model)))
+	if err := c.sendRequestAndUnmarshal("GET", appendQuery(rootURL, query), "", "", found.Interface(), reqOpts); err != nil {
+		return err
+	}
+	if found.Elem().Len() > 0 {
+		reflect.ValueOf(model).Elem().Set(found.Elem().Index(0).Elem())
+		return nil
+	}
+	return c.Create(model, opts...)
+}
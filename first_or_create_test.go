@@ -0,0 +1,100 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"testing"
+)
+
+func TestFirstOrCreateFindsExisting(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	server.todos[1].IsCompleted = true
+	todo := &testTodo{Title: "ignored"}
+	if err := NewClient().FirstOrCreate(todo, Query{"IsCompleted": {"true"}}); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "2" || todo.Title != "b" {
+		t.Errorf("Expected the first matching todo but got %+v", todo)
+	}
+	if server.count("POST") != 0 {
+		t.Errorf("Expected nothing to be created but got %v", server.Requests())
+	}
+}
+
+func TestFirstOrCreateCreatesMissing(t *testing.T) {
+	server := newTodoServer(t, "a")
+	todo := &testTodo{Title: "done", IsCompleted: true}
+	if err := NewClient().FirstOrCreate(todo, Query{"IsCompleted": {"true"}}); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "2" {
+		t.Errorf("Expected the todo to be created but got %+v", todo)
+	}
+	expected := []string{"GET /todos?IsCompleted=true", "POST /todos"}
+	if got := server.Requests(); len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("Expected requests %v but got %v", expected, got)
+	}
+}
+
+func TestFirstOrCreateUpsert(t *testing.T) {
+	server := newTodoServer(t)
+	todo := &testTodo{DefaultId: DefaultId{Id: "chosen"}, Title: "a"}
+	if err := NewClient().FirstOrCreate(todo, Query{"Title": {"a"}}, WithUpsert()); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Requests(); len(got) != 1 || got[0] != "PUT /todos/chosen" {
+		t.Errorf("Expected a single PUT but got %v", got)
+	}
+
+	// Without an id there is nothing to PUT to, so the query is used
+	if err := NewClient().FirstOrCreate(&testTodo{Title: "b"}, Query{"Title": {"b"}}, WithUpsert()); err != nil {
+		t.Fatal(err)
+	}
+	if server.count("POST") != 1 {
+		t.Errorf("Expected the todo without an id to be created but got %v", server.Requests())
+	}
+}
+
+func TestFirstOrCreateRejectsNonPointer(t *testing.T) {
+	newTodoServer(t)
+	if _, ok := NewClient().FirstOrCreate(stringModel("a"), nil).(TypeError); !ok {
+		t.Error("Expected a TypeError for a non-pointer model")
+	}
+}
+
+func TestFirstOrCreateRouteResolver(t *testing.T) {
+	server := newTodoServer(t, "a")
+	shardURL := testRootURL
+	useFakeRootURL(t)
+	var decoded []interface{}
+	client := NewClient()
+	client.RouteResolver = func(Model, Action) (string, error) {
+		return shardURL, nil
+	}
+	client.OnDecode = func(method string, url string, v interface{}) error {
+		decoded = append(decoded, v)
+		return nil
+	}
+	todo := &testTodo{}
+	if err := client.FirstOrCreate(todo, Query{"Title": {"a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "1" || todo.Title != "a" {
+		t.Errorf("Expected the todo found on the resolved server but got %+v", todo)
+	}
+	if len(decoded) != 1 {
+		t.Fatalf("Expected the found todos to be passed to OnDecode but got %v", decoded)
+	}
+	if _, ok := decoded[0].(*[]*testTodo); !ok {
+		t.Errorf("Expected the found todos to be decoded as a slice of todos but got %T", decoded[0])
+	}
+	if err := client.FirstOrCreate(&testTodo{}, Query{"Title": {"b"}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"GET /todos?Title=a", "GET /todos?Title=b", "POST /todos"}
+	if got := server.Requests(); len(got) != 3 || got[0] != expected[0] || got[1] != expected[1] || got[2] != expected[2] {
+		t.Errorf("Expected requests %v but got %v", expected, got)
+	}
+}
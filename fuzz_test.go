@@ -0,0 +1,143 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The fuzz targets in this file exercise the reflection-based encoders and
+// decoders of the package. The guaranteed behavior is that encoding and
+// decoding never panic and never loop forever, whatever the input and
+// whatever the model type, including recursive types, deeply nested data, and
+// floats which are NaN or infinite. Such inputs may result in an error
+// instead: NaN and infinite floats cannot be url-encoded, pointers nested more
+// than 64 levels deep are rejected, and JSON nested more deeply than
+// encoding/json allows cannot be decoded. Run them with e.g.
+//
+//	go test -fuzz FuzzDecodeResponse
+//
+
+// fuzzModel is a model with a field of each kind the url encoder supports.
+type fuzzModel struct {
+	DefaultId
+	fuzzEmbedded
+	Title     string
+	Count     int
+	Small     int8
+	Unsigned  uint16
+	Price     float64
+	Ratio     float32
+	Done      bool
+	Data      []byte
+	Raw       json.RawMessage
+	Pointer   *string
+	Amount    *big.Rat
+	At        time.Time
+	Excluded  string `rest:"-"`
+	SnakeCase string `json:"snake_case"`
+	private   string
+}
+
+// fuzzDocument is a model with fields which only JSON can represent, including
+// a recursive type.
+type fuzzDocument struct {
+	fuzzModel
+	*fuzzNode
+	Any  interface{}
+	Tags []string
+	Meta map[string]interface{}
+}
+
+// fuzzNode is a recursive type.
+type fuzzNode struct {
+	*fuzzNode
+	Name     string
+	Parent   *fuzzNode
+	Children []*fuzzNode
+}
+
+// fuzzEmbedded is an unexported embedded struct with exported fields.
+type fuzzEmbedded struct {
+	Promoted string
+}
+
+// RootURL satisfies Model.
+func (*fuzzModel) RootURL() string {
+	return "http://localhost/fuzz"
+}
+
+// fuzzSeeds are the inputs both fuzz targets start from.
+var fuzzSeeds = []string{
+	`{"Id": "1", "Title": "Write tests", "Count": 3, "Done": true}`,
+	`{"Title": "a&b=c", "Price": 1e308, "Ratio": -0.5, "Small": -128, "Unsigned": 65535}`,
+	`{"Data": "aGVsbG8=", "Raw": {"nested": [1, 2]}, "Pointer": "p", "Amount": "1/3"}`,
+	`{"At": "2015-01-02T15:04:05Z", "snake_case": "x", "Promoted": "y", "Excluded": "z"}`,
+	`{"Name": "root", "Children": [{"Name": "child", "Children": [{"Name": "grandchild"}]}]}`,
+	`[{"Id": "1", "Tags": ["a"], "Meta": {"k": null}}, {"Any": [true, 1.5, "s"]}]`,
+	`{"id": "1", "title": "wrong case", "unknown": 1}`,
+	`[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]]`,
+	`null`,
+	``,
+}
+
+// FuzzURLEncode decodes data as JSON into a model with fields of many types
+// and url-encodes the result, checking that the encoded string can be parsed
+// and holds the title of the model. It also url-encodes data as a MapModel.
+func FuzzURLEncode(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		model := &fuzzModel{}
+		if err := json.Unmarshal(data, model); err == nil {
+			encoded, err := urlEncodeFields(model)
+			if err != nil {
+				t.Fatalf("could not url-encode %#v: %s", model, err)
+			}
+			values, err := url.ParseQuery(encoded)
+			if err != nil {
+				t.Fatalf("url-encoded %#v as %q, which cannot be parsed: %s", model, encoded, err)
+			}
+			if values.Get("Title") != model.Title {
+				t.Fatalf("url-encoded title %q as %q", model.Title, values.Get("Title"))
+			}
+		}
+		mapModel := NewMapModel("http://localhost/fuzz")
+		if err := json.Unmarshal(data, mapModel); err == nil {
+			urlEncodeFields(mapModel)
+		}
+		node := &fuzzNode{}
+		if err := json.Unmarshal(data, node); err == nil {
+			if _, err := encodeString(reflect.ValueOf(node)); err == nil {
+				t.Fatalf("url-encoded %#v, which has no url encoding", node)
+			}
+		}
+	})
+}
+
+// FuzzDecodeResponse decodes data as the body of a response into a model, a
+// slice of models with fields of many types, and a recursive type, with each
+// of the FieldMatching modes.
+func FuzzDecodeResponse(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, matching := range []FieldMatching{MatchExact, MatchTolerant, MatchStrict} {
+			c := &Client{FieldMatching: matching, UseNumber: matching == MatchTolerant}
+			c.unmarshal(data, &fuzzModel{})
+			c.unmarshal(data, &[]*fuzzDocument{})
+			c.unmarshal(data, &fuzzDocument{})
+			c.unmarshal(data, &fuzzNode{})
+			c.unmarshal(data, NewMapModel("http://localhost/fuzz"))
+		}
+	})
+}
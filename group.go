@@ -0,0 +1,204 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Group runs requests concurrently with shared cancellation, which makes it
+// easy to load all the data for a page in parallel:
+//
+//	g := client.NewGroup(ctx)
+//	g.Read("1", user)
+//	g.ReadAll(&todos)
+//	err := g.Wait()
+//
+// If any request fails, the context of the group is canceled, which aborts the
+// other requests. A Group must not be reused after Wait returns.
+type Group struct {
+	client *Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	errs   []error
+	// budget is true for groups created with NewBudgetGroup
+	budget bool
+	// timedOut are the names of the tasks which did not finish within the
+	// budget
+	timedOut []string
+	// tasks is the number of tasks started, used to name unnamed tasks
+	tasks int
+	mut   sync.Mutex
+}
+
+// GroupError is returned by Group.Wait when more than one request failed.
+type GroupError struct {
+	// Errors are the errors returned by the failed requests, in the order in
+	// which they failed.
+	Errors []error
+}
+
+// Error satisfies the error interface
+func (e GroupError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "rest: " + strings.Join(msgs, "; ")
+}
+
+// PartialResultError is returned by the Wait method of a Group created with
+// NewBudgetGroup when not every request completed successfully. The requests
+// which did complete have filled in their models as usual.
+type PartialResultError struct {
+	// TimedOut are the names of the requests which did not complete within the
+	// time budget. The name of a Read is the resource name and id of the
+	// model (e.g. "todos/1"), the name of a ReadAll is the resource name
+	// (e.g. "todos"), and the name of a function passed to GoNamed is the
+	// given name.
+	TimedOut []string
+	// Errors are the errors of requests which failed for other reasons.
+	Errors []error
+}
+
+// Error satisfies the error interface
+func (e PartialResultError) Error() string {
+	msgs := []string{}
+	if len(e.TimedOut) > 0 {
+		msgs = append(msgs, "timed out: "+strings.Join(e.TimedOut, ", "))
+	}
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "rest: partial result: " + strings.Join(msgs, "; ")
+}
+
+// NewGroup returns a new Group whose requests are sent by c and are canceled
+// when ctx is canceled.
+func (c *Client) NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{
+		client: c,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// NewBudgetGroup returns a new Group whose requests must complete within the
+// given time budget. Unlike a group returned by NewGroup, a failed request does
+// not cancel the others. Once the budget expires, any requests which are still
+// in progress are canceled and Wait returns a PartialResultError listing them,
+// so that e.g. a dashboard can show whichever collections did load instead
+// of nothing at all.
+func (c *Client) NewBudgetGroup(ctx context.Context, budget time.Duration) *Group {
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	return &Group{
+		client: c,
+		ctx:    ctx,
+		cancel: cancel,
+		budget: true,
+	}
+}
+
+// Context returns the context of the group, which is canceled as soon as any
+// function run by the group fails or Wait returns.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go calls f in a new goroutine with the context of the group. If f returns an
+// error, the context of the group is canceled, unless the group was created
+// with NewBudgetGroup.
+func (g *Group) Go(f func(ctx context.Context) error) {
+	g.GoNamed("", f)
+}
+
+// GoNamed is like Go but gives the function a name, which is used to identify
+// it in a PartialResultError.
+func (g *Group) GoNamed(name string, f func(ctx context.Context) error) {
+	g.mut.Lock()
+	g.tasks++
+	if name == "" {
+		name = fmt.Sprintf("#%d", g.tasks)
+	}
+	g.mut.Unlock()
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(g.ctx); err != nil {
+			g.fail(name, err)
+		}
+	}()
+}
+
+// Read calls Client.Read in a new goroutine with the context of the group.
+func (g *Group) Read(id string, model Model, opts ...RequestOption) {
+	// opts may be shared with other calls, so we append to a copy
+	opts = append([]RequestOption(nil), opts...)
+	g.GoNamed(resourceName(model.RootURL())+"/"+id, func(ctx context.Context) error {
+		return g.client.Read(id, model, append(opts, WithContext(ctx))...)
+	})
+}
+
+// ReadAll calls Client.ReadAll in a new goroutine with the context of the
+// group.
+func (g *Group) ReadAll(models interface{}, opts ...RequestOption) {
+	name := ""
+	if rootURL, err := getURLFromModels(models); err == nil {
+		name = resourceName(rootURL)
+	}
+	opts = append([]RequestOption(nil), opts...)
+	g.GoNamed(name, func(ctx context.Context) error {
+		return g.client.ReadAll(models, append(opts, WithContext(ctx))...)
+	})
+}
+
+// Wait waits for all the functions run by the group to return. It returns nil
+// if all of them succeeded, the error if exactly one failed, or a GroupError
+// if more than one failed. Requests which are aborted because another one
+// failed are not counted as failures. For a group created with NewBudgetGroup,
+// Wait returns a PartialResultError if anything failed or timed out.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	switch {
+	case g.budget && (len(g.timedOut) > 0 || len(g.errs) > 0):
+		return PartialResultError{TimedOut: g.timedOut, Errors: g.errs}
+	case len(g.errs) == 0:
+		return nil
+	case len(g.errs) == 1:
+		return g.errs[0]
+	default:
+		return GroupError{Errors: g.errs}
+	}
+}
+
+// fail records that the function with the given name failed with err and,
+// unless the group has a time budget, cancels the context of the group.
+func (g *Group) fail(name string, err error) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if g.budget {
+		if g.ctx.Err() == context.DeadlineExceeded {
+			g.timedOut = append(g.timedOut, name)
+		} else {
+			g.errs = append(g.errs, err)
+		}
+		return
+	}
+	if len(g.errs) > 0 && err == g.ctx.Err() {
+		// Caused by the cancellation below
+		return
+	}
+	g.errs = append(g.errs, err)
+	g.cancel()
+}
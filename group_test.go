@@ -0,0 +1,158 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	newTodoServer(t, "a", "b")
+	g := NewClient().NewGroup(context.Background())
+	todo := &testTodo{}
+	todos := []*testTodo{}
+	g.Read("2", todo)
+	g.ReadAll(&todos)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "b" || len(todos) != 2 {
+		t.Errorf("Expected both requests to complete but got %+v and %d todos", todo, len(todos))
+	}
+	if g.Context().Err() == nil {
+		t.Error("Expected the context of the group to be canceled after Wait")
+	}
+}
+
+func TestGroupSharedOptions(t *testing.T) {
+	newTodoServer(t, "a", "b", "c")
+	g := NewClient().NewGroup(context.Background())
+	// opts has spare capacity, so that appending to it in place would make
+	// the requests overwrite each other's options, which go test -race reports
+	opts := make([]RequestOption, 1, 8)
+	opts[0] = WithFields("Id", "Title")
+	todos := []*testTodo{{}, {}, {}}
+	for i, todo := range todos {
+		g.Read(strconv.Itoa(i+1), todo, opts...)
+	}
+	g.ReadAll(&[]*testTodo{}, opts...)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if todos[0].Title != "a" || todos[1].Title != "b" || todos[2].Title != "c" {
+		t.Errorf("Expected each todo to be read but got %+v", todos)
+	}
+	if len(opts) != 1 {
+		t.Errorf("Expected the options of the caller to be left unchanged but got %d", len(opts))
+	}
+}
+
+func TestGroupCancelsOnFailure(t *testing.T) {
+	server := newBlockingServer(t, http.StatusOK)
+	g := NewClient().NewGroup(context.Background())
+	failure := errors.New("failed")
+	g.Read("1", &testTodo{})
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	server.waitForRequests(t, 1)
+	g.Go(func(ctx context.Context) error {
+		return failure
+	})
+	if err := g.Wait(); err != failure {
+		t.Errorf("Expected only the error which caused the cancellation but got %v", err)
+	}
+}
+
+func TestGroupError(t *testing.T) {
+	g := NewClient().NewGroup(context.Background())
+	first, second := errors.New("first"), errors.New("second")
+	failed := make(chan struct{})
+	g.Go(func(ctx context.Context) error {
+		defer close(failed)
+		return first
+	})
+	g.Go(func(ctx context.Context) error {
+		<-failed
+		<-ctx.Done()
+		return second
+	})
+	err := g.Wait()
+	expected := GroupError{Errors: []error{first, second}}
+	if !reflect.DeepEqual(err, expected) {
+		t.Fatalf("Expected %v but got %v", expected, err)
+	}
+	if err.Error() != "rest: first; second" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+}
+
+func TestGroupParentCanceled(t *testing.T) {
+	newBlockingServer(t, http.StatusOK)
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewClient().NewGroup(ctx)
+	g.Read("1", &testTodo{})
+	cancel()
+	if err := g.Wait(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+}
+
+func TestBudgetGroup(t *testing.T) {
+	newTodoServer(t, "a")
+	client := NewClient()
+	g := client.NewBudgetGroup(context.Background(), 50*time.Millisecond)
+	todos := []*testTodo{}
+	failure := errors.New("failed")
+	g.ReadAll(&todos)
+	g.Read("7", &testTodo{})
+	g.GoNamed("stats", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go(func(ctx context.Context) error {
+		return failure
+	})
+	err := g.Wait()
+	partial, ok := err.(PartialResultError)
+	if !ok {
+		t.Fatalf("Expected a PartialResultError but got %v", err)
+	}
+	sort.Strings(partial.TimedOut)
+	if !reflect.DeepEqual(partial.TimedOut, []string{"#4", "stats"}) {
+		t.Errorf("Expected the slow tasks to time out but got %v", partial.TimedOut)
+	}
+	if len(partial.Errors) != 2 {
+		t.Fatalf("Expected the 404 and the failure but got %v", partial.Errors)
+	}
+	if len(todos) != 1 {
+		t.Errorf("Expected the completed collection to be filled in but got %d todos", len(todos))
+	}
+
+	g = client.NewBudgetGroup(context.Background(), time.Second)
+	g.ReadAll(&todos)
+	if err := g.Wait(); err != nil {
+		t.Errorf("Expected no error when everything completes in time but got %v", err)
+	}
+}
+
+func TestPartialResultError(t *testing.T) {
+	err := PartialResultError{TimedOut: []string{"todos", "users/1"}, Errors: []error{errors.New("failed")}}
+	if err.Error() != "rest: partial result: timed out: todos, users/1; failed" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+}
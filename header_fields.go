@@ -0,0 +1,137 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setHeaderFields sets the fields of v which are tagged with a header option,
+// e.g. `rest:"-,header=ETag"`, to the values of the corresponding headers of
+// res. Fields whose header is missing are left unchanged. v is typically the
+// model the response is decoded into. If v is not a pointer to a struct,
+// setHeaderFields does nothing.
+func setHeaderFields(res *http.Response, v interface{}) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr {
+		return nil
+	}
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	return setHeaderFieldsOf(res, val)
+}
+
+// setHeaderFieldsOf sets the header fields of the struct value val, including
+// those of embedded structs.
+func setHeaderFieldsOf(res *http.Response, val reflect.Value) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := setHeaderFieldsOf(res, val.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, opts := parseTag(field)
+		name, found := opts.Get("header")
+		if !found || name == "" || field.PkgPath != "" {
+			continue
+		}
+		value := res.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if name := http.CanonicalHeaderKey(name); name == "Location" || name == "Content-Location" {
+			// Make relative locations absolute
+			if res.Request != nil {
+				if location, err := res.Request.URL.Parse(value); err == nil {
+					value = location.String()
+				}
+			}
+		}
+		if err := setFieldFromString(val.Field(i), value); err != nil {
+			return fmt.Errorf("rest: could not set %s.%s from %s header: %s", typ.String(), field.Name, name, err)
+		}
+	}
+	return nil
+}
+
+// setFieldFromString parses str according to the type of field and sets field
+// to the result. It supports strings, bools, numbers, time.Time (in the http
+// date format), and types which implement encoding.TextUnmarshaler.
+func setFieldFromString(field reflect.Value, str string) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFieldFromString(field.Elem(), str)
+	}
+	if field.Type() == timeType {
+		t, err := http.ParseTime(str)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			// e.g. Retry-After
+			seconds, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(time.Duration(seconds) * time.Second))
+			return nil
+		}
+		i, err := strconv.ParseInt(str, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// versionedTodo is a testTodo with fields which are set from response
+// headers.
+type versionedTodo struct {
+	testTodo
+	ETag       string        `rest:"-,header=ETag"`
+	Modified   *time.Time    `rest:"-,header=Last-Modified"`
+	Location   string        `rest:"-,header=location"`
+	Version    int           `rest:"-,header=X-Version"`
+	RetryAfter time.Duration `rest:"-,header=Retry-After"`
+}
+
+func TestHeaderFields(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v3"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Header().Set("Location", "/todos/1")
+		w.Header().Set("X-Version", "3")
+		w.Header().Set("Retry-After", "5")
+		w.Write([]byte(`{"Id": "1", "Title": "a"}`))
+	})
+	todo := &versionedTodo{}
+	if err := NewClient().Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "a" || todo.ETag != `"v3"` || todo.Version != 3 || todo.RetryAfter != 5*time.Second {
+		t.Errorf("Expected the fields to be set from the headers but got %+v", todo)
+	}
+	if todo.Location != testRootURL+"/todos/1" {
+		t.Errorf("Expected the location to be made absolute but got %s", todo.Location)
+	}
+	if todo.Modified == nil || !todo.Modified.Equal(time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)) {
+		t.Errorf("Expected the last modified time to be parsed but got %v", todo.Modified)
+	}
+
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "latest")
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	if err := NewClient().Read("1", todo); err == nil {
+		t.Error("Expected an error for a header which cannot be parsed")
+	}
+}
+
+func TestHeaderFieldsMissing(t *testing.T) {
+	newTodoServer(t, "a")
+	todo := &versionedTodo{ETag: `"v1"`, Version: 1}
+	if err := NewClient().Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.ETag != `"v1"` || todo.Version != 1 || todo.Modified != nil {
+		t.Errorf("Expected fields whose header is missing to be unchanged but got %+v", todo)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// HealthCheck sends a GET request to url and returns nil if the server
+// responds with a 2xx status code. Otherwise it returns an HTTPError or the
+// error that prevented the request from being sent. The request includes the
+// default headers of the client, but it is sent only once and is not subject
+// to retries, failover, or concurrency limits, so the result reflects the
+// health of url itself.
+func (c *Client) HealthCheck(ctx context.Context, url string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("Something went wrong building GET request to %s: %s", url, err.Error())
+	}
+	req = req.WithContext(ctx)
+	c.applyHeadersAndVars(req)
+	res, err := c.sender().Do(req)
+	if err != nil {
+		return fmt.Errorf("Something went wrong with GET request to %s: %s", url, err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return newHTTPError(res)
+	}
+	ioutil.ReadAll(res.Body)
+	return nil
+}
+
+// MonitorHealth checks the health of each of the given urls with HealthCheck
+// every interval, in the background, until ctx is done. Whenever the health of
+// a url changes, a HostHealthy or HostUnhealthy event is published. A url is
+// assumed to be healthy until it has been checked, so a HostUnhealthy event is
+// also published if the first check fails. If a url starts with one of the
+// endpoints of the client's FailoverPolicy, the results are also used to fail
+// over from or recover to that endpoint without waiting for requests to fail.
+func (c *Client) MonitorHealth(ctx context.Context, interval time.Duration, urls ...string) {
+	go func() {
+		for {
+			for _, url := range urls {
+				c.checkHealth(ctx, url)
+			}
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Healthy returns false if the most recent health check of url by
+// MonitorHealth failed, and true otherwise.
+func (c *Client) Healthy(url string) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	unhealthy, found := c.unhealthy[url]
+	return !found || !unhealthy
+}
+
+// checkHealth checks the health of url and records the result.
+func (c *Client) checkHealth(ctx context.Context, url string) {
+	err := c.HealthCheck(ctx, url)
+	if ctx.Err() != nil {
+		// The monitor was stopped during the check
+		return
+	}
+	healthy := err == nil
+	c.mut.Lock()
+	if c.unhealthy == nil {
+		c.unhealthy = map[string]bool{}
+	}
+	changed := c.unhealthy[url] == healthy
+	c.unhealthy[url] = !healthy
+	c.mut.Unlock()
+	if f := c.getFailover(); f != nil {
+		if endpoint := f.recordHealth(url, healthy); endpoint != "" {
+			c.publish(Event{Type: EndpointChanged, Method: "GET", URL: endpoint})
+		}
+	}
+	if !changed {
+		return
+	}
+	event := Event{Type: HostHealthy, Method: "GET", URL: url}
+	if !healthy {
+		event.Type = HostUnhealthy
+		event.Err = err
+		if httpErr, ok := err.(HTTPError); ok {
+			event.StatusCode = httpErr.StatusCode
+		}
+	}
+	c.publish(event)
+}
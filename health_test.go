@@ -0,0 +1,110 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHealthServer returns a test server whose /health endpoint responds with
+// the status stored in status.
+func newHealthServer(t *testing.T, status *int32) *requestLog {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+	})
+	return log
+}
+
+func TestHealthCheck(t *testing.T) {
+	status := int32(http.StatusOK)
+	log := newHealthServer(t, &status)
+	client := NewClient()
+	client.Header = http.Header{"X-Client": {"test"}}
+	client.Retry = &RetryPolicy{MaxAttempts: 3, StatusCodes: []int{http.StatusServiceUnavailable}}
+	if err := client.HealthCheck(context.Background(), testRootURL+"/health"); err != nil {
+		t.Fatal(err)
+	}
+	if log.last().Header.Get("X-Client") != "test" {
+		t.Error("Expected the health check to include the default headers")
+	}
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	err := client.HealthCheck(context.Background(), testRootURL+"/health")
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected an HTTPError with status 503 but got %v", err)
+	}
+	if len(log.all()) != 2 {
+		t.Errorf("Expected health checks not to be retried but got %d requests", len(log.all()))
+	}
+	if err := client.HealthCheck(context.Background(), "http://127.0.0.1:1/health"); err == nil {
+		t.Error("Expected an error for an unreachable server")
+	}
+}
+
+func TestCheckHealthEvents(t *testing.T) {
+	status := int32(http.StatusOK)
+	newHealthServer(t, &status)
+	url := testRootURL + "/health"
+	client := NewClient()
+	events := []Event{}
+	client.Events().Subscribe(func(event Event) {
+		events = append(events, event)
+	}, HostHealthy, HostUnhealthy)
+	ctx := context.Background()
+	client.checkHealth(ctx, url)
+	if len(events) != 0 || !client.Healthy(url) {
+		t.Fatalf("Expected no event while the url stays healthy but got %v", events)
+	}
+	atomic.StoreInt32(&status, http.StatusInternalServerError)
+	client.checkHealth(ctx, url)
+	client.checkHealth(ctx, url)
+	if len(events) != 1 || events[0].Type != HostUnhealthy || events[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected a single HostUnhealthy event but got %v", events)
+	}
+	if client.Healthy(url) {
+		t.Error("Expected the url to be unhealthy")
+	}
+	atomic.StoreInt32(&status, http.StatusOK)
+	client.checkHealth(ctx, url)
+	if len(events) != 2 || events[1].Type != HostHealthy || !client.Healthy(url) {
+		t.Errorf("Expected a HostHealthy event but got %v", events)
+	}
+}
+
+func TestMonitorHealthFailover(t *testing.T) {
+	status := int32(http.StatusServiceUnavailable)
+	newHealthServer(t, &status)
+	client := NewClient()
+	client.Failover = &FailoverPolicy{Endpoints: []string{testRootURL, "http://127.0.0.1:1"}}
+	changed := make(chan string, 10)
+	client.Events().Subscribe(func(event Event) {
+		changed <- event.URL
+	}, EndpointChanged)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.MonitorHealth(ctx, 10*time.Millisecond, testRootURL+"/health")
+	select {
+	case endpoint := <-changed:
+		if endpoint != "http://127.0.0.1:1" {
+			t.Errorf("Expected to fail over to the secondary but got %s", endpoint)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the monitor to fail over")
+	}
+	atomic.StoreInt32(&status, http.StatusOK)
+	select {
+	case endpoint := <-changed:
+		if endpoint != testRootURL {
+			t.Errorf("Expected to recover the primary but got %s", endpoint)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the monitor to recover the primary")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testTodo is the model used throughout the tests. Its RootURL points at the
+// server started by the most recent call to newTestServer.
+type testTodo struct {
+	DefaultId
+	Title       string
+	IsCompleted bool
+}
+
+// testRootURL is the url of the current test server.
+var testRootURL string
+
+func (t *testTodo) RootURL() string {
+	return testRootURL + "/todos"
+}
+
+// newTestServer starts a server which handles requests with handler and
+// points the RootURL of testTodo at it for the rest of the test.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	previous := testRootURL
+	testRootURL = srv.URL
+	t.Cleanup(func() {
+		srv.Close()
+		testRootURL = previous
+	})
+	return srv
+}
+
+// todoServer is an in-memory REST server for testTodos, which records the
+// requests it receives. The collection can be filtered by IsCompleted and
+// Title.
+type todoServer struct {
+	todos    []testTodo
+	nextId   int
+	requests []string
+	mut      sync.Mutex
+}
+
+// newTodoServer starts a todoServer holding the given todos, with ids
+// assigned in order starting at 1, and points testTodo at it.
+func newTodoServer(t *testing.T, titles ...string) *todoServer {
+	t.Helper()
+	s := &todoServer{nextId: 1}
+	for _, title := range titles {
+		s.add(title, false)
+	}
+	newTestServer(t, s.ServeHTTP)
+	return s
+}
+
+// add adds a todo and returns it.
+func (s *todoServer) add(title string, isCompleted bool) testTodo {
+	todo := testTodo{Title: title, IsCompleted: isCompleted}
+	todo.Id = strconv.Itoa(s.nextId)
+	s.nextId++
+	s.todos = append(s.todos, todo)
+	return todo
+}
+
+// Requests returns the requests received so far, in the form "GET /todos?q".
+func (s *todoServer) Requests() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// count returns the number of requests received with the given method.
+func (s *todoServer) count(method string) int {
+	n := 0
+	for _, req := range s.Requests() {
+		if strings.HasPrefix(req, method+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *todoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/todos"), "/")
+	index := -1
+	for i, todo := range s.todos {
+		if id != "" && todo.Id == id {
+			index = i
+		}
+	}
+	if id != "" && index == -1 && r.Method != "PUT" {
+		http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == "GET" && id == "":
+		list := []testTodo{}
+		for _, todo := range s.todos {
+			query := r.URL.Query()
+			if isCompleted := query.Get("IsCompleted"); isCompleted != "" && isCompleted != strconv.FormatBool(todo.IsCompleted) {
+				continue
+			}
+			if title := query.Get("Title"); title != "" && title != todo.Title {
+				continue
+			}
+			list = append(list, todo)
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.Method == "GET":
+		json.NewEncoder(w).Encode(s.todos[index])
+	case r.Method == "POST":
+		fields := decodeTodoFields(r)
+		todo := s.add(fields.Get("Title"), fields.Get("IsCompleted") == "true")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(todo)
+	case r.Method == "PATCH" || r.Method == "PUT":
+		if index == -1 {
+			s.todos = append(s.todos, testTodo{DefaultId: DefaultId{Id: id}})
+			index = len(s.todos) - 1
+		}
+		fields := decodeTodoFields(r)
+		if _, found := fields["Title"]; found {
+			s.todos[index].Title = fields.Get("Title")
+		}
+		if _, found := fields["IsCompleted"]; found {
+			s.todos[index].IsCompleted = fields.Get("IsCompleted") == "true"
+		}
+		json.NewEncoder(w).Encode(s.todos[index])
+	case r.Method == "DELETE":
+		s.todos = append(s.todos[:index], s.todos[index+1:]...)
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeTodoFields returns the fields in the JSON or url-encoded body of r.
+func decodeTodoFields(r *http.Request) url.Values {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), string(ContentJSON)) {
+		fields := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&fields)
+		values := url.Values{}
+		for key, value := range fields {
+			values.Set(key, fmt.Sprint(value))
+		}
+		return values
+	}
+	r.ParseForm()
+	return r.PostForm
+}
+
+// requestLog records the requests received by a test server.
+type requestLog struct {
+	requests []*http.Request
+	mut      sync.Mutex
+}
+
+// add records r.
+func (l *requestLog) add(r *http.Request) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.requests = append(l.requests, r)
+}
+
+// all returns the requests recorded so far.
+func (l *requestLog) all() []*http.Request {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return append([]*http.Request{}, l.requests...)
+}
+
+// last returns the last request recorded, or nil if there is none.
+func (l *requestLog) last() *http.Request {
+	all := l.all()
+	if len(all) == 0 {
+		return nil
+	}
+	return all[len(all)-1]
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Hydrater is implemented by models which keep track of whether they have
+// been fully loaded (hydrated) from the server, as opposed to holding only the
+// summary fields returned by a sparse read. The easiest way to implement it is
+// to embed HydrationState.
+type Hydrater interface {
+	// Hydrated returns true if all the fields of the model have been loaded.
+	Hydrated() bool
+	// SetHydrated records whether all the fields of the model have been
+	// loaded.
+	SetHydrated(hydrated bool)
+}
+
+// HydrationState can be embedded in a model to implement Hydrater. It is not
+// included when the model is encoded.
+type HydrationState struct {
+	hydrated bool
+}
+
+// Hydrated satisfies the Hydrated method of Hydrater.
+func (state *HydrationState) Hydrated() bool {
+	return state.hydrated
+}
+
+// SetHydrated satisfies the SetHydrated method of Hydrater.
+func (state *HydrationState) SetHydrated(hydrated bool) {
+	state.hydrated = hydrated
+}
+
+var hydrationStateType = reflect.TypeOf(HydrationState{})
+
+// WithFields returns a RequestOption which asks the server to include only the
+// given fields in the response, by adding them to the query string as a
+// comma-separated list under the name c.FieldsParam (e.g.
+// "?fields=Id,Title"). It can be used with ReadAll to fetch summaries of
+// large records, which can then be filled in on demand with Hydrate. Models
+// read with WithFields are not marked as hydrated.
+func WithFields(fields ...string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.fields = append(opts.fields, fields...)
+	}
+}
+
+// fieldsQuery returns the query which requests the fields given by WithFields,
+// or nil if there are none.
+func (c *Client) fieldsQuery(reqOpts *requestOptions) Query {
+	if len(reqOpts.fields) == 0 {
+		return nil
+	}
+	param := c.FieldsParam
+	if param == "" {
+		param = "fields"
+	}
+	return Query{param: {strings.Join(reqOpts.fields, ",")}}
+}
+
+// Hydrate fills in all the fields of a model which may have been read with
+// WithFields, by reading it from the server with Read. If model implements
+// Hydrater and is already hydrated, Hydrate does nothing, so it is cheap to
+// call before accessing fields which are not part of the summary. Otherwise
+// model is marked as hydrated once it has been read.
+func (c *Client) Hydrate(model Model, opts ...RequestOption) error {
+	if err := checkModelPointer("Hydrate", model); err != nil {
+		return err
+	}
+	if hydrater, ok := model.(Hydrater); ok && hydrater.Hydrated() {
+		return nil
+	}
+	if _, ok := model.(CompositeModel); ok {
+		return c.Read("", model, opts...)
+	}
+	return c.Read(model.ModelId(), model, opts...)
+}
+
+// markHydrated marks model as hydrated if it implements Hydrater and was read
+// without WithFields.
+func markHydrated(model Model, reqOpts *requestOptions) {
+	if hydrater, ok := model.(Hydrater); ok && len(reqOpts.fields) == 0 {
+		hydrater.SetHydrated(true)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// hydratedTodo is a testTodo which keeps track of its hydration state.
+type hydratedTodo struct {
+	testTodo
+	HydrationState
+}
+
+func TestHydrate(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	todos := []*hydratedTodo{}
+	if err := client.ReadAll(&todos, WithFields("Id", "Title")); err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.Requests(); requests[0] != "GET /todos?fields=Id%2CTitle" {
+		t.Errorf("Expected the fields to be requested but got %v", requests)
+	}
+	todo := todos[0]
+	if todo.Hydrated() {
+		t.Fatal("Expected a todo from a sparse read not to be hydrated")
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Hydrate(todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !todo.Hydrated() || todo.Title != "a" {
+		t.Errorf("Expected the todo to be hydrated but got %+v", todo)
+	}
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected Hydrate to read the todo once but got %v", server.Requests())
+	}
+
+	sparse := &hydratedTodo{}
+	if err := client.Read("2", sparse, WithFields("Title")); err != nil {
+		t.Fatal(err)
+	}
+	if sparse.Hydrated() {
+		t.Error("Expected a todo read with WithFields not to be hydrated")
+	}
+	if err := client.Read("2", sparse); err != nil {
+		t.Fatal(err)
+	}
+	if !sparse.Hydrated() {
+		t.Error("Expected a todo read without WithFields to be hydrated")
+	}
+	if err := client.Hydrate(stringModel("1")); err == nil {
+		t.Error("Expected an error for a model which is not a pointer")
+	}
+}
+
+func TestFieldsParam(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	client := NewClient()
+	client.FieldsParam = "only"
+	if err := client.Read("1", &testTodo{}, WithFields("Id"), WithFields("IsCompleted")); err != nil {
+		t.Fatal(err)
+	}
+	if query := log.last().URL.Query().Get("only"); query != "Id,IsCompleted" {
+		t.Errorf("Expected the fields under the custom param but got %s", log.last().URL)
+	}
+}
+
+func TestHydrationStateNotEncoded(t *testing.T) {
+	todo := &hydratedTodo{}
+	todo.SetHydrated(true)
+	for _, contentType := range []ContentType{ContentURLEncoded, ContentJSON} {
+		client := NewClient()
+		client.ContentType = contentType
+		data, err := client.encodeFields(todo, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ToLower(data), "hydrat") {
+			t.Errorf("Expected the hydration state not to be encoded with %s but got %s", contentType, data)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+// As returns a child client (see Scope) which acts on behalf of the user with
+// the given id by setting the ImpersonationHeader on every request it sends.
+// The child keeps the credentials of c, so the server can authorize the
+// request as the real caller while recording both the caller and the user in
+// its audit log. This is meant for admin tools which act on behalf of other
+// users:
+//
+//	err := client.As(userID).Update(&settings)
+//
+// Calling As on a child client replaces the user it acts on behalf of, and an
+// empty userID returns a child which acts as the real caller again.
+func (c *Client) As(userID string) *Client {
+	return c.Scope(func(child *Client) {
+		header := child.impersonationHeader()
+		if userID == "" {
+			child.Header.Del(header)
+			return
+		}
+		ScopeHeader(header, userID)(child)
+	})
+}
+
+// ActingAs returns the id of the user c acts on behalf of, as set by As, or
+// an empty string if c acts as the real caller.
+func (c *Client) ActingAs() string {
+	return c.Header.Get(c.impersonationHeader())
+}
+
+// impersonationHeader returns the name of the header used by As.
+func (c *Client) impersonationHeader() string {
+	if c.ImpersonationHeader == "" {
+		return "X-On-Behalf-Of"
+	}
+	return c.ImpersonationHeader
+}
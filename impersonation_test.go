@@ -0,0 +1,99 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// newImpersonationServer starts a server for testTodo which records the
+// requests it receives.
+func newImpersonationServer(t *testing.T) *requestLog {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	return log
+}
+
+func TestAs(t *testing.T) {
+	log := newImpersonationServer(t)
+	client := NewClient()
+	client.Header = http.Header{"Authorization": {"Bearer admin"}}
+	alice := client.As("alice")
+
+	if err := alice.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	req := log.last()
+	if got := req.Header.Get("X-On-Behalf-Of"); got != "alice" {
+		t.Errorf("Expected X-On-Behalf-Of to be alice but got %q", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer admin" {
+		t.Errorf("Expected the child to keep the credentials of its parent but Authorization was %q", got)
+	}
+	if got := alice.ActingAs(); got != "alice" {
+		t.Errorf("Expected ActingAs to return alice but got %q", got)
+	}
+
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := log.last().Header.Get("X-On-Behalf-Of"); got != "" {
+		t.Errorf("Expected the parent not to act on behalf of anyone but X-On-Behalf-Of was %q", got)
+	}
+	if got := client.ActingAs(); got != "" {
+		t.Errorf("Expected ActingAs to return an empty string for the parent but got %q", got)
+	}
+}
+
+func TestAsOnChild(t *testing.T) {
+	log := newImpersonationServer(t)
+	alice := NewClient().As("alice")
+
+	if err := alice.As("bob").Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := log.last().Header["X-On-Behalf-Of"]; len(got) != 1 || got[0] != "bob" {
+		t.Errorf("Expected As to replace the user the child acts on behalf of but X-On-Behalf-Of was %v", got)
+	}
+
+	caller := alice.As("")
+	if err := caller.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := log.last().Header.Get("X-On-Behalf-Of"); got != "" {
+		t.Errorf("Expected As(\"\") to act as the real caller but X-On-Behalf-Of was %q", got)
+	}
+	if got := caller.ActingAs(); got != "" {
+		t.Errorf("Expected ActingAs to return an empty string but got %q", got)
+	}
+	if got := alice.ActingAs(); got != "alice" {
+		t.Errorf("Expected the original child to still act as alice but got %q", got)
+	}
+}
+
+func TestImpersonationHeader(t *testing.T) {
+	log := newImpersonationServer(t)
+	client := NewClient()
+	client.ImpersonationHeader = "X-Act-As"
+	alice := client.As("alice")
+
+	if err := alice.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	req := log.last()
+	if got := req.Header.Get("X-Act-As"); got != "alice" {
+		t.Errorf("Expected X-Act-As to be alice but got %q", got)
+	}
+	if got := req.Header.Get("X-On-Behalf-Of"); got != "" {
+		t.Errorf("Expected the default header not to be set but X-On-Behalf-Of was %q", got)
+	}
+	if got := alice.ActingAs(); got != "alice" {
+		t.Errorf("Expected ActingAs to return alice but got %q", got)
+	}
+}
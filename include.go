@@ -0,0 +1,214 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// IncludeMode determines how WithInclude loads related models.
+type IncludeMode string
+
+const (
+	// IncludeQuery is the default IncludeMode. The names of the relations
+	// are sent to the server as a comma-separated list in the query string
+	// (e.g. "?include=author,comments", as used by JSON:API and many HAL
+	// APIs), and the server is expected to embed the related models in the
+	// response, where they are decoded like any other field.
+	IncludeQuery IncludeMode = ""
+	// IncludeFanOut causes the client to load related models itself, with
+	// additional requests sent after the main response has been decoded.
+	// Each relation field must have a "ref" or "backref" option in its rest
+	// struct tag (see WithInclude).
+	IncludeFanOut IncludeMode = "fanout"
+)
+
+// WithInclude returns a RequestOption which causes Read and ReadAll to load
+// the given relations of the models, identified by the names of their fields
+// (e.g. WithInclude("Author", "Comments")). How they are loaded depends on the
+// client's IncludeMode. With IncludeFanOut, each relation field needs a rest
+// struct tag which says how to find the related models:
+//
+//	type Post struct {
+//		rest.DefaultId
+//		AuthorId string
+//		// Read from the root url of User with the id in AuthorId
+//		Author *User `rest:"-,ref=AuthorId" json:"-"`
+//		// Read from the root url of Comment with the query ?postId=<Id>
+//		Comments []*Comment `rest:"-,backref=postId" json:"-"`
+//	}
+//
+// To-one relations are loaded with ReadMany, so each related model is only
+// requested once, and to-many relations with one ReadAll per model.
+func WithInclude(relations ...string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.include = append(opts.include, relations...)
+	}
+}
+
+// includeQuery returns the query which asks the server to embed the relations
+// given by WithInclude, or nil if there are none or the client loads them
+// itself.
+func (c *Client) includeQuery(reqOpts *requestOptions) Query {
+	if len(reqOpts.include) == 0 || c.IncludeMode != IncludeQuery {
+		return nil
+	}
+	param := c.IncludeParam
+	if param == "" {
+		param = "include"
+	}
+	names := []string{}
+	for _, relation := range reqOpts.include {
+		if relation != "" {
+			names = append(names, strings.ToLower(relation[:1])+relation[1:])
+		}
+	}
+	return Query{param: {strings.Join(names, ",")}}
+}
+
+// loadIncludes loads the relations given by WithInclude into v, which is
+// either a model or a pointer to a slice of models, if the client's
+// IncludeMode is IncludeFanOut.
+func (c *Client) loadIncludes(v interface{}, reqOpts *requestOptions) error {
+	if len(reqOpts.include) == 0 || c.IncludeMode != IncludeFanOut {
+		return nil
+	}
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr && !val.IsNil() && val.Elem().Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	parents := []reflect.Value{}
+	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
+		for i := 0; i < val.Elem().Len(); i++ {
+			if parent, ok := structOf(val.Elem().Index(i)); ok {
+				parents = append(parents, parent)
+			}
+		}
+	} else if parent, ok := structOf(val); ok {
+		parents = append(parents, parent)
+	}
+	if len(parents) == 0 {
+		return nil
+	}
+	// Only the context carries over to the requests for the related models,
+	// since the other options are specific to the main request
+	ctx := reqOpts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for _, relation := range reqOpts.include {
+		field, found := parents[0].Type().FieldByName(relation)
+		if !found {
+			return fmt.Errorf("rest: cannot include %s: %s has no such field", relation, parents[0].Type())
+		}
+		_, opts := parseTag(field)
+		if ref, ok := opts.Get("ref"); ok {
+			if err := c.includeRefs(ctx, parents, field, ref); err != nil {
+				return err
+			}
+		} else if backref, ok := opts.Get("backref"); ok {
+			if err := c.includeBackrefs(ctx, parents, field, backref); err != nil {
+				return err
+			}
+		} else {
+			return fmt.Errorf("rest: cannot include %s: the field needs a ref or backref option in its rest tag", relation)
+		}
+	}
+	return nil
+}
+
+// structOf returns the struct val points to, if any.
+func structOf(val reflect.Value) (reflect.Value, bool) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}, false
+		}
+		val = val.Elem()
+	}
+	return val, val.Kind() == reflect.Struct && val.CanSet()
+}
+
+// includeRefs loads the to-one relation field of each parent. The id of the
+// related model is held by the field of the parent named ref.
+func (c *Client) includeRefs(ctx context.Context, parents []reflect.Value, field reflect.StructField, ref string) error {
+	if !field.Type.Implements(modelType) {
+		return fmt.Errorf("rest: cannot include %s: %s does not implement Model", field.Name, field.Type)
+	}
+	ids := []string{}
+	seen := map[string]bool{}
+	parentIds := make([]string, len(parents))
+	for i, parent := range parents {
+		refVal := parent.FieldByName(ref)
+		if !refVal.IsValid() {
+			return fmt.Errorf("rest: cannot include %s: %s has no field %s", field.Name, parent.Type(), ref)
+		}
+		id, err := encodeString(refVal)
+		if err == nilFieldError {
+			continue
+		} else if err != nil {
+			return err
+		}
+		parentIds[i] = id
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	related := reflect.New(reflect.SliceOf(field.Type))
+	err := c.ReadMany(ids, related.Interface(), WithContext(ctx))
+	if _, missing := err.(MissingIdsError); err != nil && !missing {
+		return err
+	}
+	byId := map[string]reflect.Value{}
+	for i := 0; i < related.Elem().Len(); i++ {
+		byId[ids[i]] = related.Elem().Index(i)
+	}
+	for i, parent := range parents {
+		if model, found := byId[parentIds[i]]; found {
+			parent.FieldByIndex(field.Index).Set(model)
+		}
+	}
+	return nil
+}
+
+// includeBackrefs loads the to-many relation field of each parent by reading
+// the collection of related models, filtered by the query parameter backref
+// set to the id of the parent.
+func (c *Client) includeBackrefs(ctx context.Context, parents []reflect.Value, field reflect.StructField, backref string) error {
+	if field.Type.Kind() != reflect.Slice || !field.Type.Elem().Implements(modelType) {
+		return fmt.Errorf("rest: cannot include %s: %s is not a slice of models", field.Name, field.Type)
+	}
+	group := c.NewGroup(ctx)
+	var mut sync.Mutex
+	for _, parent := range parents {
+		parent := parent
+		model, ok := parent.Addr().Interface().(Model)
+		if !ok {
+			return fmt.Errorf("rest: cannot include %s: %s does not implement Model", field.Name, parent.Type())
+		}
+		id := model.ModelId()
+		if id == "" {
+			continue
+		}
+		group.Go(func(ctx context.Context) error {
+			related := reflect.New(field.Type)
+			if err := c.ReadAll(related.Interface(), WithContext(ctx), WithQuery(Query{backref: {id}})); err != nil {
+				return err
+			}
+			mut.Lock()
+			parent.FieldByIndex(field.Index).Set(related.Elem())
+			mut.Unlock()
+			return nil
+		})
+	}
+	return group.Wait()
+}
@@ -0,0 +1,143 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type post struct {
+	DefaultId
+	AuthorId string
+	Author   *author    `rest:"-,ref=AuthorId" json:"-"`
+	Comments []*comment `rest:"-,backref=postId" json:"-"`
+	Tags     []string   `rest:"-" json:"-"`
+}
+
+func (*post) RootURL() string { return testRootURL + "/posts" }
+
+type author struct {
+	DefaultId
+	Name string
+}
+
+func (*author) RootURL() string { return testRootURL + "/users" }
+
+type comment struct {
+	DefaultId
+	Text string
+}
+
+func (*comment) RootURL() string { return testRootURL + "/comments" }
+
+// newBlogServer serves three posts, the first two by the same author, and a
+// comment on each of the first two posts.
+func newBlogServer(t *testing.T) *requestLog {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/posts":
+			w.Write([]byte(`[{"Id": "1", "AuthorId": "u1"}, {"Id": "2", "AuthorId": "u1"}, {"Id": "3", "AuthorId": "u2"}]`))
+		case "/posts/1":
+			w.Write([]byte(`{"Id": "1", "AuthorId": "u2"}`))
+		case "/users":
+			authors := []author{}
+			for _, id := range strings.Split(query.Get("ids"), ",") {
+				authors = append(authors, author{DefaultId{id}, "name " + id})
+			}
+			json.NewEncoder(w).Encode(authors)
+		case "/comments":
+			comments := []comment{}
+			if postId := query.Get("postId"); postId != "3" {
+				comments = append(comments, comment{DefaultId{"c" + postId}, "on " + postId})
+			}
+			json.NewEncoder(w).Encode(comments)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return requests
+}
+
+func TestIncludeQuery(t *testing.T) {
+	requests := newBlogServer(t)
+	client := NewClient()
+	if err := client.ReadAll(&[]*post{}, WithInclude("Author", "Comments")); err != nil {
+		t.Fatal(err)
+	}
+	if query := requests.last().URL.Query().Get("include"); query != "author,comments" {
+		t.Errorf("Expected include=author,comments but got %q", query)
+	}
+	client.IncludeParam = "embed"
+	if err := client.Read("1", &post{}, WithInclude("Author")); err != nil {
+		t.Fatal(err)
+	}
+	if query := requests.last().URL.Query().Get("embed"); query != "author" {
+		t.Errorf("Expected embed=author but got %q", query)
+	}
+	if n := len(requests.all()); n != 2 {
+		t.Errorf("Expected no additional requests but got %d requests", n)
+	}
+}
+
+func TestIncludeFanOut(t *testing.T) {
+	requests := newBlogServer(t)
+	client := NewClient()
+	client.IncludeMode = IncludeFanOut
+	posts := []*post{}
+	if err := client.ReadAll(&posts, WithInclude("Author", "Comments")); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 3 {
+		t.Fatalf("Expected 3 posts but got %d", len(posts))
+	}
+	for _, p := range posts {
+		if p.Author == nil || p.Author.Id != p.AuthorId || p.Author.Name != "name "+p.AuthorId {
+			t.Errorf("Expected post %s to have author %s but got %+v", p.Id, p.AuthorId, p.Author)
+		}
+	}
+	if len(posts[0].Comments) != 1 || posts[0].Comments[0].Text != "on 1" || len(posts[2].Comments) != 0 {
+		t.Errorf("Expected the comments of each post but got %+v, %+v", posts[0].Comments, posts[2].Comments)
+	}
+	users := 0
+	for _, req := range requests.all() {
+		if req.URL.Path == "/users" {
+			users++
+			if ids := req.URL.Query().Get("ids"); ids != "u1,u2" {
+				t.Errorf("Expected each author to be requested once but got ids=%s", ids)
+			}
+		}
+		if req.URL.Query().Get("include") != "" {
+			t.Errorf("Expected no include parameter with IncludeFanOut but got %s", req.URL)
+		}
+	}
+	if users != 1 {
+		t.Errorf("Expected a single request for the authors but got %d", users)
+	}
+
+	single := &post{}
+	if err := client.Read("1", single, WithInclude("Author")); err != nil {
+		t.Fatal(err)
+	}
+	if single.Author == nil || single.Author.Id != "u2" || single.Comments != nil {
+		t.Errorf("Expected only the author of the post to be loaded but got %+v", single)
+	}
+}
+
+func TestIncludeFanOutErrors(t *testing.T) {
+	newBlogServer(t)
+	client := NewClient()
+	client.IncludeMode = IncludeFanOut
+	for _, relation := range []string{"Editor", "Tags"} {
+		if err := client.ReadAll(&[]*post{}, WithInclude(relation)); err == nil || !strings.Contains(err.Error(), relation) {
+			t.Errorf("Expected an error for including %s but got %v", relation, err)
+		}
+	}
+}
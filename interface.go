@@ -0,0 +1,39 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+// ModelReader is implemented by types which can read models from a REST API.
+// Application code which only needs to fetch models can depend on ModelReader
+// instead of *Client, which makes it easy to substitute a fake in tests.
+type ModelReader interface {
+	Read(id string, model Model, opts ...RequestOption) error
+	ReadAll(models interface{}, opts ...RequestOption) error
+}
+
+// ModelWriter is implemented by types which can create and change models
+// through a REST API.
+type ModelWriter interface {
+	Create(model Model, opts ...RequestOption) error
+	Update(model Model, opts ...RequestOption) error
+	Put(model Model, opts ...RequestOption) error
+}
+
+// ModelDeleter is implemented by types which can delete models through a REST
+// API.
+type ModelDeleter interface {
+	Delete(model Model, opts ...RequestOption) error
+}
+
+// Interface is implemented by types which can perform all the CRUD operations
+// of Client. It is the combination of ModelReader, ModelWriter, and
+// ModelDeleter.
+type Interface interface {
+	ModelReader
+	ModelWriter
+	ModelDeleter
+}
+
+// Client satisfies Interface and all of the role interfaces.
+var _ Interface = (*Client)(nil)
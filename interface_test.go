@@ -0,0 +1,60 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeReader is a ModelReader which does not send any requests.
+type fakeReader struct {
+	err error
+}
+
+func (r fakeReader) Read(id string, model Model, opts ...RequestOption) error {
+	if r.err != nil {
+		return r.err
+	}
+	return model.(*testTodo).SetModelId(id)
+}
+
+func (r fakeReader) ReadAll(models interface{}, opts ...RequestOption) error {
+	return r.err
+}
+
+// todoTitle is application code which only depends on ModelReader.
+func todoTitle(reader ModelReader, id string) (string, error) {
+	todo := &testTodo{}
+	if err := reader.Read(id, todo); err != nil {
+		return "", err
+	}
+	return todo.Id + ":" + todo.Title, nil
+}
+
+func TestModelReader(t *testing.T) {
+	newTodoServer(t, "a")
+	readers := map[string]ModelReader{
+		"Client": NewClient(),
+		"fake":   fakeReader{},
+	}
+	expected := map[string]string{
+		"Client": "1:a",
+		"fake":   "1:",
+	}
+	for name, reader := range readers {
+		title, err := todoTitle(reader, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title != expected[name] {
+			t.Errorf("Expected %q from %s but got %q", expected[name], name, title)
+		}
+	}
+	broken := errors.New("broken")
+	if _, err := todoTitle(fakeReader{err: broken}, "1"); err != broken {
+		t.Errorf("Expected the error of the fake but got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONBackend is an implementation of JSON encoding and decoding. By default
+// a Client uses the encoding/json package (StandardJSON), but decoding often
+// dominates the time taken by ReadAll for large collections, so a faster
+// implementation (e.g. json-iterator or segmentio/encoding) can be plugged in
+// by setting the JSON field of the client. Most of them only need a small
+// adapter, e.g. for json-iterator:
+//
+//	type jsoniterBackend struct {
+//		api jsoniter.API
+//	}
+//
+//	func (b jsoniterBackend) Marshal(v interface{}) ([]byte, error) {
+//		return b.api.Marshal(v)
+//	}
+//
+//	func (b jsoniterBackend) Unmarshal(data []byte, v interface{}) error {
+//		return b.api.Unmarshal(data, v)
+//	}
+//
+//	func (b jsoniterBackend) NewDecoder(r io.Reader) rest.JSONDecoder {
+//		return b.api.NewDecoder(r)
+//	}
+//
+//	client.JSON = jsoniterBackend{api: jsoniter.ConfigCompatibleWithStandardLibrary}
+//
+// The backend is used to encode JSON request bodies (unless another Encoder
+// has been registered for ContentJSON) and to decode responses. Matching keys
+// to fields with MatchTolerant or MatchStrict always uses encoding/json.
+type JSONBackend interface {
+	// Marshal returns the JSON encoding of v.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v interface{}) error
+	// NewDecoder returns a JSONDecoder which reads from r.
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+// JSONDecoder reads and decodes JSON values from a stream. It is satisfied by
+// *json.Decoder.
+type JSONDecoder interface {
+	// UseNumber causes numbers to be decoded into an interface{} as a
+	// json.Number instead of a float64.
+	UseNumber()
+	// Decode decodes the next JSON value into v.
+	Decode(v interface{}) error
+}
+
+// StandardJSON is the JSONBackend which uses the encoding/json package.
+var StandardJSON JSONBackend = standardJSON{}
+
+// standardJSON is the type of StandardJSON.
+type standardJSON struct{}
+
+// Marshal satisfies JSONBackend by calling json.Marshal.
+func (standardJSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal satisfies JSONBackend by calling json.Unmarshal.
+func (standardJSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// NewDecoder satisfies JSONBackend by calling json.NewDecoder.
+func (standardJSON) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
+
+// jsonBackend returns the JSONBackend used by the client.
+func (c *Client) jsonBackend() JSONBackend {
+	if c.JSON == nil {
+		return StandardJSON
+	}
+	return c.JSON
+}
@@ -0,0 +1,88 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// countingJSON is a JSONBackend which records which of its methods are called
+// and delegates to StandardJSON.
+type countingJSON struct {
+	calls []string
+	mut   sync.Mutex
+}
+
+func (b *countingJSON) record(call string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.calls = append(b.calls, call)
+}
+
+func (b *countingJSON) Marshal(v interface{}) ([]byte, error) {
+	b.record("Marshal")
+	return StandardJSON.Marshal(v)
+}
+
+func (b *countingJSON) Unmarshal(data []byte, v interface{}) error {
+	b.record("Unmarshal")
+	return StandardJSON.Unmarshal(data, v)
+}
+
+func (b *countingJSON) NewDecoder(r io.Reader) JSONDecoder {
+	b.record("NewDecoder")
+	return StandardJSON.NewDecoder(r)
+}
+
+func TestJSONBackend(t *testing.T) {
+	newTodoServer(t, "a")
+	backend := &countingJSON{}
+	client := NewClient()
+	client.ContentType = ContentJSON
+	client.JSON = backend
+	if err := client.Create(&testTodo{Title: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[1].Title != "b" {
+		t.Errorf("Expected the todos to be encoded and decoded by the backend but got %v", todos)
+	}
+	client.UseNumber = true
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Marshal", "Unmarshal", "Unmarshal", "NewDecoder"}
+	if !reflect.DeepEqual(backend.calls, expected) {
+		t.Errorf("Expected the calls %v but got %v", expected, backend.calls)
+	}
+}
+
+func TestJSONBackendRegisteredEncoder(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	registerEncoder(t, ContentJSON, EncoderFunc(func(model Model) ([]byte, error) {
+		return json.Marshal(map[string]string{"custom": "yes"})
+	}))
+	backend := &countingJSON{}
+	client := NewClient()
+	client.ContentType = ContentJSON
+	client.JSON = backend
+	if err := client.Update(&testTodo{DefaultId: DefaultId{Id: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != `{"custom":"yes"}` {
+		t.Errorf("Expected the registered encoder to take precedence over the backend but got %s", body)
+	}
+	if !reflect.DeepEqual(backend.calls, []string{"Unmarshal"}) {
+		t.Errorf("Expected the backend to be used only for decoding but got %v", backend.calls)
+	}
+}
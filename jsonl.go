@@ -0,0 +1,135 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Export writes every record of a collection to w as JSON Lines, i.e. one
+// compact JSON object per line, which is a convenient format for backups and
+// for seeding other environments with Import. models is only used as a
+// prototype to determine the collection: it must be a pointer to a slice of
+// models (e.g. *[]*Todo) and is not changed. Records are streamed page by page,
+// following the Link header of each response like ReadAllComplete, so the
+// collection does not need to fit in memory. The WithQuery option applies to
+// the first page only. Export returns the number of records written.
+func (c *Client) Export(models interface{}, w io.Writer, opts ...RequestOption) (int, error) {
+	if err := checkModelsType(models, false); err != nil {
+		return 0, err
+	}
+	reqOpts := newRequestOptions(opts).forModel(models)
+	rootURL, err := c.routeModels(models)
+	if err != nil {
+		return 0, err
+	}
+	query, err := toQuery(reqOpts.query)
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	buf := &bytes.Buffer{}
+	for url := appendQuery(rootURL, query); url != ""; {
+		res, body, err := c.getWithContext(reqOpts.context(), url)
+		if err != nil {
+			return written, err
+		}
+		records := []json.RawMessage{}
+		if err := json.Unmarshal(body, &records); err != nil {
+			return written, fmt.Errorf("rest: expected an array of records from %s: %s", url, err.Error())
+		}
+		for _, record := range records {
+			buf.Reset()
+			if err := json.Compact(buf, record); err != nil {
+				return written, err
+			}
+			buf.WriteByte('\n')
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return written, err
+			}
+			written++
+		}
+		url = ""
+		if next, found := parseLinks(res.Header["Link"])["next"]; found {
+			if nextURL, err := res.Request.URL.Parse(next); err == nil {
+				url = nextURL.String()
+			}
+		}
+	}
+	return written, nil
+}
+
+// Import reads records written by Export from r and creates each of them on
+// the server with Create. models is only used as a prototype to determine the
+// type of the records: it must be a pointer to a slice of models (e.g.
+// *[]*Todo) and is not changed. Records are created in batches of batchSize
+// concurrent requests, and each batch must succeed before the next one is
+// started. Blank lines are skipped. Import returns the number of records
+// created; if a batch fails, some of its records may have been created even
+// though they are not counted.
+func (c *Client) Import(models interface{}, r io.Reader, batchSize int, opts ...RequestOption) (int, error) {
+	if err := checkModelsType(models, false); err != nil {
+		return 0, err
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	elemType := reflect.TypeOf(models).Elem().Elem()
+	ctx := newRequestOptions(opts).context()
+	scanner := bufio.NewScanner(r)
+	// Records may be much longer than the default limit of a line
+	scanner.Buffer(nil, 64*1024*1024)
+	created := 0
+	batch := []Model{}
+	flush := func() error {
+		group := c.NewGroup(ctx)
+		// The options are shared by the goroutines, so they must be built
+		// before any of them starts.
+		createOpts := append(append([]RequestOption{}, opts...), WithContext(group.ctx))
+		for _, model := range batch {
+			model := model
+			group.Go(func(context.Context) error {
+				return c.Create(model, createOpts...)
+			})
+		}
+		if err := group.Wait(); err != nil {
+			return err
+		}
+		created += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for line := 1; scanner.Scan(); line++ {
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+		model := newModelOfType(elemType).Interface().(Model)
+		if err := c.unmarshal(record, model); err != nil {
+			return created, fmt.Errorf("rest: error decoding line %d: %s", line, err.Error())
+		}
+		batch = append(batch, model)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return created, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return created, err
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
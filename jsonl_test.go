@@ -0,0 +1,99 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	newTodoServer(t, "a", "b", "c")
+	client := NewClient()
+	var buf bytes.Buffer
+	n, err := client.Export(&[]*testTodo{}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || strings.Count(buf.String(), "\n") != 3 {
+		t.Fatalf("Expected 3 lines but got %d: %q", n, buf.String())
+	}
+
+	target := newTodoServer(t)
+	// Leave spare capacity, so that appending to opts in the goroutines
+	// would race
+	opts := make([]RequestOption, 0, 8)
+	opts = append(opts, WithContext(context.Background()))
+	created, err := client.Import(&[]*testTodo{}, strings.NewReader(buf.String()+"\n"), 2, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 3 || target.count("POST") != 3 {
+		t.Errorf("Expected 3 todos to be created but got %d: %v", created, target.Requests())
+	}
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, todo := range todos {
+		titles = append(titles, todo.Title)
+	}
+	if len(titles) != 3 {
+		t.Errorf("Expected the imported todos but got %v", titles)
+	}
+}
+
+func TestImportInvalidRecord(t *testing.T) {
+	newTodoServer(t)
+	created, err := NewClient().Import(&[]*testTodo{}, strings.NewReader(`{"Title": "a"}`+"\nnot json\n"), 10)
+	if err == nil || !strings.Contains(err.Error(), "line 2") || created != 0 {
+		t.Errorf("Expected an error for line 2 and nothing created but got %d and %v", created, err)
+	}
+}
+
+func TestExportUsesRouteResolver(t *testing.T) {
+	routed := newTodoServer(t, "routed")
+	// Point the RootURL of testTodo somewhere else, so that only the
+	// RouteResolver leads to the server
+	routedURL := testRootURL
+	testRootURL = "http://unreachable.invalid"
+	client := NewClient()
+	client.RouteResolver = func(model Model, action Action) (string, error) {
+		if action != ActionReadAll {
+			t.Errorf("Expected ActionReadAll but got %s", action)
+		}
+		return routedURL, nil
+	}
+	var buf bytes.Buffer
+	if _, err := client.Export(&[]*testTodo{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "routed") || routed.count("GET") != 1 {
+		t.Errorf("Expected the export to be routed but got %q", buf.String())
+	}
+}
+
+func TestExportFollowsLinks(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"Id": "3"}]`))
+			return
+		}
+		w.Header().Set("Link", `</todos?page=2>; rel="next"`)
+		w.Write([]byte(`[{"Id": "1"}, {"Id": "2"}]`))
+	})
+	var buf bytes.Buffer
+	n, err := NewClient().Export(&[]*testTodo{}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 records from 2 pages but got %d: %q", n, buf.String())
+	}
+}
@@ -28,6 +28,26 @@ func (t Todo) RootURL() string {
 	return "http://localhost:3000/todos"
 }
 
+// formTodo is a Todo which is always sent as url-encoded form data,
+// regardless of the ContentType of the client.
+type formTodo struct {
+	Id          int
+	Title       string
+	IsCompleted bool
+}
+
+func (t formTodo) ModelId() string {
+	return strconv.Itoa(t.Id)
+}
+
+func (t formTodo) RootURL() string {
+	return "http://localhost:3000/todos"
+}
+
+func (formTodo) ContentType() rest.ContentType {
+	return rest.ContentURLEncoded
+}
+
 const (
 	statusUnprocessableEntity = 422
 )
@@ -43,7 +63,7 @@ func main() {
 		client.ContentType = contentType
 		wg := sync.WaitGroup{}
 		// Need to update this if we add more tests.
-		wg.Add(6)
+		wg.Add(8)
 
 		qunit.Test("ReadAll "+string(contentType), func(assert qunit.QUnitAssert) {
 			qunit.Expect(2)
@@ -111,6 +131,23 @@ func main() {
 			}()
 		})
 
+		qunit.Test("Create with ContentTyper "+string(contentType), func(assert qunit.QUnitAssert) {
+			qunit.Expect(3)
+			done := assert.Async()
+			go func() {
+				newTodo := &formTodo{
+					Title:       "Test",
+					IsCompleted: true,
+				}
+				err := client.Create(newTodo)
+				assert.Ok(err == nil, fmt.Sprintf("client.Create returned an error: %v", err))
+				assert.Equal(newTodo.Id, 3, "newTodo.Id was not set correctly.")
+				assert.Equal(newTodo.Title, "Test", "newTodo.Title was incorrect.")
+				done()
+				wg.Done()
+			}()
+		})
+
 		qunit.Test("Update "+string(contentType), func(assert qunit.QUnitAssert) {
 			qunit.Expect(4)
 			done := assert.Async()
@@ -130,6 +167,25 @@ func main() {
 			}()
 		})
 
+		qunit.Test("Put "+string(contentType), func(assert qunit.QUnitAssert) {
+			qunit.Expect(4)
+			done := assert.Async()
+			go func() {
+				putTodo := &Todo{
+					Id:          42,
+					Title:       "Replaced Title",
+					IsCompleted: true,
+				}
+				err := client.Put(putTodo)
+				assert.Ok(err == nil, fmt.Sprintf("client.Put returned an error: %v", err))
+				assert.Equal(putTodo.Id, 42, "putTodo.Id was incorrect.")
+				assert.Equal(putTodo.Title, "Replaced Title", "putTodo.Title was incorrect.")
+				assert.Equal(putTodo.IsCompleted, true, "putTodo.IsCompleted was incorrect.")
+				done()
+				wg.Done()
+			}()
+		})
+
 		qunit.Test("Delete "+string(contentType), func(assert qunit.QUnitAssert) {
 			qunit.Expect(1)
 			done := assert.Async()
@@ -0,0 +1,170 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Stats holds statistics about the requests sent by a client.
+type Stats struct {
+	// ActiveRequests is the number of requests which are currently in flight.
+	ActiveRequests int
+	// QueuedRequests is the number of requests which are waiting for a slot
+	// because of MaxConcurrentRequests or MaxConcurrentRequestsPerHost.
+	QueuedRequests int
+	// QueuedRequestsByHost is the number of queued requests for each host.
+	// Hosts with no queued requests are omitted.
+	QueuedRequestsByHost map[string]int
+	// Endpoints holds the moving averages of the latency and error rate of
+	// the requests for each RootURL, for the requests sent by the methods
+	// which take a model.
+	Endpoints map[string]EndpointStats
+}
+
+// Stats returns statistics about the requests sent by the client.
+func (c *Client) Stats() Stats {
+	l := c.getLimiter()
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	stats := Stats{
+		ActiveRequests:       l.active,
+		QueuedRequests:       l.queued,
+		QueuedRequestsByHost: map[string]int{},
+	}
+	for host, queued := range l.queuedByHost {
+		if queued > 0 {
+			stats.QueuedRequestsByHost[host] = queued
+		}
+	}
+	stats.Endpoints = c.endpointStats()
+	return stats
+}
+
+// limiter limits the number of concurrent requests, both overall and for each
+// host, and keeps track of the number of active and queued requests.
+type limiter struct {
+	// global is a semaphore for all requests. It is nil if there is no limit.
+	global chan struct{}
+	// hostMax is the maximum number of concurrent requests for each host, or
+	// 0 if there is no limit.
+	hostMax int
+	// hosts holds a semaphore for each host.
+	hosts        map[string]chan struct{}
+	active       int
+	queued       int
+	queuedByHost map[string]int
+	mut          sync.Mutex
+}
+
+// getLimiter returns the limiter for the client, creating it if needed. The
+// limits are fixed the first time this happens.
+func (c *Client) getLimiter() *limiter {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.limiter == nil {
+		c.limiter = &limiter{
+			hostMax:      c.MaxConcurrentRequestsPerHost,
+			hosts:        map[string]chan struct{}{},
+			queuedByHost: map[string]int{},
+		}
+		if c.MaxConcurrentRequests > 0 {
+			c.limiter.global = make(chan struct{}, c.MaxConcurrentRequests)
+		}
+	}
+	return c.limiter
+}
+
+// acquire waits until a request to host is allowed to proceed or until ctx is
+// done, whichever happens first. If it returns a nil error, the caller must
+// call the returned function once the request is complete.
+func (l *limiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mut.Lock()
+	var hostSem chan struct{}
+	if l.hostMax > 0 {
+		hostSem = l.hosts[host]
+		if hostSem == nil {
+			hostSem = make(chan struct{}, l.hostMax)
+			l.hosts[host] = hostSem
+		}
+	}
+	l.mut.Unlock()
+	// Take the host slot first, so that requests waiting for a busy host do
+	// not hold global slots which requests to other hosts could use.
+	if err := l.wait(ctx, host, hostSem); err != nil {
+		return nil, err
+	}
+	if err := l.wait(ctx, host, l.global); err != nil {
+		if hostSem != nil {
+			<-hostSem
+		}
+		return nil, err
+	}
+	l.mut.Lock()
+	l.active++
+	l.mut.Unlock()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			if hostSem != nil {
+				<-hostSem
+			}
+			if l.global != nil {
+				<-l.global
+			}
+			l.mut.Lock()
+			l.active--
+			l.mut.Unlock()
+		})
+	}, nil
+}
+
+// wait takes a slot from sem, waiting in the queue if none is available. It
+// returns ctx.Err() if ctx is done before a slot becomes available. A nil sem
+// means there is no limit.
+func (l *limiter) wait(ctx context.Context, host string, sem chan struct{}) error {
+	if sem == nil {
+		return nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+	l.setQueued(host, 1)
+	defer l.setQueued(host, -1)
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setQueued adds delta to the number of queued requests for host.
+func (l *limiter) setQueued(host string, delta int) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.queued += delta
+	l.queuedByHost[host] += delta
+	if l.queuedByHost[host] == 0 {
+		delete(l.queuedByHost, host)
+	}
+}
+
+// releaseOnClose is an io.ReadCloser which calls release when it is closed,
+// so that a request keeps its slot until the response body has been read.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
@@ -0,0 +1,111 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimiterBusyHostDoesNotBlockOtherHosts(t *testing.T) {
+	l := &limiter{
+		global:       make(chan struct{}, 2),
+		hostMax:      1,
+		hosts:        map[string]chan struct{}{},
+		queuedByHost: map[string]int{},
+	}
+	release, err := l.acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	// Queue a second request for the busy host
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.acquire(ctx, "a")
+	for deadline := time.Now().Add(time.Second); queuedFor(l, "a") == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the request to be queued")
+		}
+	}
+
+	ctx, cancelOther := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelOther()
+	releaseOther, err := l.acquire(ctx, "b")
+	if err != nil {
+		t.Fatalf("Expected a request to another host to proceed, but got %v", err)
+	}
+	releaseOther()
+}
+
+func TestLimiterCanceledWhileQueued(t *testing.T) {
+	l := &limiter{
+		global:       make(chan struct{}, 1),
+		hosts:        map[string]chan struct{}{},
+		queuedByHost: map[string]int{},
+	}
+	release, err := l.acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "b"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded but got %v", err)
+	}
+	if l.queued != 0 || l.active != 1 {
+		t.Errorf("Expected 0 queued and 1 active requests but got %d and %d", l.queued, l.active)
+	}
+	release()
+	release()
+	if l.active != 0 || len(l.global) != 0 {
+		t.Errorf("Expected release to free the slot exactly once")
+	}
+}
+
+// queuedFor returns the number of requests queued for host.
+func queuedFor(l *limiter, host string) int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.queuedByHost[host]
+}
+
+func TestMaxConcurrentRequestsStats(t *testing.T) {
+	server := newBlockingServer(t, http.StatusOK)
+	client := NewClient()
+	client.MaxConcurrentRequests = 1
+	errs := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- client.Read("1", &testTodo{})
+		}()
+	}
+	server.waitForRequests(t, 1)
+	for deadline := time.Now().Add(time.Second); client.Stats().QueuedRequests == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the second request to be queued")
+		}
+	}
+	stats := client.Stats()
+	host := strings.TrimPrefix(testRootURL, "http://")
+	if stats.ActiveRequests != 1 || stats.QueuedRequests != 1 || stats.QueuedRequestsByHost[host] != 1 {
+		t.Errorf("Expected 1 active and 1 queued request for %s but got %+v", host, stats)
+	}
+	server.Release()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if stats := client.Stats(); stats.ActiveRequests != 0 || stats.QueuedRequests != 0 || len(stats.QueuedRequestsByHost) != 0 {
+		t.Errorf("Expected no active or queued requests but got %+v", stats)
+	}
+	if server.requests != 2 {
+		t.Errorf("Expected both requests to be sent but got %d", server.requests)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BalanceStrategy determines how a LoadBalancer distributes reads across its
+// endpoints.
+type BalanceStrategy int
+
+const (
+	// RoundRobin sends each read to the next endpoint in turn.
+	RoundRobin BalanceStrategy = iota
+	// ConsistentHash sends all reads of the same url to the same endpoint,
+	// which makes the best use of the caches of the replicas. When an
+	// endpoint is added or removed, only the urls of that endpoint move.
+	ConsistentHash
+)
+
+// virtualNodes is the number of points each endpoint has on the hash ring of
+// ConsistentHash. More points distribute the urls more evenly.
+const virtualNodes = 100
+
+// LoadBalancer is an http.RoundTripper which distributes reads across
+// replicated deployments of an API, while all other requests go to the
+// primary. It is meant for read-heavy workloads against replicas which are
+// kept in sync with the primary:
+//
+//	client := rest.FromRoundTripper(&rest.LoadBalancer{
+//		Endpoints: []string{
+//			"https://primary.example.com/api",
+//			"https://replica1.example.com/api",
+//			"https://replica2.example.com/api",
+//		},
+//		Strategy: rest.ConsistentHash,
+//	})
+//
+// Only requests to urls which start with the primary endpoint are balanced;
+// others are sent as is. Since the LoadBalancer sits below the client, all the
+// features of the client (retries, caching, etc.) work as usual. A
+// LoadBalancer must not be modified after it is first used.
+type LoadBalancer struct {
+	// Endpoints are the base urls of the deployments. The first one is the
+	// primary, which receives all requests that are not reads. Reads (GET and
+	// HEAD requests) are distributed across all the endpoints, including the
+	// primary.
+	Endpoints []string
+	// Strategy determines how reads are distributed. The default is
+	// RoundRobin.
+	Strategy BalanceStrategy
+	// Transport is used to actually send the requests. If it is nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// next is the index of the next endpoint for RoundRobin
+	next int
+	// ring holds the hashes of the virtual nodes for ConsistentHash, sorted,
+	// and owners the index of the endpoint each of them belongs to
+	ring   []uint32
+	owners map[uint32]int
+	once   sync.Once
+	mut    sync.Mutex
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (lb *LoadBalancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := lb.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if len(lb.Endpoints) < 2 || (req.Method != "GET" && req.Method != "HEAD") {
+		return transport.RoundTrip(req)
+	}
+	rawURL := req.URL.String()
+	primary := strings.TrimSuffix(lb.Endpoints[0], "/")
+	if !hasURLPrefix(rawURL, primary) {
+		return transport.RoundTrip(req)
+	}
+	suffix := rawURL[len(primary):]
+	endpoint := strings.TrimSuffix(lb.Endpoints[lb.pick(suffix)], "/")
+	if endpoint == primary {
+		return transport.RoundTrip(req)
+	}
+	target, err := url.Parse(endpoint + suffix)
+	if err != nil {
+		return nil, err
+	}
+	// A RoundTripper must not modify the request, so we work on a copy.
+	balanced := new(http.Request)
+	*balanced = *req
+	balanced.URL = target
+	balanced.Host = ""
+	return transport.RoundTrip(balanced)
+}
+
+// pick returns the index of the endpoint a read of path, the part of the url
+// which follows the endpoint, should be sent to.
+func (lb *LoadBalancer) pick(path string) int {
+	if lb.Strategy != ConsistentHash {
+		lb.mut.Lock()
+		defer lb.mut.Unlock()
+		index := lb.next
+		lb.next = (lb.next + 1) % len(lb.Endpoints)
+		return index
+	}
+	lb.once.Do(lb.buildRing)
+	hash := hashString(path)
+	i := sort.Search(len(lb.ring), func(i int) bool {
+		return lb.ring[i] >= hash
+	})
+	if i == len(lb.ring) {
+		// Wrap around the ring
+		i = 0
+	}
+	return lb.owners[lb.ring[i]]
+}
+
+// buildRing places virtualNodes points for each endpoint on the hash ring.
+func (lb *LoadBalancer) buildRing() {
+	lb.owners = map[uint32]int{}
+	for index, endpoint := range lb.Endpoints {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+		for i := 0; i < virtualNodes; i++ {
+			hash := hashString(endpoint + "#" + strconv.Itoa(i))
+			if _, taken := lb.owners[hash]; taken {
+				continue
+			}
+			lb.owners[hash] = index
+			lb.ring = append(lb.ring, hash)
+		}
+	}
+	sort.Slice(lb.ring, func(i, j int) bool {
+		return lb.ring[i] < lb.ring[j]
+	})
+}
+
+// hashString returns the 32-bit FNV-1a hash of s, mixed with the finalizer
+// of MurmurHash3. FNV-1a alone spreads strings which only differ in their
+// last few bytes, such as the urls of models with consecutive ids, over a
+// small part of the ring.
+func hashString(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	hash := h.Sum32()
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+	return hash
+}
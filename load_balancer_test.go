@@ -0,0 +1,139 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newReplicas starts a primary server for testTodo and two replicas, and
+// returns their logs and the endpoints of all three, primary first.
+func newReplicas(t *testing.T) ([]*requestLog, []string) {
+	logs := []*requestLog{{}, {}, {}}
+	handler := func(log *requestLog) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			log.add(r)
+			w.Write([]byte(`{"Id": "1"}`))
+		}
+	}
+	newTestServer(t, handler(logs[0]))
+	endpoints := []string{testRootURL + "/"}
+	for _, log := range logs[1:] {
+		srv := httptest.NewServer(handler(log))
+		t.Cleanup(srv.Close)
+		endpoints = append(endpoints, srv.URL)
+	}
+	return logs, endpoints
+}
+
+func TestLoadBalancerRoundRobin(t *testing.T) {
+	logs, endpoints := newReplicas(t)
+	client := FromRoundTripper(&LoadBalancer{Endpoints: endpoints})
+	for i := 0; i < 6; i++ {
+		if err := client.Read("1", &testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, log := range logs {
+		requests := log.all()
+		if len(requests) != 2 {
+			t.Errorf("Expected endpoint %d to receive 2 reads but got %d", i, len(requests))
+			continue
+		}
+		if path := requests[0].URL.Path; path != "/todos/1" {
+			t.Errorf("Expected endpoint %d to receive a read of /todos/1 but got %s", i, path)
+		}
+	}
+
+	todo := &testTodo{}
+	todo.Id = "1"
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete(todo); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(logs[0].all()); n != 4 {
+		t.Errorf("Expected writes to go to the primary but it received %d requests", n)
+	}
+}
+
+func TestLoadBalancerConsistentHash(t *testing.T) {
+	logs, endpoints := newReplicas(t)
+	client := FromRoundTripper(&LoadBalancer{Endpoints: endpoints, Strategy: ConsistentHash})
+	endpointOf := func(id string) int {
+		t.Helper()
+		if err := client.Read(id, &testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+		for i, log := range logs {
+			if last := log.last(); last != nil && last.URL.Path == "/todos/"+id {
+				return i
+			}
+		}
+		t.Fatalf("Expected the read of %s to reach an endpoint", id)
+		return -1
+	}
+	used := map[int]bool{}
+	for i := 0; i < 30; i++ {
+		id := strconv.Itoa(i)
+		first := endpointOf(id)
+		if again := endpointOf(id); again != first {
+			t.Errorf("Expected all reads of todo %s to go to endpoint %d but one went to %d", id, first, again)
+		}
+		used[first] = true
+	}
+	if len(used) != 3 {
+		t.Errorf("Expected reads to be spread across all 3 endpoints but only %d were used", len(used))
+	}
+}
+
+func TestLoadBalancerRemovedEndpoint(t *testing.T) {
+	endpoints := []string{"https://primary.example.com", "https://replica1.example.com", "https://replica2.example.com"}
+	all := &LoadBalancer{Endpoints: endpoints, Strategy: ConsistentHash}
+	fewer := &LoadBalancer{Endpoints: endpoints[:2], Strategy: ConsistentHash}
+	for i := 0; i < 100; i++ {
+		path := "/todos/" + strconv.Itoa(i)
+		if before := all.pick(path); before != 2 && fewer.pick(path) != before {
+			t.Errorf("Expected %s to stay on endpoint %d when another endpoint is removed", path, before)
+		}
+	}
+}
+
+func TestLoadBalancerOtherURLs(t *testing.T) {
+	var sent []string
+	lb := &LoadBalancer{
+		Endpoints: []string{"https://primary.example.com/api", "https://replica.example.com/api"},
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent = append(sent, req.URL.String())
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+	}
+	for _, rawURL := range []string{
+		"https://other.example.com/api/todos",
+		"https://primary.example.com/apiv2/todos",
+		"https://primary.example.com/api/todos",
+		"https://primary.example.com/api/todos",
+	} {
+		req, _ := http.NewRequest("GET", rawURL, nil)
+		if _, err := lb.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{
+		"https://other.example.com/api/todos",
+		"https://primary.example.com/apiv2/todos",
+		"https://primary.example.com/api/todos",
+		"https://replica.example.com/api/todos",
+	}
+	for i, rawURL := range sent {
+		if rawURL != expected[i] {
+			t.Errorf("Expected request %d to be sent to %s but got %s", i, expected[i], rawURL)
+		}
+	}
+}
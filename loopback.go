@@ -0,0 +1,117 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// HandlerTransport returns an http.RoundTripper which dispatches requests
+// directly to handler in the same process, without opening a network
+// connection. It is intended for server-side rendering of isomorphic apps, so
+// that the same models and client code can talk to the application's own
+// handlers without any network overhead:
+//
+//	client := rest.FromRoundTripper(rest.HandlerTransport(router))
+//
+// The handler sees a request like one received by an http.Server, with the
+// request's url path and host, and runs in the goroutine that sent the
+// request. The response is buffered in memory.
+func HandlerTransport(handler http.Handler) http.RoundTripper {
+	return handlerTransport{handler: handler}
+}
+
+// handlerTransport is the http.RoundTripper returned by HandlerTransport.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	// A RoundTripper must not modify the request, so the handler gets a copy
+	// which looks like an incoming server request.
+	serverReq := req.Clone(req.Context())
+	serverReq.RequestURI = req.URL.RequestURI()
+	serverReq.RemoteAddr = "127.0.0.1:0"
+	if serverReq.Host == "" {
+		serverReq.Host = req.URL.Host
+	}
+	if req.Body == nil {
+		serverReq.Body = http.NoBody
+	} else {
+		// The handler may hold on to or partially read the body, so we give
+		// it its own copy.
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		serverReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	w := &responseBuffer{header: http.Header{}}
+	t.handler.ServeHTTP(w, serverReq)
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	return w.response(req), nil
+}
+
+// responseBuffer is the http.ResponseWriter a HandlerTransport passes to its
+// handler. It buffers the response in memory.
+type responseBuffer struct {
+	header http.Header
+	// sent is a snapshot of header, taken when the status was written
+	sent   http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header satisfies http.ResponseWriter.
+func (w *responseBuffer) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader satisfies http.ResponseWriter.
+func (w *responseBuffer) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	w.sent = w.header.Clone()
+}
+
+// Write satisfies http.ResponseWriter. Like an http.Server, it detects the
+// Content-Type from the data if the handler did not set one.
+func (w *responseBuffer) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		if w.header.Get("Content-Type") == "" && len(data) > 0 {
+			w.header.Set("Content-Type", http.DetectContentType(data))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(data)
+}
+
+// response returns the buffered response to req.
+func (w *responseBuffer) response(req *http.Request) *http.Response {
+	w.WriteHeader(http.StatusOK)
+	return &http.Response{
+		Status:        strconv.Itoa(w.status) + " " + http.StatusText(w.status),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.sent,
+		Body:          ioutil.NopCloser(bytes.NewReader(w.body.Bytes())),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandlerTransport(t *testing.T) {
+	server := &todoServer{nextId: 1}
+	server.add("a", false)
+	testRootURL = "http://app.local"
+	defer func() { testRootURL = "" }()
+	client := FromRoundTripper(HandlerTransport(server))
+
+	todo := &testTodo{Title: "b"}
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[1].Title != "b" {
+		t.Errorf("Unexpected todos: %v", todos)
+	}
+	if err := client.Read("3", &testTodo{}); err == nil {
+		t.Error("Expected an error for a missing todo")
+	}
+}
+
+func TestHandlerTransportResponse(t *testing.T) {
+	var got *http.Request
+	transport := HandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("X-Before", "1")
+		w.WriteHeader(http.StatusTeapot)
+		w.Header().Set("X-After", "1")
+		w.Write([]byte("<html></html>"))
+	}))
+	req, _ := http.NewRequest("POST", "http://app.local/brew?kind=earl", strings.NewReader("hot"))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusTeapot || res.Status != "418 I'm a teapot" || string(body) != "<html></html>" || res.ContentLength != 13 {
+		t.Errorf("Unexpected response: %+v with body %q", res, body)
+	}
+	if res.Header.Get("X-Before") != "1" || res.Header.Get("X-After") != "" {
+		t.Errorf("Expected only the headers set before WriteHeader but got %v", res.Header)
+	}
+	if res.Request != req {
+		t.Error("Expected the response to refer to the original request")
+	}
+	if got.RequestURI != "/brew?kind=earl" || got.Host != "app.local" {
+		t.Errorf("Expected a request like an incoming server request but got %+v", got)
+	}
+
+	transport = HandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	}))
+	req, _ = http.NewRequest("GET", "http://app.local/", nil)
+	if res, err = transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected a 200 with a detected Content-Type but got %d and %v", res.StatusCode, res.Header)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != context.Canceled {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+}
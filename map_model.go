@@ -0,0 +1,170 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// MapModel is a Model whose fields are held in a map instead of a struct. It
+// is useful for admin tools and scripts which work with resources whose types
+// are not known at compile time. A *MapModel can be passed to all of the
+// methods of Client which accept a Model, and is encoded as a JSON object or
+// as url-encoded data depending on the ContentType, just like a struct. Use
+// ReadAllMaps to read a collection of MapModels.
+type MapModel struct {
+	// Root is returned by RootURL
+	Root string
+	// IdField is the key of the field which holds the id of the model. The
+	// default is "id".
+	IdField string
+	// Fields holds the fields of the model. Numbers in responses are decoded
+	// as json.Number so that large ids are not rounded.
+	Fields map[string]interface{}
+}
+
+// NewMapModel returns a new, empty MapModel with the given root url.
+func NewMapModel(rootURL string) *MapModel {
+	return &MapModel{
+		Root:   rootURL,
+		Fields: map[string]interface{}{},
+	}
+}
+
+// RootURL satisfies the RootURL method of rest.Model.
+func (m *MapModel) RootURL() string {
+	return m.Root
+}
+
+// ModelId satisfies the ModelId method of rest.Model. It returns the value of
+// the id field formatted as a string, or an empty string if there is none.
+func (m *MapModel) ModelId() string {
+	switch id := m.Fields[m.idField()].(type) {
+	case nil:
+		return ""
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+// SetModelId sets the id field to id.
+func (m *MapModel) SetModelId(id string) error {
+	m.Set(m.idField(), id)
+	return nil
+}
+
+// Get returns the value of the field with the given key, or nil if there is
+// none.
+func (m *MapModel) Get(key string) interface{} {
+	return m.Fields[key]
+}
+
+// Set sets the field with the given key to value.
+func (m *MapModel) Set(key string, value interface{}) {
+	if m.Fields == nil {
+		m.Fields = map[string]interface{}{}
+	}
+	m.Fields[key] = value
+}
+
+// idField returns the key of the id field.
+func (m *MapModel) idField() string {
+	if m.IdField == "" {
+		return "id"
+	}
+	return m.IdField
+}
+
+// MarshalJSON satisfies json.Marshaler. Only the fields are encoded.
+func (m *MapModel) MarshalJSON() ([]byte, error) {
+	if m.Fields == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(m.Fields)
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler. The keys in data are merged into
+// the existing fields, just as the json package does for structs.
+func (m *MapModel) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	fields := map[string]interface{}{}
+	if err := dec.Decode(&fields); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		m.Set(key, value)
+	}
+	return nil
+}
+
+// urlValues returns the fields of m as url.Values. Slices are encoded as
+// repeated keys and nil values are omitted. Nested objects cannot be
+// url-encoded.
+func (m *MapModel) urlValues() (url.Values, error) {
+	values := url.Values{}
+	for key, value := range m.Fields {
+		elems := []interface{}{value}
+		if arr, ok := value.([]interface{}); ok {
+			elems = arr
+		}
+		for _, elem := range elems {
+			if elem == nil {
+				continue
+			}
+			if number, ok := elem.(json.Number); ok {
+				values.Add(key, number.String())
+				continue
+			}
+			str, err := encodeString(reflect.ValueOf(elem))
+			if err != nil {
+				if typeErr, ok := err.(TypeError); ok {
+					typeErr.Expected = fmt.Sprintf("field %q to have a type which can be url-encoded", key)
+					return nil, typeErr
+				}
+				return nil, err
+			}
+			values.Add(key, str)
+		}
+	}
+	return values, nil
+}
+
+// ReadAllMaps is like ReadAll, but for collections of resources which are read
+// into MapModels. It sends a GET request to rootURL and returns a MapModel with
+// the given root url for each object in the response. The WithQuery option can
+// be used to filter the models returned by the server.
+func (c *Client) ReadAllMaps(rootURL string, opts ...RequestOption) ([]*MapModel, error) {
+	reqOpts := newRequestOptions(opts)
+	query, err := toQuery(reqOpts.query)
+	if err != nil {
+		return nil, err
+	}
+	fullURL, err := normalizeRootURL(rootURL)
+	if err != nil {
+		return nil, err
+	}
+	models := []*MapModel{}
+	if err := c.sendRequestAndUnmarshal("GET", appendQuery(fullURL, query), "", "", &models, reqOpts); err != nil {
+		return nil, err
+	}
+	result := make([]*MapModel, 0, len(models))
+	for _, model := range models {
+		if model != nil {
+			model.Root = rootURL
+			result = append(result, model)
+		}
+	}
+	return result, nil
+}
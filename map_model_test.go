@@ -0,0 +1,105 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMapModelCRUD(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"id": 12345678901234567890, "title": "a"}`)
+	client := NewClient()
+	model := NewMapModel(testRootURL + "/todos")
+	model.Set("title", "a")
+	model.Set("tags", []interface{}{"x", "y"})
+	if err := client.Create(model); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != "tags=x&tags=y&title=a" {
+		t.Errorf("Expected url-encoded fields but got %q", body)
+	}
+	if id := model.ModelId(); id != "12345678901234567890" {
+		t.Fatalf("Expected the large id not to be rounded but got %s", id)
+	}
+	if model.Get("tags") == nil {
+		t.Error("Expected the fields which are not in the response to be kept")
+	}
+
+	client.ContentType = ContentJSON
+	model.Set("done", true)
+	if err := client.Update(model); err != nil {
+		t.Fatal(err)
+	}
+	req := server.last()
+	if req.Method != "PATCH" || req.URL.Path != "/todos/12345678901234567890" {
+		t.Errorf("Expected PATCH /todos/12345678901234567890 but got %s %s", req.Method, req.URL.Path)
+	}
+	if body := server.lastBody(); body != `{"done":true,"id":12345678901234567890,"tags":["x","y"],"title":"a"}` {
+		t.Errorf("Expected the fields to be encoded as JSON but got %s", body)
+	}
+
+	read := NewMapModel(testRootURL + "/todos")
+	if err := client.Read("12345678901234567890", read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Get("title") != "a" {
+		t.Errorf("Expected the fields to be read but got %v", read.Fields)
+	}
+	if err := client.Delete(read); err != nil {
+		t.Fatal(err)
+	}
+	if req := server.last(); req.Method != "DELETE" || req.URL.Path != "/todos/12345678901234567890" {
+		t.Errorf("Expected DELETE /todos/12345678901234567890 but got %s %s", req.Method, req.URL.Path)
+	}
+}
+
+func TestMapModelIdField(t *testing.T) {
+	model := &MapModel{IdField: "slug"}
+	if model.ModelId() != "" {
+		t.Errorf("Expected an empty id but got %q", model.ModelId())
+	}
+	model.SetModelId("a-b")
+	if model.Get("slug") != "a-b" || model.ModelId() != "a-b" {
+		t.Errorf("Expected the id to be stored in the slug field but got %v", model.Fields)
+	}
+	model.Set("slug", 3.0)
+	if model.ModelId() != "3" {
+		t.Errorf("Expected a float id to be formatted as an integer but got %q", model.ModelId())
+	}
+}
+
+func TestMapModelNestedURLEncoded(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{}`)
+	model := NewMapModel(testRootURL + "/todos")
+	model.Set("owner", map[string]interface{}{"name": "a"})
+	err := NewClient().Create(model)
+	if _, ok := err.(TypeError); !ok {
+		t.Errorf("Expected a TypeError for a nested object but got %v", err)
+	}
+	if len(server.all()) != 0 {
+		t.Error("Expected no request to be sent")
+	}
+}
+
+func TestReadAllMaps(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	server.add("b", true)
+	models, err := NewClient().ReadAllMaps(testRootURL+"/todos", WithQuery(Query{"Title": {"b"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models but got %d", len(models))
+	}
+	for _, model := range models {
+		if model.Get("Title") != "b" || model.RootURL() != testRootURL+"/todos" {
+			t.Errorf("Expected a todo with the title b and the root url but got %+v", model)
+		}
+	}
+	if reqs := server.Requests(); reqs[0] != "GET /todos?Title=b" {
+		t.Errorf("Expected the query to be sent but got %s", reqs[0])
+	}
+}
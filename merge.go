@@ -0,0 +1,147 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+var modelType = reflect.TypeOf((*Model)(nil)).Elem()
+
+// WithMerge returns a RequestOption which causes ReadAll to merge the models
+// returned by the server into the existing contents of models instead of
+// replacing them. Models are matched by id: existing models with a matching
+// id are updated in place (so pointers to them held elsewhere remain valid),
+// and models with new ids are added. If removeMissing is true, existing models
+// whose ids are not in the response are removed.
+//
+// When merging, models may be either a pointer to a slice of models or a
+// pointer to a map of models keyed by id (e.g. *map[string]*Todo).
+func WithMerge(removeMissing bool) RequestOption {
+	return func(opts *requestOptions) {
+		opts.merge = true
+		opts.removeMissing = removeMissing
+	}
+}
+
+// readAllMerge is the implementation of ReadAll for the WithMerge option.
+func (c *Client) readAllMerge(models interface{}, query Query, reqOpts *requestOptions) error {
+	if err := checkModelsType(models, true); err != nil {
+		return err
+	}
+	container := reflect.ValueOf(models).Elem()
+	elemType := container.Type().Elem()
+	rootURL, err := c.routeModelType(elemType)
+	if err != nil {
+		return err
+	}
+	records := []json.RawMessage{}
+	if err := c.sendRequestAndUnmarshal("GET", appendQuery(rootURL, query), "", "", &records, reqOpts); err != nil {
+		return err
+	}
+	if container.Kind() == reflect.Slice {
+		return c.mergeSlice(container, records, reqOpts.removeMissing)
+	}
+	return c.mergeMap(container, records, reqOpts.removeMissing)
+}
+
+// decodeRecord unmarshals record into a new value of elemType and returns the
+// value and its id.
+func (c *Client) decodeRecord(elemType reflect.Type, record json.RawMessage) (reflect.Value, string, error) {
+	ptr := reflect.New(elemType)
+	ptr.Elem().Set(newModelOfType(elemType))
+	if err := c.unmarshal(record, ptr.Interface()); err != nil {
+		return reflect.Value{}, "", err
+	}
+	return ptr.Elem(), ptr.Elem().Interface().(Model).ModelId(), nil
+}
+
+// mergeSlice merges records into the slice.
+func (c *Client) mergeSlice(slice reflect.Value, records []json.RawMessage, removeMissing bool) error {
+	index := map[string]int{}
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() == reflect.Ptr && elem.IsNil() {
+			continue
+		}
+		index[elem.Interface().(Model).ModelId()] = i
+	}
+	seen := map[string]bool{}
+	for _, record := range records {
+		fresh, id, err := c.decodeRecord(slice.Type().Elem(), record)
+		if err != nil {
+			return err
+		}
+		seen[id] = true
+		if i, found := index[id]; found {
+			// Decode into the existing element so that it is updated in place.
+			if err := c.unmarshal(record, slice.Index(i).Addr().Interface()); err != nil {
+				return err
+			}
+			continue
+		}
+		slice.Set(reflect.Append(slice, fresh))
+		index[id] = slice.Len() - 1
+	}
+	if removeMissing {
+		kept := reflect.MakeSlice(slice.Type(), 0, len(seen))
+		for i := 0; i < slice.Len(); i++ {
+			elem := slice.Index(i)
+			if elem.Kind() == reflect.Ptr && elem.IsNil() {
+				continue
+			}
+			if seen[elem.Interface().(Model).ModelId()] {
+				kept = reflect.Append(kept, elem)
+			}
+		}
+		slice.Set(kept)
+	}
+	return nil
+}
+
+// mergeMap merges records into m, which is a map of models keyed by id.
+func (c *Client) mergeMap(m reflect.Value, records []json.RawMessage, removeMissing bool) error {
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	elemType := m.Type().Elem()
+	seen := map[string]bool{}
+	for _, record := range records {
+		fresh, id, err := c.decodeRecord(elemType, record)
+		if err != nil {
+			return err
+		}
+		seen[id] = true
+		key := reflect.ValueOf(id).Convert(m.Type().Key())
+		existing := m.MapIndex(key)
+		switch {
+		case !existing.IsValid():
+			m.SetMapIndex(key, fresh)
+		case existing.Kind() == reflect.Ptr && !existing.IsNil():
+			// Decode into the existing model so that it is updated in place.
+			if err := c.unmarshal(record, existing.Interface()); err != nil {
+				return err
+			}
+		default:
+			// Map elements are not addressable, so we need to decode into a
+			// copy and then store it.
+			ptr := reflect.New(elemType)
+			ptr.Elem().Set(existing)
+			if err := c.unmarshal(record, ptr.Interface()); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, ptr.Elem())
+		}
+	}
+	if removeMissing {
+		for _, key := range m.MapKeys() {
+			if !seen[key.String()] {
+				m.SetMapIndex(key, reflect.Value{})
+			}
+		}
+	}
+	return nil
+}
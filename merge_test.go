@@ -0,0 +1,73 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"testing"
+)
+
+func TestMergeSlice(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	first := &testTodo{DefaultId: DefaultId{Id: "1"}, Title: "stale"}
+	local := &testTodo{DefaultId: DefaultId{Id: "local"}, Title: "local"}
+	todos := []*testTodo{first, local, nil}
+	if err := client.ReadAll(&todos, WithMerge(false)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 4 || todos[0] != first || todos[1] != local || todos[3].Title != "b" {
+		t.Fatalf("Expected the new todo to be appended to the existing ones but got %v", todos)
+	}
+	if first.Title != "a" {
+		t.Errorf("Expected the existing todo to be updated in place but got %+v", first)
+	}
+
+	server.todos = server.todos[1:]
+	if err := client.ReadAll(&todos, WithMerge(true)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0].Id != "2" {
+		t.Errorf("Expected only the todo which is still on the server but got %v", todos)
+	}
+}
+
+func TestMergeMap(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	first := &testTodo{DefaultId: DefaultId{Id: "1"}}
+	todos := map[string]*testTodo{"1": first, "local": {Title: "local"}}
+	if err := client.ReadAll(&todos, WithMerge(false)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 3 || todos["1"] != first || first.Title != "a" || todos["2"].Title != "b" {
+		t.Errorf("Expected the todos to be merged by id but got %v", todos)
+	}
+
+	server.todos = server.todos[:1]
+	values := map[string]testTodo{}
+	if err := client.ReadAll(&values, WithMerge(true)); err == nil {
+		t.Error("Expected an error for a map of values whose pointers implement Model")
+	}
+	var fresh map[string]*testTodo
+	if err := client.ReadAll(&fresh, WithMerge(true)); err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh) != 1 || fresh["1"].Title != "a" {
+		t.Errorf("Expected a nil map to be created but got %v", fresh)
+	}
+	if err := client.ReadAll(&todos, WithMerge(true)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos["1"] != first {
+		t.Errorf("Expected the missing todos to be removed but got %v", todos)
+	}
+}
+
+func TestMergeRequiresOption(t *testing.T) {
+	newTodoServer(t, "a")
+	if _, ok := NewClient().ReadAll(&map[string]*testTodo{}).(TypeError); !ok {
+		t.Error("Expected a TypeError for a map without WithMerge")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+)
+
+// WithMeta returns a RequestOption which causes ReadAll to expect the records
+// to be wrapped in an object alongside collection-level metadata, such as the
+// total number of records, facets, or aggregations, and to decode the
+// metadata into meta, which must be a pointer:
+//
+//	{"data": [...], "meta": {"total": 1234, "facets": {...}}}
+//
+// The keys are given by the RecordsKey and MetaKey of the client. If the
+// response is a plain array, it is decoded into the models as usual and meta
+// is left unchanged. WithMeta cannot be combined with WithMerge or WithReuse.
+func WithMeta(meta interface{}) RequestOption {
+	return func(opts *requestOptions) {
+		opts.meta = meta
+	}
+}
+
+// envelope is the target of a ReadAll with WithMeta.
+type envelope struct {
+	records interface{}
+	meta    interface{}
+}
+
+// decodeEnvelope decodes data, which is either a plain array of records or
+// an object holding the records and the metadata, into env.
+func (c *Client) decodeEnvelope(data []byte, env envelope) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return c.unmarshal(data, env.records)
+	}
+	return c.decodeInto(data, DecodeInto{
+		c.recordsKey(): env.records,
+		c.metaKey():    env.meta,
+	})
+}
+
+// recordsKey returns the key of the records in a response with metadata.
+func (c *Client) recordsKey() string {
+	if c.RecordsKey == "" {
+		return "data"
+	}
+	return c.RecordsKey
+}
+
+// metaKey returns the key of the metadata in a response with metadata.
+func (c *Client) metaKey() string {
+	if c.MetaKey == "" {
+		return "meta"
+	}
+	return c.MetaKey
+}
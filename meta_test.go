@@ -0,0 +1,82 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// todoListMeta is the collection-level metadata used in the tests.
+type todoListMeta struct {
+	Total  int
+	Facets map[string]int
+}
+
+func TestWithMeta(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"data": [{"Id": "1", "Title": "a"}, {"Id": "2", "Title": "b"}],
+			"meta": {"Total": 1234, "Facets": {"done": 1000}}
+		}`))
+	})
+	todos := []*testTodo{}
+	meta := todoListMeta{}
+	if err := NewClient().ReadAll(&todos, WithMeta(&meta)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].Title != "a" || todos[1].Title != "b" {
+		t.Errorf("Expected the records to be decoded but got %+v", todos)
+	}
+	if meta.Total != 1234 || meta.Facets["done"] != 1000 {
+		t.Errorf("Expected the metadata to be decoded but got %+v", meta)
+	}
+}
+
+func TestWithMetaCustomKeys(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": [{"Id": "1", "Title": "a"}], "pagination": {"Total": 1}}`))
+	})
+	client := NewClient()
+	client.RecordsKey = "items"
+	client.MetaKey = "pagination"
+	todos := []*testTodo{}
+	meta := todoListMeta{}
+	if err := client.ReadAll(&todos, WithMeta(&meta)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || meta.Total != 1 {
+		t.Errorf("Expected 1 record and a total of 1 but got %+v and %+v", todos, meta)
+	}
+}
+
+func TestWithMetaPlainArray(t *testing.T) {
+	newTodoServer(t, "Write a book", "Take out the trash")
+	todos := []*testTodo{}
+	meta := todoListMeta{Total: -1}
+	if err := NewClient().ReadAll(&todos, WithMeta(&meta)); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 {
+		t.Errorf("Expected a plain array to be decoded into the models but got %+v", todos)
+	}
+	if meta.Total != -1 {
+		t.Errorf("Expected the metadata to be left unchanged but got %+v", meta)
+	}
+}
+
+func TestWithMetaInvalidCombinations(t *testing.T) {
+	server := newTodoServer(t, "Write a book")
+	client := NewClient()
+	for name, opt := range map[string]RequestOption{"WithMerge": WithMerge(false), "WithReuse": WithReuse()} {
+		todos := []*testTodo{}
+		if err := client.ReadAll(&todos, WithMeta(&todoListMeta{}), opt); err == nil {
+			t.Errorf("Expected an error combining WithMeta with %s", name)
+		}
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("Expected no requests to be sent but got %d", n)
+	}
+}
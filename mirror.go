@@ -0,0 +1,144 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// MirrorPolicy describes how a Client mirrors read requests to a secondary
+// backend, e.g. to verify that a new backend returns the same data as the
+// current one before cutting over. Mirrored requests are sent in the
+// background and never affect the result of the original request.
+type MirrorPolicy struct {
+	// BaseURL is the base url of the secondary backend, e.g.
+	// "https://new-api.example.com". The mirrored url is the url of the
+	// original request with From replaced by BaseURL.
+	BaseURL string
+	// From is the prefix of the urls which are mirrored, e.g.
+	// "https://api.example.com". If it is empty, requests to any url are
+	// mirrored and only their scheme and host are replaced by BaseURL.
+	From string
+	// Percent is the percentage of GET requests which are mirrored, between 0
+	// and 100.
+	Percent float64
+	// OnResult is called with the result of each mirrored request, from a
+	// separate goroutine.
+	OnResult func(result MirrorResult)
+}
+
+// MirrorResult compares the response to a request with the response to the
+// same request mirrored to the secondary backend of a MirrorPolicy.
+type MirrorResult struct {
+	// Method is the http method of the request
+	Method string
+	// URL is the url of the original request
+	URL string
+	// MirrorURL is the url the request was mirrored to
+	MirrorURL string
+	// StatusCode is the status code of the original response
+	StatusCode int
+	// MirrorStatusCode is the status code of the mirrored response
+	MirrorStatusCode int
+	// Body is the body of the original response
+	Body []byte
+	// MirrorBody is the body of the mirrored response
+	MirrorBody []byte
+	// Match is true iff the status codes are the same and the bodies are
+	// equal. JSON bodies are compared by value, so differences in key order
+	// and whitespace are ignored.
+	Match bool
+	// Err is the error which prevented the request from being mirrored, if
+	// any. Match is always false if Err is not nil.
+	Err error
+}
+
+// mirror sends a copy of req, which was sent by the client and got res with
+// the given body in response, to the secondary backend of c.Mirror in the
+// background, if req is sampled. model is the model the response is for, and
+// is used to mask PII in the result.
+func (c *Client) mirror(req *http.Request, res *http.Response, body []byte, model interface{}) {
+	policy := c.Mirror
+	if policy == nil || policy.OnResult == nil || req.Method != "GET" || rand.Float64()*100 >= policy.Percent {
+		return
+	}
+	mirrorURL, ok := policy.mirrorURL(req.URL)
+	if !ok {
+		return
+	}
+	header := req.Header.Clone()
+	go func() {
+		result := MirrorResult{
+			Method:     req.Method,
+			URL:        req.URL.String(),
+			MirrorURL:  mirrorURL,
+			StatusCode: res.StatusCode,
+			Body:       body,
+		}
+		result.MirrorStatusCode, result.MirrorBody, result.Err = c.sendMirror(detach(req.Context()), mirrorURL, header)
+		if result.Err == nil {
+			result.Match = result.StatusCode == result.MirrorStatusCode && bodiesEqual(result.Body, result.MirrorBody)
+		}
+		if c.PII != nil {
+			result.Body = c.MaskPII(result.Body, model)
+			result.MirrorBody = c.MaskPII(result.MirrorBody, model)
+		}
+		policy.OnResult(result)
+	}()
+}
+
+// mirrorURL returns the url u should be mirrored to, and false if it should
+// not be mirrored.
+func (policy *MirrorPolicy) mirrorURL(u *url.URL) (string, bool) {
+	original := u.String()
+	if policy.From != "" {
+		if !strings.HasPrefix(original, policy.From) {
+			return "", false
+		}
+		return strings.TrimSuffix(policy.BaseURL, "/") + original[len(policy.From):], true
+	}
+	return strings.TrimSuffix(policy.BaseURL, "/") + u.RequestURI(), true
+}
+
+// sendMirror sends a GET request to mirrorURL with the given context and
+// headers and returns the status code and body of the response. It bypasses
+// the retries, events, and cache of the client, so that mirrored requests are
+// invisible to the rest of the application.
+func (c *Client) sendMirror(ctx context.Context, mirrorURL string, header http.Header) (int, []byte, error) {
+	req, err := http.NewRequest("GET", mirrorURL, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header = header
+	res, err := c.sender().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, body, nil
+}
+
+// bodiesEqual returns true iff a and b are equal JSON values or, if either of
+// them is not valid JSON, the same bytes.
+func bodiesEqual(a []byte, b []byte) bool {
+	var valueA, valueB interface{}
+	if json.Unmarshal(a, &valueA) != nil || json.Unmarshal(b, &valueB) != nil {
+		return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
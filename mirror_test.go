@@ -0,0 +1,121 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newMirrorServers starts a primary server for testTodo and a secondary
+// server to mirror to, which agrees with the primary about the todo with id 1
+// and disagrees about the todo with id 2.
+func newMirrorServers(t *testing.T) (primary *requestLog, secondary *requestLog, secondaryURL string) {
+	primary, secondary = &requestLog{}, &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		primary.add(r)
+		w.Write([]byte(`{"Id": "1", "Title": "a"}`))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondary.add(r)
+		if r.URL.Path == "/todos/1" {
+			w.Write([]byte(`{ "Title": "a",  "Id": "1" }`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return primary, secondary, srv.URL
+}
+
+// waitForMirror returns the next result sent to results, or fails the test if
+// there is none within a second.
+func waitForMirror(t *testing.T, results chan MirrorResult) MirrorResult {
+	t.Helper()
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a mirrored request")
+		return MirrorResult{}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	_, secondary, secondaryURL := newMirrorServers(t)
+	results := make(chan MirrorResult, 10)
+	client := NewClient()
+	client.Header = http.Header{"X-App": {"test"}}
+	client.Mirror = &MirrorPolicy{
+		BaseURL:  secondaryURL + "/",
+		Percent:  100,
+		OnResult: func(result MirrorResult) { results <- result },
+	}
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	result := waitForMirror(t, results)
+	if !result.Match || result.Err != nil {
+		t.Errorf("Expected equal JSON bodies to match but got %+v", result)
+	}
+	if result.URL != testRootURL+"/todos/1" || result.MirrorURL != secondaryURL+"/todos/1" {
+		t.Errorf("Expected the request to be mirrored to the secondary backend but got %s and %s", result.URL, result.MirrorURL)
+	}
+	if req := secondary.last(); req.Header.Get("X-App") != "test" {
+		t.Errorf("Expected the headers to be mirrored but got %v", req.Header)
+	}
+
+	if err := client.Read("2", &testTodo{}); err != nil {
+		t.Fatalf("Expected the mirrored request not to affect the original one but got %v", err)
+	}
+	result = waitForMirror(t, results)
+	if result.Match || result.StatusCode != http.StatusOK || result.MirrorStatusCode != http.StatusNotFound {
+		t.Errorf("Expected a mismatch but got %+v", result)
+	}
+
+	if err := client.Create(&testTodo{Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	client.Mirror.Percent = 0
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case result := <-results:
+		t.Errorf("Expected only sampled GET requests to be mirrored but got %+v", result)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if n := len(secondary.all()); n != 2 {
+		t.Errorf("Expected 2 mirrored requests but got %d", n)
+	}
+}
+
+func TestMirrorFrom(t *testing.T) {
+	_, secondary, secondaryURL := newMirrorServers(t)
+	results := make(chan MirrorResult, 10)
+	client := NewClient()
+	client.Mirror = &MirrorPolicy{
+		BaseURL:  secondaryURL,
+		From:     testRootURL + "/todos",
+		Percent:  100,
+		OnResult: func(result MirrorResult) { results <- result },
+	}
+	if err := client.Read("1", &tenantTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	result := waitForMirror(t, results)
+	if result.MirrorURL != secondaryURL+"/1" {
+		t.Errorf("Expected From to be replaced by BaseURL but got %s", result.MirrorURL)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(secondary.all()); n != 1 {
+		t.Errorf("Expected only urls starting with From to be mirrored but got %d requests", n)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Policy declares how requests for a particular type of model should be sent,
+// which keeps resource-specific behavior next to the model definition. It is
+// merged with the settings of the client: any field which is not set falls
+// back to the client's setting.
+type Policy struct {
+	// Retry, if not nil, replaces the client's Retry for requests for the
+	// model.
+	Retry *RetryPolicy
+	// CacheTTL, if not zero, replaces the client's CacheTTL (and CacheTTLs)
+	// for responses for the model.
+	CacheTTL time.Duration
+	// NoCache prevents responses for the model from being cached or served
+	// from the cache.
+	NoCache bool
+	// Scopes are the authorization scopes requests for the model require.
+	// They are passed to the client's Authorize function.
+	Scopes []string
+	// Headers are added to requests for the model, e.g. to opt in to
+	// experimental fields which are gated behind a feature flag header. They
+	// take precedence over the client's ResourceHeaders and Header.
+	Headers http.Header
+}
+
+// PolicyModel is implemented by models which declare a Policy. The client
+// calls Policy on a zero value of the model type for ReadAll, so it should
+// not depend on the fields of the model.
+type PolicyModel interface {
+	Model
+	Policy() Policy
+}
+
+// policyOf returns the Policy declared by v, which may be a Model or a pointer
+// to a slice or map of models, or nil if it does not declare one.
+func policyOf(v interface{}) *Policy {
+	if model, ok := v.(PolicyModel); ok {
+		policy := model.Policy()
+		return &policy
+	}
+	if checkModelsType(v, true) != nil {
+		return nil
+	}
+	elemType := reflect.TypeOf(v).Elem().Elem()
+	if elemType.Implements(reflect.TypeOf((*PolicyModel)(nil)).Elem()) {
+		policy := newModelOfType(elemType).Interface().(PolicyModel).Policy()
+		return &policy
+	}
+	return nil
+}
+
+// forModel records the Policy declared by v, if any, and the RootURL of v in
+// opts and returns opts.
+func (opts *requestOptions) forModel(v interface{}) *requestOptions {
+	opts.policy = policyOf(v)
+	opts.rootURL = rootURLOf(v)
+	return opts
+}
+
+// policyKey is the context key for the *Policy of a request.
+type policyKey struct{}
+
+// withPolicy returns a copy of ctx which carries policy.
+func withPolicy(ctx context.Context, policy *Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+// policyFrom returns the *Policy carried by ctx, or nil if there is none.
+func policyFrom(ctx context.Context) *Policy {
+	policy, _ := ctx.Value(policyKey{}).(*Policy)
+	return policy
+}
+
+// retryFor returns the RetryPolicy for req.
+func (c *Client) retryFor(req *http.Request) *RetryPolicy {
+	if policy := policyFrom(req.Context()); policy != nil && policy.Retry != nil {
+		return policy.Retry
+	}
+	return c.Retry
+}
+
+// authorize calls c.Authorize for req if its Policy requires any scopes.
+func (c *Client) authorize(req *http.Request) error {
+	policy := policyFrom(req.Context())
+	if policy == nil || len(policy.Scopes) == 0 {
+		return nil
+	}
+	if c.Authorize == nil {
+		return fmt.Errorf("rest: %s request to %s requires scopes %s but the client has no Authorize function", req.Method, req.URL.String(), strings.Join(policy.Scopes, ", "))
+	}
+	return c.Authorize(req, policy.Scopes)
+}
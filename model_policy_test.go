@@ -0,0 +1,138 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// policyTodoPolicy is the Policy declared by policyTodo.
+var policyTodoPolicy Policy
+
+// policyTodo is a testTodo which declares policyTodoPolicy.
+type policyTodo struct {
+	testTodo
+}
+
+func (*policyTodo) Policy() Policy {
+	return policyTodoPolicy
+}
+
+// setPolicy sets the Policy declared by policyTodo for the rest of the test.
+func setPolicy(t *testing.T, policy Policy) {
+	policyTodoPolicy = policy
+	t.Cleanup(func() {
+		policyTodoPolicy = Policy{}
+	})
+}
+
+func TestPolicyRetryAndHeaders(t *testing.T) {
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 1)
+	log := &requestLog{}
+	client := FromRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		log.add(req)
+		return http.DefaultTransport.RoundTrip(req)
+	}))
+	client.Header = http.Header{"X-Feature": {"stable"}, "X-Client": {"test"}}
+	setPolicy(t, Policy{
+		Retry:   &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, StatusCodes: []int{http.StatusServiceUnavailable}},
+		Headers: http.Header{"X-Feature": {"beta"}},
+	})
+	if err := client.Read("1", &policyTodo{}); err != nil {
+		t.Fatalf("Expected the Policy of the model to retry the request but got %v", err)
+	}
+	if server.count() != 2 {
+		t.Errorf("Expected 2 attempts but got %d", server.count())
+	}
+	if header := log.last().Header; header.Get("X-Feature") != "beta" || header.Get("X-Client") != "test" {
+		t.Errorf("Expected the headers of the Policy to take precedence but got %v", header)
+	}
+
+	server = newFlakyServer(t, http.StatusServiceUnavailable, 1)
+	if err := client.Read("1", &testTodo{}); err == nil {
+		t.Error("Expected models without a Policy not to be retried")
+	}
+}
+
+func TestPolicyCache(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	setPolicy(t, Policy{NoCache: true})
+	for i := 0; i < 2; i++ {
+		if err := client.Read("1", &policyTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.count("GET"); got != 2 {
+		t.Errorf("Expected NoCache to bypass the cache but got %v", server.Requests())
+	}
+
+	setPolicy(t, Policy{CacheTTL: 20 * time.Millisecond})
+	client.CacheTTL = time.Hour
+	for i := 0; i < 2; i++ {
+		if err := client.Read("1", &policyTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := client.Read("1", &policyTodo{}, WithCachePolicy(CacheOnly)); err != ErrNotCached {
+		t.Errorf("Expected the CacheTTL of the Policy to apply but got %v", err)
+	}
+	if got := server.count("GET"); got != 3 {
+		t.Errorf("Expected 3 GET requests but got %v", server.Requests())
+	}
+}
+
+func TestPolicyScopes(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	setPolicy(t, Policy{Scopes: []string{"todos:read"}})
+	client := NewClient()
+	if err := client.Read("1", &policyTodo{}); err == nil {
+		t.Error("Expected an error when the client has no Authorize function")
+	}
+	var scopes []string
+	client.Authorize = func(req *http.Request, s []string) error {
+		scopes = s
+		req.Header.Set("Authorization", "Bearer token")
+		return nil
+	}
+	if err := client.Read("1", &policyTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scopes, []string{"todos:read"}) || log.last().Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected Authorize to be called with the scopes but got %v", scopes)
+	}
+	denied := errors.New("denied")
+	client.Authorize = func(*http.Request, []string) error { return denied }
+	if err := client.Read("1", &policyTodo{}); err == nil {
+		t.Error("Expected the error of Authorize to be returned")
+	}
+	if len(log.all()) != 1 {
+		t.Errorf("Expected unauthorized requests not to be sent but got %d requests", len(log.all()))
+	}
+}
+
+func TestPolicyOf(t *testing.T) {
+	setPolicy(t, Policy{NoCache: true})
+	for _, v := range []interface{}{&policyTodo{}, &[]*policyTodo{}, &map[string]*policyTodo{}} {
+		if policy := policyOf(v); policy == nil || !policy.NoCache {
+			t.Errorf("Expected the Policy of the model for %T but got %v", v, policy)
+		}
+	}
+	for _, v := range []interface{}{&testTodo{}, &[]*testTodo{}, []*policyTodo{}} {
+		if policy := policyOf(v); policy != nil {
+			t.Errorf("Expected no Policy for %T but got %v", v, policy)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	// ContentJSONPatch is the Content-Type for JSON Patch (RFC 6902)
+	// documents.
+	ContentJSONPatch ContentType = "application/json-patch+json"
+	// ContentMergePatch is the Content-Type for JSON Merge Patch (RFC 7396)
+	// documents.
+	ContentMergePatch ContentType = "application/merge-patch+json"
+)
+
+// PatchMode determines the format of the body sent by Update.
+type PatchMode string
+
+const (
+	// PatchFields is the default PatchMode. The fields of the model are
+	// encoded according to the client's ContentType.
+	PatchFields PatchMode = ""
+	// PatchJSONPatch causes Update to send a JSON Patch (RFC 6902) document
+	// containing the operations needed to turn the original model into the
+	// updated one. It requires the WithOriginal option.
+	PatchJSONPatch PatchMode = "json-patch"
+	// PatchMergePatch causes Update to send a JSON Merge Patch (RFC 7396)
+	// document. If the WithOriginal option is provided, the document only
+	// contains the fields which changed. Otherwise it contains all of the
+	// fields of the model.
+	PatchMergePatch PatchMode = "merge-patch"
+)
+
+// ErrOriginalRequired is returned by Update when the PatchMode requires the
+// original version of the model but the WithOriginal option was not provided.
+var ErrOriginalRequired = errors.New("rest: the WithOriginal option is required to generate a JSON Patch")
+
+// WithPatchMode returns a RequestOption which causes Update to use the given
+// PatchMode instead of the client's PatchMode.
+func WithPatchMode(mode PatchMode) RequestOption {
+	return func(opts *requestOptions) {
+		opts.patchMode = &mode
+	}
+}
+
+// WithOriginal returns a RequestOption which provides the version of the model
+// before it was changed. Update uses it to generate patches which only contain
+// the changes.
+func WithOriginal(original Model) RequestOption {
+	return func(opts *requestOptions) {
+		opts.original = original
+	}
+}
+
+// patchMode returns the PatchMode which should be used for a request.
+func (c *Client) patchMode(reqOpts *requestOptions) PatchMode {
+	if reqOpts.patchMode != nil {
+		return *reqOpts.patchMode
+	}
+	return c.PatchMode
+}
+
+// jsonPatchOp is a single operation in a JSON Patch document.
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON satisfies json.Marshaler. RFC 6902 requires a value for add and
+// replace operations, even if it is null, and none for remove operations.
+func (op jsonPatchOp) MarshalJSON() ([]byte, error) {
+	if op.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path})
+	}
+	type plain jsonPatchOp
+	return json.Marshal(plain(op))
+}
+
+// encodePatch encodes the changes from original to model according to mode.
+// original may be nil, except for PatchJSONPatch.
+func encodePatch(mode PatchMode, original Model, model Model) (string, ContentType, error) {
+	var changes []Change
+	if original != nil {
+		var err error
+		if changes, err = Diff(original, model); err != nil {
+			return "", "", err
+		}
+	}
+	switch mode {
+	case PatchJSONPatch:
+		if original == nil {
+			return "", "", ErrOriginalRequired
+		}
+		data, err := json.Marshal(jsonPatch(changes))
+		return string(data), ContentJSONPatch, err
+	case PatchMergePatch:
+		if original == nil {
+			data, err := json.Marshal(model)
+			return string(data), ContentMergePatch, err
+		}
+		data, err := json.Marshal(mergePatch(changes))
+		return string(data), ContentMergePatch, err
+	default:
+		return "", "", errors.New("rest: unknown PatchMode: " + string(mode))
+	}
+}
+
+// toJSONObject converts v to a generic JSON object by marshaling and then
+// unmarshaling it.
+func toJSONObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// jsonPatch returns the JSON Patch operations corresponding to changes.
+func jsonPatch(changes []Change) []jsonPatchOp {
+	ops := make([]jsonPatchOp, 0, len(changes))
+	for _, change := range changes {
+		switch change.Kind {
+		case FieldAdded:
+			ops = append(ops, jsonPatchOp{Op: "add", Path: change.Pointer(), Value: change.New})
+		case FieldRemoved:
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: change.Pointer()})
+		case FieldModified:
+			ops = append(ops, jsonPatchOp{Op: "replace", Path: change.Pointer(), Value: change.New})
+		}
+	}
+	return ops
+}
+
+// mergePatch returns the JSON Merge Patch document corresponding to changes.
+func mergePatch(changes []Change) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for _, change := range changes {
+		obj := patch
+		for _, key := range change.Path[:len(change.Path)-1] {
+			child, ok := obj[key].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				obj[key] = child
+			}
+			obj = child
+		}
+		// New is nil for removed fields, which is how merge patches express
+		// removal.
+		obj[change.Path[len(change.Path)-1]] = change.New
+	}
+	return patch
+}
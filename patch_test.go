@@ -0,0 +1,116 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateJSONPatch(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, "{}")
+	client := NewClient()
+	client.PatchMode = PatchJSONPatch
+	original := testTodo{DefaultId: DefaultId{Id: "1"}, Title: "a"}
+	todo := original
+	todo.Title = "b"
+	todo.IsCompleted = true
+	if err := client.Update(&todo, WithOriginal(&original)); err != nil {
+		t.Fatal(err)
+	}
+	req := server.last()
+	if req.Method != "PATCH" || req.Header.Get("Content-Type") != string(ContentJSONPatch) {
+		t.Errorf("Unexpected request: %s with Content-Type %s", req.Method, req.Header.Get("Content-Type"))
+	}
+	expected := `[{"op":"replace","path":"/IsCompleted","value":true},{"op":"replace","path":"/Title","value":"b"}]`
+	if body := server.lastBody(); body != expected {
+		t.Errorf("Expected the body to be %s but got %s", expected, body)
+	}
+
+	if err := client.Update(&todo); err != ErrOriginalRequired {
+		t.Errorf("Expected ErrOriginalRequired but got %v", err)
+	}
+	if err := client.Update(&todo, WithPatchMode(PatchFields)); err != nil {
+		t.Fatal(err)
+	}
+	if req := server.last(); req.Header.Get("Content-Type") != string(ContentURLEncoded) {
+		t.Errorf("Expected WithPatchMode to override the PatchMode of the client but got %s", req.Header.Get("Content-Type"))
+	}
+}
+
+func TestUpdateMergePatch(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, "{}")
+	client := NewClient()
+	original := testTodo{DefaultId: DefaultId{Id: "1"}, Title: "a"}
+	todo := original
+	todo.Title = "b"
+	if err := client.Update(&todo, WithPatchMode(PatchMergePatch), WithOriginal(&original)); err != nil {
+		t.Fatal(err)
+	}
+	if req := server.last(); req.Header.Get("Content-Type") != string(ContentMergePatch) {
+		t.Errorf("Expected a merge patch but got %s", req.Header.Get("Content-Type"))
+	}
+	if body := server.lastBody(); body != `{"Title":"b"}` {
+		t.Errorf("Expected only the changed fields but got %s", body)
+	}
+	if err := client.Update(&todo, WithPatchMode(PatchMergePatch)); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != `{"Id":"1","Title":"b","IsCompleted":false}` {
+		t.Errorf("Expected all of the fields without WithOriginal but got %s", body)
+	}
+	if err := client.Update(&todo, WithPatchMode("xml-patch")); err == nil {
+		t.Error("Expected an error for an unknown PatchMode")
+	}
+}
+
+func TestPatchNestedChanges(t *testing.T) {
+	changes := []Change{
+		{Kind: FieldAdded, Path: []string{"Author", "Name"}, New: "x"},
+		{Kind: FieldRemoved, Path: []string{"Author", "a/b"}, Old: "y"},
+		{Kind: FieldModified, Path: []string{"Title"}, Old: "a", New: "b"},
+	}
+	ops, _ := json.Marshal(jsonPatch(changes))
+	expectedOps := `[{"op":"add","path":"/Author/Name","value":"x"},{"op":"remove","path":"/Author/a~1b"},{"op":"replace","path":"/Title","value":"b"}]`
+	if string(ops) != expectedOps {
+		t.Errorf("Expected the operations %s but got %s", expectedOps, ops)
+	}
+	expectedPatch := map[string]interface{}{
+		"Author": map[string]interface{}{"Name": "x", "a/b": nil},
+		"Title":  "b",
+	}
+	if patch := mergePatch(changes); !reflect.DeepEqual(patch, expectedPatch) {
+		t.Errorf("Expected the merge patch %v but got %v", expectedPatch, patch)
+	}
+}
+
+// patchNote is a model with a field which can be null.
+type patchNote struct {
+	DefaultId
+	Note *string
+}
+
+func (*patchNote) RootURL() string { return testRootURL + "/notes" }
+
+func TestJSONPatchNullValue(t *testing.T) {
+	note := "a"
+	original := &patchNote{DefaultId: DefaultId{Id: "1"}, Note: &note}
+	model := &patchNote{DefaultId: DefaultId{Id: "1"}}
+	body, _, err := encodePatch(PatchJSONPatch, original, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"op":"replace","path":"/Note","value":null}]`
+	if body != expected {
+		t.Errorf("Expected the body to be %s but got %s", expected, body)
+	}
+
+	ops, _ := json.Marshal(jsonPatch([]Change{{Kind: FieldAdded, Path: []string{"Note"}}}))
+	if expected := `[{"op":"add","path":"/Note","value":null}]`; string(ops) != expected {
+		t.Errorf("Expected the operations %s but got %s", expected, ops)
+	}
+}
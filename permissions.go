@@ -0,0 +1,146 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ForbiddenError is returned instead of sending a request when the client
+// remembers that the server responded to the same method and url with 403
+// Forbidden. See Client.RememberForbidden.
+type ForbiddenError struct {
+	// Method is the http method of the request, e.g. "DELETE"
+	Method string
+	// URL is the url the request would have been sent to
+	URL string
+}
+
+// Error satisfies the error interface
+func (e ForbiddenError) Error() string {
+	return fmt.Sprintf("rest: %s request to %s is forbidden (remembered from an earlier 403 response)", e.Method, e.URL)
+}
+
+// IsForbidden returns true iff err is a ForbiddenError or an HTTPError with
+// the status code 403.
+func IsForbidden(err error) bool {
+	switch e := err.(type) {
+	case ForbiddenError:
+		return true
+	case HTTPError:
+		return e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// Permitted returns true unless the client knows that method is not permitted
+// for model, either because it remembers a 403 response to the same method
+// and url (see RememberForbidden), or because the cached result of
+// Capabilities for the resource does not allow method. Permitted never sends
+// a request, so it is cheap enough to decide whether to show e.g. a delete
+// button each time a view is rendered. Use RefreshPermissions to forget what
+// the client knows.
+func (c *Client) Permitted(model Model, method string) bool {
+	method = strings.ToUpper(method)
+	rootURL, err := normalizeRootURL(model.RootURL())
+	if err != nil {
+		return true
+	}
+	rootURL = c.expandVars(rootURL)
+	keys := []string{forbiddenKey(method, rootURL)}
+	if fullURL, err := c.urlForModel(model); err == nil && fullURL != rootURL {
+		keys = append(keys, forbiddenKey(method, c.expandVars(fullURL)))
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	for _, key := range keys {
+		if c.forbidden[key] {
+			return false
+		}
+	}
+	if caps, found := c.capabilities[rootURL]; found && len(caps.Methods) > 0 {
+		return caps.Allows(method)
+	}
+	return true
+}
+
+// RefreshPermissions forgets the remembered 403 responses and the cached
+// capabilities of the given models, or of all resources if no models are
+// given. It should be called whenever the permissions of the user may have
+// changed, e.g. after logging in as someone else.
+func (c *Client) RefreshPermissions(models ...Model) {
+	c.ForgetCapabilities(models...)
+	prefixes := []string{}
+	for _, model := range models {
+		if rootURL, err := normalizeRootURL(model.RootURL()); err == nil {
+			prefixes = append(prefixes, resourceURL(c.expandVars(rootURL)))
+		}
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if len(models) == 0 {
+		c.forbidden = nil
+		return
+	}
+	for key := range c.forbidden {
+		for _, prefix := range prefixes {
+			target := key[strings.Index(key, " ")+1:]
+			if target == prefix || strings.HasPrefix(target, prefix+"/") {
+				delete(c.forbidden, key)
+				break
+			}
+		}
+	}
+}
+
+// checkForbidden returns a ForbiddenError if c.RememberForbidden is true and
+// the client remembers a 403 response to the method and url of req.
+func (c *Client) checkForbidden(req *http.Request) error {
+	if !c.RememberForbidden {
+		return nil
+	}
+	c.mut.RLock()
+	forbidden := c.forbidden[forbiddenKey(req.Method, req.URL.String())]
+	c.mut.RUnlock()
+	if forbidden {
+		return ForbiddenError{Method: req.Method, URL: req.URL.String()}
+	}
+	return nil
+}
+
+// rememberForbidden records that the server responded to the method and url
+// of req with 403 Forbidden, if c.RememberForbidden is true.
+func (c *Client) rememberForbidden(req *http.Request) {
+	if !c.RememberForbidden {
+		return
+	}
+	c.mut.Lock()
+	if c.forbidden == nil {
+		c.forbidden = map[string]bool{}
+	}
+	c.forbidden[forbiddenKey(req.Method, req.URL.String())] = true
+	c.mut.Unlock()
+}
+
+// forbiddenKey returns the key under which a 403 response to method and
+// rawURL is remembered.
+func forbiddenKey(method string, rawURL string) string {
+	return strings.ToUpper(method) + " " + resourceURL(rawURL)
+}
+
+// resourceURL returns rawURL without its query, fragment, and trailing slash,
+// so that e.g. paginated requests for a forbidden collection are treated as
+// the same resource.
+func resourceURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.RawQuery = ""
+		u.Fragment = ""
+		rawURL = u.String()
+	}
+	return strings.TrimSuffix(rawURL, "/")
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// newForbiddingServer starts a server which forbids deleting the todo with id
+// 1 and reading the collection of todos, and allows everything else.
+func newForbiddingServer(t *testing.T) *requestLog {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		switch {
+		case r.Method == "DELETE" && r.URL.Path == "/todos/1",
+			r.Method == "GET" && r.URL.Path == "/todos":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case r.Method == "OPTIONS":
+			w.Header().Set("Allow", "GET, POST")
+		default:
+			w.Write([]byte(`{}`))
+		}
+	})
+	return requests
+}
+
+func TestRememberForbidden(t *testing.T) {
+	requests := newForbiddingServer(t)
+	client := NewClient()
+	client.RememberForbidden = true
+	todo := &testTodo{DefaultId: DefaultId{Id: "1"}}
+	err := client.Delete(todo)
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusForbidden || !IsForbidden(err) {
+		t.Fatalf("Expected an HTTPError with status 403 but got %v", err)
+	}
+	err = client.Delete(todo)
+	if forbiddenErr, ok := err.(ForbiddenError); !ok || forbiddenErr.Method != "DELETE" || !IsForbidden(err) {
+		t.Errorf("Expected a ForbiddenError but got %v", err)
+	}
+	if n := len(requests.all()); n != 1 {
+		t.Errorf("Expected the forbidden request not to be sent again but got %d requests", n)
+	}
+	if err := client.Read("1", todo); err != nil {
+		t.Errorf("Expected other methods to be unaffected but got %v", err)
+	}
+	if err := client.Delete(&testTodo{DefaultId: DefaultId{Id: "2"}}); err != nil {
+		t.Errorf("Expected other urls to be unaffected but got %v", err)
+	}
+
+	client.ReadAll(&[]*testTodo{})
+	if err := client.ReadAll(&[]*testTodo{}, WithQuery(Query{"page": {"2"}})); !IsForbidden(err) {
+		t.Errorf("Expected the query to be ignored but got %v", err)
+	} else if _, ok := err.(ForbiddenError); !ok {
+		t.Errorf("Expected a ForbiddenError but got %v", err)
+	}
+	if n := len(requests.all()); n != 4 {
+		t.Errorf("Expected 4 requests but got %d", n)
+	}
+
+	client.RefreshPermissions(todo)
+	if err := client.Delete(todo); err == nil || len(requests.all()) != 5 {
+		t.Errorf("Expected RefreshPermissions to cause the request to be sent again but got %v", err)
+	}
+	client.RefreshPermissions()
+	client.ReadAll(&[]*testTodo{})
+	if n := len(requests.all()); n != 6 {
+		t.Errorf("Expected RefreshPermissions to forget everything but got %d requests", n)
+	}
+
+	client.RememberForbidden = false
+	client.Delete(todo)
+	if n := len(requests.all()); n != 7 {
+		t.Errorf("Expected nothing to be short-circuited without RememberForbidden but got %d requests", n)
+	}
+}
+
+func TestPermitted(t *testing.T) {
+	newForbiddingServer(t)
+	client := NewClient()
+	client.RememberForbidden = true
+	first := &testTodo{DefaultId: DefaultId{Id: "1"}}
+	second := &testTodo{DefaultId: DefaultId{Id: "2"}}
+	client.Delete(first)
+	if client.Permitted(first, "delete") {
+		t.Error("Expected deleting the first todo not to be permitted")
+	}
+	if !client.Permitted(second, "DELETE") || !client.Permitted(first, "PATCH") {
+		t.Error("Expected other urls and methods to be permitted")
+	}
+
+	if _, err := client.Capabilities(second); err != nil {
+		t.Fatal(err)
+	}
+	if client.Permitted(second, "DELETE") || !client.Permitted(second, "GET") {
+		t.Error("Expected the capabilities of the resource to be taken into account")
+	}
+	client.RefreshPermissions(second)
+	if !client.Permitted(first, "DELETE") || !client.Permitted(second, "DELETE") {
+		t.Error("Expected RefreshPermissions to forget the 403 response and the capabilities")
+	}
+}
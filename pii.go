@@ -0,0 +1,237 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// PIIPolicy controls the masking of personally identifiable information, i.e.
+// of the fields of models tagged with the "pii" option:
+//
+//	type User struct {
+//		rest.DefaultId
+//		Name  string
+//		Email string `rest:",pii" json:"email"`
+//	}
+//
+// When a client has a PIIPolicy, the values of such fields are masked in the
+// bodies of HTTPErrors and in MirrorResults, so that they do not leak into
+// logs and error trackers. With MaskRequests, they are also masked in the
+// bodies of requests to servers which are not listed in Production, so that
+// real user data is never sent to test systems.
+type PIIPolicy struct {
+	// Mask replaces masked string values. Other values are replaced by null.
+	// The default is "***".
+	Mask string
+	// Fields lists additional keys to mask regardless of the model, e.g.
+	// "email" or "phone". Keys are matched case-insensitively, the same way
+	// encoding/json matches keys to fields.
+	Fields []string
+	// MaskRequests causes PII to be masked in the bodies of JSON and
+	// url-encoded requests to servers which are not listed in Production.
+	MaskRequests bool
+	// Production lists the base urls of production servers, e.g.
+	// "https://api.example.com/". Requests to urls which start with any of
+	// them are never masked.
+	Production []string
+}
+
+// mask returns the string which replaces masked string values.
+func (policy *PIIPolicy) mask() string {
+	if policy.Mask == "" {
+		return "***"
+	}
+	return policy.Mask
+}
+
+// isProduction returns true iff rawURL belongs to one of the production
+// servers of policy.
+func (policy *PIIPolicy) isProduction(rawURL string) bool {
+	for _, prefix := range policy.Production {
+		if strings.HasPrefix(rawURL, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskPII returns a copy of body, which is a JSON document or url-encoded
+// form data, in which the values of the fields of model tagged with the
+// "pii" option are masked, along with any keys listed in c.PII.Fields. model
+// may be a model, a slice of models, or a pointer to either, and is only
+// used for its type. It can be used to keep PII out of application logs.
+// Bodies which are neither valid JSON nor form data are returned unchanged.
+func (c *Client) MaskPII(body []byte, model interface{}) []byte {
+	policy := c.PII
+	if policy == nil {
+		policy = &PIIPolicy{}
+	}
+	return maskPII(body, policy.keys(model), policy.mask())
+}
+
+// maskPII returns a copy of body in which the values of keys are replaced by
+// mask.
+func maskPII(body []byte, keys []string, mask string) []byte {
+	if len(keys) == 0 || len(body) == 0 {
+		return body
+	}
+	if json.Valid(body) {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil || !maskJSONValue(doc, keys, mask) {
+			return body
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(doc); err != nil {
+			return body
+		}
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+	masked := false
+	for key := range values {
+		if containsFold(keys, key) {
+			for i := range values[key] {
+				values[key][i] = mask
+			}
+			masked = true
+		}
+	}
+	if !masked {
+		// Leave bodies which merely look like form data (e.g. plain text)
+		// alone
+		return body
+	}
+	return []byte(values.Encode())
+}
+
+// maskJSONValue masks the values of keys in the decoded JSON value v, at any
+// depth, and returns true iff anything was masked.
+func maskJSONValue(v interface{}, keys []string, mask string) bool {
+	masked := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if !containsFold(keys, key) {
+				masked = maskJSONValue(value, keys, mask) || masked
+				continue
+			}
+			if _, ok := value.(string); ok {
+				v[key] = mask
+			} else {
+				v[key] = nil
+			}
+			masked = true
+		}
+	case []interface{}:
+		for _, elem := range v {
+			masked = maskJSONValue(elem, keys, mask) || masked
+		}
+	}
+	return masked
+}
+
+// keys returns the keys to mask for model: the names (both in url-encoded
+// and JSON form) of the fields of its type tagged with the "pii" option, and
+// policy.Fields.
+func (policy *PIIPolicy) keys(model interface{}) []string {
+	var keys []string
+	if model != nil {
+		keys = append(keys, piiFields(reflect.TypeOf(model))...)
+	}
+	return append(keys, policy.Fields...)
+}
+
+var (
+	// piiFieldsCache holds the result of piiFields for each type.
+	piiFieldsCache   = map[reflect.Type][]string{}
+	piiFieldsCacheMu sync.RWMutex
+)
+
+// piiFields returns the keys of the fields tagged with the "pii" option in
+// typ, which may be a struct or a pointer, slice, array, or map of them.
+// Nested and embedded structs are searched as well.
+func piiFields(typ reflect.Type) []string {
+	piiFieldsCacheMu.RLock()
+	keys, found := piiFieldsCache[typ]
+	piiFieldsCacheMu.RUnlock()
+	if found {
+		return keys
+	}
+	keys = collectPIIFields(typ, nil, map[reflect.Type]bool{})
+	piiFieldsCacheMu.Lock()
+	piiFieldsCache[typ] = keys
+	piiFieldsCacheMu.Unlock()
+	return keys
+}
+
+// collectPIIFields appends the keys of the fields tagged with the "pii"
+// option in typ to keys. visited guards against recursive types.
+func collectPIIFields(typ reflect.Type, keys []string, visited map[reflect.Type]bool) []string {
+	for i := 0; i < maxIndirections && isContainerKind(typ.Kind()); i++ {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || visited[typ] {
+		return keys
+	}
+	visited[typ] = true
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if _, opts := parseTag(field); opts.Contains("pii") {
+			keys = append(keys, field.Name)
+			if name, _ := splitTag(field.Tag.Get("json")); name != "" && name != "-" {
+				keys = append(keys, name)
+			}
+			continue
+		}
+		keys = collectPIIFields(field.Type, keys, visited)
+	}
+	return keys
+}
+
+// isContainerKind returns true iff values of the given kind hold other values
+// which may contain PII.
+func isContainerKind(kind reflect.Kind) bool {
+	return kind == reflect.Ptr || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+// maskRequestBody returns data, the body of a request to fullURL with the
+// given content type which encodes model, with PII masked if c.PII requires
+// it.
+func (c *Client) maskRequestBody(fullURL string, data []byte, contentType ContentType, model interface{}) []byte {
+	policy := c.PII
+	if policy == nil || !policy.MaskRequests || policy.isProduction(fullURL) {
+		return data
+	}
+	mediaType, _, err := mime.ParseMediaType(string(contentType))
+	if err != nil || (mediaType != string(ContentJSON) && mediaType != string(ContentURLEncoded)) {
+		return data
+	}
+	return maskPII(data, policy.keys(model), policy.mask())
+}
+
+// maskError masks PII in the body of err if it is an HTTPError and c has a
+// PIIPolicy. model is the model the response was meant for.
+func (c *Client) maskError(err error, model interface{}) error {
+	httpErr, ok := err.(HTTPError)
+	if !ok || c.PII == nil {
+		return err
+	}
+	httpErr.Body = maskPII(httpErr.Body, c.PII.keys(model), c.PII.mask())
+	return httpErr
+}
@@ -0,0 +1,187 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// piiUser is a model with fields tagged with the "pii" option, including one
+// in a nested struct.
+type piiUser struct {
+	DefaultId
+	Name    string
+	Email   string `rest:",pii" json:"email"`
+	Age     int    `rest:",pii"`
+	Address piiAddress
+}
+
+type piiAddress struct {
+	City   string
+	Street string `rest:",pii"`
+}
+
+func (u *piiUser) RootURL() string {
+	return testRootURL + "/users"
+}
+
+func TestMaskPII(t *testing.T) {
+	client := NewClient()
+	tests := []struct {
+		name  string
+		body  string
+		model interface{}
+		want  string
+	}{
+		{
+			name:  "JSON",
+			body:  `{"Id":"1","Name":"Alice","email":"alice@example.com","Age":30,"Address":{"City":"Paris","Street":"1 Rue"}}`,
+			model: &piiUser{},
+			want:  `{"Address":{"City":"Paris","Street":"***"},"Age":null,"Id":"1","Name":"Alice","email":"***"}`,
+		},
+		{
+			name:  "JSON array",
+			body:  `[{"Name":"Alice","EMAIL":"a@example.com"},{"Name":"Bob","email":"b@example.com"}]`,
+			model: []*piiUser{},
+			want:  `[{"EMAIL":"***","Name":"Alice"},{"Name":"Bob","email":"***"}]`,
+		},
+		{
+			name:  "url-encoded",
+			body:  "Email=alice%40example.com&Name=Alice",
+			model: &piiUser{},
+			want:  "Email=%2A%2A%2A&Name=Alice",
+		},
+		{
+			name:  "nothing to mask",
+			body:  `{"Name": "Alice"}`,
+			model: &piiUser{},
+			want:  `{"Name": "Alice"}`,
+		},
+		{
+			name:  "plain text",
+			body:  "Not found",
+			model: &piiUser{},
+			want:  "Not found",
+		},
+		{
+			name:  "model without PII",
+			body:  `{"Title": "a"}`,
+			model: &testTodo{},
+			want:  `{"Title": "a"}`,
+		},
+	}
+	for _, test := range tests {
+		if got := string(client.MaskPII([]byte(test.body), test.model)); got != test.want {
+			t.Errorf("%s: Expected %s but got %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestMaskPIIFieldsAndMask(t *testing.T) {
+	client := NewClient()
+	client.PII = &PIIPolicy{Mask: "[redacted]", Fields: []string{"phone"}}
+	got := string(client.MaskPII([]byte(`{"Phone":"555-1234","email":"a@example.com","Title":"a"}`), &piiUser{}))
+	want := `{"Phone":"[redacted]","Title":"a","email":"[redacted]"}`
+	if got != want {
+		t.Errorf("Expected %s but got %s", want, got)
+	}
+	got = string(client.MaskPII([]byte(`{"phone":"555-1234"}`), nil))
+	if want := `{"phone":"[redacted]"}`; got != want {
+		t.Errorf("Expected Fields to be masked without a model: expected %s but got %s", want, got)
+	}
+}
+
+func TestPIIInHTTPErrors(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error":"invalid","email":"alice@example.com"}`))
+	})
+	client := NewClient()
+	err := client.Read("1", &piiUser{})
+	if httpErr, ok := err.(HTTPError); !ok || string(httpErr.Body) != `{"error":"invalid","email":"alice@example.com"}` {
+		t.Errorf("Expected the body to be left alone without a PIIPolicy but got %v", err)
+	}
+
+	client.PII = &PIIPolicy{}
+	err = client.Read("1", &piiUser{})
+	httpErr, ok := err.(HTTPError)
+	if !ok {
+		t.Fatalf("Expected an HTTPError but got %T: %v", err, err)
+	}
+	if want := `{"email":"***","error":"invalid"}`; string(httpErr.Body) != want {
+		t.Errorf("Expected the error body to be %s but got %s", want, httpErr.Body)
+	}
+}
+
+func TestPIIMaskRequests(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	client := NewClient()
+	client.ContentType = ContentJSON
+	client.PII = &PIIPolicy{MaskRequests: true, Production: []string{"https://api.example.com/"}}
+	user := &piiUser{Name: "Alice", Email: "alice@example.com", Address: piiAddress{City: "Paris", Street: "1 Rue"}}
+	user.Id = "1"
+	sent := func() map[string]interface{} {
+		t.Helper()
+		if err := client.Update(user); err != nil {
+			t.Fatal(err)
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(server.lastBody()), &fields); err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+
+	fields := sent()
+	if got := fields["email"]; got != "***" {
+		t.Errorf("Expected email to be masked in a request to a test server but got %v", got)
+	}
+	if got := fields["Address"].(map[string]interface{})["Street"]; got != "***" {
+		t.Errorf("Expected Address.Street to be masked in a request to a test server but got %v", got)
+	}
+	if got := fields["Name"]; got != "Alice" {
+		t.Errorf("Expected Name not to be masked but got %v", got)
+	}
+	if user.Email != "alice@example.com" {
+		t.Errorf("Expected the model to be left alone but Email is %q", user.Email)
+	}
+
+	client.PII.Production = append(client.PII.Production, testRootURL)
+	if got := sent()["email"]; got != "alice@example.com" {
+		t.Errorf("Expected email not to be masked in a request to a production server but got %v", got)
+	}
+
+	client.PII = &PIIPolicy{}
+	if got := sent()["email"]; got != "alice@example.com" {
+		t.Errorf("Expected requests not to be masked without MaskRequests but email was %v", got)
+	}
+}
+
+func TestPIIInMirrorResults(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"1","email":"alice@example.com"}`))
+	})
+	results := make(chan MirrorResult, 1)
+	client := NewClient()
+	client.PII = &PIIPolicy{}
+	client.Mirror = &MirrorPolicy{
+		BaseURL:  testRootURL,
+		Percent:  100,
+		OnResult: func(result MirrorResult) { results <- result },
+	}
+	if err := client.Read("1", &piiUser{}); err != nil {
+		t.Fatal(err)
+	}
+	result := waitForMirror(t, results)
+	want := `{"Id":"1","email":"***"}`
+	if string(result.Body) != want || string(result.MirrorBody) != want {
+		t.Errorf("Expected both bodies to be %s but got %s and %s", want, result.Body, result.MirrorBody)
+	}
+	if !result.Match {
+		t.Error("Expected the responses to match")
+	}
+}
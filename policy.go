@@ -0,0 +1,28 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrMethodNotAllowedByPolicy is returned when the client is asked to send a
+// request with an http method which is not in its AllowedMethods.
+var ErrMethodNotAllowedByPolicy = errors.New("rest: http method not allowed by client policy")
+
+// checkMethodAllowed returns ErrMethodNotAllowedByPolicy if method is not in
+// c.AllowedMethods. All methods are allowed if c.AllowedMethods is empty.
+func (c *Client) checkMethodAllowed(method string) error {
+	if len(c.AllowedMethods) == 0 {
+		return nil
+	}
+	for _, allowed := range c.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return nil
+		}
+	}
+	return ErrMethodNotAllowedByPolicy
+}
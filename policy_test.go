@@ -0,0 +1,42 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAllowedMethods(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.AllowedMethods = []string{"get", "HEAD"}
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Errorf("Expected GET to be allowed case-insensitively but got %v", err)
+	}
+	todo := &testTodo{DefaultId: DefaultId{Id: "1"}}
+	writes := map[string]func() error{
+		"Create": func() error { return client.Create(&testTodo{}) },
+		"Update": func() error { return client.Update(todo) },
+		"Put":    func() error { return client.Put(todo) },
+		"Delete": func() error { return client.Delete(todo) },
+		"Upload": func() error {
+			return client.Upload(testRootURL+"/files", strings.NewReader("a"), 1, "text/plain", nil)
+		},
+	}
+	for name, write := range writes {
+		if err := write(); err != ErrMethodNotAllowedByPolicy {
+			t.Errorf("Expected %s to return ErrMethodNotAllowedByPolicy but got %v", name, err)
+		}
+	}
+	client.AllowedMethods = []string{"POST"}
+	if err := client.Download(testRootURL+"/todos/1", &bytes.Buffer{}); err != ErrMethodNotAllowedByPolicy {
+		t.Errorf("Expected Download to return ErrMethodNotAllowedByPolicy but got %v", err)
+	}
+	if got := server.Requests(); len(got) != 1 {
+		t.Errorf("Expected only the read to be sent but got %v", got)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// FieldEncoder converts a field value to the string that should be used for
+// it in url-encoded data. It is only used when the client's ContentType is
+// ContentURLEncoded. Fields encoded as JSON use the json package as usual,
+// so types which need special handling should implement json.Marshaler.
+type FieldEncoder func(value reflect.Value) (string, error)
+
+var (
+	fieldEncoders   = map[reflect.Type]FieldEncoder{}
+	fieldEncodersMu sync.RWMutex
+)
+
+// RegisterFieldEncoder registers encoder as the FieldEncoder for all fields of
+// the given type. It can be used to add url-encoding support for types the
+// rest package does not know about (e.g. a third-party decimal type which
+// does not implement encoding.TextMarshaler), or to change how an existing
+// type is encoded. Registering an encoder for a type that already has one
+// replaces the old encoder.
+func RegisterFieldEncoder(typ reflect.Type, encoder FieldEncoder) {
+	fieldEncodersMu.Lock()
+	defer fieldEncodersMu.Unlock()
+	fieldEncoders[typ] = encoder
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// encodeCustomString encodes value using a registered FieldEncoder or its
+// MarshalText method, checking each level of indirection in turn. The second
+// return value is false if neither applies, in which case the caller should
+// fall back to the default encoding. Values which cannot be accessed through
+// reflection (fields promoted from unexported embedded structs) are never
+// passed to custom encoders.
+func encodeCustomString(value reflect.Value) (string, bool, error) {
+	if !value.CanInterface() {
+		return "", false, nil
+	}
+	for i := 0; i <= maxIndirections; i++ {
+		fieldEncodersMu.RLock()
+		encoder, found := fieldEncoders[value.Type()]
+		fieldEncodersMu.RUnlock()
+		if found {
+			str, err := encoder(value)
+			return str, true, err
+		}
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return "", false, nil
+			}
+			if value.Type().Implements(textMarshalerType) {
+				return marshalText(value)
+			}
+			value = value.Elem()
+			continue
+		}
+		if value.Type().Implements(textMarshalerType) {
+			return marshalText(value)
+		}
+		if reflect.PtrTo(value.Type()).Implements(textMarshalerType) {
+			// MarshalText has a pointer receiver (as is the case for the types
+			// in math/big) so we need an addressable copy of the value.
+			ptr := reflect.New(value.Type())
+			ptr.Elem().Set(value)
+			return marshalText(ptr)
+		}
+		return "", false, nil
+	}
+	return "", false, nil
+}
+
+// marshalText calls MarshalText on value, which must implement
+// encoding.TextMarshaler.
+func marshalText(value reflect.Value) (string, bool, error) {
+	text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", true, err
+	}
+	return string(text), true, nil
+}
+
+var (
+	// moneyWarned keeps track of the model types which we have already warned
+	// about, so that each type is only reported once.
+	moneyWarned   = map[reflect.Type]bool{}
+	moneyWarnedMu sync.Mutex
+)
+
+// checkMoneyFields logs a warning for each float field of model which is
+// tagged as money, including the fields of embedded structs. It does nothing
+// unless c.WarnFloatMoney is true.
+func (c *Client) checkMoneyFields(model Model) {
+	if !c.WarnFloatMoney {
+		return
+	}
+	typ := reflect.TypeOf(model)
+	if typ != nil {
+		typ = derefType(typ)
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return
+	}
+	moneyWarnedMu.Lock()
+	defer moneyWarnedMu.Unlock()
+	if moneyWarned[typ] {
+		return
+	}
+	moneyWarned[typ] = true
+	warnFloatMoney(typ, typ, map[reflect.Type]bool{})
+}
+
+// warnFloatMoney logs a warning for each float field of the struct type
+// structType, which is embedded in the model type typ, that is tagged as
+// money. visited holds the struct types which have already been checked, so
+// that recursive embedding through pointers terminates.
+func warnFloatMoney(typ reflect.Type, structType reflect.Type, visited map[reflect.Type]bool) {
+	if visited[structType] {
+		return
+	}
+	visited[structType] = true
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldType := derefType(field.Type)
+		if field.Anonymous && fieldType.Kind() == reflect.Struct {
+			warnFloatMoney(typ, fieldType, visited)
+			continue
+		}
+		if _, opts := parseTag(field); !opts.Contains("money") {
+			continue
+		}
+		if fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64 {
+			log.Printf("rest: warning: %s.%s is tagged as money but has type %s, which cannot represent decimal amounts exactly. Consider using a decimal type or *big.Rat instead.", typ.String(), field.Name, field.Type.String())
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// precisionBase is embedded in precisionInvoice, so that its money field is
+// promoted.
+type precisionBase struct {
+	Fee float32 `rest:",money"`
+}
+
+// precisionInvoice is a model with float fields tagged as money, directly and
+// in an embedded struct, and fields which are not.
+type precisionInvoice struct {
+	DefaultId
+	*precisionBase
+	Total    float64  `rest:",money"`
+	Discount *float64 `rest:",money"`
+	Exact    *big.Rat `rest:",money"`
+	Weight   float64
+}
+
+func (*precisionInvoice) RootURL() string { return testRootURL + "/invoices" }
+
+// captureLog redirects the standard logger to a buffer for the rest of the
+// test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+	})
+	return buf
+}
+
+func TestWarnFloatMoney(t *testing.T) {
+	buf := captureLog(t)
+	t.Cleanup(func() {
+		moneyWarnedMu.Lock()
+		delete(moneyWarned, reflect.TypeOf(precisionInvoice{}))
+		moneyWarnedMu.Unlock()
+	})
+	client := NewClient()
+	client.checkMoneyFields(&precisionInvoice{})
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no warnings unless WarnFloatMoney is set but got %q", buf.String())
+	}
+	client.WarnFloatMoney = true
+	client.checkMoneyFields(&precisionInvoice{})
+	for _, field := range []string{"Total", "Discount", "Fee"} {
+		if !strings.Contains(buf.String(), "rest.precisionInvoice."+field+" is tagged as money") {
+			t.Errorf("Expected a warning for %s but got %q", field, buf.String())
+		}
+	}
+	for _, field := range []string{"Exact", "Weight"} {
+		if strings.Contains(buf.String(), "."+field+" ") {
+			t.Errorf("Expected no warning for %s but got %q", field, buf.String())
+		}
+	}
+	if n := strings.Count(buf.String(), "rest: warning"); n != 3 {
+		t.Errorf("Expected 3 warnings but got %d", n)
+	}
+
+	buf.Reset()
+	client.checkMoneyFields(&precisionInvoice{})
+	if buf.Len() != 0 {
+		t.Errorf("Expected each type to be reported only once but got %q", buf.String())
+	}
+}
+
+// precisionCents is an amount of money in cents, which has no MarshalText
+// method.
+type precisionCents int64
+
+// precisionOrder is a model with fields which are url-encoded with a
+// registered FieldEncoder or their MarshalText method.
+type precisionOrder struct {
+	DefaultId
+	Price    precisionCents
+	Shipping *precisionCents
+	Rate     *big.Rat
+	Amount   big.Int
+	Placed   time.Time
+	Missing  *big.Rat
+}
+
+func (*precisionOrder) RootURL() string { return testRootURL + "/orders" }
+
+func TestRegisterFieldEncoder(t *testing.T) {
+	typ := reflect.TypeOf(precisionCents(0))
+	RegisterFieldEncoder(typ, func(value reflect.Value) (string, error) {
+		cents := value.Int()
+		return fmt.Sprintf("%d.%02d", cents/100, cents%100), nil
+	})
+	t.Cleanup(func() {
+		fieldEncodersMu.Lock()
+		delete(fieldEncoders, typ)
+		fieldEncodersMu.Unlock()
+	})
+	shipping := precisionCents(5)
+	encoded, err := urlEncodeFields(&precisionOrder{Price: 1234, Shipping: &shipping})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := values.Get("Price"); got != "12.34" {
+		t.Errorf("Expected Price to be encoded with the FieldEncoder but got %q", got)
+	}
+	if got := values.Get("Shipping"); got != "0.05" {
+		t.Errorf("Expected the FieldEncoder to be used through a pointer but got %q", got)
+	}
+
+	RegisterFieldEncoder(typ, func(value reflect.Value) (string, error) {
+		return "", fmt.Errorf("cannot encode %d", value.Int())
+	})
+	if _, err := urlEncodeFields(&precisionOrder{Price: 1}); err == nil || !strings.Contains(err.Error(), "cannot encode 1") {
+		t.Errorf("Expected the error of the replaced FieldEncoder but got %v", err)
+	}
+}
+
+func TestTextMarshalerEncoding(t *testing.T) {
+	order := &precisionOrder{
+		Rate:   big.NewRat(1, 3),
+		Placed: time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	order.Amount.SetString("123456789012345678901234567890", 10)
+	encoded, err := urlEncodeFields(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"Price":  "0",
+		"Rate":   "1/3",
+		"Amount": "123456789012345678901234567890",
+		"Placed": "2015-06-01T12:00:00Z",
+	}
+	for field, want := range expected {
+		if got := values.Get(field); got != want {
+			t.Errorf("Expected %s to be encoded as %q but got %q", field, want, got)
+		}
+	}
+	for _, field := range []string{"Shipping", "Missing"} {
+		if _, found := values[field]; found {
+			t.Errorf("Expected the nil field %s to be skipped but got %q", field, values.Get(field))
+		}
+	}
+}
+
+// precisionPayment is only used by TestWarnFloatMoneyPut, so that it has not
+// been reported before.
+type precisionPayment struct {
+	DefaultId
+	Amount float64 `rest:",money"`
+}
+
+func (*precisionPayment) RootURL() string { return testRootURL + "/payments" }
+
+func TestWarnFloatMoneyPut(t *testing.T) {
+	newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	buf := captureLog(t)
+	t.Cleanup(func() {
+		moneyWarnedMu.Lock()
+		delete(moneyWarned, reflect.TypeOf(precisionPayment{}))
+		moneyWarnedMu.Unlock()
+	})
+	client := NewClient()
+	client.WarnFloatMoney = true
+	if err := client.Put(&precisionPayment{DefaultId: DefaultId{Id: "1"}, Amount: 9.99}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "rest.precisionPayment.Amount is tagged as money") {
+		t.Errorf("Expected Put to warn about Amount but got %q", buf.String())
+	}
+}
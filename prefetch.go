@@ -0,0 +1,76 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+)
+
+// Prefetch fetches the given resources in the background and stores the
+// responses in the client's Cache, so that subsequent calls to Read or
+// ReadAll for the same resources can be served without waiting for the
+// network. Each target may be a url (string), a Model (in which case the
+// url for model.ModelId() is fetched), or a pointer to a slice of models (in
+// which case the url used by ReadAll is fetched).
+//
+// Prefetch returns immediately. Resources are fetched one at a time so that
+// prefetching does not compete with more important requests, and resources
+// which are already cached are skipped. Errors are ignored, since a failed
+// prefetch just means a later request will go to the network. Canceling ctx
+// stops any remaining prefetches. Prefetch does nothing if the client does not
+// have a Cache.
+func (c *Client) Prefetch(ctx context.Context, targets ...interface{}) {
+	if c.Cache == nil {
+		return
+	}
+	urls := []string{}
+	for _, target := range targets {
+		switch t := target.(type) {
+		case string:
+			urls = append(urls, t)
+		case Model:
+			if url, err := c.urlForModel(t); err == nil {
+				urls = append(urls, url)
+			}
+		default:
+			if rootURL, err := getURLFromModels(target); err == nil {
+				urls = append(urls, rootURL)
+			}
+		}
+	}
+	go func() {
+		for _, url := range urls {
+			if ctx.Err() != nil {
+				return
+			}
+			if _, found := c.cachedBody(url); found {
+				continue
+			}
+			c.prefetch(ctx, url)
+		}
+	}()
+}
+
+// prefetch sends a GET request to url and stores the response in the cache if
+// it was successful.
+func (c *Client) prefetch(ctx context.Context, url string) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	res, err := c.do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	body, err := c.readResponse(res)
+	if err != nil {
+		return
+	}
+	c.storeCache(url, body)
+}
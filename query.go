@@ -0,0 +1,240 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query holds parameters which are used to filter a collection of models on
+// the server. It is encoded into the query string of the request url, so e.g.
+// Query{"IsCompleted": {"true"}} results in a request to
+// "/todos?IsCompleted=true".
+type Query url.Values
+
+// Encode encodes the query into url-encoded form ("bar=baz&foo=quux"), sorted
+// by key.
+func (q Query) Encode() string {
+	return url.Values(q).Encode()
+}
+
+// appendQuery returns rawURL with the encoded query appended to its query
+// string. If query is empty, rawURL is returned unchanged.
+func appendQuery(rawURL string, query Query) string {
+	encoded := query.Encode()
+	if encoded == "" {
+		return rawURL
+	}
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + encoded
+	}
+	return rawURL + "?" + encoded
+}
+
+// mergeQueries returns a new Query containing the values of all the given
+// queries.
+func mergeQueries(queries ...Query) Query {
+	merged := Query{}
+	for _, query := range queries {
+		for key, values := range query {
+			merged[key] = append(merged[key], values...)
+		}
+	}
+	return merged
+}
+
+// toQuery converts v to a Query. v may be a Query, a url.Values, or a value
+// which can be encoded with EncodeQuery.
+func toQuery(v interface{}) (Query, error) {
+	switch q := v.(type) {
+	case nil:
+		return nil, nil
+	case Query:
+		return q, nil
+	case url.Values:
+		return Query(q), nil
+	default:
+		values, err := EncodeQuery(v)
+		if err != nil {
+			return nil, err
+		}
+		return Query(values), nil
+	}
+}
+
+// EncodeQuery converts the struct v (or a pointer to one) into url.Values
+// suitable for use in a query string. By default each exported field is
+// encoded using the field name as the key. Fields may be customized with a
+// `query` struct tag consisting of a name followed by options, e.g.:
+//
+//	type TodoFilter struct {
+//		Status  []string  `query:"status,omitempty,comma"`
+//		Since   time.Time `query:"since,omitempty,format=2006-01-02"`
+//		Author  struct {
+//			Name string `query:"name"`
+//		} `query:"author"`
+//		Private string `query:"-"`
+//	}
+//
+// A name of "-" causes the field to be skipped. The supported options are:
+//
+//   - omitempty: skip the field if it has the zero value for its type.
+//   - repeat, comma, pipe: the style used for slices and arrays. repeat (the
+//     default) results in "status=a&status=b", comma results in "status=a,b",
+//     and pipe results in "status=a|b".
+//   - format=<layout>: the layout used for time.Time fields. The special
+//     layout "unix" encodes the time as seconds since the Unix epoch. The
+//     default is RFC 3339.
+//
+// Nested structs are encoded with their keys in brackets, e.g.
+// "author[name]=bob". Embedded structs without a tag are flattened into the
+// outer struct. Nil pointers are skipped. Other values are converted to
+// strings the same way as url-encoded model fields (see RegisterFieldEncoder).
+func EncodeQuery(v interface{}) (url.Values, error) {
+	values := url.Values{}
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return values, nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rest: EncodeQuery expects a struct or a pointer to a struct but got %T", v)
+	}
+	if err := encodeQueryStruct(values, "", val); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// encodeQueryStruct adds the fields of the struct val to values. prefix is the
+// key of the struct itself, or an empty string for the outermost struct.
+func encodeQueryStruct(values url.Values, prefix string, val reflect.Value) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// Skip unexported fields
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("query")
+		name, opts := splitTag(tag)
+		if name == "-" {
+			continue
+		}
+		fieldVal := val.Field(i)
+		for fieldVal.Kind() == reflect.Ptr {
+			if fieldVal.IsNil() {
+				break
+			}
+			fieldVal = fieldVal.Elem()
+		}
+		if fieldVal.Kind() == reflect.Ptr {
+			// Skip nil pointers
+			continue
+		}
+		if opts.Contains("omitempty") && fieldVal.IsZero() {
+			continue
+		}
+		if field.Anonymous && !hasTag && fieldVal.Kind() == reflect.Struct {
+			if err := encodeQueryStruct(values, prefix, fieldVal); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "[" + name + "]"
+		}
+		if err := encodeQueryValue(values, key, fieldVal, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeQueryValue adds val to values with the given key, taking into account
+// the options from the field's tag.
+func encodeQueryValue(values url.Values, key string, val reflect.Value, opts tagOptions) error {
+	switch {
+	case val.Type() == timeType:
+		layout, _ := opts.Get("format")
+		values.Add(key, formatQueryTime(val.Interface().(time.Time), layout))
+		return nil
+	case val.Kind() == reflect.Struct:
+		if str, ok, err := encodeCustomString(val); ok {
+			if err != nil {
+				return err
+			}
+			values.Add(key, str)
+			return nil
+		}
+		return encodeQueryStruct(values, key, val)
+	case (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) && val.Type().Elem().Kind() != reflect.Uint8:
+		strs := make([]string, 0, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			elem := val.Index(i)
+			if elem.Kind() == reflect.Ptr && elem.IsNil() {
+				continue
+			}
+			if elem.Type() == timeType {
+				layout, _ := opts.Get("format")
+				strs = append(strs, formatQueryTime(elem.Interface().(time.Time), layout))
+				continue
+			}
+			str, err := encodeString(elem)
+			if err != nil {
+				return fmt.Errorf("rest: error encoding query parameter %s: %s", key, err.Error())
+			}
+			strs = append(strs, str)
+		}
+		switch {
+		case opts.Contains("comma"):
+			values.Add(key, strings.Join(strs, ","))
+		case opts.Contains("pipe"):
+			values.Add(key, strings.Join(strs, "|"))
+		default:
+			for _, str := range strs {
+				values.Add(key, str)
+			}
+		}
+		return nil
+	default:
+		str, err := encodeString(val)
+		if err != nil {
+			return fmt.Errorf("rest: error encoding query parameter %s: %s", key, err.Error())
+		}
+		values.Add(key, str)
+		return nil
+	}
+}
+
+// formatQueryTime formats t according to layout. If layout is "unix", t is
+// formatted as the number of seconds since the Unix epoch. If layout is
+// empty, RFC 3339 is used.
+func formatQueryTime(t time.Time, layout string) string {
+	switch layout {
+	case "":
+		return t.Format(time.RFC3339)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	default:
+		return t.Format(layout)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendQuery(t *testing.T) {
+	tests := []struct {
+		url      string
+		query    Query
+		expected string
+	}{
+		{"http://example.com/todos", nil, "http://example.com/todos"},
+		{"http://example.com/todos", Query{}, "http://example.com/todos"},
+		{"http://example.com/todos", Query{"b": {"2"}, "a": {"1", "x y"}}, "http://example.com/todos?a=1&a=x+y&b=2"},
+		{"http://example.com/todos?page=2", Query{"a": {"1"}}, "http://example.com/todos?page=2&a=1"},
+	}
+	for _, test := range tests {
+		if got := appendQuery(test.url, test.query); got != test.expected {
+			t.Errorf("Expected %q but got %q", test.expected, got)
+		}
+	}
+}
+
+func TestMergeQueries(t *testing.T) {
+	merged := mergeQueries(Query{"a": {"1"}}, nil, Query{"a": {"2"}, "b": {"3"}})
+	if merged.Encode() != "a=1&a=2&b=3" {
+		t.Errorf("Unexpected merged query: %v", merged)
+	}
+}
+
+// todoFilter exercises the options of the query struct tag.
+type todoFilter struct {
+	queryPaging
+	Status  []string  `query:"status,omitempty,comma"`
+	Labels  []string  `query:"label"`
+	Owners  []string  `query:"owner,pipe"`
+	Since   time.Time `query:"since,omitempty,format=2006-01-02"`
+	Until   time.Time `query:"until,format=unix"`
+	Done    *bool     `query:"done"`
+	Author  queryAuthor
+	Private string `query:"-"`
+	hidden  string
+}
+
+type queryPaging struct {
+	Page int `query:"page"`
+}
+
+type queryAuthor struct {
+	Name string `query:"name"`
+}
+
+func TestEncodeQuery(t *testing.T) {
+	done := false
+	filter := &todoFilter{
+		queryPaging: queryPaging{Page: 2},
+		Status:      []string{"open", "blocked"},
+		Labels:      []string{"a", "b"},
+		Owners:      []string{"x", "y"},
+		Since:       time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC),
+		Until:       time.Unix(1000, 0),
+		Done:        &done,
+		Author:      queryAuthor{Name: "bob"},
+		Private:     "secret",
+		hidden:      "secret",
+	}
+	values, err := EncodeQuery(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := url.Values{
+		"page":         {"2"},
+		"status":       {"open,blocked"},
+		"label":        {"a", "b"},
+		"owner":        {"x|y"},
+		"since":        {"2015-03-04"},
+		"until":        {"1000"},
+		"done":         {"false"},
+		"Author[name]": {"bob"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v but got %v", expected, values)
+	}
+
+	values, err = EncodeQuery(todoFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"status", "since", "done"} {
+		if _, found := values[key]; found {
+			t.Errorf("Expected %s to be omitted but got %v", key, values)
+		}
+	}
+
+	var nilFilter *todoFilter
+	if values, err := EncodeQuery(nilFilter); err != nil || len(values) != 0 {
+		t.Errorf("Expected no values for a nil pointer but got %v and %v", values, err)
+	}
+	if _, err := EncodeQuery("status=open"); err == nil {
+		t.Error("Expected an error for a value which is not a struct")
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	server.todos[0].IsCompleted = true
+	client := NewClient()
+	queries := []interface{}{
+		Query{"IsCompleted": {"true"}},
+		url.Values{"IsCompleted": {"true"}},
+		struct {
+			IsCompleted bool
+		}{true},
+	}
+	for _, query := range queries {
+		todos := []*testTodo{}
+		if err := client.ReadAll(&todos, WithQuery(query)); err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 1 || todos[0].Title != "a" {
+			t.Errorf("Expected only the completed todo for %T but got %v", query, todos)
+		}
+	}
+	if err := client.ReadAll(&[]*testTodo{}, WithQuery(42)); err == nil {
+		t.Error("Expected an error for a query which cannot be encoded")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned for requests which are not sent because the
+// client has exceeded an enforced QuotaPolicy.
+var ErrQuotaExceeded = errors.New("rest: quota exceeded")
+
+// QuotaLimit identifies one of the limits of a QuotaPolicy.
+type QuotaLimit string
+
+const (
+	// QuotaRequests is the limit on the number of requests.
+	QuotaRequests QuotaLimit = "requests"
+	// QuotaBytes is the limit on the number of bytes downloaded.
+	QuotaBytes QuotaLimit = "bytes"
+)
+
+// QuotaPolicy limits the number of requests a client sends and the number of
+// bytes it downloads, e.g. to stay within a metered mobile data plan or to
+// catch accidental request loops during development. Every attempt counts as
+// a request, including retries, and every byte read from a response body
+// counts as downloaded. The counts cover the lifetime of the client (the
+// session) until ResetQuota is called.
+type QuotaPolicy struct {
+	// MaxRequests is the number of requests the client may send. Zero means
+	// no limit.
+	MaxRequests int
+	// MaxBytes is the number of bytes the client may download. Zero means no
+	// limit.
+	MaxBytes int64
+	// OnExceeded, if not nil, is called when a limit is first exceeded, with
+	// the usage at that time. It is called at most once per limit until
+	// ResetQuota is called.
+	OnExceeded func(usage QuotaUsage)
+	// Enforce causes requests to fail with ErrQuotaExceeded without being
+	// sent once any limit has been exceeded. Otherwise the limits only
+	// trigger OnExceeded.
+	Enforce bool
+}
+
+// QuotaUsage describes how much of its QuotaPolicy a client has used.
+type QuotaUsage struct {
+	// Requests is the number of requests sent
+	Requests int
+	// Bytes is the number of bytes downloaded
+	Bytes int64
+	// Exceeded is the limit which was just exceeded, when the usage is
+	// passed to OnExceeded. It is empty otherwise.
+	Exceeded QuotaLimit
+}
+
+// quota holds the usage of a client with a QuotaPolicy.
+type quota struct {
+	usage    QuotaUsage
+	exceeded map[QuotaLimit]bool
+	// mut protects usage and exceeded
+	mut sync.Mutex
+}
+
+// getQuota returns the quota state of c, creating it if necessary.
+func (c *Client) getQuota() *quota {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.quota == nil {
+		c.quota = &quota{exceeded: map[QuotaLimit]bool{}}
+	}
+	return c.quota
+}
+
+// Usage returns the number of requests c has sent and the number of bytes it
+// has downloaded since it was created or since ResetQuota was last called.
+// Usage is only tracked if c has a QuotaPolicy.
+func (c *Client) Usage() QuotaUsage {
+	q := c.getQuota()
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return q.usage
+}
+
+// ResetQuota resets the usage of c to zero, e.g. at the start of a new
+// session, so that requests which were refused because of an enforced
+// QuotaPolicy are allowed again.
+func (c *Client) ResetQuota() {
+	q := c.getQuota()
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	q.usage = QuotaUsage{}
+	q.exceeded = map[QuotaLimit]bool{}
+}
+
+// countRequest counts a request against the quota of c. It returns
+// ErrQuotaExceeded if the request must not be sent.
+func (c *Client) countRequest() error {
+	policy := c.Quota
+	if policy == nil {
+		return nil
+	}
+	q := c.getQuota()
+	q.mut.Lock()
+	if policy.Enforce && len(q.exceeded) > 0 {
+		q.mut.Unlock()
+		return ErrQuotaExceeded
+	}
+	q.usage.Requests++
+	if policy.MaxRequests > 0 && q.usage.Requests > policy.MaxRequests {
+		usage, first := q.exceed(QuotaRequests)
+		if policy.Enforce {
+			// The request which exceeds the limit is not sent either
+			q.usage.Requests--
+			usage.Requests--
+		}
+		q.mut.Unlock()
+		if first && policy.OnExceeded != nil {
+			policy.OnExceeded(usage)
+		}
+		if policy.Enforce {
+			return ErrQuotaExceeded
+		}
+		return nil
+	}
+	q.mut.Unlock()
+	return nil
+}
+
+// countBytes counts n downloaded bytes against the quota of c.
+func (c *Client) countBytes(n int) {
+	policy := c.Quota
+	if policy == nil || n == 0 {
+		return
+	}
+	q := c.getQuota()
+	q.mut.Lock()
+	q.usage.Bytes += int64(n)
+	if policy.MaxBytes <= 0 || q.usage.Bytes <= policy.MaxBytes {
+		q.mut.Unlock()
+		return
+	}
+	usage, first := q.exceed(QuotaBytes)
+	q.mut.Unlock()
+	if first && policy.OnExceeded != nil {
+		policy.OnExceeded(usage)
+	}
+}
+
+// exceed records that limit was exceeded and returns the usage to pass to
+// OnExceeded, and whether this is the first time. q.mut must be held.
+func (q *quota) exceed(limit QuotaLimit) (QuotaUsage, bool) {
+	first := !q.exceeded[limit]
+	q.exceeded[limit] = true
+	usage := q.usage
+	usage.Exceeded = limit
+	return usage, first
+}
+
+// countingBody is a response body which counts the bytes read from it
+// against the quota of client.
+type countingBody struct {
+	io.ReadCloser
+	client *Client
+}
+
+func (b countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.client.countBytes(n)
+	return n, err
+}
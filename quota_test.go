@@ -0,0 +1,120 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestQuotaRequests(t *testing.T) {
+	server := newTodoServer(t, "Write a book")
+	var exceeded []QuotaUsage
+	client := NewClient()
+	client.Quota = &QuotaPolicy{
+		MaxRequests: 2,
+		OnExceeded:  func(usage QuotaUsage) { exceeded = append(exceeded, usage) },
+	}
+	for i := 0; i < 4; i++ {
+		if err := client.Read("1", &testTodo{}); err != nil {
+			t.Fatalf("Expected requests to be sent when the quota is not enforced but got %s", err)
+		}
+	}
+	if n := server.count("GET"); n != 4 {
+		t.Errorf("Expected 4 requests to be sent but got %d", n)
+	}
+	if len(exceeded) != 1 {
+		t.Fatalf("Expected OnExceeded to be called once but it was called %d times", len(exceeded))
+	}
+	if exceeded[0].Exceeded != QuotaRequests || exceeded[0].Requests != 3 {
+		t.Errorf("Expected OnExceeded to be called with 3 requests for QuotaRequests but got %+v", exceeded[0])
+	}
+	if usage := client.Usage(); usage.Requests != 4 || usage.Exceeded != "" {
+		t.Errorf("Expected a usage of 4 requests but got %+v", usage)
+	}
+}
+
+func TestQuotaEnforced(t *testing.T) {
+	server := newTodoServer(t, "Write a book")
+	calls := 0
+	client := NewClient()
+	client.Quota = &QuotaPolicy{
+		MaxRequests: 2,
+		Enforce:     true,
+		OnExceeded:  func(QuotaUsage) { calls++ },
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Read("1", &testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Read("1", &testTodo{}); err != ErrQuotaExceeded {
+			t.Errorf("Expected ErrQuotaExceeded but got %v", err)
+		}
+	}
+	if n := server.count("GET"); n != 2 {
+		t.Errorf("Expected only 2 requests to be sent but got %d", n)
+	}
+	if calls != 1 {
+		t.Errorf("Expected OnExceeded to be called once but it was called %d times", calls)
+	}
+	if usage := client.Usage(); usage.Requests != 2 {
+		t.Errorf("Expected requests which were not sent not to be counted but got %+v", usage)
+	}
+
+	client.ResetQuota()
+	if usage := client.Usage(); usage != (QuotaUsage{}) {
+		t.Errorf("Expected ResetQuota to reset the usage but got %+v", usage)
+	}
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Errorf("Expected requests to be allowed after ResetQuota but got %s", err)
+	}
+}
+
+func TestQuotaBytes(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1", "Title": "Write a book"}`)
+	var exceeded []QuotaUsage
+	client := NewClient()
+	client.Quota = &QuotaPolicy{
+		MaxBytes:   50,
+		Enforce:    true,
+		OnExceeded: func(usage QuotaUsage) { exceeded = append(exceeded, usage) },
+	}
+	size := int64(len(`{"Id": "1", "Title": "Write a book"}`))
+	for i := 0; i < 2; i++ {
+		if err := client.Read("1", &testTodo{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if usage := client.Usage(); usage.Bytes != 2*size {
+		t.Errorf("Expected %d bytes to be counted but got %+v", 2*size, usage)
+	}
+	if len(exceeded) != 1 || exceeded[0].Exceeded != QuotaBytes || exceeded[0].Bytes != 2*size {
+		t.Errorf("Expected OnExceeded to be called once for QuotaBytes but got %+v", exceeded)
+	}
+	if err := client.Read("1", &testTodo{}); err != ErrQuotaExceeded {
+		t.Errorf("Expected ErrQuotaExceeded once the byte limit was exceeded but got %v", err)
+	}
+	if n := len(server.all()); n != 2 {
+		t.Errorf("Expected only 2 requests to be sent but got %d", n)
+	}
+}
+
+func TestQuotaScope(t *testing.T) {
+	newTodoServer(t, "Write a book")
+	client := NewClient()
+	client.Quota = &QuotaPolicy{MaxRequests: 1, Enforce: true}
+	child := client.Scope()
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Read("1", &testTodo{}); err != nil {
+		t.Errorf("Expected the child to have its own quota usage but got %s", err)
+	}
+	if usage := client.Usage(); usage.Requests != 1 {
+		t.Errorf("Expected the requests of the child not to count against the parent but got %+v", usage)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// RawField holds a schemaless JSON sub-document, such as a freeform metadata
+// blob, which is carried through decoding and encoding untouched. It is like
+// json.RawMessage (which models may also use), except that an empty RawField is
+// encoded as null instead of causing an error, and it can be url-encoded, in
+// which case the value is the compact JSON text.
+type RawField []byte
+
+// MarshalJSON satisfies json.Marshaler.
+func (f RawField) MarshalJSON() ([]byte, error) {
+	if len(f) == 0 {
+		return []byte("null"), nil
+	}
+	return f, nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler. It stores a copy of data.
+func (f *RawField) UnmarshalJSON(data []byte) error {
+	*f = append((*f)[:0], data...)
+	return nil
+}
+
+// MarshalText satisfies encoding.TextMarshaler, which is used when the field
+// is url-encoded.
+func (f RawField) MarshalText() ([]byte, error) {
+	if len(f) == 0 {
+		return []byte{}, nil
+	}
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// IsNull returns true iff f is empty or holds the JSON null.
+func (f RawField) IsNull() bool {
+	trimmed := bytes.TrimSpace(f)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// Decode decodes the JSON held by f into v.
+func (f RawField) Decode(v interface{}) error {
+	if len(f) == 0 {
+		return nil
+	}
+	return json.Unmarshal(f, v)
+}
+
+// Set replaces the JSON held by f with the encoding of v.
+func (f *RawField) Set(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	*f = data
+	return nil
+}
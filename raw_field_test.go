@@ -0,0 +1,82 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type rawTodo struct {
+	DefaultId
+	Meta  RawField
+	Extra json.RawMessage
+}
+
+func (*rawTodo) RootURL() string { return testRootURL + "/todos" }
+
+func TestRawFieldJSON(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1", "Meta": {"b": [1, 2], "a": {"c": null}}, "Extra": "x"}`)
+	client := NewClient()
+	client.ContentType = ContentJSON
+	todo := &rawTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if string(todo.Meta) != `{"b": [1, 2], "a": {"c": null}}` || string(todo.Extra) != `"x"` {
+		t.Errorf("Expected the raw JSON to be kept but got %s and %s", todo.Meta, todo.Extra)
+	}
+	meta := struct{ B []int }{}
+	if err := todo.Meta.Decode(&meta); err != nil || len(meta.B) != 2 {
+		t.Errorf("Expected Decode to decode the JSON but got %v, %v", meta, err)
+	}
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != `{"Id":"1","Meta":{"b":[1,2],"a":{"c":null}},"Extra":"x"}` {
+		t.Errorf("Expected the raw JSON to be sent back unchanged but got %s", body)
+	}
+
+	empty := &rawTodo{DefaultId: DefaultId{Id: "2"}, Extra: json.RawMessage(`1`)}
+	if err := client.Update(empty); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != `{"Id":"2","Meta":null,"Extra":1}` {
+		t.Errorf("Expected an empty RawField to be encoded as null but got %s", body)
+	}
+}
+
+func TestRawFieldURLEncoded(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	todo := &rawTodo{DefaultId: DefaultId{Id: "1"}, Meta: RawField(`{ "a": [1, 2] }`), Extra: json.RawMessage(`{"b": 1}`)}
+	if err := NewClient().Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := url.ParseQuery(server.lastBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields.Get("Meta") != `{"a":[1,2]}` || fields.Get("Extra") != `{"b": 1}` {
+		t.Errorf("Expected the raw JSON to be url-encoded as text but got %v", fields)
+	}
+}
+
+func TestRawFieldHelpers(t *testing.T) {
+	var f RawField
+	if !f.IsNull() || !RawField(" null ").IsNull() || RawField("0").IsNull() {
+		t.Error("Expected IsNull to be true only for empty fields and null")
+	}
+	if err := f.Decode(&struct{}{}); err != nil {
+		t.Errorf("Expected decoding an empty field to do nothing but got %v", err)
+	}
+	if err := f.Set(map[string]int{"a": 1}); err != nil || string(f) != `{"a":1}` {
+		t.Errorf("Expected Set to encode the value but got %s, %v", f, err)
+	}
+	if _, err := RawField("{").MarshalText(); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
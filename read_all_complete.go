@@ -0,0 +1,86 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrTooManyRecords is returned by ReadAllComplete when a collection has more
+// records than the given maximum.
+var ErrTooManyRecords = errors.New("rest: collection has too many records")
+
+// ReadAllComplete is like ReadAll but follows pagination to read every page of
+// the collection into models. The url of each next page is taken from the
+// Link header of the response (e.g. `Link: <https://api.example.com/todos?page=2>;
+// rel="next"`), as sent by many APIs; reading stops at the first page without
+// one. Because a collection may be much larger than expected, reading is
+// aborted with ErrTooManyRecords as soon as the number of records exceeds
+// maxRecords, in which case models is left unchanged. The WithQuery option
+// applies to the first page only, since the links to the other pages are
+// expected to include the query.
+func (c *Client) ReadAllComplete(models interface{}, maxRecords int, opts ...RequestOption) error {
+	reqOpts := newRequestOptions(opts)
+	rootURL, err := c.routeModels(models)
+	if err != nil {
+		return err
+	}
+	query, err := toQuery(reqOpts.query)
+	if err != nil {
+		return err
+	}
+	sliceType := reflect.TypeOf(models).Elem()
+	all := reflect.MakeSlice(sliceType, 0, 0)
+	for url := appendQuery(rootURL, query); url != ""; {
+		res, body, err := c.getWithContext(reqOpts.context(), url)
+		if err != nil {
+			return err
+		}
+		page := reflect.New(sliceType)
+		if err := c.unmarshal(body, page.Interface()); err != nil {
+			return err
+		}
+		if all.Len()+page.Elem().Len() > maxRecords {
+			return ErrTooManyRecords
+		}
+		all = reflect.AppendSlice(all, page.Elem())
+		url = ""
+		if next, found := parseLinks(res.Header["Link"])["next"]; found {
+			if nextURL, err := res.Request.URL.Parse(next); err == nil {
+				url = nextURL.String()
+			}
+		}
+	}
+	reflect.ValueOf(models).Elem().Set(all)
+	return nil
+}
+
+// parseLinks parses the values of Link headers (RFC 8288) and returns the
+// target of each link by relation type.
+func parseLinks(values []string) map[string]string {
+	links := map[string]string{}
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "rel=") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(param[len("rel="):], `"`)) {
+					links[strings.ToLower(rel)] = target
+				}
+			}
+		}
+	}
+	return links
+}
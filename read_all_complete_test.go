@@ -0,0 +1,89 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// newPagedServer returns a test server which serves n todos, two per page,
+// linking each page to the next with a Link header.
+func newPagedServer(t *testing.T, n int) *requestLog {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		todos := []testTodo{}
+		for i := (page-1)*2 + 1; i <= page*2 && i <= n; i++ {
+			todo := testTodo{Title: fmt.Sprint(i)}
+			todo.Id = strconv.Itoa(i)
+			todos = append(todos, todo)
+		}
+		if page*2 < n {
+			w.Header().Add("Link", fmt.Sprintf(`</todos?page=%d>; rel="next", </todos?page=1>; rel="first"`, page+1))
+		}
+		json.NewEncoder(w).Encode(todos)
+	})
+	return log
+}
+
+func TestReadAllComplete(t *testing.T) {
+	log := newPagedServer(t, 5)
+	client := NewClient()
+	todos := []*testTodo{}
+	if err := client.ReadAllComplete(&todos, 5, WithQuery(Query{"IsCompleted": {"false"}})); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 5 || todos[0].Title != "1" || todos[4].Title != "5" {
+		t.Fatalf("Expected every page to be read in order but got %v", todos)
+	}
+	requests := log.all()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests but got %d", len(requests))
+	}
+	if requests[0].URL.Query().Get("IsCompleted") != "false" || requests[2].URL.RawQuery != "page=3" {
+		t.Errorf("Expected the query on the first page only but got %s and %s", requests[0].URL, requests[2].URL)
+	}
+}
+
+func TestReadAllCompleteTooManyRecords(t *testing.T) {
+	log := newPagedServer(t, 6)
+	client := NewClient()
+	todos := []*testTodo{{Title: "existing"}}
+	if err := client.ReadAllComplete(&todos, 3); err != ErrTooManyRecords {
+		t.Fatalf("Expected ErrTooManyRecords but got %v", err)
+	}
+	if len(todos) != 1 || todos[0].Title != "existing" {
+		t.Errorf("Expected the models to be unchanged but got %v", todos)
+	}
+	if len(log.all()) != 2 {
+		t.Errorf("Expected reading to stop at the page which exceeds the cap but got %d requests", len(log.all()))
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	links := parseLinks([]string{
+		`<https://api.example.com/todos?page=2>; rel="next", <https://api.example.com/todos?page=9>; rel="last"`,
+		`<https://api.example.com/todos?page=1>; title="start"; rel="First Prev"`,
+		`https://api.example.com/invalid; rel="self"`,
+	})
+	expected := map[string]string{
+		"next":  "https://api.example.com/todos?page=2",
+		"last":  "https://api.example.com/todos?page=9",
+		"first": "https://api.example.com/todos?page=1",
+		"prev":  "https://api.example.com/todos?page=1",
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected %v but got %v", expected, links)
+	}
+}
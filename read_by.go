@@ -0,0 +1,80 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ReadByMode determines how ReadBy addresses a model.
+type ReadByMode string
+
+const (
+	// ReadByPath is the default ReadByMode. ReadBy sends a GET request to
+	// model.RootURL() + "/by-<field>/<value>" and expects a single object in
+	// the response, just like Read.
+	ReadByPath ReadByMode = ""
+	// ReadByQuery causes ReadBy to send a GET request to
+	// model.RootURL() + "?<field>=<value>&limit=1" and to use the first
+	// object in the array of the response.
+	ReadByQuery ReadByMode = "query"
+)
+
+// ReadBy sends an http request to read the model for which field has the given
+// value, for resources which are addressed by an alternate key such as a slug or
+// an email address rather than by their id. How the request is sent depends on
+// the client's ReadByMode. If the request was successful, ReadBy mutates model by
+// setting the fields to the values in the JSON response, so model should be a
+// pointer. If no model matches, ReadBy returns an HTTPError with a status code of
+// 404 in either mode.
+func (c *Client) ReadBy(model Model, field string, value string, opts ...RequestOption) error {
+	if err := checkModelPointer("ReadBy", model); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	c.checkMoneyFields(model)
+	if c.ReadByMode == ReadByQuery {
+		return c.readByQuery(model, field, value, reqOpts)
+	}
+	fullURL, err := joinURL(model.RootURL(), "by-"+escapeId(field)+"/"+escapeId(value))
+	if err != nil {
+		return err
+	}
+	fullURL = appendQuery(fullURL, c.fieldsQuery(reqOpts))
+	if err := c.sendRequestAndUnmarshal("GET", fullURL, "", "", model, reqOpts); err != nil {
+		return err
+	}
+	markHydrated(model, reqOpts)
+	return nil
+}
+
+// readByQuery implements ReadBy for ReadByQuery.
+func (c *Client) readByQuery(model Model, field string, value string, reqOpts *requestOptions) error {
+	rootURL, err := normalizeRootURL(model.RootURL())
+	if err != nil {
+		return err
+	}
+	query := Query{field: {value}, "limit": {"1"}}
+	if fields := c.fieldsQuery(reqOpts); fields != nil {
+		query = mergeQueries(query, fields)
+	}
+	fullURL := appendQuery(rootURL, query)
+	found := []json.RawMessage{}
+	if err := c.sendRequestAndUnmarshal("GET", fullURL, "", "", &found, reqOpts); err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return HTTPError{
+			URL:        c.expandVars(fullURL),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+	if err := c.unmarshal(found[0], model); err != nil {
+		return err
+	}
+	markHydrated(model, reqOpts)
+	return nil
+}
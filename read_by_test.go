@@ -0,0 +1,53 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReadByPath(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "3", "Title": "a b"}`)
+	todo := &testTodo{}
+	if err := NewClient().ReadBy(todo, "slug", "a b/c"); err != nil {
+		t.Fatal(err)
+	}
+	req := server.last()
+	if req.Method != "GET" || req.URL.EscapedPath() != "/todos/by-slug/a%20b%2Fc" {
+		t.Errorf("Expected GET /todos/by-slug/a%%20b%%2Fc but got %s %s", req.Method, req.URL.EscapedPath())
+	}
+	if todo.Id != "3" || todo.Title != "a b" {
+		t.Errorf("Expected the model to be read but got %+v", todo)
+	}
+}
+
+func TestReadByQuery(t *testing.T) {
+	server := newTodoServer(t, "a", "b", "b")
+	client := NewClient()
+	client.ReadByMode = ReadByQuery
+	todo := &testTodo{}
+	if err := client.ReadBy(todo, "Title", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "2" || todo.Title != "b" {
+		t.Errorf("Expected the first matching todo but got %+v", todo)
+	}
+	if reqs := server.Requests(); reqs[0] != "GET /todos?Title=b&limit=1" {
+		t.Errorf("Expected the field and limit in the query but got %s", reqs[0])
+	}
+
+	err := client.ReadBy(&testTodo{}, "Title", "c")
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected an HTTPError with status 404 when nothing matches but got %v", err)
+	}
+}
+
+func TestReadByNonPointer(t *testing.T) {
+	newTodoServer(t)
+	if err := NewClient().ReadBy(stringModel("1"), "slug", "a"); err == nil {
+		t.Error("Expected an error for a model which is not a pointer")
+	}
+}
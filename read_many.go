@@ -0,0 +1,175 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MultiGetStyle determines how ReadMany asks the server for several models at
+// once.
+type MultiGetStyle string
+
+const (
+	// MultiGetCommaSeparated is the default MultiGetStyle. ReadMany sends a
+	// single GET request to model.RootURL() + "?ids=1,2,3".
+	MultiGetCommaSeparated MultiGetStyle = ""
+	// MultiGetRepeated causes ReadMany to send a single GET request to
+	// model.RootURL() + "?ids=1&ids=2&ids=3".
+	MultiGetRepeated MultiGetStyle = "repeated"
+	// MultiGetNone is for servers without a multi-get endpoint. ReadMany
+	// reads each model with a separate request instead.
+	MultiGetNone MultiGetStyle = "none"
+)
+
+// MissingIdsError is returned by ReadMany when the server did not return some
+// of the requested models.
+type MissingIdsError struct {
+	// Ids are the ids of the missing models, in the order they were
+	// requested.
+	Ids []string
+}
+
+// Error satisfies the error interface
+func (e MissingIdsError) Error() string {
+	return "rest: models not found: " + strings.Join(e.Ids, ", ")
+}
+
+// ReadMany reads the models with the given ids into models, which must be a
+// pointer to a slice of models (e.g. *[]*Todo). On success, the slice has one
+// element for each id, in the same order as ids, regardless of the order in
+// which the server returned them. How the models are requested depends on the
+// client's MultiGet style and MultiGetParam. If the server responds to a
+// multi-get request with 404, 405, or 501, which suggests that it does not
+// support them, ReadMany falls back to reading each model with a separate
+// request, at most MultiGetConcurrency at a time. If some of the models are
+// not found, the others are still read, the missing elements are left as
+// zero values (nil for pointers), and a MissingIdsError is returned.
+func (c *Client) ReadMany(ids []string, models interface{}, opts ...RequestOption) error {
+	if err := checkModelsType(models, false); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(models)
+	rootURL, err := getURLFromModels(models)
+	if err != nil {
+		return err
+	}
+	sliceType := reflect.TypeOf(models).Elem()
+	var found map[string]reflect.Value
+	if c.MultiGet != MultiGetNone {
+		found, err = c.multiGet(rootURL, ids, sliceType, reqOpts)
+		if httpErr, ok := err.(HTTPError); ok {
+			switch httpErr.StatusCode {
+			case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+				found, err = nil, nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if found == nil {
+		if found, err = c.readEach(ids, sliceType.Elem(), opts); err != nil {
+			return err
+		}
+	}
+	result := reflect.MakeSlice(sliceType, len(ids), len(ids))
+	missing := []string{}
+	for i, id := range ids {
+		if model, ok := found[id]; ok {
+			result.Index(i).Set(model)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	reflect.ValueOf(models).Elem().Set(result)
+	if len(missing) > 0 {
+		return MissingIdsError{Ids: missing}
+	}
+	return nil
+}
+
+// multiGet reads the models with the given ids from rootURL with a single
+// request and returns them by id.
+func (c *Client) multiGet(rootURL string, ids []string, sliceType reflect.Type, reqOpts *requestOptions) (map[string]reflect.Value, error) {
+	param := c.MultiGetParam
+	if param == "" {
+		param = "ids"
+	}
+	query := Query{param: {strings.Join(ids, ",")}}
+	if c.MultiGet == MultiGetRepeated {
+		query = Query{param: ids}
+	}
+	if fields := c.fieldsQuery(reqOpts); fields != nil {
+		query = mergeQueries(query, fields)
+	}
+	page := reflect.New(sliceType)
+	if err := c.sendRequestAndUnmarshal("GET", appendQuery(rootURL, query), "", "", page.Interface(), reqOpts); err != nil {
+		return nil, err
+	}
+	found := map[string]reflect.Value{}
+	for i := 0; i < page.Elem().Len(); i++ {
+		elem := page.Elem().Index(i)
+		if elem.Kind() == reflect.Ptr && elem.IsNil() {
+			continue
+		}
+		found[elem.Interface().(Model).ModelId()] = elem
+	}
+	return found, nil
+}
+
+// readEach reads the models with the given ids with a separate request for
+// each, at most c.MultiGetConcurrency at a time, and returns them by id.
+// Models which are not found are omitted.
+func (c *Client) readEach(ids []string, elemType reflect.Type, opts []RequestOption) (map[string]reflect.Value, error) {
+	concurrency := c.MultiGetConcurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	group := c.NewGroup(newRequestOptions(opts).context())
+	sem := make(chan struct{}, concurrency)
+	found := map[string]reflect.Value{}
+	var mut sync.Mutex
+	for _, id := range ids {
+		id := id
+		group.Go(func(ctx context.Context) error {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+			model := newModelOfType(elemType)
+			// Read requires a pointer, even if the elements are not
+			target := model
+			if elemType.Kind() != reflect.Ptr {
+				target = reflect.New(elemType)
+			}
+			// opts is shared by all the goroutines, so it must be copied
+			// before anything is appended to it
+			err := c.Read(id, target.Interface().(Model), append(append([]RequestOption(nil), opts...), WithContext(ctx))...)
+			if httpErr, ok := err.(HTTPError); ok && httpErr.StatusCode == http.StatusNotFound {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if elemType.Kind() != reflect.Ptr {
+				model = target.Elem()
+			}
+			mut.Lock()
+			found[id] = model
+			mut.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
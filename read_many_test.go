@@ -0,0 +1,179 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// multiGetServer serves the todos with ids 1 to 3. If multiGet is true, it
+// responds to requests for the collection with the todos whose ids are in the
+// ids parameter, in reverse order. Otherwise it responds with 404.
+type multiGetServer struct {
+	requestLog
+	multiGet    bool
+	inFlight    int32
+	maxInFlight int32
+}
+
+func newMultiGetServer(t *testing.T, multiGet bool) *multiGetServer {
+	server := &multiGetServer{multiGet: multiGet}
+	newTestServer(t, server.ServeHTTP)
+	return server
+}
+
+func (s *multiGetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.add(r)
+	exists := func(id string) bool { return id == "1" || id == "2" || id == "3" }
+	if r.URL.Path == "/todos" {
+		if !s.multiGet {
+			http.NotFound(w, r)
+			return
+		}
+		ids := r.URL.Query()["ids"]
+		if len(ids) == 1 {
+			ids = strings.Split(ids[0], ",")
+		}
+		todos := []testTodo{}
+		for i := len(ids) - 1; i >= 0; i-- {
+			if exists(ids[i]) {
+				todos = append(todos, testTodo{DefaultId: DefaultId{Id: ids[i]}, Title: "todo " + ids[i]})
+			}
+		}
+		json.NewEncoder(w).Encode(todos)
+		return
+	}
+	n := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&s.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&s.maxInFlight, max, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	id := strings.TrimPrefix(r.URL.Path, "/todos/")
+	if !exists(id) {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(testTodo{DefaultId: DefaultId{Id: id}, Title: "todo " + id})
+}
+
+// todoIds returns the ids of todos, with "nil" for nil elements.
+func todoIds(todos []*testTodo) []string {
+	ids := []string{}
+	for _, todo := range todos {
+		if todo == nil {
+			ids = append(ids, "nil")
+		} else {
+			ids = append(ids, todo.Id)
+		}
+	}
+	return ids
+}
+
+func TestReadMany(t *testing.T) {
+	server := newMultiGetServer(t, true)
+	client := NewClient()
+	todos := []*testTodo{}
+	if err := client.ReadMany([]string{"1", "3", "2"}, &todos); err != nil {
+		t.Fatal(err)
+	}
+	if ids := todoIds(todos); !reflect.DeepEqual(ids, []string{"1", "3", "2"}) {
+		t.Errorf("Expected the todos in the requested order but got %v", ids)
+	}
+	if query := server.last().URL.RawQuery; query != "ids=1%2C3%2C2" {
+		t.Errorf("Expected a comma separated list of ids but got %s", query)
+	}
+
+	client.MultiGet = MultiGetRepeated
+	err := client.ReadMany([]string{"4", "2"}, &todos)
+	if missing, ok := err.(MissingIdsError); !ok || !reflect.DeepEqual(missing.Ids, []string{"4"}) {
+		t.Errorf("Expected a MissingIdsError for 4 but got %v", err)
+	}
+	if ids := todoIds(todos); !reflect.DeepEqual(ids, []string{"nil", "2"}) {
+		t.Errorf("Expected nil for the missing todo but got %v", ids)
+	}
+	if query := server.last().URL.RawQuery; query != "ids=4&ids=2" {
+		t.Errorf("Expected repeated ids but got %s", query)
+	}
+	if n := len(server.all()); n != 2 {
+		t.Errorf("Expected one request per call but got %d", n)
+	}
+}
+
+func TestReadManyFallback(t *testing.T) {
+	server := newMultiGetServer(t, false)
+	client := NewClient()
+	client.MultiGetConcurrency = 2
+	todos := []*testTodo{}
+	err := client.ReadMany([]string{"3", "1", "5", "2"}, &todos)
+	if missing, ok := err.(MissingIdsError); !ok || !reflect.DeepEqual(missing.Ids, []string{"5"}) {
+		t.Errorf("Expected a MissingIdsError for 5 but got %v", err)
+	}
+	if ids := todoIds(todos); !reflect.DeepEqual(ids, []string{"3", "1", "nil", "2"}) {
+		t.Errorf("Expected the todos in the requested order but got %v", ids)
+	}
+	if n := len(server.all()); n != 5 {
+		t.Errorf("Expected a failed multi-get and 4 reads but got %d requests", n)
+	}
+	if max := atomic.LoadInt32(&server.maxInFlight); max > 2 {
+		t.Errorf("Expected at most 2 concurrent reads but got %d", max)
+	}
+
+	client.MultiGet = MultiGetNone
+	if err := client.ReadMany([]string{"2", "1"}, &todos); err != nil {
+		t.Fatal(err)
+	}
+	if ids := todoIds(todos); !reflect.DeepEqual(ids, []string{"2", "1"}) {
+		t.Errorf("Expected the todos in the requested order but got %v", ids)
+	}
+	if n := len(server.all()); n != 7 {
+		t.Errorf("Expected no multi-get request with MultiGetNone but got %d requests", n)
+	}
+}
+
+func TestReadManySharedOptions(t *testing.T) {
+	server := newMultiGetServer(t, false)
+	client := NewClient()
+	client.MultiGet = MultiGetNone
+	// opts has spare capacity, so that appending to it in place would make
+	// the reads overwrite each other's options, which go test -race reports
+	opts := make([]RequestOption, 1, 8)
+	opts[0] = WithFields("Id", "Title")
+	todos := []*testTodo{}
+	if err := client.ReadMany([]string{"1", "2", "3"}, &todos, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if ids := todoIds(todos); !reflect.DeepEqual(ids, []string{"1", "2", "3"}) {
+		t.Errorf("Expected the todos in the requested order but got %v", ids)
+	}
+	if len(opts) != 1 || cap(opts) != 8 {
+		t.Errorf("Expected the options of the caller to be left unchanged")
+	}
+	for _, req := range server.all() {
+		if !strings.Contains(req.URL.RawQuery, "Title") {
+			t.Errorf("Expected the options to be used for every read but %s had the query %q", req.URL.Path, req.URL.RawQuery)
+		}
+	}
+}
+
+func TestReadManyError(t *testing.T) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	todos := []*testTodo{}
+	err := NewClient().ReadMany([]string{"1"}, &todos)
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected an HTTPError with status 500 but got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registry maps resource names to registered model types.
+var (
+	registry   = map[string]registeredModel{}
+	registryMu sync.RWMutex
+	// autoRegistered holds the types which have been passed to
+	// autoRegister, so that they are only considered once.
+	autoRegistered = map[reflect.Type]bool{}
+)
+
+// registeredModel is an entry in the registry.
+type registeredModel struct {
+	typ     reflect.Type
+	rootURL string
+	// auto is true iff the entry was added by autoRegister rather than
+	// Register or RegisterAs, in which case it may be replaced.
+	auto bool
+}
+
+// Register adds the type of model to the model registry under its resource
+// name, which is the last segment of its RootURL (e.g. "todos" for a model with
+// the RootURL "http://example.com/api/todos"). model is only used to find out
+// its type and RootURL, so a zero value such as (*Todo)(nil) will do, as long
+// as RootURL can be called on it. Registered types can then be looked up by
+// name or url with NewModel, ModelType, and ModelForURL, which is useful for
+// decoding payloads which identify their resource type and for app-level
+// routing. Register panics if a different type is already registered under
+// the same name; use RegisterAs to choose a different name.
+//
+// The types of the models passed to Read and ReadAll are registered
+// automatically, so most applications only need to call Register for types
+// which are never read, or to resolve conflicts between types with the same
+// resource name. Automatic registration never replaces a type which is
+// already registered, and is itself replaced by Register and RegisterAs.
+func Register(model Model) {
+	RegisterAs(resourceName(model.RootURL()), model)
+}
+
+// RegisterAs is like Register but registers the type of model under the given
+// name instead of its resource name.
+func RegisterAs(name string, model Model) {
+	typ := reflect.TypeOf(model)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, found := registry[name]; found && existing.typ != typ && !existing.auto {
+		panic(fmt.Sprintf("rest: cannot register %s as %q because %s is already registered under that name", typ, name, existing.typ))
+	}
+	registry[name] = registeredModel{
+		typ:     typ,
+		rootURL: strings.TrimSuffix(model.RootURL(), "/"),
+	}
+}
+
+// autoRegister registers the type of model, which is passed to Read, under
+// its resource name, unless a type is already registered under that name. The
+// url is taken from model itself, so that models whose RootURL depends on
+// their fields are registered under the url they are read from.
+func autoRegister(model Model) {
+	typ := reflect.TypeOf(model)
+	registryMu.RLock()
+	seen := autoRegistered[typ]
+	registryMu.RUnlock()
+	if seen {
+		return
+	}
+	rootURL, ok := safeRootURL(model)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	autoRegistered[typ] = true
+	if !ok {
+		return
+	}
+	name := resourceName(rootURL)
+	if _, found := registry[name]; found || name == "" {
+		return
+	}
+	registry[name] = registeredModel{
+		typ:     typ,
+		rootURL: rootURL,
+		auto:    true,
+	}
+}
+
+// autoRegisterModels calls autoRegister with a new model of the type of the
+// elements of models, if it is a pointer to a slice of models.
+func autoRegisterModels(models interface{}) {
+	if checkModelsType(models, false) != nil {
+		return
+	}
+	typ := reflect.TypeOf(models).Elem().Elem()
+	if model, ok := newModelOfType(typ).Interface().(Model); ok {
+		autoRegister(model)
+	}
+}
+
+// safeRootURL returns the RootURL of model without a trailing slash. ok is
+// false if RootURL panics, e.g. because it dereferences a nil field, or if
+// the url has an empty path segment, which means that it is built from fields
+// which are not set, as in "/users//todos".
+func safeRootURL(model Model) (rootURL string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			rootURL, ok = "", false
+		}
+	}()
+	rootURL = strings.TrimSuffix(model.RootURL(), "/")
+	path := rootURL
+	if i := strings.Index(path, "://"); i != -1 {
+		path = path[i+len("://"):]
+	}
+	return rootURL, !strings.Contains(path, "//")
+}
+
+// ModelType returns the type registered under the given name.
+func ModelType(name string) (reflect.Type, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	entry, found := registry[name]
+	return entry.typ, found
+}
+
+// NewModel returns a new, empty model of the type registered under the given
+// name. If the type is a pointer type, the model points to a newly allocated
+// zero value.
+func NewModel(name string) (Model, bool) {
+	typ, found := ModelType(name)
+	if !found {
+		return nil, false
+	}
+	return newModelOfType(typ).Interface().(Model), true
+}
+
+// ModelForURL returns a new, empty model of the registered type whose RootURL
+// is a prefix of url, along with the id which follows the RootURL in url (or
+// an empty string if url is the RootURL itself). If more than one RootURL
+// matches, the longest one wins. The third return value is false if no
+// RootURL matches.
+func ModelForURL(url string) (Model, string, bool) {
+	registryMu.RLock()
+	var match registeredModel
+	for _, entry := range registry {
+		if len(entry.rootURL) > len(match.rootURL) && hasURLPrefix(url, entry.rootURL) {
+			match = entry
+		}
+	}
+	registryMu.RUnlock()
+	if match.typ == nil {
+		return nil, "", false
+	}
+	id := strings.TrimPrefix(url[len(match.rootURL):], "/")
+	if i := strings.IndexAny(id, "/?#"); i != -1 {
+		id = id[:i]
+	}
+	return newModelOfType(match.typ).Interface().(Model), id, true
+}
+
+// RegisteredNames returns the names of all the registered types, sorted.
+func RegisteredNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resourceName returns the last path segment of rootURL.
+func resourceName(rootURL string) string {
+	rootURL = strings.TrimSuffix(rootURL, "/")
+	return rootURL[strings.LastIndex(rootURL, "/")+1:]
+}
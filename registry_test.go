@@ -0,0 +1,241 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type registryWidget struct {
+	DefaultId
+}
+
+func (*registryWidget) RootURL() string {
+	return "http://registry.test/api/widgets/"
+}
+
+type registryPart struct {
+	DefaultId
+}
+
+func (*registryPart) RootURL() string {
+	return "http://registry.test/api/widgets/parts"
+}
+
+// unregister removes the given names from the registry when the test ends,
+// and forgets that the types registered under them were registered
+// automatically, so that they are registered again when they are next read.
+func unregister(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		for _, name := range names {
+			delete(autoRegistered, registry[name].typ)
+			delete(registry, name)
+		}
+	})
+}
+
+func TestRegister(t *testing.T) {
+	unregister(t, "widgets", "parts")
+	Register((*registryWidget)(nil))
+	Register(&registryPart{})
+	Register(&registryPart{})
+	if typ, found := ModelType("widgets"); !found || typ != reflect.TypeOf(&registryWidget{}) {
+		t.Errorf("Expected *registryWidget to be registered as widgets but got %v", typ)
+	}
+	model, found := NewModel("parts")
+	if part, ok := model.(*registryPart); !found || !ok || part == nil {
+		t.Errorf("Expected a new *registryPart but got %#v", model)
+	}
+	if _, found := NewModel("gizmos"); found {
+		t.Error("Expected no model for an unregistered name")
+	}
+	names := RegisteredNames()
+	for _, name := range []string{"parts", "widgets"} {
+		found := false
+		for _, registered := range names {
+			found = found || registered == name
+		}
+		if !found {
+			t.Errorf("Expected %s in %v", name, names)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic when registering a different type under the same name")
+		}
+	}()
+	RegisterAs("widgets", &registryPart{})
+}
+
+func TestModelForURL(t *testing.T) {
+	unregister(t, "widgets", "parts")
+	Register(&registryWidget{})
+	Register(&registryPart{})
+	tests := []struct {
+		url string
+		typ reflect.Type
+		id  string
+	}{
+		{url: "http://registry.test/api/widgets", typ: reflect.TypeOf(&registryWidget{})},
+		{url: "http://registry.test/api/widgets/7?expand=parts", typ: reflect.TypeOf(&registryWidget{}), id: "7"},
+		{url: "http://registry.test/api/widgets/parts/3/history", typ: reflect.TypeOf(&registryPart{}), id: "3"},
+		{url: "http://registry.test/api/widgetsparts/3"},
+	}
+	for _, test := range tests {
+		model, id, found := ModelForURL(test.url)
+		if test.typ == nil {
+			if found {
+				t.Errorf("Expected no match for %s but got %T", test.url, model)
+			}
+			continue
+		}
+		if !found || reflect.TypeOf(model) != test.typ || id != test.id {
+			t.Errorf("Expected %s with id %q for %s but got %T with id %q", test.typ, test.id, test.url, model, id)
+		}
+	}
+}
+
+func TestAutoRegister(t *testing.T) {
+	unregister(t, "widgets", "parts")
+	autoRegisterModels(&[]*registryWidget{})
+	if typ, found := ModelType("widgets"); !found || typ != reflect.TypeOf(&registryWidget{}) {
+		t.Fatalf("Expected the element type to be registered automatically but got %v", typ)
+	}
+	// Register replaces an automatically registered type without panicking
+	RegisterAs("widgets", &registryPart{})
+	if typ, _ := ModelType("widgets"); typ != reflect.TypeOf(&registryPart{}) {
+		t.Errorf("Expected Register to replace the automatic registration but got %v", typ)
+	}
+	registryMu.Lock()
+	delete(autoRegistered, reflect.TypeOf(&registryWidget{}))
+	registryMu.Unlock()
+	autoRegister(&registryWidget{})
+	if typ, _ := ModelType("widgets"); typ != reflect.TypeOf(&registryPart{}) {
+		t.Errorf("Expected automatic registration not to replace a registered type but got %v", typ)
+	}
+}
+
+// registryGadget is only used by TestAutoRegisterOnRead, so that it is not
+// registered before the test starts.
+type registryGadget struct {
+	DefaultId
+	Name string
+}
+
+func (*registryGadget) RootURL() string {
+	return testRootURL + "/gadgets"
+}
+
+// registrySprocket is only used by TestAutoRegisterOnRead.
+type registrySprocket struct {
+	DefaultId
+}
+
+func (*registrySprocket) RootURL() string {
+	return testRootURL + "/sprockets"
+}
+
+func TestAutoRegisterOnRead(t *testing.T) {
+	unregister(t, "gadgets", "sprockets")
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gadgets/1" {
+			w.Write([]byte(`{"Id": "1", "Name": "a"}`))
+			return
+		}
+		w.Write([]byte(`[{"Id": "1"}]`))
+	})
+	for _, name := range []string{"gadgets", "sprockets"} {
+		if _, found := ModelType(name); found {
+			t.Fatalf("Expected %s not to be registered before it is read", name)
+		}
+	}
+	client := NewClient()
+	if err := client.Read("1", &registryGadget{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := ModelType("gadgets"); found {
+		t.Fatal("Expected Read not to register the type unless AutoRegister is set")
+	}
+	client.AutoRegister = true
+	if err := client.Read("1", &registryGadget{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReadAll(&[]*registrySprocket{}); err != nil {
+		t.Fatal(err)
+	}
+
+	model, id, found := ModelForURL(testRootURL + "/gadgets/1")
+	if _, ok := model.(*registryGadget); !found || !ok || id != "1" {
+		t.Errorf("Expected Read to register *registryGadget but got %T with id %q", model, id)
+	}
+	if typ, found := ModelType("sprockets"); !found || typ != reflect.TypeOf(&registrySprocket{}) {
+		t.Errorf("Expected ReadAll to register *registrySprocket but got %v", typ)
+	}
+}
+
+// registryOwner is the parent of registryTask.
+type registryOwner struct {
+	DefaultId
+}
+
+// registryTask is a model whose RootURL is built from its parent, like that
+// of a nested resource.
+type registryTask struct {
+	DefaultId
+	Owner *registryOwner
+}
+
+func (task *registryTask) RootURL() string {
+	return testRootURL + "/owners/" + task.Owner.Id + "/tasks"
+}
+
+func TestAutoRegisterInstanceURL(t *testing.T) {
+	unregister(t, "tasks")
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	client := NewClient()
+	client.AutoRegister = true
+	tasks := []*registryTask{}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Expected automatic registration not to panic when RootURL panics on a new model but got %v", r)
+			}
+		}()
+		autoRegisterModels(&tasks)
+	}()
+	if _, found := ModelType("tasks"); found {
+		t.Fatal("Expected a type whose RootURL panics not to be registered")
+	}
+
+	registryMu.Lock()
+	delete(autoRegistered, reflect.TypeOf(&registryTask{}))
+	registryMu.Unlock()
+	if err := client.Read("1", &registryTask{Owner: &registryOwner{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := ModelType("tasks"); found {
+		t.Error("Expected a type whose RootURL has an empty segment not to be registered")
+	}
+
+	registryMu.Lock()
+	delete(autoRegistered, reflect.TypeOf(&registryTask{}))
+	registryMu.Unlock()
+	owner := &registryOwner{}
+	owner.Id = "7"
+	if err := client.Read("1", &registryTask{Owner: owner}); err != nil {
+		t.Fatal(err)
+	}
+	model, id, found := ModelForURL(testRootURL + "/owners/7/tasks/1")
+	if _, ok := model.(*registryTask); !found || !ok || id != "1" {
+		t.Errorf("Expected Read to register *registryTask under the url of the model but got %T with id %q", model, id)
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// BodyEncoder converts a value into the body of a request.
+type BodyEncoder func(v interface{}) ([]byte, error)
+
+// BodyDecoder converts the body of a response into the value v.
+type BodyDecoder func(data []byte, v interface{}) error
+
+// RequestBuilder is used to construct and send requests which do not fit the
+// standard CRUD methods, while still getting the behavior of the client (e.g.
+// default headers, template variables, retries, caching, and error handling).
+// Create one with Client.NewRequestBuilder, configure it by chaining calls,
+// and send it with Execute:
+//
+//	stats := TodoStats{}
+//	err := client.NewRequestBuilder("GET", "/todos/stats").
+//		Query(rest.Query{"since": {"2015-01-01"}}).
+//		Header("X-Report", "daily").
+//		Into(&stats).
+//		Execute(ctx)
+type RequestBuilder struct {
+	client      *Client
+	method      string
+	url         string
+	query       Query
+	header      http.Header
+	body        interface{}
+	hasBody     bool
+	rawBody     []byte
+	contentType ContentType
+	encoder     BodyEncoder
+	target      interface{}
+	decoder     BodyDecoder
+	err         error
+}
+
+// NewRequestBuilder returns a RequestBuilder for a request with the given
+// method and url.
+func (c *Client) NewRequestBuilder(method string, url string) *RequestBuilder {
+	return &RequestBuilder{
+		client: c,
+		method: method,
+		url:    url,
+		header: http.Header{},
+	}
+}
+
+// Query adds query to the query string of the request url. query can be
+// anything accepted by the WithQuery option.
+func (rb *RequestBuilder) Query(query interface{}) *RequestBuilder {
+	q, err := toQuery(query)
+	if err != nil {
+		rb.err = err
+		return rb
+	}
+	if rb.query == nil {
+		rb.query = Query{}
+	}
+	for key, values := range q {
+		rb.query[key] = append(rb.query[key], values...)
+	}
+	return rb
+}
+
+// Header sets the request header with the given key to value.
+func (rb *RequestBuilder) Header(key string, value string) *RequestBuilder {
+	rb.header.Set(key, value)
+	return rb
+}
+
+// Body sets the value which is encoded to form the body of the request. By
+// default, a Model is encoded the same way as for Create and Update, and any
+// other value is encoded according to the ContentType of the request (JSON
+// for ContentJSON; url.Values, Query, or EncodeQuery for ContentURLEncoded).
+// Use Encoder to change how the body is encoded.
+func (rb *RequestBuilder) Body(v interface{}) *RequestBuilder {
+	rb.body = v
+	rb.hasBody = true
+	rb.rawBody = nil
+	return rb
+}
+
+// RawBody sets the body of the request to data, which is sent as is.
+func (rb *RequestBuilder) RawBody(data []byte, contentType ContentType) *RequestBuilder {
+	rb.rawBody = data
+	rb.contentType = contentType
+	rb.body = nil
+	rb.hasBody = false
+	return rb
+}
+
+// ContentType sets the ContentType used to encode the body and sent in the
+// Content-Type header. The default is the ContentType of the client (or of the
+// body, if it is a Model which implements ContentTyper).
+func (rb *RequestBuilder) ContentType(contentType ContentType) *RequestBuilder {
+	rb.contentType = contentType
+	return rb
+}
+
+// Encoder sets the BodyEncoder used to encode the value passed to Body.
+func (rb *RequestBuilder) Encoder(encoder BodyEncoder) *RequestBuilder {
+	rb.encoder = encoder
+	return rb
+}
+
+// Into sets the value into which the response is decoded. If no target is set,
+// the body of the response is discarded.
+func (rb *RequestBuilder) Into(target interface{}) *RequestBuilder {
+	rb.target = target
+	return rb
+}
+
+// Decoder sets the BodyDecoder used to decode the response into the target.
+// By default, the response is decoded as JSON.
+func (rb *RequestBuilder) Decoder(decoder BodyDecoder) *RequestBuilder {
+	rb.decoder = decoder
+	return rb
+}
+
+// Execute sends the request, waiting for the response and decoding it into
+// the target set with Into. It returns an HTTPError if the response has a
+// non-2xx status code.
+func (rb *RequestBuilder) Execute(ctx context.Context) error {
+	return rb.execute(&requestOptions{ctx: ctx})
+}
+
+// encodeBody returns the encoded body of the request and its content type.
+func (rb *RequestBuilder) encodeBody() ([]byte, ContentType, error) {
+	if rb.rawBody != nil || !rb.hasBody {
+		return rb.rawBody, rb.contentType, nil
+	}
+	contentType := rb.contentType
+	if contentType == "" {
+		if model, ok := rb.body.(Model); ok {
+			contentType = rb.client.contentTypeFor(model)
+		} else {
+			contentType = rb.client.ContentType
+		}
+	}
+	if rb.encoder != nil {
+		data, err := rb.encoder(rb.body)
+		return data, contentType, err
+	}
+	if model, ok := rb.body.(Model); ok {
+		data, err := rb.client.encodeFields(model, contentType)
+		return []byte(data), contentType, err
+	}
+	switch contentType {
+	case ContentJSON:
+		data, err := rb.client.jsonBackend().Marshal(rb.body)
+		return data, contentType, err
+	case ContentURLEncoded:
+		query, err := toQuery(rb.body)
+		if err != nil {
+			return nil, contentType, err
+		}
+		return []byte(url.Values(query).Encode()), contentType, nil
+	default:
+		return nil, contentType, fmt.Errorf("rest: don't know how to handle ContentType: %s", contentType)
+	}
+}
+
+// execute sends the request with the given options, which may be nil.
+func (rb *RequestBuilder) execute(reqOpts *requestOptions) error {
+	if rb.err != nil {
+		return rb.err
+	}
+	if reqOpts == nil {
+		reqOpts = &requestOptions{}
+	}
+	c := rb.client
+	fullURL := appendQuery(rb.url, rb.query)
+	data, contentType, err := rb.encodeBody()
+	if err != nil {
+		return err
+	}
+	key, shared := rb.flightKey(fullURL, reqOpts)
+	// Use a cached response if there is one
+	if rb.method == "GET" && reqOpts.cachePolicy != NoCache && (reqOpts.policy == nil || !reqOpts.policy.NoCache) {
+		if entry, found := c.cachedEntry(fullURL, rb.header, reqOpts.policy); found {
+			if c.dueForRefresh(entry) {
+				rb.refresh(reqOpts.context(), key, shared, fullURL, data, contentType)
+			}
+			if err := rb.decode(entry.Body); err != nil {
+				rb.reportDecodeError(reqOpts.context(), fullURL, err)
+				return err
+			}
+			return rb.checkDecoded(fullURL)
+		}
+		if reqOpts.cachePolicy == CacheOnly {
+			return ErrNotCached
+		}
+	}
+	fetch := func() (*http.Response, []byte, error) {
+		return rb.fetch(reqOpts.context(), fullURL, data, contentType)
+	}
+	var res *http.Response
+	var body []byte
+	if rb.method == "GET" && c.Cache != nil && shared {
+		// Avoid sending the same request many times at once when a popular
+		// cache entry expires
+		res, body, err = c.fetchShared(reqOpts.context(), key, fetch)
+	} else {
+		res, body, err = fetch()
+	}
+	if err != nil {
+		return err
+	}
+	recordAccepted(res, reqOpts)
+	if err := rb.decode(body); err != nil {
+		rb.reportDecodeError(reqOpts.context(), fullURL, err)
+		return err
+	}
+	if err := setHeaderFields(res, rb.target); err != nil {
+		return err
+	}
+	return rb.checkDecoded(fullURL)
+}
+
+// fetch sends the request and returns the response along with its body, which
+// has already been read and closed. The body of a successful GET request is
+// stored in the cache.
+func (rb *RequestBuilder) fetch(ctx context.Context, fullURL string, data []byte, contentType ContentType) (*http.Response, []byte, error) {
+	c := rb.client
+	req, err := rb.build(ctx, fullURL, data, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := c.readResponse(res)
+	if httpErr, ok := err.(HTTPError); ok {
+		c.mirror(req, res, httpErr.Body, rb.target)
+	} else if err == nil {
+		c.mirror(req, res, body, rb.target)
+	}
+	if err != nil {
+		return nil, nil, c.maskError(err, rb.target)
+	}
+	if rb.method == "GET" && res.StatusCode/100 == 2 && res.StatusCode != http.StatusAccepted {
+		c.storeCacheWithPolicy(fullURL, rb.header, body, policyFrom(ctx))
+	}
+	return res, body, nil
+}
+
+// build returns the http.Request for the given url, body, and content type.
+func (rb *RequestBuilder) build(ctx context.Context, fullURL string, data []byte, contentType ContentType) (*http.Request, error) {
+	if rb.hasBody {
+		data = rb.client.maskRequestBody(rb.client.expandVars(fullURL), data, contentType, rb.body)
+	} else {
+		data = rb.client.maskRequestBody(rb.client.expandVars(fullURL), data, contentType, rb.target)
+	}
+	var reqBody io.Reader = nil
+	if len(data) > 0 {
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(rb.method, fullURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("Something went wrong building %s request to %s: %s", rb.method, fullURL, err.Error())
+	}
+	req = req.WithContext(ctx)
+	// Set the Content-Type and checksum headers only if data was provided
+	if len(data) > 0 {
+		req.Header.Set("Content-Type", string(contentType))
+		if err := rb.client.setBodyDigest(req, data); err != nil {
+			return nil, err
+		}
+	}
+	// Specify that we want json as the response type. This is especially useful
+	// for applications which share things between client and server
+	req.Header.Set("Accept", "application/json")
+	for key, values := range rb.header {
+		req.Header[key] = values
+	}
+	return req, nil
+}
+
+// DoRequest sends the request described by rb through the full stack of the
+// client (default headers, template variables, method policy, concurrency
+// limits, retries, and events) and returns the response without reading it.
+// Unlike Execute, it does not use the cache, does not decode the response,
+// and does not return an error for non-2xx status codes. The caller is
+// responsible for closing the body of the response. DoRequest is useful for
+// streaming a response (e.g. server-sent events) or proxying it to another
+// writer.
+func (c *Client) DoRequest(ctx context.Context, rb *RequestBuilder) (*http.Response, error) {
+	if rb.err != nil {
+		return nil, rb.err
+	}
+	data, contentType, err := rb.encodeBody()
+	if err != nil {
+		return nil, err
+	}
+	req, err := rb.build(ctx, appendQuery(rb.url, rb.query), data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// decode decodes body into the target of the request, if any. Empty bodies
+// (e.g. from a 204 No Content response) leave the target unchanged.
+func (rb *RequestBuilder) decode(body []byte) error {
+	if rb.target == nil || isEmptyBody(body) {
+		return nil
+	}
+	if rb.decoder != nil {
+		return rb.decoder(body, rb.target)
+	}
+	return rb.client.unmarshal(body, rb.target)
+}
+
+// reportDecodeError reports err, which was returned while decoding the
+// response to the request for fullURL with the context ctx, to the OnError
+// hook of the client.
+func (rb *RequestBuilder) reportDecodeError(ctx context.Context, fullURL string, err error) {
+	rb.client.reportError(ErrorReport{
+		Kind:    ErrorDecode,
+		Method:  rb.method,
+		URL:     rb.client.expandVars(fullURL),
+		Err:     err,
+		Context: ctx,
+	})
+}
+
+// checkDecoded passes the target of rb to the OnDecode hook of the client, if
+// any, once the response has been decoded into it.
+func (rb *RequestBuilder) checkDecoded(fullURL string) error {
+	if rb.client.OnDecode == nil || rb.target == nil {
+		return nil
+	}
+	return rb.client.OnDecode(rb.method, rb.client.expandVars(fullURL), rb.target)
+}
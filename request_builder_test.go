@@ -0,0 +1,195 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// echoServer responds to every request with response and records the
+// requests it receives along with their bodies.
+type echoServer struct {
+	requestLog
+	bodies []string
+}
+
+func newEchoServer(t *testing.T, status int, response string) *echoServer {
+	server := &echoServer{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		server.mut.Lock()
+		server.bodies = append(server.bodies, string(body))
+		server.mut.Unlock()
+		server.add(r)
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	})
+	return server
+}
+
+func (server *echoServer) lastBody() string {
+	server.mut.Lock()
+	defer server.mut.Unlock()
+	return server.bodies[len(server.bodies)-1]
+}
+
+func TestRequestBuilderGet(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Total": 3}`)
+	stats := struct{ Total int }{}
+	err := NewClient().NewRequestBuilder("GET", testRootURL+"/todos/stats").
+		Query(Query{"since": {"2015-01-01"}}).
+		Query(struct{ Page int }{Page: 2}).
+		Header("X-Report", "daily").
+		Into(&stats).
+		Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 3 {
+		t.Errorf("Expected the response to be decoded into the target but got %+v", stats)
+	}
+	req := server.last()
+	if req.URL.Path != "/todos/stats" || req.URL.Query().Get("since") != "2015-01-01" || req.URL.Query().Get("Page") != "2" {
+		t.Errorf("Unexpected request url: %s", req.URL)
+	}
+	if req.Header.Get("X-Report") != "daily" || req.Header.Get("Accept") != "application/json" {
+		t.Errorf("Unexpected request headers: %v", req.Header)
+	}
+}
+
+func TestRequestBuilderBody(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{}`)
+	client := NewClient()
+	ctx := context.Background()
+	err := client.NewRequestBuilder("POST", testRootURL+"/todos/archive").
+		ContentType(ContentJSON).
+		Body(map[string]int{"Days": 7}).
+		Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != `{"Days":7}` || server.last().Header.Get("Content-Type") != string(ContentJSON) {
+		t.Errorf("Expected a JSON body but got %q", body)
+	}
+
+	err = client.NewRequestBuilder("POST", testRootURL+"/todos/archive").
+		Body(Query{"Days": {"7"}}).
+		Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != "Days=7" {
+		t.Errorf("Expected a url encoded body but got %q", body)
+	}
+
+	todo := &testTodo{DefaultId: DefaultId{Id: "1"}, Title: "a"}
+	if err := client.NewRequestBuilder("PUT", testRootURL+"/todos/1").Body(todo).Execute(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); !strings.Contains(body, "Title=a") {
+		t.Errorf("Expected a model to be encoded like for Update but got %q", body)
+	}
+
+	err = client.NewRequestBuilder("POST", testRootURL+"/upload").
+		RawBody([]byte("raw"), ContentType("text/plain")).
+		Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != "raw" || server.last().Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected the raw body to be sent as is but got %q", body)
+	}
+}
+
+func TestRequestBuilderCodecs(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, "pong")
+	var got string
+	err := NewClient().NewRequestBuilder("POST", testRootURL+"/ping").
+		Body("ping").
+		Encoder(func(v interface{}) ([]byte, error) {
+			return bytes.ToUpper([]byte(v.(string))), nil
+		}).
+		Into(&got).
+		Decoder(func(data []byte, v interface{}) error {
+			*v.(*string) = string(data)
+			return nil
+		}).
+		Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := server.lastBody(); body != "PING" {
+		t.Errorf("Expected the body to be encoded with the custom encoder but got %q", body)
+	}
+	if got != "pong" {
+		t.Errorf("Expected the response to be decoded with the custom decoder but got %q", got)
+	}
+}
+
+func TestRequestBuilderErrors(t *testing.T) {
+	server := newEchoServer(t, http.StatusConflict, `{"error": "conflict"}`)
+	client := NewClient()
+	err := client.NewRequestBuilder("POST", testRootURL+"/todos/archive").Execute(context.Background())
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusConflict {
+		t.Errorf("Expected an HTTPError with status 409 but got %v", err)
+	}
+	err = client.NewRequestBuilder("GET", testRootURL+"/todos").Query(42).Execute(context.Background())
+	if err == nil {
+		t.Error("Expected an error for an invalid query")
+	}
+	err = client.NewRequestBuilder("POST", testRootURL+"/todos").
+		ContentType(ContentType("text/plain")).
+		Body(42).
+		Execute(context.Background())
+	if err == nil {
+		t.Error("Expected an error for a body with an unknown ContentType")
+	}
+	if len(server.all()) != 1 {
+		t.Errorf("Expected invalid requests not to be sent but got %d requests", len(server.all()))
+	}
+}
+
+func TestOnDecode(t *testing.T) {
+	server := newTodoServer(t, "a", "b")
+	client := NewClient()
+	errNoTitle := errors.New("todo has no title")
+	var decoded []string
+	client.OnDecode = func(method string, url string, v interface{}) error {
+		decoded = append(decoded, fmt.Sprintf("%s %s %T", method, strings.TrimPrefix(url, testRootURL), v))
+		if todo, ok := v.(*testTodo); ok && todo.Title == "" {
+			return errNoTitle
+		}
+		return nil
+	}
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Create(&testTodo{Title: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Create(&testTodo{}); err != errNoTitle {
+		t.Errorf("Expected the error from OnDecode but got %v", err)
+	}
+	expected := []string{
+		"GET /todos *[]*rest.testTodo",
+		"POST /todos *rest.testTodo",
+		"POST /todos *rest.testTodo",
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected OnDecode to be called with %v but got %v", expected, decoded)
+	}
+	if n := server.count("POST"); n != 2 {
+		t.Errorf("Expected the rejected model to have been sent anyway but got %d POST requests", n)
+	}
+}
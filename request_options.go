@@ -0,0 +1,204 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"time"
+)
+
+// RequestOption configures a single request sent by the client. Request options
+// can be passed to any of the methods of Client which accept them.
+type RequestOption func(*requestOptions)
+
+// requestOptions holds the settings for a single request.
+type requestOptions struct {
+	// ctx is the context for the request. If it is nil, context.Background()
+	// is used.
+	ctx context.Context
+	// rootURL is the RootURL of the model the request is for, if any. It is
+	// used to group the EndpointStats of the client.
+	rootURL string
+	// progress is called as the body of a response is read.
+	progress func(written, total int64)
+	// chunkSize is the maximum size of each chunk sent by Upload. If it is 0,
+	// the upload is sent in a single request.
+	chunkSize int64
+	// resumeFrom is the offset at which a chunked Upload or a Download
+	// should start.
+	resumeFrom int64
+	// ifRange is the ETag a resumed Download expects the resource to have.
+	ifRange string
+	// upsert causes FirstOrCreate to use a PUT request instead of searching.
+	upsert bool
+	// query is added to the query string of the request url. It can be
+	// anything accepted by toQuery.
+	query interface{}
+	// merge causes ReadAll to merge the response into the existing models.
+	merge bool
+	// removeMissing causes a merging ReadAll to remove models which are not
+	// in the response.
+	removeMissing bool
+	// reuse causes ReadAll to reuse the memory held by the existing models.
+	reuse bool
+	// meta, if not nil, is where ReadAll decodes collection-level metadata.
+	meta interface{}
+	// csv causes ReadAll to request and decode CSV.
+	csv bool
+	// deleteViaPost is the path DeleteWhere should send a POST request to,
+	// relative to the root url. If it is empty, a DELETE request is used.
+	deleteViaPost string
+	// patchMode, if not nil, overrides the PatchMode of the client.
+	patchMode *PatchMode
+	// original is the version of the model before it was changed.
+	original Model
+	// decodeInto, if not nil, holds the targets the response should be
+	// decoded into instead of the model.
+	decodeInto DecodeInto
+	// accepted, if not nil, is filled in if the response is 202 Accepted.
+	accepted *Accepted
+	// cachePolicy determines how the request uses the cache.
+	cachePolicy CachePolicy
+	// fields are the fields the server should include in the response.
+	fields []string
+	// timing, if not nil, is filled in with the timing of the request.
+	timing *Timing
+	// policy is the Policy declared by the model the request is for.
+	policy *Policy
+	// emptyBody causes Create to send a request without a body.
+	emptyBody bool
+	// include are the relations which should be loaded along with the
+	// models.
+	include []string
+	// acceptStatus are the non-2xx status codes which should be treated as
+	// successful.
+	acceptStatus []int
+	// timeout, if not nil, overrides Client.Timeout.
+	timeout *time.Duration
+}
+
+// newRequestOptions returns the requestOptions that result from applying opts
+// in order.
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	reqOpts := &requestOptions{}
+	for _, opt := range opts {
+		opt(reqOpts)
+	}
+	return reqOpts
+}
+
+// context returns the context for the request, which carries the Timing given
+// by WithTiming, the Policy and RootURL of the model, the status codes given
+// by WithAcceptStatus, and the timeout given by WithTimeout, if any. It is
+// safe to call on a nil *requestOptions.
+func (opts *requestOptions) context() context.Context {
+	if opts == nil {
+		return context.Background()
+	}
+	ctx := opts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.timing != nil {
+		ctx = withTiming(ctx, opts.timing)
+	}
+	if opts.policy != nil {
+		ctx = withPolicy(ctx, opts.policy)
+	}
+	if len(opts.acceptStatus) > 0 {
+		ctx = withAcceptStatus(ctx, opts.acceptStatus)
+	}
+	if opts.rootURL != "" {
+		ctx = withRootURL(ctx, opts.rootURL)
+	}
+	if opts.timeout != nil {
+		ctx = withRequestTimeout(ctx, *opts.timeout)
+	}
+	return ctx
+}
+
+// WithContext returns a RequestOption which causes the request to use ctx.
+// If ctx is canceled or its deadline passes, the request is aborted. The
+// values of ctx (e.g. a user id or trace info) are available throughout the
+// request: to Authorize and middleware via the context of the http.Request,
+// to OnError via ErrorReport.Context, and to event subscribers via
+// Event.Context. They also carry over to the requests for included models and
+// to background cache refreshes and mirrored requests, which are not canceled
+// along with ctx.
+func WithContext(ctx context.Context) RequestOption {
+	return func(opts *requestOptions) {
+		opts.ctx = ctx
+	}
+}
+
+// WithProgress returns a RequestOption which causes progress to be called
+// each time a chunk of the response body is read. written is the total number
+// of bytes read so far and total is the value of the Content-Length header of
+// the response, or -1 if it is unknown.
+func WithProgress(progress func(written, total int64)) RequestOption {
+	return func(opts *requestOptions) {
+		opts.progress = progress
+	}
+}
+
+// WithChunkSize returns a RequestOption which causes Upload to split the
+// upload into chunks of at most size bytes, each of which is sent in a
+// separate request with a Content-Range header.
+func WithChunkSize(size int64) RequestOption {
+	return func(opts *requestOptions) {
+		opts.chunkSize = size
+	}
+}
+
+// WithResumeFrom returns a RequestOption which causes a chunked Upload or a
+// Download to start at the given offset instead of at the beginning. It is
+// typically used with the Offset of an UploadError or DownloadError to resume
+// a transfer that failed part-way through. If the reader passed to Upload is
+// not an io.Seeker, the bytes before offset are read and discarded, so it
+// must start at the beginning of the data.
+func WithResumeFrom(offset int64) RequestOption {
+	return func(opts *requestOptions) {
+		opts.resumeFrom = offset
+	}
+}
+
+// WithIfRange returns a RequestOption which causes a Download resumed with
+// WithResumeFrom to only resume if the resource still has the given ETag,
+// which is typically the ETag of a DownloadError. Otherwise the download
+// starts over. Downloads are only resumed if a strong ETag is given.
+func WithIfRange(etag string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.ifRange = etag
+	}
+}
+
+// WithUpsert returns a RequestOption which causes FirstOrCreate to use the
+// server's upsert endpoint (a PUT request to the url for the model's id)
+// instead of searching for an existing model first. It should only be used
+// if the server supports creating models with client-chosen ids.
+func WithUpsert() RequestOption {
+	return func(opts *requestOptions) {
+		opts.upsert = true
+	}
+}
+
+// WithEmptyBody returns a RequestOption which causes Create to send a request
+// with no body and no Content-Type header, for endpoints where the server
+// generates everything about the new model and rejects requests with a body.
+// The response is still used to set the fields of the model.
+func WithEmptyBody() RequestOption {
+	return func(opts *requestOptions) {
+		opts.emptyBody = true
+	}
+}
+
+// WithQuery returns a RequestOption which adds query to the query string of the
+// request url, e.g. to filter the models returned by ReadAll. query may be a
+// Query, a url.Values, or a struct which will be encoded with EncodeQuery.
+func WithQuery(query interface{}) RequestOption {
+	return func(opts *requestOptions) {
+		opts.query = query
+	}
+}
@@ -14,15 +14,17 @@
 package rest
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"reflect"
-	"strings"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // ContentType represents a Content-Type header.
@@ -41,15 +43,288 @@ type Client struct {
 	// is ContentURLEncoded, which corresponds to the Content-Type header
 	// "application/x-www-form-urlencoded". To send requests encoded as JSON,
 	// you can set this to ContentJSON, which corresponds to the Content-Type
-	// header "application/json".
+	// header "application/json". Other content types can be used once an
+	// Encoder has been registered for them with RegisterEncoder.
 	ContentType ContentType
+	// UseNumber causes numbers in JSON responses to be decoded as json.Number
+	// instead of float64 whenever the destination is an interface{}. Use it to
+	// avoid losing precision on large integers or monetary amounts.
+	UseNumber bool
+	// FieldMatching determines how keys in JSON responses are matched to
+	// struct fields. By default (MatchExact), keys are matched the same way as
+	// the encoding/json package. Use MatchTolerant or MatchStrict to also match
+	// keys which use a different naming convention, e.g. "is_completed" for a
+	// field named IsCompleted.
+	FieldMatching FieldMatching
+	// WarnFloatMoney causes the client to log a warning whenever it encounters
+	// a model with a float32 or float64 field tagged with `rest:",money"`.
+	// Floats cannot represent most decimal amounts exactly, so such fields
+	// should use a decimal type or a *big.Int/*big.Rat instead.
+	WarnFloatMoney bool
+	// BodyDigest is the algorithm used to compute a checksum over the body of
+	// each request. If it is DigestMD5, the checksum is sent in a Content-MD5
+	// header. If it is DigestSHA256, the checksum is sent in a Digest header.
+	// By default no checksum is sent.
+	BodyDigest DigestAlgorithm
+	// DigestHeader is the name of the header used to send SHA-256 checksums
+	// and to look for them in responses. The default is "Digest".
+	DigestHeader string
+	// VerifyResponseDigest causes the client to verify any Content-MD5 or
+	// Digest headers sent by the server against the body of the response. If
+	// a checksum does not match, a DigestError is returned.
+	VerifyResponseDigest bool
+	// Cache is used to store the responses to GET requests. If it is not nil,
+	// Read and ReadAll will use a cached response when one is available
+	// instead of sending a request, and Create, Update, Put, and Delete will
+	// remove any cached responses for the model they change. By default there
+	// is no cache.
+	Cache CacheStore
+	// CacheTTL is how long cached responses remain valid. If it is zero, cached
+	// responses never expire.
+	CacheTTL time.Duration
+	// CacheTTLs overrides CacheTTL for particular resources. The keys are root
+	// urls (as returned by the RootURL method of a model) and the values are
+	// how long responses for urls starting with that root url remain valid.
+	// For example, todos could be cached for 30 seconds and users for 10
+	// minutes. If more than one root url matches, the longest one wins.
+	CacheTTLs map[string]time.Duration
+	// CacheRefreshAhead, if not zero, causes cached responses which will
+	// expire within CacheRefreshAhead to be refreshed in the background the
+	// next time they are used, so that popular entries are kept fresh instead
+	// of expiring and being fetched by many callers at once. Concurrent
+	// requests for the same url are always combined into one when there is a
+	// Cache.
+	CacheRefreshAhead time.Duration
+	// OnDeprecation, if not nil, is called whenever the server responds with a
+	// Deprecation, Sunset, or Warning header. It can be used to find out about
+	// deprecated endpoints before they stop working.
+	OnDeprecation func(info DeprecationInfo)
+	// LogDeprecations causes the client to log a warning whenever the server
+	// responds with a Deprecation, Sunset, or Warning header.
+	LogDeprecations bool
+	// AllowedMethods, if not empty, is the list of http methods (e.g. "GET")
+	// the client is permitted to use. Any attempt to send a request with a
+	// different method fails immediately with ErrMethodNotAllowedByPolicy,
+	// without sending anything over the network. It can be used to create a
+	// read-only client.
+	AllowedMethods []string
+	// Header contains headers which are added to every request sent by the
+	// client, unless the request already has a header with the same name.
+	// Template variables (see SetVar) are substituted into the values.
+	Header http.Header
+	// ResourceHeaders contains headers which are only added to requests for
+	// particular resources, keyed by root url (e.g. "/todos" or
+	// "https://api.example.com/todos"). A request matches a root url if its
+	// url is the root url or starts with it followed by "/" or "?". Headers
+	// for a longer matching root url take precedence, and all of them take
+	// precedence over Header. This keeps e.g. experimental feature flags from
+	// leaking to every endpoint. Template variables (see SetVar) are
+	// substituted into the root urls and values.
+	ResourceHeaders map[string]http.Header
+	// CacheVary lists request headers whose values are part of the cache key,
+	// e.g. "Accept-Language" or "Authorization", so that responses which vary
+	// by locale or user are cached separately instead of colliding. The value
+	// of each header is the one the request would be sent with, taking Header,
+	// ResourceHeaders, and model policies into account. Values are hashed, so
+	// credentials do not appear in the keys of the Cache.
+	CacheVary []string
+	// MaxConcurrentRequests is the maximum number of requests the client will
+	// send at the same time. Additional requests wait in a queue until a slot
+	// becomes available or their context is canceled. Zero means no limit. It
+	// must be set before the first request is sent.
+	MaxConcurrentRequests int
+	// MaxConcurrentRequestsPerHost is like MaxConcurrentRequests, but limits
+	// the number of requests to each host separately.
+	MaxConcurrentRequestsPerHost int
+	// HTTPClient is the http.Client used to send requests. If it is nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+	// Transport, if not nil, sends the requests of the client instead of
+	// HTTPClient. See Transport.
+	Transport Transport
+	// PatchMode determines the format of the body sent by Update. By default
+	// (PatchFields), the fields of the model are encoded according to
+	// ContentType. It can be overridden for a single call with the
+	// WithPatchMode option.
+	PatchMode PatchMode
+	// Retry determines whether and how failed requests are retried. If it is
+	// nil, requests are never retried.
+	Retry *RetryPolicy
+	// Failover, if not nil, causes requests to be sent to alternative
+	// endpoints when the primary one is unreachable or failing. Use it along
+	// with Retry so that failed requests are retried on the next endpoint. It
+	// can be changed at any time, which resets the failover state.
+	Failover *FailoverPolicy
+	// RPCURL is the url prefix used by Call, e.g.
+	// "https://api.example.com/twirp".
+	RPCURL string
+	// OnTiming, if not nil, is called with a breakdown of the time taken by
+	// each request once its response headers have been received. It can be
+	// used to feed a metrics collector or to diagnose slow API calls. See
+	// also the WithTiming option.
+	OnTiming func(method string, url string, timing Timing)
+	// OnDecode, if not nil, is called with the model (or slice of models)
+	// each response was decoded into, before it is handed back to the caller.
+	// If it returns an error, the method which sent the request returns that
+	// error, so it can be used to enforce invariants centrally, e.g. that
+	// every model has an id after Create. Note that the model has already
+	// been mutated by then.
+	OnDecode func(method string, url string, v interface{}) error
+	// OnError, if not nil, is called whenever a request fails, with a report
+	// which classifies the failure as a network error, a timeout, a 4xx or 5xx
+	// response, or a response which could not be decoded. It can be used to
+	// feed failures into an error reporting service from one place instead of
+	// wrapping every call. Requests canceled by the caller are not reported.
+	OnError func(report ErrorReport)
+	// Authorize, if not nil, is called before sending each request for a
+	// model whose Policy requires authorization scopes, e.g. to attach a
+	// token which grants those scopes. If it returns an error, the request is
+	// not sent. Requests for models which require scopes fail if Authorize
+	// is nil.
+	Authorize func(req *http.Request, scopes []string) error
+	// FieldsParam is the name of the query parameter used by WithFields. The
+	// default is "fields".
+	FieldsParam string
+	// CompositeKeyPattern determines how the ids of a CompositeModel are
+	// joined into the path of its url. "{0}", "{1}", and so on are replaced
+	// with the escaped ids in order, e.g. "{0}/lines/{1}" or "{0};{1}". The
+	// default is to join the ids with slashes.
+	CompositeKeyPattern string
+	// RecordsKey and MetaKey are the keys of the records and of the
+	// collection-level metadata in the responses to ReadAll with WithMeta.
+	// The defaults are "data" and "meta".
+	RecordsKey string
+	MetaKey    string
+	// BaseURL, if not empty, is prepended to relative urls, so that models
+	// can return relative RootURLs (e.g. "/todos") and the same code can
+	// target different servers, e.g. "https://staging.example.com/api".
+	// It may contain template variables (see SetVar). Absolute urls are sent
+	// as is. The default is to send relative urls as they are, which in the
+	// browser means they are relative to the current page.
+	BaseURL string
+	// RouteResolver, if not nil, chooses the base url of the requests sent
+	// by Create, Read, ReadAll, Update, Put, and Delete, e.g. to route them
+	// to the shard which holds a model. See RouteResolver.
+	RouteResolver RouteResolver
+	// URLConvention determines where the id of a model goes in the urls
+	// used by Read, Update, Put, and Delete. The default is IdInPath; use
+	// IdInQuery for APIs which expect the id in a query parameter.
+	URLConvention URLConvention
+	// ReadByMode determines how ReadBy addresses a model by an alternate key.
+	// The default is ReadByPath.
+	ReadByMode ReadByMode
+	// MultiGet determines how ReadMany requests several models at once. The
+	// default is MultiGetCommaSeparated.
+	MultiGet MultiGetStyle
+	// MultiGetParam is the name of the query parameter ReadMany uses for the
+	// ids. The default is "ids".
+	MultiGetParam string
+	// MultiGetConcurrency is the maximum number of requests ReadMany sends at
+	// once when it reads each model separately. The default is 4.
+	MultiGetConcurrency int
+	// IncludeMode determines how the relations given by WithInclude are
+	// loaded. The default is IncludeQuery.
+	IncludeMode IncludeMode
+	// IncludeParam is the name of the query parameter used by WithInclude in
+	// IncludeQuery mode. The default is "include".
+	IncludeParam string
+	// CapabilitiesHeader is the name of the response header which lists the
+	// features of a resource discovered by Capabilities. The default is
+	// "X-Capabilities".
+	CapabilitiesHeader string
+	// CapabilitiesTTL is how long the result of Capabilities is cached. The
+	// default is to cache it until ForgetCapabilities is called.
+	CapabilitiesTTL time.Duration
+	// RememberForbidden causes the client to remember each method and url
+	// (ignoring the query) the server responded to with 403 Forbidden, and
+	// to fail later requests with the same method and url immediately with a
+	// ForbiddenError, without sending anything over the network. It avoids
+	// noisy failed requests when the UI offers actions the user may not be
+	// permitted to take. See also Permitted and RefreshPermissions.
+	RememberForbidden bool
+	// Mirror, if not nil, causes a percentage of the GET requests sent by
+	// Read, ReadAll, and the other methods which decode a response to be
+	// mirrored to a secondary backend, and the responses compared. It can be
+	// used to validate a new backend before cutting over to it.
+	Mirror *MirrorPolicy
+	// JSON is the implementation used to encode JSON request bodies and to
+	// decode responses. The default is StandardJSON, which uses the
+	// encoding/json package.
+	JSON JSONBackend
+	// PII, if not nil, causes the values of the fields of models tagged with
+	// the "pii" option to be masked in error bodies, mirrored responses, and
+	// optionally in the bodies of requests to non-production servers. See
+	// PIIPolicy.
+	PII *PIIPolicy
+	// Quota, if not nil, limits the number of requests the client sends and
+	// the number of bytes it downloads. See QuotaPolicy.
+	Quota *QuotaPolicy
+	// LatencySmoothing is the weight, between 0 and 1, of the latest request
+	// in the moving averages of EndpointStats. Higher values make the
+	// averages follow changes more quickly. The default is 0.2.
+	LatencySmoothing float64
+	// SlowLatency, if positive, is the average latency above which the
+	// requests for a RootURL are considered degraded. See EndpointDegraded.
+	SlowLatency time.Duration
+	// SlowErrorRate, if positive, is the average error rate above which the
+	// requests for a RootURL are considered degraded. See EndpointDegraded.
+	SlowErrorRate float64
+	// ImpersonationHeader is the name of the request header which the
+	// clients returned by As use to tell the server which user they act on
+	// behalf of. The default is "X-On-Behalf-Of".
+	ImpersonationHeader string
+	// Timeout, if positive, is the maximum time a request may take,
+	// including all retries and reading the response body. Requests which
+	// take longer are aborted and return a TimeoutError. It can be
+	// overridden for a single request with WithTimeout. The default is no
+	// timeout.
+	Timeout time.Duration
+	// AutoRegister causes Read and ReadAll to add the types of the models
+	// they read to the model registry, as if they had been passed to
+	// Register, unless a type is already registered under the same name.
+	// Types whose RootURL panics or depends on fields which are not set are
+	// skipped.
+	AutoRegister bool
+	// vars holds the template variables set with SetVar
+	vars map[string]string
+	// limiter enforces MaxConcurrentRequests and MaxConcurrentRequestsPerHost
+	limiter *limiter
+	// events is the EventBus returned by Events
+	events *EventBus
+	// failover holds the state of Failover
+	failover *failover
+	// unhealthy records which urls failed their last health check
+	unhealthy map[string]bool
+	// flights holds the GET requests which are in progress, by url
+	flights map[string]*flight
+	// capabilities holds the result of Capabilities, by expanded root url
+	capabilities map[string]Capabilities
+	// forbidden holds the methods and urls the server responded to with 403
+	// Forbidden, if RememberForbidden is true
+	forbidden map[string]bool
+	// quota holds the usage counted against Quota
+	quota *quota
+	// endpoints holds the EndpointStats for each RootURL
+	endpoints map[string]EndpointStats
+	// cacheKeys holds the keys of the cache entries stored by the client,
+	// by expanded url without the query, so that every variant of a url can
+	// be invalidated
+	cacheKeys map[string]map[string]bool
+	// mut protects vars, limiter, events, failover, unhealthy, flights,
+	// capabilities, forbidden, quota, endpoints, and cacheKeys
+	mut sync.RWMutex
 }
 
-// NewClient returns a new client with all the default settings.
-func NewClient() *Client {
-	return &Client{
+// NewClient returns a new client with all the default settings, modified by
+// opts, which are applied in order.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
 		ContentType: ContentURLEncoded,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Model must be satisfied by all models. Satisfying this interface allows you to
@@ -58,43 +333,113 @@ func NewClient() *Client {
 // Because of the way reflection is used to encode the data, a Model must have an
 // underlying type of a struct, and all fields you wish to be included in requests
 // and responses must be exported.
+//
+// Fields can be filled in from the headers of responses with a struct tag of
+// the form `rest:"-,header=ETag" json:"-"`. The "-" excludes the field from
+// url-encoded requests (the json tag does the same for JSON). This lets e.g. a
+// version token travel with the model for later conditional requests.
+// Headers are not available for responses served from the Cache.
 type Model interface {
 	// ModelId returns a unique identifier for the model. It is used for determining
-	// which URL to send a request to.
+	// which URL to send a request to, and is path escaped, so it may contain
+	// characters such as slashes or spaces.
 	ModelId() string
 	// RootURL returns the url for the REST resource corresponding to this model.
 	// If you want to send requests to the same server, it should look something
 	// like "/todos". If you want to send requests to a different server, you can
-	// include the entire domain in the url, e.g. "http://example.com/todos". Note
-	// that the trailing slash should not be included.
+	// include the entire domain in the url, e.g. "http://example.com/todos".
+	// Trailing and duplicate slashes are removed before it is used. A RootURL
+	// which is empty, contains whitespace, or has a scheme other than http or
+	// https causes requests to fail with a URLError.
 	RootURL() string
 }
 
+// ContentTyper can optionally be implemented by models which need to be sent
+// with a different encoding than the rest. If a model implements ContentTyper,
+// the ContentType it returns is used instead of the ContentType of the client
+// for Create and Update. Returning an empty string means the client's
+// ContentType should be used.
+type ContentTyper interface {
+	ContentType() ContentType
+}
+
 // Create sends an http request to create the given model. It uses reflection to
 // convert the fields of model to url-encoded data. Then it sends a POST request to
 // model.RootURL() with the encoded data in the body and the appropriate Content-Type
 // header. It expects a JSON response containing the created object from the server
 // if the request was successful, in which case it will mutate model by setting the
 // fields to the values in the JSON response. Since model may be mutated, it should
-// be a pointer.
-func (c *Client) Create(model Model) error {
-	fullURL := model.RootURL()
-	encodedModelData, err := c.encodeFields(model)
+// be a pointer. If model has no fields which can be encoded, or if the
+// WithEmptyBody option is provided, the request is sent without a body.
+func (c *Client) Create(model Model, opts ...RequestOption) error {
+	if err := checkModelPointer("Create", model); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	c.checkMoneyFields(model)
+	rootURL, err := c.routeRootURL(model, ActionCreate)
 	if err != nil {
 		return err
 	}
-	return c.sendRequestAndUnmarshal("POST", fullURL, encodedModelData, model)
+	fullURL, err := normalizeRootURL(rootURL)
+	if err != nil {
+		return err
+	}
+	var contentType ContentType
+	var encodedModelData string
+	if !reqOpts.emptyBody && hasEncodableFields(model) {
+		contentType = c.contentTypeFor(model)
+		encodedModelData, err = c.encodeFields(model, contentType)
+		if err != nil {
+			return err
+		}
+	}
+	if err := c.sendRequestAndUnmarshal("POST", fullURL, contentType, encodedModelData, model, reqOpts); err != nil {
+		return err
+	}
+	c.invalidateCache(model)
+	c.publishModelEvent(reqOpts.context(), ModelCreated, "POST", fullURL, model)
+	return nil
 }
 
 // Read sends an http request to read (or fetch) the model with the given id
-// from the server. It sends a GET request to model.RootURL() + "/" + model.ModelId().
+// from the server. It sends a GET request to model.RootURL() + "/" + id, where id
+// is path escaped, or to the url given by the client's URLConvention. If model
+// implements CompositeModel and id is empty, the url is built from
+// model.ModelIds() instead. If model.RootURL() is malformed, Read returns a
+// URLError.
 // Read expects a JSON response containing the data for the requested model if the
 // request was successful, in which case it will mutate model by setting the fields
 // to the values in the JSON response. Since model may be mutated, it should be
 // a pointer.
-func (c *Client) Read(id string, model Model) error {
-	fullURL := model.RootURL() + "/" + id
-	return c.sendRequestAndUnmarshal("GET", fullURL, "", model)
+func (c *Client) Read(id string, model Model, opts ...RequestOption) error {
+	if err := checkModelPointer("Read", model); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	c.checkMoneyFields(model)
+	if c.AutoRegister {
+		autoRegister(model)
+	}
+	rootURL, err := c.routeRead(id, model)
+	if err != nil {
+		return err
+	}
+	var fullURL string
+	if _, ok := model.(CompositeModel); ok && id == "" {
+		fullURL, err = c.urlForModelAt(rootURL, model)
+	} else {
+		fullURL, err = c.idURL(rootURL, id)
+	}
+	if err != nil {
+		return err
+	}
+	fullURL = appendQuery(fullURL, mergeQueries(c.fieldsQuery(reqOpts), c.includeQuery(reqOpts)))
+	if err := c.sendRequestAndUnmarshal("GET", fullURL, "", "", model, reqOpts); err != nil {
+		return err
+	}
+	markHydrated(model, reqOpts)
+	return c.loadIncludes(model, reqOpts)
 }
 
 // ReadAll sends an http request to get all the models of a particular
@@ -103,13 +448,57 @@ func (c *Client) Read(id string, model Model) error {
 // where each object contains data for one model. models must be a pointer to a slice
 // of some type which implements Model. ReadAll will mutate models by growing or shrinking
 // the slice as needed, and by setting the fields of each element to the values in the JSON
-// response.
-func (c *Client) ReadAll(models interface{}) error {
-	rootURL, err := getURLFromModels(models)
+// response. The WithQuery option can be used to filter the models returned by
+// the server, the WithMerge option can be used to merge the response into
+// the existing models instead of replacing them, the WithReuse option can be
+// used to reuse the memory held by the existing models, and the WithInclude
+// option can be used to load related models as well.
+func (c *Client) ReadAll(models interface{}, opts ...RequestOption) error {
+	reqOpts := newRequestOptions(opts)
+	if err := checkModelsType(models, reqOpts.merge); err != nil {
+		return err
+	}
+	reqOpts.forModel(models)
+	if c.AutoRegister {
+		autoRegisterModels(models)
+	}
+	query, err := toQuery(reqOpts.query)
+	if err != nil {
+		return err
+	}
+	if fields := c.fieldsQuery(reqOpts); fields != nil {
+		query = mergeQueries(query, fields)
+	}
+	if include := c.includeQuery(reqOpts); include != nil {
+		query = mergeQueries(query, include)
+	}
+	if reqOpts.meta != nil && (reqOpts.merge || reqOpts.reuse) {
+		return fmt.Errorf("rest: WithMeta cannot be combined with WithMerge or WithReuse")
+	}
+	if reqOpts.csv && (reqOpts.merge || reqOpts.reuse || reqOpts.meta != nil) {
+		return fmt.Errorf("rest: WithCSV cannot be combined with WithMerge, WithReuse, or WithMeta")
+	}
+	if reqOpts.csv {
+		return c.readAllCSV(models, query, reqOpts)
+	}
+	if reqOpts.merge {
+		return c.readAllMerge(models, query, reqOpts)
+	}
+	if reqOpts.reuse {
+		return c.readAllReuse(models, query, reqOpts)
+	}
+	rootURL, err := c.routeModels(models)
 	if err != nil {
 		return err
 	}
-	return c.sendRequestAndUnmarshal("GET", rootURL, "", models)
+	var target interface{} = models
+	if reqOpts.meta != nil {
+		target = envelope{records: models, meta: reqOpts.meta}
+	}
+	if err := c.sendRequestAndUnmarshal("GET", appendQuery(rootURL, query), "", "", target, reqOpts); err != nil {
+		return err
+	}
+	return c.loadIncludes(models, reqOpts)
 }
 
 // Update sends an http request to update an existing model, i.e. to change some or all
@@ -118,28 +507,94 @@ func (c *Client) ReadAll(models interface{}) error {
 // the appropriate Content-Type header. Update expects a JSON response containing the data
 // for the updated model if the request was successful, in which case it will mutate model
 // by setting the fields to the values in the JSON response. Since model may be mutated,
-// it should be a pointer.
-func (c *Client) Update(model Model) error {
-	fullURL := model.RootURL() + "/" + model.ModelId()
-	encodedModelData, err := c.encodeFields(model)
+// it should be a pointer. The client's PatchMode (or the WithPatchMode option) can be
+// used to send a JSON Patch or JSON Merge Patch document instead, in which case the
+// WithOriginal option should be used to provide the model as it was before the changes.
+func (c *Client) Update(model Model, opts ...RequestOption) error {
+	if err := checkModelPointer("Update", model); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	c.checkMoneyFields(model)
+	fullURL, err := c.routeURLForModel(model, ActionUpdate)
 	if err != nil {
 		return err
 	}
-	return c.sendRequestAndUnmarshal("PATCH", fullURL, encodedModelData, model)
+	var contentType ContentType
+	var encodedModelData string
+	if mode := c.patchMode(reqOpts); mode != PatchFields {
+		encodedModelData, contentType, err = encodePatch(mode, reqOpts.original, model)
+	} else {
+		contentType = c.contentTypeFor(model)
+		encodedModelData, err = c.encodeFields(model, contentType)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.sendRequestAndUnmarshal("PATCH", fullURL, contentType, encodedModelData, model, reqOpts); err != nil {
+		return err
+	}
+	c.invalidateCache(model)
+	c.publishModelEvent(reqOpts.context(), ModelUpdated, "PATCH", fullURL, model)
+	return nil
+}
+
+// Put sends an http request to create or replace the model with the id given by
+// model.ModelId(). Unlike Update, which sends a PATCH request and may be used to
+// change only some of the fields, Put sends a PUT request containing all of the
+// fields of model to model.RootURL() + "/" + model.ModelId(). Servers typically
+// treat this as an idempotent "create or replace", which means it can be used to
+// create models with client-chosen ids. Put expects a JSON response containing
+// the data for the model if the request was successful, in which case it will
+// mutate model by setting the fields to the values in the JSON response. Since
+// model may be mutated, it should be a pointer.
+func (c *Client) Put(model Model, opts ...RequestOption) error {
+	if err := checkModelPointer("Put", model); err != nil {
+		return err
+	}
+	reqOpts := newRequestOptions(opts).forModel(model)
+	c.checkMoneyFields(model)
+	fullURL, err := c.routeURLForModel(model, ActionPut)
+	if err != nil {
+		return err
+	}
+	contentType := c.contentTypeFor(model)
+	encodedModelData, err := c.encodeFields(model, contentType)
+	if err != nil {
+		return err
+	}
+	if err := c.sendRequestAndUnmarshal("PUT", fullURL, contentType, encodedModelData, model, reqOpts); err != nil {
+		return err
+	}
+	c.invalidateCache(model)
+	c.publishModelEvent(reqOpts.context(), ModelUpdated, "PUT", fullURL, model)
+	return nil
 }
 
 // Delete sends an http request to delete an existing model. It sends a DELETE request
 // to model.RootURL() + "/" + model.ModelId(). DELETE will not do anything with the
 // response from the server and will not mutate model.
-func (c *Client) Delete(model Model) error {
-	fullURL := model.RootURL() + "/" + model.ModelId()
+func (c *Client) Delete(model Model, opts ...RequestOption) error {
+	reqOpts := newRequestOptions(opts).forModel(model)
+	fullURL, err := c.routeURLForModel(model, ActionDelete)
+	if err != nil {
+		return err
+	}
 	req, err := http.NewRequest("DELETE", fullURL, nil)
 	if err != nil {
 		return fmt.Errorf("Something went wrong building DELETE request to %s: %s", fullURL, err.Error())
 	}
-	if _, err := http.DefaultClient.Do(req); err != nil {
-		return fmt.Errorf("Something went wrong with DELETE request to %s: %s", fullURL, err.Error())
+	res, err := c.do(req.WithContext(reqOpts.context()))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if _, err := c.readResponse(res); err != nil {
+		return err
 	}
+	recordAccepted(res, reqOpts)
+	c.invalidateCache(model)
+	c.publishModelEvent(reqOpts.context(), ModelDeleted, "DELETE", fullURL, model)
 	return nil
 }
 
@@ -148,22 +603,22 @@ func (c *Client) Delete(model Model) error {
 // calling RootURL on it. models should be a pointer to a slice of models.
 func getURLFromModels(models interface{}) (string, error) {
 	// Check the type of models
-	typ := reflect.TypeOf(models)
-	switch {
-	// Make sure its a pointer
-	case typ.Kind() != reflect.Ptr:
-		return "", fmt.Errorf("models must be a pointer to a slice of models. %T is not a pointer.", models)
-	// Make sure its a pointer to a slice
-	case typ.Elem().Kind() != reflect.Slice:
-		return "", fmt.Errorf("models must be a pointer to a slice of models. %T is not a pointer to a slice", models)
-	// Make sure the type of the elements of the slice implement Model
-	case !typ.Elem().Elem().Implements(reflect.TypeOf([]Model{}).Elem()):
-		return "", fmt.Errorf("models must be a pointer to a slice of models. The elem type %s does not implement model", typ.Elem().Elem().String())
+	// Make sure it is a pointer to a slice of models
+	if err := checkModelsType(models, false); err != nil {
+		return "", err
 	}
 	// modelType is the type of the elements of models
-	modelType := typ.Elem().Elem()
+	modelType := reflect.TypeOf(models).Elem().Elem()
+	// Once we have a Model we can get what we wanted by calling RootURL
+	return normalizeRootURL(newModelOfType(modelType).Interface().(Model).RootURL())
+}
+
+// newModelOfType instantiates a new value of the given type, which should
+// implement Model. If modelType is a pointer type, the returned value points
+// to a newly allocated zero value.
+func newModelOfType(modelType reflect.Type) reflect.Value {
 	// Ultimately, we need to be able to instantiate a new object of a type that
-	// implements Model so that we can call RootURL on it. The trouble is that
+	// implements Model so that we can call its methods. The trouble is that
 	// reflect.New only works for things that are not pointers, and the type of
 	// the elements of models could be pointers. To solve for this, we are going
 	// to get the Elem of modelType if it is a pointer and keep track of the number
@@ -183,65 +638,307 @@ func getURLFromModels(models interface{}) (string, error) {
 	for i := 0; i < numDeref; i++ {
 		newModelVal = newModelVal.Addr()
 	}
-	// Now we can use a type assertion to convert the object we instantiated to a Model
-	newModel := newModelVal.Interface().(Model)
-	// Finally, once we have a Model we can get what we wanted by calling RootURL
-	return newModel.RootURL(), nil
+	return newModelVal
+}
+
+// do sends req and returns the response. Every request sent by the client
+// goes through do. If the client has a RetryPolicy, do takes care of retrying
+// the request. The errors returned by do are suitable for returning directly
+// to the caller.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	return c.doWith(req, c.sender().Do)
+}
+
+// doWith is like do but uses send to actually send each attempt.
+func (c *Client) doWith(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if err := c.resolveBaseURL(req); err != nil {
+		return nil, err
+	}
+	if timeout := c.timeoutFor(req); timeout > 0 {
+		return c.doWithTimeout(req, send, timeout)
+	}
+	if err := c.checkMethodAllowed(req.Method); err != nil {
+		return nil, err
+	}
+	c.applyHeadersAndVars(req)
+	if err := c.checkForbidden(req); err != nil {
+		return nil, err
+	}
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	retry := c.retryFor(req)
+	timing, req := c.startTiming(req)
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if timing != nil {
+			timing.Attempts = attempt
+		}
+		res, err := c.attempt(req, send)
+		if !retry.shouldRetry(req, res, err, attempt) {
+			c.recordEndpoint(req, res, err, time.Since(start))
+			if err != nil {
+				if ctxErr := req.Context().Err(); ctxErr != nil {
+					// Report that the request was canceled rather than how
+					// the transport happened to notice
+					err = ctxErr
+				}
+				c.reportSendError(req, unwrapURLError(err))
+				if err != ErrAttemptBudgetExhausted && err != ErrQuotaExceeded && err != req.Context().Err() {
+					err = fmt.Errorf("Something went wrong with %s request to %s: %s", req.Method, req.URL.String(), err.Error())
+				}
+				c.publish(Event{
+					Type:    RequestFailed,
+					Method:  req.Method,
+					URL:     req.URL.String(),
+					Err:     err,
+					Context: req.Context(),
+				})
+				return nil, err
+			}
+			if res.StatusCode >= 400 && !acceptsStatus(req, res.StatusCode) {
+				if res.StatusCode == http.StatusForbidden {
+					c.rememberForbidden(req)
+				}
+				c.reportStatus(req, res.StatusCode)
+				c.publish(Event{
+					Type:       RequestFailed,
+					Method:     req.Method,
+					URL:        req.URL.String(),
+					StatusCode: res.StatusCode,
+					Err: HTTPError{
+						URL:        req.URL.String(),
+						StatusCode: res.StatusCode,
+					},
+					Context: req.Context(),
+				})
+			}
+			c.checkDeprecation(res)
+			c.finishTiming(req, timing, start)
+			return res, nil
+		}
+		if res != nil {
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}
+		select {
+		case <-time.After(retry.backoff(attempt)):
+		case <-req.Context().Done():
+			c.reportSendError(req, req.Context().Err())
+			return nil, req.Context().Err()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// attempt makes a single attempt at sending req with send, subject to the
+// attempt budget of its context and the client's concurrency limits.
+func (c *Client) attempt(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if err := takeAttempt(req.Context()); err != nil {
+		return nil, err
+	}
+	if err := c.countRequest(); err != nil {
+		return nil, err
+	}
+	release, err := c.getLimiter().acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	finishTrace := func() {}
+	if timing := timingFrom(req.Context()); timing != nil {
+		req, finishTrace = traceAttempt(req, timing)
+	}
+	debug := debugging()
+	if debug {
+		c.dumpRequest(req)
+	}
+	start := time.Now()
+	res, err := c.sendWithFailover(req, send)
+	if err != nil {
+		if debug {
+			c.dumpError(req, err)
+		}
+		release()
+		return nil, err
+	}
+	finishTrace()
+	if debug {
+		c.dumpResponse(req, res, time.Since(start))
+	}
+	if c.Quota != nil {
+		res.Body = countingBody{ReadCloser: res.Body, client: c}
+	}
+	res.Body = releaseOnClose{ReadCloser: res.Body, release: release}
+	return res, nil
 }
 
 // sendRequestAndUnmarshal constructs a request with the given method, url, and
 // data. If data is an empty string, it will construct a request without any
 // data in the body. If data is a non-empty string, it will send it as the body
-// of the request and set the Content-Type header depending on what contentType has
-// been set to. Then sendRequestAndUnmarshal sends the request using http.DefaultClient
-// and marshals the response into v using the json package.
-// TODO: do something if the response status code is non-200.
-func (c *Client) sendRequestAndUnmarshal(method string, url string, data string, v interface{}) error {
-	// Build the request
-	var reqBody io.Reader = nil
-	if data != "" {
-		reqBody = strings.NewReader(data)
+// of the request and set the Content-Type header to contentType. Then
+// sendRequestAndUnmarshal sends the request using c.do and unmarshals the
+// response into v using the json package, or into the targets given by
+// WithDecodeInto. reqOpts may be nil.
+func (c *Client) sendRequestAndUnmarshal(method string, url string, contentType ContentType, data string, v interface{}, reqOpts *requestOptions) error {
+	if reqOpts != nil && reqOpts.decodeInto != nil {
+		v = reqOpts.decodeInto
 	}
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return fmt.Errorf("Something went wrong building %s request to %s: %s", method, url, err.Error())
-	}
-	// Set the Content-Type header only if data was provided
+	rb := c.NewRequestBuilder(method, url).Into(v)
 	if data != "" {
-		req.Header.Set("Content-Type", string(c.ContentType))
+		rb.RawBody([]byte(data), contentType)
 	}
-	// Specify that we want json as the response type. This is especially useful
-	// for applications which share things between client and server
-	req.Header.Set("Accept", "application/json")
-	// Send the request using the default client
-	res, err := http.DefaultClient.Do(req)
+	return rb.execute(reqOpts)
+}
+
+// unmarshalResponse checks the status code of res, returning an HTTPError if
+// it is non-2xx, and then reads the response body and unmarshals it into v.
+func (c *Client) unmarshalResponse(res *http.Response, v interface{}) error {
+	body, err := c.readResponse(res)
 	if err != nil {
-		return fmt.Errorf("Something went wrong with %s request to %s: %s", req.Method, req.URL.String(), err.Error())
+		return err
 	}
+	return c.unmarshal(body, v)
+}
+
+// readResponse checks the status code of res, returning an HTTPError if it is
+// non-2xx and was not accepted with WithAcceptStatus, and then reads and
+// returns the response body.
+func (c *Client) readResponse(res *http.Response) ([]byte, error) {
 	// Check if the status code is 2xx, indicating success
-	if res.StatusCode/100 != 2 {
-		return newHTTPError(res)
+	if !successful(res) {
+		return nil, newHTTPError(res)
+	}
+	if hasNoContent(res) {
+		return nil, nil
 	}
-	// Unmarshal the response into v
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return fmt.Errorf("Couldn't read response to %s: %s", res.Request.URL.String(), err.Error())
+		if _, ok := err.(TimeoutError); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Couldn't read response to %s: %s", res.Request.URL.String(), err.Error())
+	}
+	if err := c.verifyResponseDigest(res, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// unmarshal decodes the json data into v. If data is empty, v is left
+// unchanged. If c.UseNumber is true, numbers
+// are decoded as json.Number instead of float64. If v is a DecodeInto, each
+// top-level key of data is decoded into the corresponding target. Keys are
+// matched to struct fields according to c.FieldMatching.
+func (c *Client) unmarshal(data []byte, v interface{}) error {
+	if isEmptyBody(data) {
+		// Nothing to decode, e.g. a 204 No Content response
+		return nil
+	}
+	if targets, ok := v.(DecodeInto); ok {
+		return c.decodeInto(data, targets)
+	}
+	if env, ok := v.(envelope); ok {
+		return c.decodeEnvelope(data, env)
+	}
+	if c.FieldMatching != MatchExact && v != nil {
+		var err error
+		if data, err = c.matchFields(data, reflect.TypeOf(v)); err != nil {
+			return err
+		}
+	}
+	if c.FieldMatching == MatchExact && c.JSON == nil {
+		if ok, err := unmarshalFast(data, v); ok && err == nil {
+			return nil
+		}
+		// Either there is no FastCodec for v or it failed. In the latter case
+		// the reflective path reports the error the same way as always.
 	}
-	return json.Unmarshal(body, v)
+	backend := c.jsonBackend()
+	if !c.UseNumber {
+		return backend.Unmarshal(data, v)
+	}
+	dec := backend.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
 }
 
-// encodeFields encodes the fields using either json encoding or url encoding, depending
-// on the value of contentType.
-func (c *Client) encodeFields(model Model) (string, error) {
-	switch c.ContentType {
-	case ContentURLEncoded:
-		return urlEncodeFields(model)
-	case ContentJSON:
-		data, err := json.Marshal(model)
+// contentTypeFor returns the ContentType that should be used to encode model.
+// If model implements ContentTyper, its ContentType method takes precedence over
+// c.ContentType.
+func (c *Client) contentTypeFor(model Model) ContentType {
+	if typer, ok := model.(ContentTyper); ok {
+		if contentType := typer.ContentType(); contentType != "" {
+			return contentType
+		}
+	}
+	return c.ContentType
+}
+
+// encodeFields encodes the fields using the Encoder registered for contentType.
+// See RegisterEncoder. The built-in Encoder for ContentJSON is replaced by the
+// JSONBackend of the client.
+func (c *Client) encodeFields(model Model, contentType ContentType) (string, error) {
+	encoder, found := LookupEncoder(contentType)
+	if !found {
+		return "", fmt.Errorf("rest: don't know how to handle ContentType: %s", contentType)
+	}
+	if _, ok := encoder.(jsonEncoder); ok && c.JSON != nil {
+		data, err := c.JSON.Marshal(model)
 		return string(data), err
-	default:
-		return "", fmt.Errorf("rest: don't know how to handle ContentType: %s", c.ContentType)
 	}
+	data, err := encoder.Encode(model)
+	return string(data), err
+}
+
+// hasEncodableFields returns true iff model has at least one field which would
+// be included in the body of a request, i.e. an exported field which is not
+// excluded with a "-" tag.
+func hasEncodableFields(model Model) bool {
+	if mapModel, ok := model.(*MapModel); ok && mapModel != nil {
+		return len(mapModel.Fields) > 0
+	}
+	modelVal := reflect.ValueOf(model)
+	for modelVal.Kind() == reflect.Ptr {
+		if modelVal.IsNil() {
+			return false
+		}
+		modelVal = modelVal.Elem()
+	}
+	if modelVal.Kind() != reflect.Struct {
+		// Let encodeFields decide what to do with it
+		return true
+	}
+	return structHasEncodableFields(modelVal.Type())
+}
+
+// structHasEncodableFields implements hasEncodableFields for the struct type
+// typ, including the fields of embedded structs.
+func structHasEncodableFields(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type == hydrationStateType || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if name, _ := parseTag(field); name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !field.Type.Implements(textMarshalerType) {
+			if structHasEncodableFields(field.Type) {
+				return true
+			}
+			continue
+		}
+		if field.PkgPath == "" {
+			return true
+		}
+	}
+	return false
 }
 
 // urlEncodeFields returns the fields of model represented as a url-encoded string.
@@ -249,22 +946,65 @@ func (c *Client) encodeFields(model Model) (string, error) {
 // It returns an error if model is a nil pointer or if it is not a struct or a pointer
 // to a struct. Any fields that are nil will not be added to the url-encoded string.
 func urlEncodeFields(model Model) (string, error) {
+	if mapModel, ok := model.(*MapModel); ok && mapModel != nil {
+		values, err := mapModel.urlValues()
+		if err != nil {
+			return "", err
+		}
+		return values.Encode(), nil
+	}
+	if codec, found := lookupFastCodec(reflect.TypeOf(model)); found && !isNilPointer(model) {
+		data, err := codec.AppendURLEncoded(nil, model)
+		return string(data), err
+	}
 	modelVal := reflect.ValueOf(model)
 	// dereference the pointer until we reach the underlying struct value.
 	for modelVal.Kind() == reflect.Ptr {
 		if modelVal.IsNil() {
-			return "", errors.New("Error encoding model as url-encoded data: model was a nil pointer.")
+			return "", TypeError{Type: modelVal.Type(), Expected: "a struct or a non-nil pointer to a struct to url-encode", Hint: "the pointer was nil"}
 		}
 		modelVal = modelVal.Elem()
 	}
 	// Make sure the type of model after dereferencing is a struct.
 	if modelVal.Kind() != reflect.Struct {
-		return "", fmt.Errorf("Error encoding model as url-encoded data: model must be a struct or a pointer to a struct.")
+		var typ reflect.Type
+		if modelVal.IsValid() {
+			typ = modelVal.Type()
+		}
+		return "", TypeError{Type: typ, Expected: "a struct or a pointer to a struct to url-encode", Hint: "use ContentJSON or register an Encoder to send other kinds of models"}
 	}
 	values := url.Values{}
-	for i := 0; i < modelVal.Type().NumField(); i++ {
-		field := modelVal.Type().Field(i)
-		fieldValue := modelVal.FieldByName(field.Name)
+	if err := addURLEncodedFields(values, modelVal); err != nil {
+		return "", err
+	}
+	return values.Encode(), nil
+}
+
+// addURLEncodedFields adds the fields of structVal to values. The fields of
+// embedded structs (e.g. DefaultId) are added as if they belonged to
+// structVal itself.
+func addURLEncodedFields(values url.Values, structVal reflect.Value) error {
+	for i := 0; i < structVal.Type().NumField(); i++ {
+		field := structVal.Type().Field(i)
+		if field.Type == hydrationStateType {
+			// Bookkeeping, not data
+			continue
+		}
+		if name, _ := parseTag(field); name == "-" {
+			continue
+		}
+		if field.PkgPath != "" && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			// Unexported fields are never encoded, but the exported fields of
+			// unexported embedded structs are
+			continue
+		}
+		fieldValue := structVal.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !field.Type.Implements(textMarshalerType) {
+			if err := addURLEncodedFields(values, fieldValue); err != nil {
+				return err
+			}
+			continue
+		}
 		valueStr, err := encodeString(fieldValue)
 		if err != nil {
 			if err == nilFieldError {
@@ -272,12 +1012,16 @@ func urlEncodeFields(model Model) (string, error) {
 				// to the encoded data.
 				continue
 			}
+			if typeErr, ok := err.(TypeError); ok {
+				typeErr.Expected = fmt.Sprintf("field %s.%s to have a type which can be url-encoded", structVal.Type(), field.Name)
+				return typeErr
+			}
 			// We should return any other kind of error
-			return "", err
+			return err
 		}
 		values.Add(field.Name, valueStr)
 	}
-	return values.Encode(), nil
+	return nil
 }
 
 var nilFieldError = errors.New("field was nil")
@@ -286,24 +1030,57 @@ var nilFieldError = errors.New("field was nil")
 // value has a type which is unsupported. It returns a special error
 // (nilFieldError) if a field has a value of nil. The supported types are int
 // and its variants (int64, int32, etc.), uint and its variants (uint64, uint32,
-// etc.), float32, float64, bool, string, and []byte.
+// etc.), float32, float64, bool, string, []byte, and json.RawMessage (along
+// with any named types based on them), as well as any type with a registered
+// FieldEncoder or which implements encoding.TextMarshaler (e.g. *big.Int,
+// *big.Float, *big.Rat, and most decimal types).
+//
+// encodeString never panics. Floats which are NaN or infinite result in an
+// error, since they cannot be represented in the same way by the server, and
+// so do pointers nested more deeply than maxIndirections (which can only
+// happen with recursive pointer types).
 func encodeString(value reflect.Value) (string, error) {
-	for value.Kind() == reflect.Ptr {
+	if str, ok, err := encodeCustomString(value); ok {
+		return str, err
+	}
+	for i := 0; value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface; i++ {
 		if value.IsNil() {
 			// Skip nil fields
 			return "", nilFieldError
 		}
+		if i == maxIndirections {
+			return "", TypeError{
+				Type:     value.Type(),
+				Expected: "a field type which can be url-encoded",
+				Hint:     "the value is nested too deeply, probably because its type is recursive",
+			}
+		}
 		value = value.Elem()
 	}
-	switch v := value.Interface().(type) {
-	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8,
-		float64, float32, bool:
-		return fmt.Sprint(v), nil
-	case string:
-		return v, nil
-	case []byte:
-		return string(v), nil
-	default:
-		return "", fmt.Errorf("Error encoding model as url-encoded data: Don't know how to convert %v of type %T to a string.", v, v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float64, reflect.Float32:
+		f := value.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("rest: cannot url-encode the float %v", f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, value.Type().Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte and json.RawMessage
+			return string(value.Bytes()), nil
+		}
+	}
+	return "", TypeError{
+		Type:     value.Type(),
+		Expected: "a field type which can be url-encoded",
+		Hint:     fmt.Sprintf("register a FieldEncoder for %s, implement encoding.TextMarshaler, or use ContentJSON", value.Type()),
 	}
 }
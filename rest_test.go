@@ -0,0 +1,159 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCRUD(t *testing.T) {
+	newTodoServer(t, "a")
+	client := NewClient()
+	todo := &testTodo{Title: "b"}
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "2" {
+		t.Errorf("Expected Create to set the id but got %q", todo.Id)
+	}
+	todo.IsCompleted = true
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	read := &testTodo{}
+	if err := client.Read("2", read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Title != "b" || !read.IsCompleted {
+		t.Errorf("Unexpected todo: %+v", read)
+	}
+	if err := client.Delete(todo); err != nil {
+		t.Fatal(err)
+	}
+	todos := []*testTodo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0].Title != "a" {
+		t.Errorf("Unexpected todos: %v", todos)
+	}
+}
+
+func TestDeleteFailure(t *testing.T) {
+	newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	events := []Event{}
+	client.Events().Subscribe(func(e Event) {
+		events = append(events, e)
+	}, ModelDeleted)
+	cached := []*testTodo{}
+	if err := client.ReadAll(&cached); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := &testTodo{DefaultId: DefaultId{Id: "404"}}
+	err := client.Delete(missing)
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != 404 {
+		t.Fatalf("Expected an HTTPError with status 404 but got %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no ModelDeleted event for a failed delete but got %v", events)
+	}
+	if _, found := client.cachedBody(testRootURL + "/todos"); !found {
+		t.Error("Expected a failed delete to leave the cache intact")
+	}
+}
+
+func TestPut(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	cached := &testTodo{}
+	if err := client.Read("1", cached); err != nil {
+		t.Fatal(err)
+	}
+
+	created := &testTodo{DefaultId: DefaultId{Id: "chosen"}, Title: "new"}
+	if err := client.Put(created); err != nil {
+		t.Fatal(err)
+	}
+	replaced := &testTodo{DefaultId: DefaultId{Id: "1"}, Title: "replaced", IsCompleted: true}
+	if err := client.Put(replaced); err != nil {
+		t.Fatal(err)
+	}
+	read := &testTodo{}
+	if err := client.Read("1", read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Title != "replaced" || !read.IsCompleted {
+		t.Errorf("Expected the replaced todo to be read past the cache but got %+v", read)
+	}
+	if len(server.todos) != 2 || server.todos[1].Id != "chosen" {
+		t.Errorf("Expected a todo to be created with the chosen id but got %v", server.todos)
+	}
+	if err := client.Put(stringModel("1")); err == nil {
+		t.Error("Expected an error for a non-pointer model")
+	}
+}
+
+// typedTodo is a testTodo which is sent with the ContentType it holds.
+type typedTodo struct {
+	testTodo
+	contentType ContentType
+}
+
+func (todo *typedTodo) ContentType() ContentType { return todo.contentType }
+
+func TestContentTyper(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"Id": "1"}`)
+	client := NewClient()
+	expectSent := func(contentType ContentType) {
+		t.Helper()
+		req := server.last()
+		if got := req.Header.Get("Content-Type"); got != string(contentType) {
+			t.Errorf("Expected a Content-Type of %s for %s but got %s", contentType, req.Method, got)
+		}
+		body := server.lastBody()
+		switch contentType {
+		case ContentJSON:
+			fields := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(body), &fields); err != nil || fields["Title"] != "a" {
+				t.Errorf("Expected the %s body to be encoded as JSON but got %s", req.Method, body)
+			}
+		case ContentURLEncoded:
+			if values, err := url.ParseQuery(body); err != nil || values.Get("Title") != "a" || body[0] == '{' {
+				t.Errorf("Expected the %s body to be url-encoded but got %s", req.Method, body)
+			}
+		}
+	}
+
+	todo := &typedTodo{testTodo: testTodo{Title: "a"}, contentType: ContentJSON}
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	expectSent(ContentJSON)
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	expectSent(ContentJSON)
+
+	// An empty ContentType falls back to the ContentType of the client
+	todo.contentType = ""
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	expectSent(ContentURLEncoded)
+
+	client.ContentType = ContentJSON
+	todo.contentType = ContentURLEncoded
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	expectSent(ContentURLEncoded)
+}
@@ -0,0 +1,375 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+// package resthandler provides http handlers for a RESTful resource which
+// follow the same conventions as the rest package: request bodies may be
+// url-encoded (with field names as keys) or JSON, responses are JSON, and
+// PATCH updates only the fields present in the request. It is useful for
+// building Go backends for apps which use rest as their client.
+//
+// A Handler serves a single resource and expects to be mounted at its root url
+// with the prefix stripped, e.g.:
+//
+//	http.Handle("/todos/", http.StripPrefix("/todos", resthandler.New(newTodo, store)))
+//
+// It can be used with any router that accepts an http.Handler, e.g. with gin:
+//
+//	router.Any("/todos/*path", gin.WrapH(http.StripPrefix("/todos", handler)))
+package resthandler
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-humble/rest"
+)
+
+// ErrNotFound should be returned by a Storage when there is no model with the
+// requested id. It results in a 404 response.
+var ErrNotFound = errors.New("resthandler: not found")
+
+// Storage is the interface a Handler uses to load and persist models.
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// All returns all the models.
+	All() ([]rest.Model, error)
+	// Get returns the model with the given id, or ErrNotFound.
+	Get(id string) (rest.Model, error)
+	// Create stores a new model. It is responsible for assigning an id to
+	// the model.
+	Create(model rest.Model) error
+	// Save stores model under model.ModelId(), replacing any existing model
+	// with the same id.
+	Save(model rest.Model) error
+	// Delete deletes the model with the given id, or returns ErrNotFound.
+	Delete(id string) error
+}
+
+// Validator may be implemented by models to validate them before they are
+// stored. If Validate returns an error, the model is not stored and the
+// handler responds with 422 Unprocessable Entity. If the error is a
+// ValidationErrors, it is sent as is; otherwise it is sent as
+// {"error": "<message>"}.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationErrors maps field names to the problems with their values.
+type ValidationErrors map[string][]string
+
+// Error satisfies the error interface
+func (errs ValidationErrors) Error() string {
+	fields := []string{}
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	msgs := []string{}
+	for _, field := range fields {
+		msgs = append(msgs, field+": "+strings.Join(errs[field], ", "))
+	}
+	return "resthandler: validation failed: " + strings.Join(msgs, "; ")
+}
+
+const statusUnprocessableEntity = 422
+
+// Handler serves the standard CRUD endpoints for a single resource:
+//
+//	GET    /      Index
+//	POST   /      Create
+//	GET    /:id   Show
+//	PATCH  /:id   Update
+//	PUT    /:id   Replace
+//	DELETE /:id   Delete
+//
+// The paths are relative to the root url of the resource.
+type Handler struct {
+	newModel func() rest.Model
+	storage  Storage
+}
+
+// New returns a Handler which stores models in storage. newModel must return a
+// new, empty model (usually a pointer to a struct), into which request bodies
+// are decoded.
+func New(newModel func() rest.Model, storage Storage) *Handler {
+	return &Handler{
+		newModel: newModel,
+		storage:  storage,
+	}
+}
+
+// ServeHTTP satisfies http.Handler by dispatching the request to the method
+// which corresponds to its method and path.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(r.URL.Path, "/")
+	if strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, ErrNotFound)
+		return
+	}
+	switch {
+	case id == "" && r.Method == "GET":
+		h.Index(w, r)
+	case id == "" && r.Method == "POST":
+		h.Create(w, r)
+	case id != "" && r.Method == "GET":
+		h.Show(w, r, id)
+	case id != "" && r.Method == "PATCH":
+		h.Update(w, r, id)
+	case id != "" && r.Method == "PUT":
+		h.Replace(w, r, id)
+	case id != "" && r.Method == "DELETE":
+		h.Delete(w, r, id)
+	default:
+		if id == "" {
+			w.Header().Set("Allow", "GET, POST")
+		} else {
+			w.Header().Set("Allow", "GET, PATCH, PUT, DELETE")
+		}
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+// Index responds with all the models as a JSON array.
+func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
+	models, err := h.storage.All()
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if models == nil {
+		models = []rest.Model{}
+	}
+	writeJSON(w, http.StatusOK, models)
+}
+
+// Create decodes a new model from the request body, stores it, and responds
+// with the stored model (including the id assigned by the Storage).
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	model := h.newModel()
+	if err := decodeBody(r, model); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !validate(w, model) {
+		return
+	}
+	if err := h.storage.Create(model); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model)
+}
+
+// Show responds with the model with the given id.
+func (h *Handler) Show(w http.ResponseWriter, r *http.Request, id string) {
+	model, err := h.storage.Get(id)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model)
+}
+
+// Update applies the fields present in the request body to the model with the
+// given id, stores it, and responds with the updated model.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request, id string) {
+	model, err := h.storage.Get(id)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if err := decodeBody(r, model); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.save(w, model, id)
+}
+
+// Replace decodes a complete model from the request body and stores it under
+// the given id, creating it if it does not exist. It responds with the stored
+// model.
+func (h *Handler) Replace(w http.ResponseWriter, r *http.Request, id string) {
+	model := h.newModel()
+	if err := decodeBody(r, model); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.save(w, model, id)
+}
+
+// save validates and stores model, which must have the given id.
+func (h *Handler) save(w http.ResponseWriter, model rest.Model, id string) {
+	if model.ModelId() != id {
+		writeError(w, statusUnprocessableEntity, fmt.Errorf("id %q in body does not match id %q in url", model.ModelId(), id))
+		return
+	}
+	if !validate(w, model) {
+		return
+	}
+	if err := h.storage.Save(model); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model)
+}
+
+// Delete deletes the model with the given id and responds with 204 No Content.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.storage.Delete(id); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validate validates model if it implements Validator, writing a 422 response
+// and returning false if it is invalid.
+func validate(w http.ResponseWriter, model rest.Model) bool {
+	validator, ok := model.(Validator)
+	if !ok {
+		return true
+	}
+	err := validator.Validate()
+	if err == nil {
+		return true
+	}
+	if errs, ok := err.(ValidationErrors); ok {
+		writeJSON(w, statusUnprocessableEntity, errs)
+	} else {
+		writeError(w, statusUnprocessableEntity, err)
+	}
+	return false
+}
+
+// writeJSON writes v as the JSON body of a response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeError writes err as a response of the form {"error": "<message>"}.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeStorageError writes the response for an error returned by a Storage.
+func writeStorageError(w http.ResponseWriter, err error) {
+	if err == ErrNotFound {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// decodeBody decodes the body of r into model according to its Content-Type.
+// Only the fields present in the body are changed.
+func decodeBody(r *http.Request, model rest.Model) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case string(rest.ContentJSON), string(rest.ContentMergePatch):
+		if err := json.NewDecoder(r.Body).Decode(model); err != nil {
+			return fmt.Errorf("invalid JSON body: %s", err)
+		}
+		return nil
+	case string(rest.ContentURLEncoded), "":
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+		return decodeForm(r.PostForm, model)
+	default:
+		return fmt.Errorf("unsupported Content-Type %q", mediaType)
+	}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// decodeForm sets the fields of model which have a key in values. Keys are
+// field names, matching the url encoding used by the rest package.
+func decodeForm(values url.Values, model rest.Model) error {
+	modelVal := reflect.ValueOf(model)
+	for modelVal.Kind() == reflect.Ptr {
+		modelVal = modelVal.Elem()
+	}
+	if modelVal.Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to a struct, got %T", model)
+	}
+	for key := range values {
+		field := modelVal.FieldByName(key)
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+		if err := setString(field, values.Get(key)); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+// setString parses str according to the type of field and sets field to the
+// result.
+func setString(field reflect.Value, str string) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		if field.Type().Implements(textUnmarshalerType) {
+			return field.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+		}
+		return setString(field.Elem(), str)
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		field.SetBytes([]byte(str))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resthandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-humble/rest"
+)
+
+type todo struct {
+	rest.DefaultId
+	Title       string
+	IsCompleted bool
+	Priority    int
+	Due         *time.Time
+}
+
+// RootURL satisfies rest.Model.
+func (*todo) RootURL() string {
+	return "http://test.local/todos"
+}
+
+// Validate satisfies Validator.
+func (t *todo) Validate() error {
+	if t.Title == "" {
+		return ValidationErrors{"Title": {"is required"}}
+	}
+	if t.Title == "invalid" {
+		return errors.New("title is invalid")
+	}
+	return nil
+}
+
+// memoryStorage is a Storage which holds todos in memory.
+type memoryStorage struct {
+	models map[string]rest.Model
+	nextId int
+	err    error
+	mut    sync.Mutex
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{models: map[string]rest.Model{}, nextId: 1}
+}
+
+func (s *memoryStorage) All() ([]rest.Model, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	models := []rest.Model{}
+	for i := 1; i < s.nextId; i++ {
+		if model, found := s.models[strconv.Itoa(i)]; found {
+			models = append(models, model)
+		}
+	}
+	return models, nil
+}
+
+func (s *memoryStorage) Get(id string) (rest.Model, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	model, found := s.models[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	stored := *model.(*todo)
+	return &stored, nil
+}
+
+func (s *memoryStorage) Create(model rest.Model) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	id := strconv.Itoa(s.nextId)
+	s.nextId++
+	model.(*todo).Id = id
+	s.models[id] = model
+	return nil
+}
+
+func (s *memoryStorage) Save(model rest.Model) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.models[model.ModelId()] = model
+	return nil
+}
+
+func (s *memoryStorage) Delete(id string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, found := s.models[id]; !found {
+		return ErrNotFound
+	}
+	delete(s.models, id)
+	return nil
+}
+
+// newTestClient returns a rest client which sends requests for todos to a
+// Handler backed by storage.
+func newTestClient(storage Storage) *rest.Client {
+	handler := http.StripPrefix("/todos", New(func() rest.Model { return &todo{} }, storage))
+	return rest.FromRoundTripper(rest.HandlerTransport(handler))
+}
+
+func TestHandlerWithClient(t *testing.T) {
+	client := newTestClient(newMemoryStorage())
+	for _, contentType := range []rest.ContentType{rest.ContentURLEncoded, rest.ContentJSON} {
+		client.ContentType = contentType
+		created := &todo{Title: "a", Priority: 2}
+		if err := client.Create(created); err != nil {
+			t.Fatal(err)
+		}
+		if created.Id == "" {
+			t.Fatalf("Expected an id to be assigned with %s", contentType)
+		}
+		created.IsCompleted = true
+		if err := client.Update(created); err != nil {
+			t.Fatal(err)
+		}
+		read := &todo{}
+		if err := client.Read(created.Id, read); err != nil {
+			t.Fatal(err)
+		}
+		if read.Title != "a" || read.Priority != 2 || !read.IsCompleted {
+			t.Errorf("Expected the updated todo with %s but got %+v", contentType, read)
+		}
+	}
+	replaced := &todo{DefaultId: rest.DefaultId{Id: "9"}, Title: "c"}
+	if err := client.Put(replaced); err != nil {
+		t.Fatal(err)
+	}
+	todos := []*todo{}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 {
+		t.Fatalf("Expected 2 todos but got %d", len(todos))
+	}
+	if err := client.Delete(todos[0]); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Read(todos[0].Id, &todo{})
+	if httpErr, ok := err.(rest.HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 for a deleted todo but got %v", err)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	storage := newMemoryStorage()
+	storage.Create(&todo{Title: "a"})
+	handler := New(func() rest.Model { return &todo{} }, storage)
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{method: "GET", path: "/7", status: http.StatusNotFound},
+		{method: "GET", path: "/1/comments", status: http.StatusNotFound},
+		{method: "DELETE", path: "/", status: http.StatusMethodNotAllowed},
+		{method: "POST", path: "/1", status: http.StatusMethodNotAllowed},
+		{method: "POST", path: "/", body: "Priority=2", status: 422, response: `{"Title":["is required"]}`},
+		{method: "POST", path: "/", body: "Title=invalid", status: 422, response: `{"error":"title is invalid"}`},
+		{method: "POST", path: "/", body: "Title=a&Priority=high", status: http.StatusBadRequest},
+		{method: "POST", path: "/", contentType: "text/xml", body: "<todo/>", status: http.StatusBadRequest},
+		{method: "POST", path: "/", contentType: "application/json", body: "{", status: http.StatusBadRequest},
+		{method: "PUT", path: "/1", contentType: "application/json", body: `{"Id": "2", "Title": "a"}`, status: 422},
+		{method: "PATCH", path: "/1", body: "Due=2015-06-01T00:00:00Z", status: http.StatusOK},
+		{method: "PATCH", path: "/1", contentType: "application/merge-patch+json", body: `{"Priority": 3}`, status: http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.contentType == "" {
+			test.contentType = "application/x-www-form-urlencoded"
+		}
+		req.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("Expected status %d for %s %s %q but got %d: %s", test.status, test.method, test.path, test.body, rec.Code, rec.Body.String())
+		}
+		if test.response != "" && strings.TrimSpace(rec.Body.String()) != test.response {
+			t.Errorf("Expected the response %s for %s %s %q but got %s", test.response, test.method, test.path, test.body, rec.Body.String())
+		}
+		if test.status == http.StatusMethodNotAllowed && rec.Header().Get("Allow") == "" {
+			t.Errorf("Expected an Allow header for %s %s", test.method, test.path)
+		}
+	}
+	model, _ := storage.Get("1")
+	if updated := model.(*todo); updated.Due == nil || updated.Due.Year() != 2015 || updated.Priority != 3 {
+		t.Errorf("Expected the PATCH requests to update the todo but got %+v", updated)
+	}
+
+	storage.err = errors.New("database is down")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 for a storage error but got %d", rec.Code)
+	}
+}
+
+func TestDecodeForm(t *testing.T) {
+	model := &todo{Title: "unchanged"}
+	values := url.Values{"IsCompleted": {"true"}, "Priority": {"4"}, "Unknown": {"x"}, "nextId": {"1"}}
+	if err := decodeForm(values, model); err != nil {
+		t.Fatal(err)
+	}
+	if model.Title != "unchanged" || !model.IsCompleted || model.Priority != 4 {
+		t.Errorf("Expected only the fields in the form to change but got %+v", model)
+	}
+	if err := decodeForm(url.Values{"IsCompleted": {"maybe"}}, model); err == nil {
+		t.Error("Expected an error for an invalid bool")
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	errs := ValidationErrors{"Title": {"is required", "is too short"}, "Due": {"is in the past"}}
+	expected := "resthandler: validation failed: Due: is in the past; Title: is required, is too short"
+	if errs.Error() != expected {
+		t.Errorf("Expected %q but got %q", expected, errs.Error())
+	}
+}
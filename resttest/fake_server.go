@@ -0,0 +1,206 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// FakeServer is an httptest.Server which serves resources from records held
+// in memory, following the conventions of the rest package: a resource is
+// served at a url whose last segment is its name (e.g. /todos or
+// /api/v1/todos), and its records at the url of the resource followed by
+// their ids. Request bodies may be url-encoded or JSON, responses are JSON,
+// PATCH updates only the fields present in the request, and GET requests for
+// a resource return the records whose fields match all the query parameters
+// which name a field. Point the RootURL of the models under test at URL, or
+// send requests to it with a client created by rest.FromRoundTripper. The
+// requests a test expects can be scripted with Expect, and the network it is
+// reached through can be simulated with SetProfile.
+type FakeServer struct {
+	*httptest.Server
+	store
+	// Strict causes requests which do not match the next expectation to fail
+	// the test. See Expect.
+	Strict bool
+
+	t            testing.TB
+	expectations []*Expectation
+	scriptMut    sync.Mutex
+	// profiled serves requests under the conditions of a NetworkProfile, if
+	// one was set
+	profiled http.Handler
+}
+
+// NewFakeServer starts a FakeServer, which is closed when the test ends.
+func NewFakeServer(t testing.TB) *FakeServer {
+	s := &FakeServer{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetProfile causes s to serve requests under the conditions of profile,
+// e.g. with the latency and bandwidth of a 3G connection.
+func (s *FakeServer) SetProfile(profile NetworkProfile) {
+	s.scriptMut.Lock()
+	defer s.scriptMut.Unlock()
+	s.profiled = profile.Wrap(http.HandlerFunc(s.serveScripted))
+}
+
+// serve responds to r under the conditions of the profile of s, if any.
+func (s *FakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.scriptMut.Lock()
+	profiled := s.profiled
+	s.scriptMut.Unlock()
+	if profiled != nil {
+		profiled.ServeHTTP(w, r)
+		return
+	}
+	s.serveScripted(w, r)
+}
+
+// serveRecords responds to r from the records of s.
+func (s *FakeServer) serveRecords(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	resource, id := segments[len(segments)-1], ""
+	if len(segments) >= 2 && s.has(segments[len(segments)-2]) {
+		resource, id = segments[len(segments)-2], resource
+	}
+	switch {
+	case id == "" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, s.filter(resource, r.URL.Query()))
+	case id == "" && r.Method == "POST":
+		body, err := s.decodeBody(r, resource)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.create(resource, body))
+	case id != "" && r.Method == "GET":
+		if record, found := s.get(resource, id); found {
+			writeJSON(w, http.StatusOK, record)
+			return
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s %s not found", resource, id))
+	case id != "" && (r.Method == "PATCH" || r.Method == "PUT"):
+		body, err := s.decodeBody(r, resource)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if r.Method == "PUT" {
+			record, created := s.put(resource, id, body)
+			status := http.StatusOK
+			if created {
+				status = http.StatusCreated
+			}
+			writeJSON(w, status, record)
+			return
+		}
+		if record, found := s.update(resource, id, body); found {
+			writeJSON(w, http.StatusOK, record)
+			return
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s %s not found", resource, id))
+	case id != "" && r.Method == "DELETE":
+		if s.remove(resource, id) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s %s not found", resource, id))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+// filter returns the records of resource whose fields match query.
+func (s *FakeServer) filter(resource string, query url.Values) []Record {
+	records := []Record{}
+	for _, record := range s.Records(resource) {
+		matches := true
+		for key, values := range query {
+			if value, found := record[key]; found && fmt.Sprint(value) != values[0] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// decodeBody decodes the body of r, which holds fields of a record of
+// resource, according to its Content-Type.
+func (s *FakeServer) decodeBody(r *http.Request, resource string) (Record, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json", "application/merge-patch+json":
+		record := Record{}
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %s", err)
+		}
+		return record, nil
+	case "application/x-www-form-urlencoded", "":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return s.decodeForm(r.PostForm, resource), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Type %q", mediaType)
+	}
+}
+
+// decodeForm converts url-encoded fields to a Record. Since url-encoded values
+// are untyped, each one is converted to the type of the same field in the
+// existing records of resource, if it can be, and left as a string otherwise.
+func (s *FakeServer) decodeForm(values url.Values, resource string) Record {
+	existing := s.Records(resource)
+	record := Record{}
+	for key := range values {
+		str := values.Get(key)
+		record[key] = str
+		var example interface{}
+		for _, other := range existing {
+			if example = other[key]; example != nil {
+				break
+			}
+		}
+		switch example.(type) {
+		case bool:
+			if b, err := strconv.ParseBool(str); err == nil {
+				record[key] = b
+			}
+		case float64:
+			if f, err := strconv.ParseFloat(str, 64); err == nil {
+				record[key] = f
+			}
+		}
+	}
+	return record
+}
+
+// writeJSON writes v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err in the body of a response with the given status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-humble/rest"
+)
+
+// todosURL is the RootURL of fakeTodo.
+var todosURL string
+
+// fakeTodo is a model served by a FakeServer or a MockClient.
+type fakeTodo struct {
+	rest.DefaultId
+	Title       string
+	IsCompleted bool
+}
+
+func (*fakeTodo) RootURL() string { return todosURL }
+
+// todoFixtures holds two todos.
+var todoFixtures = Fixtures{
+	"todos": {
+		{"Id": "1", "Title": "Write a book", "IsCompleted": false},
+		{"Id": "2", "Title": "Take out the trash", "IsCompleted": true},
+	},
+}
+
+// newTodoServer starts a FakeServer with todoFixtures and points fakeTodo at
+// it.
+func newTodoServer(t *testing.T) *FakeServer {
+	server := NewFakeServer(t)
+	server.Load(todoFixtures)
+	todosURL = server.URL + "/api/todos"
+	return server
+}
+
+func TestFakeServer(t *testing.T) {
+	server := newTodoServer(t)
+	for _, contentType := range []rest.ContentType{rest.ContentURLEncoded, rest.ContentJSON} {
+		client := rest.NewClient()
+		client.ContentType = contentType
+		todos := []*fakeTodo{}
+		if err := client.ReadAll(&todos, rest.WithQuery(rest.Query{"IsCompleted": {"true"}})); err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 1 || todos[0].Title != "Take out the trash" {
+			t.Errorf("Expected the completed todo but got %v", todos)
+		}
+
+		todo := &fakeTodo{Title: "Buy milk"}
+		if err := client.Create(todo); err != nil {
+			t.Fatal(err)
+		}
+		if todo.Id == "" {
+			t.Fatalf("Expected Create to set the id")
+		}
+		todo.IsCompleted = true
+		if err := client.Update(todo); err != nil {
+			t.Fatal(err)
+		}
+		read := &fakeTodo{}
+		if err := client.Read(todo.Id, read); err != nil {
+			t.Fatal(err)
+		}
+		if read.Title != "Buy milk" || !read.IsCompleted {
+			t.Errorf("Expected the updated todo but got %+v", read)
+		}
+		if err := client.Delete(todo); err != nil {
+			t.Fatal(err)
+		}
+		err := client.Read(todo.Id, &fakeTodo{})
+		if httpErr, ok := err.(rest.HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected an HTTPError with status 404 for a deleted todo but got %v", err)
+		}
+	}
+
+	put := &fakeTodo{DefaultId: rest.DefaultId{Id: "chosen"}, Title: "Put"}
+	if err := rest.NewClient().Put(put); err != nil {
+		t.Fatal(err)
+	}
+	if records := server.Records("todos"); len(records) != 3 || records[2]["Id"] != "chosen" {
+		t.Errorf("Expected Put to add a todo with the chosen id but got %v", records)
+	}
+}
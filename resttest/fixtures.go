@@ -0,0 +1,87 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Fixtures maps resource names (e.g. "todos") to their records. Fixtures can
+// be loaded into a FakeServer and a MockClient, so that tests which talk to a
+// server and tests which use a mock share the same data.
+type Fixtures map[string][]Record
+
+// LoadFixtures reads fixtures from path, which is either a JSON file or a
+// directory, in which case all the .json files in it are read in the order of
+// their names. A file holds either an object which maps resource names to
+// arrays of records:
+//
+//	{
+//		"todos": [
+//			{"Id": "1", "Title": "Write a book", "IsCompleted": false}
+//		],
+//		"users": [
+//			{"Id": "1", "Name": "Alex"}
+//		]
+//	}
+//
+// or just an array of records, in which case the name of the file without its
+// extension is the name of the resource (e.g. todos.json). The records of a
+// resource which appears in several files are concatenated. Since the rest
+// package does not depend on anything outside the standard library, YAML
+// fixtures are not supported and have to be converted to JSON first.
+func LoadFixtures(path string) (Fixtures, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+	fixtures := Fixtures{}
+	for _, file := range files {
+		if err := fixtures.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return fixtures, nil
+}
+
+// loadFile adds the records in the given file to fixtures.
+func (fixtures Fixtures) loadFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		records := []Record{}
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("resttest: could not decode fixtures in %s: %s", file, err)
+		}
+		resource := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		fixtures[resource] = append(fixtures[resource], records...)
+		return nil
+	}
+	resources := map[string][]Record{}
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return fmt.Errorf("resttest: could not decode fixtures in %s: %s", file, err)
+	}
+	for resource, records := range resources {
+		fixtures[resource] = append(fixtures[resource], records...)
+	}
+	return nil
+}
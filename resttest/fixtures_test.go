@@ -0,0 +1,72 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFixtures writes the given files to a new directory and returns it.
+func writeFixtures(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"a.json": `{
+			"todos": [{"Id": "1", "Title": "Write a book", "IsCompleted": false}],
+			"users": [{"Id": "1", "Name": "Alex"}]
+		}`,
+		"todos.json": `[{"Id": "2", "Title": "Take out the trash", "IsCompleted": true}]`,
+		"notes.txt":  `not a fixture`,
+	})
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Fixtures{
+		"todos": {
+			{"Id": "1", "Title": "Write a book", "IsCompleted": false},
+			{"Id": "2", "Title": "Take out the trash", "IsCompleted": true},
+		},
+		"users": {{"Id": "1", "Name": "Alex"}},
+	}
+	if !reflect.DeepEqual(fixtures, expected) {
+		t.Errorf("Expected the fixtures\n%v\nbut got\n%v", expected, fixtures)
+	}
+
+	single, err := LoadFixtures(filepath.Join(dir, "todos.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(single) != 1 || len(single["todos"]) != 1 {
+		t.Errorf("Expected the todos of a single file but got %v", single)
+	}
+}
+
+func TestLoadFixturesErrors(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"object.json": `{"todos": {"Id": "1"}}`,
+		"array.json":  `[1, 2]`,
+	})
+	for _, name := range []string{"object.json", "array.json"} {
+		if _, err := LoadFixtures(filepath.Join(dir, name)); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+	if _, err := LoadFixtures(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error for a missing file but got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/go-humble/rest"
+)
+
+// Call is a call to a method of a MockClient.
+type Call struct {
+	// Method is the name of the method, e.g. "Read"
+	Method string
+	// Resource is the name of the resource of the model, e.g. "todos"
+	Resource string
+	// Id is the id of the model, or an empty string for ReadAll and Create
+	Id string
+}
+
+// MockClient is a rest.Interface which keeps records in memory instead of
+// sending requests, for testing code which depends on rest.Interface (or one
+// of the role interfaces) without a server. Models are converted to and from
+// records by encoding them as JSON, and belong to the resource named by the
+// last segment of their RootURL. Request options are ignored. Methods which
+// would get a 404 response from a server return a rest.HTTPError with status
+// 404 instead.
+type MockClient struct {
+	store
+	calls    []Call
+	callsMut sync.Mutex
+}
+
+var _ rest.Interface = (*MockClient)(nil)
+
+// NewMockClient returns a MockClient without any records. Use Load to add
+// some.
+func NewMockClient() *MockClient {
+	return &MockClient{}
+}
+
+// Calls returns the calls made to c so far, in order.
+func (c *MockClient) Calls() []Call {
+	c.callsMut.Lock()
+	defer c.callsMut.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// record adds a call to c.calls.
+func (c *MockClient) record(method, resource, id string) {
+	c.callsMut.Lock()
+	defer c.callsMut.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Resource: resource, Id: id})
+}
+
+// Read sets the fields of model to the record of its resource with the given
+// id.
+func (c *MockClient) Read(id string, model rest.Model, opts ...rest.RequestOption) error {
+	resource := resourceName(model.RootURL())
+	c.record("Read", resource, id)
+	record, found := c.get(resource, id)
+	if !found {
+		return notFound(model.RootURL(), id)
+	}
+	return fromRecords(record, model)
+}
+
+// ReadAll sets models, which must be a pointer to a slice of models, to all
+// the records of their resource.
+func (c *MockClient) ReadAll(models interface{}, opts ...rest.RequestOption) error {
+	typ := reflect.TypeOf(models)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("resttest: models must be a pointer to a slice of models but got %T", models)
+	}
+	elemType := typ.Elem().Elem()
+	var model rest.Model
+	var ok bool
+	if elemType.Kind() == reflect.Ptr {
+		model, ok = reflect.New(elemType.Elem()).Interface().(rest.Model)
+	} else {
+		model, ok = reflect.New(elemType).Interface().(rest.Model)
+	}
+	if !ok {
+		return fmt.Errorf("resttest: models must be a pointer to a slice of models but got %T", models)
+	}
+	resource := resourceName(model.RootURL())
+	c.record("ReadAll", resource, "")
+	// Decode into a new slice, so that the elements are not merged
+	result := reflect.New(typ.Elem())
+	if err := fromRecords(c.Records(resource), result.Interface()); err != nil {
+		return err
+	}
+	reflect.ValueOf(models).Elem().Set(result.Elem())
+	return nil
+}
+
+// Create adds model to the records of its resource with a new id, and sets the
+// id of model.
+func (c *MockClient) Create(model rest.Model, opts ...rest.RequestOption) error {
+	resource := resourceName(model.RootURL())
+	c.record("Create", resource, "")
+	record, err := toRecord(model)
+	if err != nil {
+		return err
+	}
+	return fromRecords(c.create(resource, record), model)
+}
+
+// Update sets the fields of the record of model to the fields of model. Since
+// the MockClient does not know which fields changed, it sets all of them.
+func (c *MockClient) Update(model rest.Model, opts ...rest.RequestOption) error {
+	resource := resourceName(model.RootURL())
+	c.record("Update", resource, model.ModelId())
+	record, err := toRecord(model)
+	if err != nil {
+		return err
+	}
+	updated, found := c.update(resource, model.ModelId(), record)
+	if !found {
+		return notFound(model.RootURL(), model.ModelId())
+	}
+	return fromRecords(updated, model)
+}
+
+// Put replaces the record of model with model, or adds it if there is none.
+func (c *MockClient) Put(model rest.Model, opts ...rest.RequestOption) error {
+	resource := resourceName(model.RootURL())
+	c.record("Put", resource, model.ModelId())
+	if model.ModelId() == "" {
+		return fmt.Errorf("resttest: cannot put %T without an id", model)
+	}
+	record, err := toRecord(model)
+	if err != nil {
+		return err
+	}
+	stored, _ := c.put(resource, model.ModelId(), record)
+	return fromRecords(stored, model)
+}
+
+// Delete removes the record of model.
+func (c *MockClient) Delete(model rest.Model, opts ...rest.RequestOption) error {
+	resource := resourceName(model.RootURL())
+	c.record("Delete", resource, model.ModelId())
+	if !c.remove(resource, model.ModelId()) {
+		return notFound(model.RootURL(), model.ModelId())
+	}
+	return nil
+}
+
+// notFound returns the rest.HTTPError a server would return for a model which
+// does not exist.
+func notFound(rootURL, id string) error {
+	return rest.HTTPError{
+		URL:        rootURL + "/" + id,
+		Body:       []byte(`{"error": "not found"}`),
+		StatusCode: http.StatusNotFound,
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-humble/rest"
+)
+
+// intTodo is a model with an integer id.
+type intTodo struct {
+	rest.DefaultIntId
+	Title string
+}
+
+func (*intTodo) RootURL() string { return "http://example.com/counters" }
+
+func TestMockClient(t *testing.T) {
+	todosURL = "http://example.com/api/todos"
+	client := NewMockClient()
+	client.Load(todoFixtures)
+
+	todos := []*fakeTodo{{Title: "stale"}, {}, {}}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].Title != "Write a book" || !todos[1].IsCompleted {
+		t.Errorf("Expected the todos of the fixtures but got %v", todos)
+	}
+
+	todo := &fakeTodo{Title: "Buy milk"}
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != "3" {
+		t.Errorf("Expected Create to set the id to 3 but got %q", todo.Id)
+	}
+	todo.IsCompleted = true
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	read := &fakeTodo{}
+	if err := client.Read("3", read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Title != "Buy milk" || !read.IsCompleted {
+		t.Errorf("Expected the updated todo but got %+v", read)
+	}
+	if err := client.Put(&fakeTodo{DefaultId: rest.DefaultId{Id: "1"}, Title: "Replaced"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete(todo); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Delete(todo)
+	if httpErr, ok := err.(rest.HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected an HTTPError with status 404 for a deleted todo but got %v", err)
+	}
+	if records := client.Records("todos"); len(records) != 2 || records[0]["Title"] != "Replaced" {
+		t.Errorf("Expected Put to replace the first todo but got %v", records)
+	}
+
+	expected := []Call{
+		{"ReadAll", "todos", ""},
+		{"Create", "todos", ""},
+		{"Update", "todos", "3"},
+		{"Read", "todos", "3"},
+		{"Put", "todos", "1"},
+		{"Delete", "todos", "3"},
+		{"Delete", "todos", "3"},
+	}
+	if calls := client.Calls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected the calls %v but got %v", expected, calls)
+	}
+}
+
+func TestMockClientIntIds(t *testing.T) {
+	client := NewMockClient()
+	for _, title := range []string{"a", "b"} {
+		todo := &intTodo{Title: title}
+		if err := client.Create(todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	todo := &intTodo{}
+	if err := client.Read("2", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Id != 2 || todo.Title != "b" {
+		t.Errorf("Expected the second todo with id 2 but got %+v", todo)
+	}
+	if err := client.ReadAll(&[]intTodo{}); err != nil {
+		t.Errorf("Expected ReadAll to accept a slice of structs but got %v", err)
+	}
+	if err := client.ReadAll([]*intTodo{}); err == nil {
+		t.Error("Expected an error for models which are not a pointer to a slice")
+	}
+}
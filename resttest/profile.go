@@ -0,0 +1,149 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkProfile describes the conditions of a network, so that tests can
+// check how code behaves on a slow or unreliable connection. Apply it to a
+// FakeServer with SetProfile, or to any http.Handler with Wrap.
+type NetworkProfile struct {
+	// Name identifies the profile, e.g. "3g"
+	Name string
+	// Latency is added before each response
+	Latency time.Duration
+	// Jitter is the maximum amount by which the latency of a response varies,
+	// in either direction. The latency is chosen uniformly at random between
+	// Latency-Jitter and Latency+Jitter, and is never negative.
+	Jitter time.Duration
+	// Bandwidth caps the rate at which request and response bodies are
+	// transferred, in bytes per second. 0 means unlimited.
+	Bandwidth int
+	// DropRate is the probability, between 0 and 1, that a request is dropped
+	// by closing the connection without a response, which the client sees as
+	// a network error.
+	DropRate float64
+	// Seed seeds the random numbers used for Jitter and DropRate, so that the
+	// behavior of a profile is reproducible.
+	Seed int64
+}
+
+var (
+	// ProfileFast is a fast, reliable connection, such as a wired LAN.
+	ProfileFast = NetworkProfile{
+		Name:    "fast",
+		Latency: 5 * time.Millisecond,
+		Jitter:  time.Millisecond,
+	}
+	// Profile3G is a mobile connection with high latency and low bandwidth.
+	Profile3G = NetworkProfile{
+		Name:      "3g",
+		Latency:   300 * time.Millisecond,
+		Jitter:    100 * time.Millisecond,
+		Bandwidth: 96 * 1024,
+		DropRate:  0.01,
+	}
+	// ProfileFlakyWifi is a wifi connection which often drops requests and has
+	// unpredictable latency.
+	ProfileFlakyWifi = NetworkProfile{
+		Name:      "flaky-wifi",
+		Latency:   50 * time.Millisecond,
+		Jitter:    200 * time.Millisecond,
+		Bandwidth: 512 * 1024,
+		DropRate:  0.2,
+	}
+)
+
+// Profiles holds the predefined profiles, which ProfileByName looks up.
+var Profiles = []NetworkProfile{ProfileFast, Profile3G, ProfileFlakyWifi}
+
+// ProfileByName returns the profile in Profiles with the given name, ignoring
+// case, so that the profile of a test can be chosen with a flag or an
+// environment variable.
+func ProfileByName(name string) (NetworkProfile, error) {
+	for _, profile := range Profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, nil
+		}
+	}
+	return NetworkProfile{}, fmt.Errorf("resttest: unknown network profile %q", name)
+}
+
+// Wrap returns a handler which serves requests with handler under the
+// conditions of p.
+func (p NetworkProfile) Wrap(handler http.Handler) http.Handler {
+	return &profileHandler{
+		profile: p,
+		handler: handler,
+		rand:    rand.New(rand.NewSource(p.Seed)),
+	}
+}
+
+// profileHandler is the handler returned by NetworkProfile.Wrap.
+type profileHandler struct {
+	profile NetworkProfile
+	handler http.Handler
+	rand    *rand.Rand
+	randMut sync.Mutex
+}
+
+func (h *profileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.randMut.Lock()
+	drop := h.rand.Float64() < h.profile.DropRate
+	latency := h.profile.Latency
+	if h.profile.Jitter > 0 {
+		latency += time.Duration(h.rand.Int63n(int64(2*h.profile.Jitter+1))) - h.profile.Jitter
+	}
+	h.randMut.Unlock()
+	if drop {
+		// Closes the connection without a response and without logging
+		panic(http.ErrAbortHandler)
+	}
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if h.profile.Bandwidth > 0 {
+		r.Body = &throttledReader{ReadCloser: r.Body, bandwidth: h.profile.Bandwidth}
+		w = &throttledWriter{ResponseWriter: w, bandwidth: h.profile.Bandwidth}
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// transferTime returns how long it takes to transfer n bytes at bandwidth
+// bytes per second.
+func transferTime(n, bandwidth int) time.Duration {
+	return time.Duration(n) * time.Second / time.Duration(bandwidth)
+}
+
+// throttledReader limits the rate at which a request body is read.
+type throttledReader struct {
+	io.ReadCloser
+	bandwidth int
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	time.Sleep(transferTime(n, r.bandwidth))
+	return n, err
+}
+
+// throttledWriter limits the rate at which a response body is written.
+type throttledWriter struct {
+	http.ResponseWriter
+	bandwidth int
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	time.Sleep(transferTime(len(p), w.bandwidth))
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-humble/rest"
+)
+
+func TestProfileByName(t *testing.T) {
+	for _, name := range []string{"fast", "3G", "flaky-wifi"} {
+		profile, err := ProfileByName(name)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", name, err)
+			continue
+		}
+		if !strings.EqualFold(profile.Name, name) {
+			t.Errorf("Expected the profile named %q but got %q", name, profile.Name)
+		}
+	}
+	if _, err := ProfileByName("dial-up"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestProfileLatency(t *testing.T) {
+	server := newTodoServer(t)
+	server.SetProfile(NetworkProfile{Latency: 40 * time.Millisecond, Jitter: 10 * time.Millisecond})
+	start := time.Now()
+	if err := rest.NewClient().Read("1", &fakeTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected a latency of at least 30ms but the request took %s", elapsed)
+	}
+}
+
+func TestProfileBandwidth(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+	handler := NetworkProfile{Bandwidth: 20000}.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	start := time.Now()
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("Expected the body to be unchanged but got %d bytes", len(got))
+	}
+	// 1000 bytes at 20000 bytes per second take 50ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected the response to take at least 40ms but it took %s", elapsed)
+	}
+}
+
+func TestProfileDropRate(t *testing.T) {
+	server := newTodoServer(t)
+	server.SetProfile(NetworkProfile{DropRate: 1})
+	client := rest.NewClient()
+	if err := client.Read("1", &fakeTodo{}); err == nil {
+		t.Error("Expected an error for a dropped request")
+	} else if _, ok := err.(rest.HTTPError); ok {
+		t.Errorf("Expected a network error for a dropped request but got %v", err)
+	}
+	server.SetProfile(NetworkProfile{})
+	if err := client.Read("1", &fakeTodo{}); err != nil {
+		t.Errorf("Expected no error after the profile was reset but got %v", err)
+	}
+}
+
+func TestProfileSeed(t *testing.T) {
+	drops := func() []bool {
+		var result []bool
+		handler := NetworkProfile{DropRate: 0.5, Seed: 42}.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		for i := 0; i < 20; i++ {
+			dropped := func() (dropped bool) {
+				defer func() { dropped = recover() != nil }()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+				return
+			}()
+			result = append(result, dropped)
+		}
+		return result
+	}
+	first, second := drops(), drops()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected profiles with the same seed to drop the same requests but got %v and %v", first, second)
+		}
+	}
+}
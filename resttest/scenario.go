@@ -0,0 +1,165 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Expectation is a request which a FakeServer expects to receive, and
+// optionally the response it sends to it. Expectations are created with
+// FakeServer.Expect and configured with their methods, which return the
+// Expectation so that they can be chained:
+//
+//	server.Expect("PATCH", "/todos/1").
+//		WithFields(resttest.Record{"IsCompleted": true}).
+//		Respond(http.StatusOK, resttest.Record{"Id": "1", "IsCompleted": true})
+type Expectation struct {
+	method string
+	path   string
+	query  url.Values
+	fields Record
+	// status and body are the response, if status is not 0
+	status int
+	body   interface{}
+	met    bool
+}
+
+// WithFields causes the expectation to only match requests whose body holds
+// the given fields with the given values, which are compared in their string
+// form, so that they match url-encoded bodies as well as JSON. Other fields of
+// the body are ignored.
+func (e *Expectation) WithFields(fields Record) *Expectation {
+	e.fields = fields
+	return e
+}
+
+// Respond causes the server to respond to the matching request with the given
+// status and body, which is encoded as JSON unless it is nil. Without
+// Respond, the request is served from the records of the server as usual.
+func (e *Expectation) Respond(status int, body interface{}) *Expectation {
+	e.status = status
+	e.body = body
+	return e
+}
+
+// String describes the request the expectation matches.
+func (e *Expectation) String() string {
+	str := e.method + " " + e.path
+	if len(e.query) > 0 {
+		str += "?" + e.query.Encode()
+	}
+	if len(e.fields) > 0 {
+		str += fmt.Sprintf(" with %v", map[string]interface{}(e.fields))
+	}
+	return str
+}
+
+// Expect adds an expectation for a request with the given method and path,
+// which may include a query (e.g. "/todos?IsCompleted=true"). Expectations
+// must be met in the order they were added: each request is only compared to
+// the first expectation which has not been met yet. If s is Strict, a
+// request which does not match it fails the test and gets a 500 response.
+// Otherwise it is served from the records as usual. Call Verify at the end of
+// the test to check that all the expectations were met.
+func (s *FakeServer) Expect(method, path string) *Expectation {
+	e := &Expectation{method: method, path: path}
+	if i := strings.Index(path, "?"); i != -1 {
+		e.path = path[:i]
+		e.query, _ = url.ParseQuery(path[i+1:])
+	}
+	s.scriptMut.Lock()
+	defer s.scriptMut.Unlock()
+	s.expectations = append(s.expectations, e)
+	return e
+}
+
+// Verify fails the test for each expectation which has not been met.
+func (s *FakeServer) Verify() {
+	s.t.Helper()
+	s.scriptMut.Lock()
+	defer s.scriptMut.Unlock()
+	for _, e := range s.expectations {
+		if !e.met {
+			s.t.Errorf("resttest: expected a request %s but did not get one", e)
+		}
+	}
+}
+
+// serveScripted responds to r according to the expectations of s.
+func (s *FakeServer) serveScripted(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.scriptMut.Lock()
+	var next *Expectation
+	for _, e := range s.expectations {
+		if !e.met {
+			next = e
+			break
+		}
+	}
+	matched := next != nil && next.matches(r, body)
+	if matched {
+		next.met = true
+	}
+	s.scriptMut.Unlock()
+	switch {
+	case matched && next.status != 0:
+		if next.body == nil {
+			w.WriteHeader(next.status)
+			return
+		}
+		writeJSON(w, next.status, next.body)
+	case !matched && s.Strict:
+		expected := "no more requests"
+		if next != nil {
+			expected = next.String()
+		}
+		s.t.Errorf("resttest: unexpected request %s %s; expected %s", r.Method, r.URL.RequestURI(), expected)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("unexpected request"))
+	default:
+		// The body was read to compare it with the expectation
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		s.serveRecords(w, r)
+	}
+}
+
+// matches returns true iff r, whose body has already been read, matches e.
+func (e *Expectation) matches(r *http.Request, body []byte) bool {
+	if r.Method != e.method || strings.TrimSuffix(r.URL.Path, "/") != strings.TrimSuffix(e.path, "/") {
+		return false
+	}
+	query := r.URL.Query()
+	for key := range e.query {
+		if query.Get(key) != e.query.Get(key) {
+			return false
+		}
+	}
+	if len(e.fields) == 0 {
+		return true
+	}
+	fields := Record{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return false
+		}
+		for key := range values {
+			fields[key] = values.Get(key)
+		}
+	}
+	for key, want := range e.fields {
+		got, found := fields[key]
+		if !found || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
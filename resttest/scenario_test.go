@@ -0,0 +1,112 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-humble/rest"
+)
+
+// newScriptedServer starts a FakeServer with todoFixtures whose failures are
+// recorded instead of failing the test, and points fakeTodo at it.
+func newScriptedServer(t *testing.T) (*FakeServer, *recorder) {
+	rec := &recorder{TB: t}
+	server := NewFakeServer(rec)
+	server.Load(todoFixtures)
+	todosURL = server.URL + "/todos"
+	return server, rec
+}
+
+func TestExpect(t *testing.T) {
+	server, rec := newScriptedServer(t)
+	server.Strict = true
+	server.Expect("GET", "/todos?IsCompleted=false")
+	server.Expect("PATCH", "/todos/1").
+		WithFields(Record{"IsCompleted": true}).
+		Respond(http.StatusOK, Record{"Id": "1", "Title": "Scripted", "IsCompleted": true})
+	server.Expect("DELETE", "/todos/2").Respond(http.StatusNoContent, nil)
+
+	client := rest.NewClient()
+	todos := []*fakeTodo{}
+	if err := client.ReadAll(&todos, rest.WithQuery(rest.Query{"IsCompleted": {"false"}})); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0].Id != "1" {
+		t.Errorf("Expected a request without a response to be served from the records but got %v", todos)
+	}
+	todo := todos[0]
+	todo.IsCompleted = true
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "Scripted" {
+		t.Errorf("Expected the scripted response but got %+v", todo)
+	}
+	if records := server.Records("todos"); records[0]["IsCompleted"] != false {
+		t.Errorf("Expected a scripted response to leave the records unchanged but got %v", records[0])
+	}
+	if err := client.Delete(&fakeTodo{DefaultId: rest.DefaultId{Id: "2"}}); err != nil {
+		t.Fatal(err)
+	}
+	server.Verify()
+	if len(rec.failures) != 0 {
+		t.Errorf("Expected all the expectations to be met but got %v", rec.failures)
+	}
+}
+
+func TestExpectStrict(t *testing.T) {
+	server, rec := newScriptedServer(t)
+	server.Strict = true
+	server.Expect("PATCH", "/todos/1").WithFields(Record{"IsCompleted": true})
+	client := rest.NewClient()
+
+	// Out of order
+	if err := client.Read("1", &fakeTodo{}); err == nil {
+		t.Error("Expected an error for an unexpected request")
+	}
+	// Wrong body
+	todo := &fakeTodo{DefaultId: rest.DefaultId{Id: "1"}, IsCompleted: false}
+	if err := client.Update(todo); err == nil {
+		t.Error("Expected an error for a request with the wrong fields")
+	}
+	todo.IsCompleted = true
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	// No expectations left
+	if err := client.Read("1", &fakeTodo{}); err == nil {
+		t.Error("Expected an error for a request after the last expectation")
+	}
+	server.Verify()
+	if len(rec.failures) != 3 {
+		t.Fatalf("Expected 3 failures but got %v", rec.failures)
+	}
+	if !strings.Contains(rec.failures[0], "unexpected request GET /todos/1; expected PATCH /todos/1") {
+		t.Errorf("Expected the failure to describe the request and the expectation but got %q", rec.failures[0])
+	}
+	if !strings.Contains(rec.failures[2], "expected no more requests") {
+		t.Errorf("Expected a failure for the extra request but got %q", rec.failures[2])
+	}
+}
+
+func TestExpectLenient(t *testing.T) {
+	server, rec := newScriptedServer(t)
+	server.Expect("POST", "/todos")
+	server.Expect("DELETE", "/todos/1")
+	client := rest.NewClient()
+	if err := client.Read("2", &fakeTodo{}); err != nil {
+		t.Errorf("Expected an unexpected request to be served when the server is not strict but got %v", err)
+	}
+	if err := client.Create(&fakeTodo{Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	server.Verify()
+	if len(rec.failures) != 1 || !strings.Contains(rec.failures[0], "expected a request DELETE /todos/1") {
+		t.Errorf("Expected Verify to report the missing DELETE but got %v", rec.failures)
+	}
+}
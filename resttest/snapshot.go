@@ -0,0 +1,167 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+// package resttest provides helpers for testing code which uses the rest
+// package: a FakeServer which serves resources from memory, a MockClient which
+// satisfies rest.Interface without a server, Fixtures to seed both with the
+// same data, NetworkProfiles which simulate slow or unreliable networks, and
+// golden-file snapshots of decoded models.
+package resttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// SnapshotDir is the directory where Snapshot stores golden files, relative to
+// the directory of the package under test.
+var SnapshotDir = filepath.Join("testdata", "snapshots")
+
+// UpdateSnapshots causes Snapshot to overwrite golden files with the values it
+// is given instead of comparing them. It is true if the UPDATE_SNAPSHOTS
+// environment variable is set to a non-empty value, e.g.:
+//
+//	UPDATE_SNAPSHOTS=1 go test ./...
+var UpdateSnapshots = os.Getenv("UPDATE_SNAPSHOTS") != ""
+
+// timestampPlaceholder replaces timestamps in snapshots.
+const timestampPlaceholder = "<timestamp>"
+
+// Snapshot compares got (usually a model or slice of models decoded by a
+// rest.Client) to the golden file for name in SnapshotDir, and fails the test
+// with a line-by-line diff if they differ. got is serialized as indented JSON
+// with object keys in sorted order, and every string which is an RFC 3339
+// timestamp is replaced with "<timestamp>", so that snapshots are stable
+// across runs. If the golden file does not exist yet, or UpdateSnapshots is
+// true, it is written instead and the test passes.
+func Snapshot(t testing.TB, name string, got interface{}) {
+	t.Helper()
+	data, err := serializeSnapshot(got)
+	if err != nil {
+		t.Fatalf("resttest: could not serialize snapshot %s: %s", name, err)
+	}
+	path := filepath.Join(SnapshotDir, snapshotFilename(name))
+	want, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || UpdateSnapshots {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("resttest: could not write snapshot %s: %s", name, err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("resttest: could not write snapshot %s: %s", name, err)
+		}
+		return
+	} else if err != nil {
+		t.Fatalf("resttest: could not read snapshot %s: %s", name, err)
+	}
+	if !bytes.Equal(want, data) {
+		t.Errorf("resttest: snapshot %s does not match %s (run with UPDATE_SNAPSHOTS=1 to update it):\n%s", name, path, diffLines(string(want), string(data)))
+	}
+}
+
+// serializeSnapshot returns v as indented JSON with sorted keys and
+// normalized timestamps, followed by a newline.
+func serializeSnapshot(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through interface{} so that the keys of every object are
+	// sorted, regardless of the order of the struct fields.
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(normalizeTimestamps(generic)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeTimestamps replaces every string in v which is an RFC 3339
+// timestamp with timestampPlaceholder.
+func normalizeTimestamps(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, elem := range value {
+			value[key] = normalizeTimestamps(elem)
+		}
+	case []interface{}:
+		for i, elem := range value {
+			value[i] = normalizeTimestamps(elem)
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return timestampPlaceholder
+		}
+	}
+	return v
+}
+
+// snapshotFilename returns the name of the golden file for the snapshot with
+// the given name.
+func snapshotFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+	return name + ".json"
+}
+
+// diffLines returns a line-by-line diff of want and got, with removed lines
+// prefixed with "-", added lines prefixed with "+", and unchanged lines
+// omitted. It uses the longest common subsequence of the lines, which is
+// fast enough for the size of a typical snapshot.
+func diffLines(want string, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff bytes.Buffer
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&diff, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&diff, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder is a testing.TB which records failures instead of failing the
+// test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+type snapshotTodo struct {
+	Title     string
+	Id        int64
+	CreatedAt time.Time
+	Tags      []string
+}
+
+// useSnapshotDir points SnapshotDir at a temporary directory for the rest of
+// the test.
+func useSnapshotDir(t *testing.T) {
+	previous := SnapshotDir
+	SnapshotDir = t.TempDir()
+	t.Cleanup(func() { SnapshotDir = previous })
+}
+
+func TestSnapshot(t *testing.T) {
+	useSnapshotDir(t)
+	todos := []snapshotTodo{{Title: "a <b>", Id: 12345678901234567, CreatedAt: time.Now(), Tags: []string{"x"}}}
+	rec := &recorder{TB: t}
+	Snapshot(rec, "todos/all", todos)
+	if len(rec.failures) != 0 {
+		t.Fatalf("Expected the first run to write the snapshot but got %v", rec.failures)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(SnapshotDir, "todos_all.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "CreatedAt": "<timestamp>",
+    "Id": 12345678901234567,
+    "Tags": [
+      "x"
+    ],
+    "Title": "a <b>"
+  }
+]
+`
+	if string(data) != expected {
+		t.Errorf("Expected the snapshot to have sorted keys and normalized timestamps but got:\n%s", data)
+	}
+
+	todos[0].CreatedAt = time.Now().Add(time.Hour)
+	Snapshot(rec, "todos/all", todos)
+	if len(rec.failures) != 0 {
+		t.Errorf("Expected a different timestamp to match but got %v", rec.failures)
+	}
+
+	todos[0].Title = "c"
+	Snapshot(rec, "todos/all", todos)
+	if len(rec.failures) != 1 || !strings.Contains(rec.failures[0], `-     "Title": "a <b>"`) || !strings.Contains(rec.failures[0], `+     "Title": "c"`) {
+		t.Errorf("Expected a diff of the changed line but got %v", rec.failures)
+	}
+}
+
+func TestUpdateSnapshots(t *testing.T) {
+	useSnapshotDir(t)
+	UpdateSnapshots = true
+	defer func() { UpdateSnapshots = false }()
+	rec := &recorder{TB: t}
+	Snapshot(rec, "todo", snapshotTodo{Title: "a"})
+	Snapshot(rec, "todo", snapshotTodo{Title: "b"})
+	UpdateSnapshots = false
+	Snapshot(rec, "todo", snapshotTodo{Title: "b"})
+	if len(rec.failures) != 0 {
+		t.Errorf("Expected UpdateSnapshots to overwrite the snapshot but got %v", rec.failures)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("a\nb\nc\nd", "a\nc\nx\nd")
+	if got != "- b\n+ x\n" {
+		t.Errorf("Unexpected diff:\n%s", got)
+	}
+}
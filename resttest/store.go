@@ -0,0 +1,222 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package resttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IdKey is the key which holds the id of a Record. It matches the Id field of
+// rest.DefaultId and its variants.
+var IdKey = "Id"
+
+// Record is a single model of a resource in the form of a JSON object.
+type Record map[string]interface{}
+
+// id returns the id of r as a string, or an empty string if it has none.
+func (r Record) id() string {
+	switch id := r[IdKey].(type) {
+	case nil:
+		return ""
+	case string:
+		return id
+	case float64:
+		if id == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+// copy returns a shallow copy of r.
+func (r Record) copy() Record {
+	result := Record{}
+	for key, value := range r {
+		result[key] = value
+	}
+	return result
+}
+
+// store holds the records of the resources served by a FakeServer or a
+// MockClient. It is safe for concurrent use.
+type store struct {
+	mut       sync.Mutex
+	resources map[string][]Record
+}
+
+// Load adds the records in fixtures to the resources they belong to, after
+// the records which are already there. Records with the id of an existing
+// record replace it.
+func (s *store) Load(fixtures Fixtures) {
+	for resource, records := range fixtures {
+		for _, record := range records {
+			s.put(resource, record.id(), record)
+		}
+	}
+}
+
+// Records returns copies of the records of the given resource, in the order
+// they were added.
+func (s *store) Records(resource string) []Record {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	records := make([]Record, 0, len(s.resources[resource]))
+	for _, record := range s.resources[resource] {
+		records = append(records, record.copy())
+	}
+	return records
+}
+
+// has returns true iff resource has any records or has had any.
+func (s *store) has(resource string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	_, found := s.resources[resource]
+	return found
+}
+
+// get returns a copy of the record of the given resource with the given id.
+func (s *store) get(resource, id string) (Record, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if i := s.index(resource, id); i != -1 {
+		return s.resources[resource][i].copy(), true
+	}
+	return nil, false
+}
+
+// create adds record to resource with a new id, which is numeric if the id of
+// record is a number (as for rest.DefaultIntId) and a string otherwise, and
+// returns a copy of the stored record.
+func (s *store) create(resource string, record Record) Record {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	record = record.copy()
+	next := 1
+	for _, existing := range s.resources[resource] {
+		if n, err := strconv.Atoi(existing.id()); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	// The zero value of rest.DefaultIntId is url-encoded as "0"
+	if _, numeric := record[IdKey].(float64); numeric || record[IdKey] == "0" {
+		record[IdKey] = float64(next)
+	} else {
+		record[IdKey] = strconv.Itoa(next)
+	}
+	s.add(resource, record)
+	return record.copy()
+}
+
+// update sets the fields of the record of resource with the given id to the
+// values in changes, and returns a copy of the result.
+func (s *store) update(resource, id string, changes Record) (Record, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	i := s.index(resource, id)
+	if i == -1 {
+		return nil, false
+	}
+	record := s.resources[resource][i]
+	for key, value := range changes {
+		if key != IdKey {
+			record[key] = value
+		}
+	}
+	return record.copy(), true
+}
+
+// put replaces the record of resource with the given id with record, or adds
+// it if there is none, and returns a copy of the stored record and whether it
+// was added.
+func (s *store) put(resource, id string, record Record) (Record, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	record = record.copy()
+	if _, found := record[IdKey]; !found || record.id() == "" {
+		record[IdKey] = id
+	}
+	if i := s.index(resource, id); i != -1 {
+		s.resources[resource][i] = record
+		return record.copy(), false
+	}
+	s.add(resource, record)
+	return record.copy(), true
+}
+
+// remove deletes the record of resource with the given id and returns true
+// iff it existed.
+func (s *store) remove(resource, id string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	i := s.index(resource, id)
+	if i == -1 {
+		return false
+	}
+	records := s.resources[resource]
+	s.resources[resource] = append(records[:i:i], records[i+1:]...)
+	return true
+}
+
+// add appends record to resource. The caller must hold s.mut.
+func (s *store) add(resource string, record Record) {
+	if s.resources == nil {
+		s.resources = map[string][]Record{}
+	}
+	s.resources[resource] = append(s.resources[resource], record)
+}
+
+// index returns the index of the record of resource with the given id, or -1.
+// The caller must hold s.mut.
+func (s *store) index(resource, id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, record := range s.resources[resource] {
+		if record.id() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// toRecord converts v to a Record by encoding it as JSON.
+func toRecord(v interface{}) (Record, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	record := Record{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// fromRecords decodes v, a Record or a slice of Records, into target.
+func fromRecords(v interface{}, target interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// resourceName returns the name of the resource at rootURL, which is its last
+// path segment (e.g. "todos" for "http://example.com/api/todos").
+func resourceName(rootURL string) string {
+	if u, err := url.Parse(rootURL); err == nil {
+		rootURL = u.Path
+	}
+	return path.Base("/" + strings.Trim(rootURL, "/"))
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy determines whether and how requests which fail are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is attempted,
+	// including the first attempt. Values less than 2 disable retries.
+	MaxAttempts int
+	// Backoff is how long to wait before the first retry. The wait doubles
+	// after each subsequent attempt.
+	Backoff time.Duration
+	// MaxBackoff, if not zero, is the maximum time to wait between attempts.
+	MaxBackoff time.Duration
+	// StatusCodes is the list of response status codes which should cause a
+	// request to be retried, e.g. 502, 503, and 504. Requests which could not
+	// be sent at all (e.g. because of a network error) are always retried.
+	StatusCodes []int
+	// RetryNonIdempotent causes POST and PATCH requests to be retried too.
+	// By default only GET, HEAD, OPTIONS, PUT, and DELETE requests, which are
+	// safe to repeat, are retried.
+	RetryNonIdempotent bool
+}
+
+// shouldRetry returns true iff a request which resulted in res and err on the
+// given attempt should be attempted again.
+func (policy *RetryPolicy) shouldRetry(req *http.Request, res *http.Response, err error, attempt int) bool {
+	if policy == nil || attempt >= policy.MaxAttempts {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// We have no way to send the body again.
+		return false
+	}
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+	default:
+		if !policy.RetryNonIdempotent {
+			return false
+		}
+	}
+	if err != nil {
+		return err != ErrAttemptBudgetExhausted && err != ErrQuotaExceeded
+	}
+	if acceptsStatus(req, res.StatusCode) {
+		// The caller considers the response a success
+		return false
+	}
+	for _, code := range policy.StatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait after the given attempt.
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := policy.Backoff
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if policy.MaxBackoff > 0 && wait > policy.MaxBackoff {
+			break
+		}
+	}
+	if policy.MaxBackoff > 0 && wait > policy.MaxBackoff {
+		wait = policy.MaxBackoff
+	}
+	return wait
+}
+
+// ErrAttemptBudgetExhausted is returned when a request cannot be attempted
+// because the attempt budget of its context has been used up.
+var ErrAttemptBudgetExhausted = errors.New("rest: attempt budget exhausted")
+
+// attemptBudget is a limit on the number of request attempts which can be
+// made with a context. Budgets can be nested, in which case each attempt
+// counts against every budget in the chain.
+type attemptBudget struct {
+	remaining int
+	parent    *attemptBudget
+	mut       sync.Mutex
+}
+
+type attemptBudgetKey struct{}
+
+// WithAttemptBudget returns a copy of ctx which allows at most n request
+// attempts, counting both first attempts and retries, across every request
+// made with the returned context or a context derived from it. This lets a
+// single logical user action which sends several requests (each of which may
+// be retried) share one overall cap, instead of the caps multiplying. If ctx
+// already has a budget, attempts count against both budgets, so the tighter
+// of the two applies. Use the WithContext option to send a request with the
+// returned context.
+func WithAttemptBudget(ctx context.Context, n int) context.Context {
+	budget := &attemptBudget{
+		remaining: n,
+	}
+	if parent, ok := ctx.Value(attemptBudgetKey{}).(*attemptBudget); ok {
+		budget.parent = parent
+	}
+	return context.WithValue(ctx, attemptBudgetKey{}, budget)
+}
+
+// AttemptsRemaining returns the number of request attempts remaining in the
+// budget of ctx, taking into account any enclosing budgets. The second return
+// value is false if ctx does not have a budget.
+func AttemptsRemaining(ctx context.Context) (int, bool) {
+	budget, ok := ctx.Value(attemptBudgetKey{}).(*attemptBudget)
+	if !ok {
+		return 0, false
+	}
+	remaining := -1
+	for b := budget; b != nil; b = b.parent {
+		b.mut.Lock()
+		if remaining == -1 || b.remaining < remaining {
+			remaining = b.remaining
+		}
+		b.mut.Unlock()
+	}
+	return remaining, true
+}
+
+// takeAttempt uses up one attempt from the budget of ctx, if any. It returns
+// ErrAttemptBudgetExhausted if ctx or one of its enclosing budgets has no
+// attempts remaining.
+func takeAttempt(ctx context.Context) error {
+	budget, ok := ctx.Value(attemptBudgetKey{}).(*attemptBudget)
+	if !ok {
+		return nil
+	}
+	taken := []*attemptBudget{}
+	for b := budget; b != nil; b = b.parent {
+		b.mut.Lock()
+		if b.remaining <= 0 {
+			b.mut.Unlock()
+			// Give back the attempts we already took from inner budgets.
+			for _, t := range taken {
+				t.mut.Lock()
+				t.remaining++
+				t.mut.Unlock()
+			}
+			return ErrAttemptBudgetExhausted
+		}
+		b.remaining--
+		b.mut.Unlock()
+		taken = append(taken, b)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer responds with status to the first failures requests and with
+// an empty todo after that.
+type flakyServer struct {
+	status   int
+	failures int
+	requests int
+	mut      sync.Mutex
+}
+
+func newFlakyServer(t *testing.T, status int, failures int) *flakyServer {
+	server := &flakyServer{status: status, failures: failures}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		server.mut.Lock()
+		server.requests++
+		fail := server.requests <= server.failures
+		server.mut.Unlock()
+		if fail {
+			w.WriteHeader(server.status)
+			return
+		}
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	return server
+}
+
+func (server *flakyServer) count() int {
+	server.mut.Lock()
+	defer server.mut.Unlock()
+	return server.requests
+}
+
+func TestRetry(t *testing.T) {
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 2)
+	client := NewClient(WithRetry(&RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}))
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 3 {
+		t.Errorf("Expected 3 attempts but got %d", server.count())
+	}
+}
+
+func TestRetryLimits(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 5)
+	client := NewClient(WithRetry(policy))
+	if err := client.Read("1", &testTodo{}); err == nil {
+		t.Error("Expected an error after the last attempt failed")
+	}
+	if server.count() != 2 {
+		t.Errorf("Expected MaxAttempts to limit the attempts to 2 but got %d", server.count())
+	}
+	if err := client.Create(&testTodo{}); err == nil {
+		t.Error("Expected an error for a failed POST request")
+	}
+	if server.count() != 3 {
+		t.Errorf("Expected POST requests not to be retried but got %d requests", server.count())
+	}
+
+	server = newFlakyServer(t, http.StatusInternalServerError, 5)
+	if err := client.Read("1", &testTodo{}); err == nil {
+		t.Error("Expected an error for a 500 response")
+	}
+	if server.count() != 1 {
+		t.Errorf("Expected status codes not in StatusCodes not to be retried but got %d requests", server.count())
+	}
+
+	policy.RetryNonIdempotent = true
+	server = newFlakyServer(t, http.StatusServiceUnavailable, 1)
+	if err := client.Create(&testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 2 {
+		t.Errorf("Expected RetryNonIdempotent to retry POST requests but got %d requests", server.count())
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &RetryPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, wait := range expected {
+		if got := policy.backoff(i + 1); got != wait {
+			t.Errorf("Expected a backoff of %s after attempt %d but got %s", wait, i+1, got)
+		}
+	}
+}
+
+func TestAttemptBudget(t *testing.T) {
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 100)
+	client := NewClient(WithRetry(&RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}))
+	outer := WithAttemptBudget(context.Background(), 4)
+	inner := WithAttemptBudget(outer, 10)
+	if remaining, ok := AttemptsRemaining(inner); !ok || remaining != 4 {
+		t.Errorf("Expected the tighter budget of 4 to apply but got %d, %v", remaining, ok)
+	}
+	if err := client.Read("1", &testTodo{}, WithContext(inner)); err == nil {
+		t.Error("Expected an error after the last attempt failed")
+	}
+	if err := client.Read("1", &testTodo{}, WithContext(inner)); err != ErrAttemptBudgetExhausted {
+		t.Errorf("Expected ErrAttemptBudgetExhausted but got %v", err)
+	}
+	if server.count() != 4 {
+		t.Errorf("Expected the budget to limit the attempts to 4 but got %d", server.count())
+	}
+	if remaining, _ := AttemptsRemaining(inner); remaining != 0 {
+		t.Errorf("Expected no attempts remaining but got %d", remaining)
+	}
+	if remaining, _ := AttemptsRemaining(WithAttemptBudget(context.Background(), 1)); remaining != 1 {
+		t.Errorf("Expected a separate budget to be unaffected but got %d", remaining)
+	}
+	if _, ok := AttemptsRemaining(context.Background()); ok {
+		t.Error("Expected a context without a budget to report ok=false")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// WithReuse returns a RequestOption which causes ReadAll to reuse the memory
+// held by models instead of allocating fresh elements. The result is the same
+// as without the option (the slice holds exactly the models in the response,
+// in order), but the backing array of the slice is reused if it has enough
+// capacity and, when the elements are pointers, each existing model whose id
+// is in the response is reused for that model, so that pointers to it held
+// elsewhere remain valid. Fields which are missing from the response are
+// reset, as they would be for a fresh model. It cuts garbage collection
+// pressure when a large collection is refreshed frequently. WithReuse has no
+// effect if WithMerge is used as well.
+func WithReuse() RequestOption {
+	return func(opts *requestOptions) {
+		opts.reuse = true
+	}
+}
+
+// readAllReuse is the implementation of ReadAll for the WithReuse option.
+func (c *Client) readAllReuse(models interface{}, query Query, reqOpts *requestOptions) error {
+	rootURL, err := c.routeModels(models)
+	if err != nil {
+		return err
+	}
+	var body json.RawMessage
+	if err := c.sendRequestAndUnmarshal("GET", appendQuery(rootURL, query), "", "", &body, reqOpts); err != nil {
+		return err
+	}
+	if err := c.decodeReusing(reflect.ValueOf(models).Elem(), body); err != nil {
+		return err
+	}
+	return c.loadIncludes(models, reqOpts)
+}
+
+// decodeReusing decodes body, which holds a JSON array, into slice, reusing
+// its backing array and, if the elements are pointers, the models they point
+// to where the ids match.
+func (c *Client) decodeReusing(slice reflect.Value, body []byte) error {
+	elems := newArrayDecoder(c, body)
+	if err := elems.start(); err != nil {
+		return err
+	}
+	elemType := slice.Type().Elem()
+	oldLen := slice.Len()
+	n := 0
+	if elemType.Kind() != reflect.Ptr {
+		// Values cannot be shared, so decoding in order is all it takes.
+		for elems.more() {
+			grow(slice, n+1)
+			elem := slice.Index(n)
+			elem.Set(reflect.Zero(elemType))
+			if err := elems.decode(elem.Addr().Interface()); err != nil {
+				return err
+			}
+			n++
+		}
+		shrink(slice, n, oldLen)
+		return nil
+	}
+	existing := make(map[string]reflect.Value, oldLen)
+	for i := 0; i < oldLen; i++ {
+		if elem := slice.Index(i); !elem.IsNil() {
+			existing[elem.Interface().(Model).ModelId()] = elem
+		}
+	}
+	// Each element is decoded into scratch first to find out its id. If there
+	// is an existing model with that id, scratch is copied into it and reused
+	// for the next element. Otherwise scratch becomes the new element.
+	var scratch reflect.Value
+	for elems.more() {
+		if !scratch.IsValid() {
+			scratch = newModelOfType(elemType)
+		} else {
+			scratch.Elem().Set(reflect.Zero(scratch.Elem().Type()))
+		}
+		if err := elems.decode(scratch.Interface()); err != nil {
+			return err
+		}
+		elem := scratch
+		id := scratch.Interface().(Model).ModelId()
+		if model, found := existing[id]; found {
+			// Only reuse each model once, in case the response has duplicates
+			delete(existing, id)
+			model.Elem().Set(scratch.Elem())
+			elem = model
+		} else {
+			scratch = reflect.Value{}
+		}
+		grow(slice, n+1)
+		slice.Index(n).Set(elem)
+		n++
+	}
+	shrink(slice, n, oldLen)
+	return nil
+}
+
+// grow sets the length of slice to n, which is at most one more than its
+// current length, reallocating the backing array only if it is too small.
+func grow(slice reflect.Value, n int) {
+	if n <= slice.Len() {
+		return
+	}
+	if n <= slice.Cap() {
+		slice.SetLen(n)
+		return
+	}
+	slice.Set(reflect.Append(slice, reflect.Zero(slice.Type().Elem())))
+}
+
+// shrink sets the length of slice to n and zeroes the elements of its
+// backing array between n and oldLen, so that they can be garbage collected.
+func shrink(slice reflect.Value, n int, oldLen int) {
+	if n >= slice.Len() {
+		return
+	}
+	if oldLen < slice.Len() {
+		oldLen = slice.Len()
+	}
+	zero := reflect.Zero(slice.Type().Elem())
+	for i := n; i < oldLen; i++ {
+		slice.Index(i).Set(zero)
+	}
+	slice.SetLen(n)
+}
+
+// arrayDecoder decodes the elements of a JSON array one at a time, the same
+// way the client would decode each of them on its own.
+type arrayDecoder struct {
+	client  *Client
+	decoder *json.Decoder
+	// record holds the current element when it needs to go through
+	// Client.unmarshal, i.e. when the client matches fields tolerantly or
+	// has its own JSONBackend. Its memory is reused for each element.
+	record json.RawMessage
+	err    error
+	null   bool
+}
+
+// newArrayDecoder returns an arrayDecoder for the JSON array in body.
+func newArrayDecoder(c *Client, body []byte) *arrayDecoder {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if c.UseNumber {
+		decoder.UseNumber()
+	}
+	return &arrayDecoder{client: c, decoder: decoder}
+}
+
+// start reads the opening bracket of the array. An empty body or null is
+// treated as an empty array.
+func (d *arrayDecoder) start() error {
+	token, err := d.decoder.Token()
+	if err == io.EOF || (err == nil && token == nil) {
+		d.null = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("rest: expected a JSON array but got %v", token)
+	}
+	return nil
+}
+
+// more returns true iff there is another element in the array.
+func (d *arrayDecoder) more() bool {
+	return !d.null && d.decoder.More()
+}
+
+// decode decodes the next element of the array into v.
+func (d *arrayDecoder) decode(v interface{}) error {
+	if d.client.FieldMatching == MatchExact && d.client.JSON == nil {
+		return d.decoder.Decode(v)
+	}
+	if err := d.decoder.Decode(&d.record); err != nil {
+		return err
+	}
+	return d.client.unmarshal(d.record, v)
+}
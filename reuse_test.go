@@ -0,0 +1,63 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import "testing"
+
+func TestWithReuse(t *testing.T) {
+	newTodoServer(t, "a", "b", "c")
+	second := &testTodo{DefaultId: DefaultId{Id: "2"}, Title: "old", IsCompleted: true}
+	stale := &testTodo{DefaultId: DefaultId{Id: "9"}}
+	todos := make([]*testTodo, 0, 10)
+	todos = append(todos, stale, second)
+	backing := todos[:cap(todos)]
+	if err := NewClient().ReadAll(&todos, WithReuse()); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 3 || &todos[0] != &backing[0] {
+		t.Fatalf("Expected 3 todos in the same backing array but got %d", len(todos))
+	}
+	for i, title := range []string{"a", "b", "c"} {
+		if todos[i].Title != title {
+			t.Errorf("Expected todo %d to have the title %s but got %+v", i, title, todos[i])
+		}
+	}
+	if todos[1] != second {
+		t.Error("Expected the model with a matching id to be reused")
+	}
+	if second.Title != "b" || second.IsCompleted {
+		t.Errorf("Expected the reused model to be overwritten but got %+v", second)
+	}
+	if todos[0] == stale {
+		t.Error("Expected the model without a matching id not to be reused")
+	}
+
+	// The collection shrinks
+	todos = todos[:3]
+	if err := NewClient().ReadAll(&todos, WithReuse(), WithQuery(Query{"Title": {"b"}})); err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0] != second {
+		t.Fatalf("Expected only the second todo but got %v", todos)
+	}
+	if backing[1] != nil || backing[2] != nil {
+		t.Error("Expected the elements beyond the new length to be zeroed")
+	}
+}
+
+func TestWithReuseGrow(t *testing.T) {
+	newTodoServer(t, "a", "b")
+	todos := []stringModel{}
+	if err := NewClient().ReadAll(&todos, WithReuse()); err == nil {
+		t.Error("Expected an error for elements which are not structs")
+	}
+	grown := make([]*testTodo, 1, 1)
+	if err := NewClient().ReadAll(&grown, WithReuse()); err != nil {
+		t.Fatal(err)
+	}
+	if len(grown) != 2 || grown[0].Title != "a" || grown[1].Title != "b" {
+		t.Errorf("Expected the slice to grow but got %v", grown)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+)
+
+// httpClient returns the http.Client used to send requests.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// transport returns the http.RoundTripper used by the client: its Transport,
+// if any, or else the Transport of its http.Client.
+func (c *Client) transport() http.RoundTripper {
+	if c.Transport != nil {
+		return transportRoundTripper{transport: c.Transport}
+	}
+	if transport := c.httpClient().Transport; transport != nil {
+		return transport
+	}
+	return http.DefaultTransport
+}
+
+// FromRoundTripper returns a new client with the default settings which uses
+// rt to send requests. It can be used to plug an existing http.RoundTripper
+// (e.g. one which adds tracing or routes through a proxy) into the client.
+func FromRoundTripper(rt http.RoundTripper) *Client {
+	c := NewClient()
+	c.HTTPClient = &http.Client{
+		Transport: rt,
+	}
+	return c
+}
+
+// AsRoundTripper returns an http.RoundTripper which sends requests through
+// the client, so that they get the same treatment as requests sent by the
+// client itself: default headers and template variables, the method policy,
+// concurrency limits, retries, events, and deprecation warnings. The
+// requests are ultimately sent with the Transport of the client, or the
+// Transport of its HTTPClient if it has none. It can be used to give any
+// http.Client in the application the behavior of the rest client:
+//
+//	httpClient := &http.Client{Transport: client.AsRoundTripper()}
+//
+// Note that the response body is not read, so caching, digest verification,
+// and the handling of non-2xx status codes are left to the caller.
+func (c *Client) AsRoundTripper() http.RoundTripper {
+	return clientRoundTripper{client: c}
+}
+
+// clientRoundTripper is the http.RoundTripper returned by AsRoundTripper.
+type clientRoundTripper struct {
+	client *Client
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (rt clientRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request, so we work on a copy.
+	req = req.Clone(req.Context())
+	return rt.client.doWith(req, rt.client.transport().RoundTrip)
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAsRoundTripper(t *testing.T) {
+	var got http.Header
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	})
+	client := NewClient()
+	client.Header = http.Header{"X-App": {"todos"}}
+	client.AllowedMethods = []string{"GET"}
+	httpClient := &http.Client{Transport: client.AsRoundTripper()}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got.Get("X-App") != "todos" {
+		t.Errorf("Expected the client's default headers to be added but got %v", got)
+	}
+	if req.Header.Get("X-App") != "" {
+		t.Error("Expected the original request not to be modified")
+	}
+	if _, err := httpClient.Post(server.URL, "text/plain", nil); err == nil {
+		t.Error("Expected the client's method policy to reject POST")
+	}
+}
+
+func TestFromRoundTripper(t *testing.T) {
+	newTodoServer(t, "a")
+	called := false
+	client := FromRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		called = true
+		return http.DefaultTransport.RoundTrip(req)
+	}))
+	todo := &testTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if !called || todo.Title != "a" {
+		t.Errorf("Expected the request to go through the round tripper, got %+v", todo)
+	}
+}
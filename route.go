@@ -0,0 +1,120 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Action identifies the operation a request is sent for. It is passed to a
+// RouteResolver.
+type Action string
+
+const (
+	// ActionCreate is the action of Create.
+	ActionCreate Action = "create"
+	// ActionRead is the action of Read.
+	ActionRead Action = "read"
+	// ActionReadAll is the action of ReadAll, ReadAllComplete, Export, and
+	// ExportCSV.
+	ActionReadAll Action = "readAll"
+	// ActionUpdate is the action of Update.
+	ActionUpdate Action = "update"
+	// ActionPut is the action of Put.
+	ActionPut Action = "put"
+	// ActionDelete is the action of Delete.
+	ActionDelete Action = "delete"
+)
+
+// RouteResolver returns the base url to which the request for action on
+// model should be sent, e.g. the url of the shard which holds the model:
+//
+//	client.RouteResolver = func(model rest.Model, action rest.Action) (string, error) {
+//		id, _ := strconv.Atoi(model.ModelId())
+//		return fmt.Sprintf("https://shard%d.example.com", id%4), nil
+//	}
+//
+// The path of the RootURL of model is appended to the base url, replacing the
+// scheme and host of the RootURL, if any. If the base url is empty, the
+// RootURL is used as is. An error aborts the request and is returned to the
+// caller.
+//
+// For ReadAll, model is a new, empty model of the type of the elements of the
+// slice. For Read, the id to read is set on model beforehand if model has a
+// SetModelId method (as DefaultId and the like do), so that it can be used for
+// routing.
+type RouteResolver func(model Model, action Action) (baseURL string, err error)
+
+// idSetter is satisfied by models whose id can be set, such as DefaultId.
+type idSetter interface {
+	SetModelId(id string) error
+}
+
+// routeRootURL returns the root url for action on model according to
+// c.RouteResolver, or model.RootURL() if there is none.
+func (c *Client) routeRootURL(model Model, action Action) (string, error) {
+	rootURL := model.RootURL()
+	if c.RouteResolver == nil {
+		return rootURL, nil
+	}
+	baseURL, err := c.RouteResolver(model, action)
+	if err != nil || baseURL == "" {
+		return rootURL, err
+	}
+	if i := strings.Index(rootURL, "://"); i != -1 {
+		// Keep only the path of an absolute RootURL
+		rootURL = rootURL[i+len("://"):]
+		if j := strings.Index(rootURL, "/"); j != -1 {
+			rootURL = rootURL[j:]
+		} else {
+			rootURL = ""
+		}
+	}
+	return joinPath(baseURL, rootURL), nil
+}
+
+// routeURLForModel is like urlForModel but uses the root url returned by
+// routeRootURL.
+func (c *Client) routeURLForModel(model Model, action Action) (string, error) {
+	rootURL, err := c.routeRootURL(model, action)
+	if err != nil {
+		return "", err
+	}
+	return c.urlForModelAt(rootURL, model)
+}
+
+// routeRead returns the root url for reading the model with the given id into
+// model. If c has a RouteResolver, the id is set on model first, if possible.
+func (c *Client) routeRead(id string, model Model) (string, error) {
+	if c.RouteResolver != nil && id != "" {
+		if setter, ok := model.(idSetter); ok {
+			if err := setter.SetModelId(id); err != nil {
+				return "", err
+			}
+		}
+	}
+	return c.routeRootURL(model, ActionRead)
+}
+
+// routeModels returns the normalized root url for reading the models of the
+// type of the elements of models, which must be a pointer to a slice.
+func (c *Client) routeModels(models interface{}) (string, error) {
+	if err := checkModelsType(models, false); err != nil {
+		return "", err
+	}
+	return c.routeModelType(reflect.TypeOf(models).Elem().Elem())
+}
+
+// routeModelType returns the normalized root url for reading the models of
+// the given type, which must implement Model.
+func (c *Client) routeModelType(modelType reflect.Type) (string, error) {
+	model := newModelOfType(modelType).Interface().(Model)
+	rootURL, err := c.routeRootURL(model, ActionReadAll)
+	if err != nil {
+		return "", err
+	}
+	return normalizeRootURL(rootURL)
+}
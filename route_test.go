@@ -0,0 +1,156 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// shardHandler returns a handler which records requests for testTodos in log
+// and responds to requests for the collection with an empty list and to all
+// other requests with the todo with id 2.
+func shardHandler(log *requestLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		if r.Method == "GET" && r.URL.Path == "/todos" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`{"Id": "2", "Title": "a"}`))
+	}
+}
+
+// newShard starts a server with shardHandler and returns its log and url.
+func newShard(t *testing.T) (*requestLog, string) {
+	log := &requestLog{}
+	srv := httptest.NewServer(shardHandler(log))
+	t.Cleanup(srv.Close)
+	return log, srv.URL
+}
+
+func TestRouteResolver(t *testing.T) {
+	defaultLog := &requestLog{}
+	newTestServer(t, shardHandler(defaultLog))
+	evenLog, evenURL := newShard(t)
+	oddLog, oddURL := newShard(t)
+	var actions []Action
+	client := NewClient()
+	client.RouteResolver = func(model Model, action Action) (string, error) {
+		actions = append(actions, action)
+		id, err := strconv.Atoi(model.ModelId())
+		if err != nil {
+			// New models and collections go to the default server
+			return "", nil
+		}
+		if id%2 == 0 {
+			return evenURL + "/", nil
+		}
+		return oddURL, nil
+	}
+
+	todo := &testTodo{Title: "a"}
+	if err := client.Create(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Read("2", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put(todo); err != nil {
+		t.Fatal(err)
+	}
+	odd := &testTodo{}
+	odd.Id = "3"
+	if err := client.Delete(odd); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReadAll(&[]*testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRequests := func(name string, log *requestLog, expected ...string) {
+		t.Helper()
+		requests := log.all()
+		if len(requests) != len(expected) {
+			t.Errorf("Expected %d requests to be sent to the %s server but got %d", len(expected), name, len(requests))
+			return
+		}
+		for i, req := range requests {
+			if got := req.Method + " " + req.URL.Path; got != expected[i] {
+				t.Errorf("Expected request %d to the %s server to be %s but got %s", i, name, expected[i], got)
+			}
+		}
+	}
+	expectRequests("default", defaultLog, "POST /todos", "GET /todos")
+	expectRequests("even", evenLog, "GET /todos/2", "PATCH /todos/2", "PUT /todos/2")
+	expectRequests("odd", oddLog, "DELETE /todos/3")
+
+	expectedActions := []Action{ActionCreate, ActionRead, ActionUpdate, ActionPut, ActionDelete, ActionReadAll}
+	if len(actions) != len(expectedActions) {
+		t.Fatalf("Expected the actions %v but got %v", expectedActions, actions)
+	}
+	for i, action := range actions {
+		if action != expectedActions[i] {
+			t.Errorf("Expected the actions %v but got %v", expectedActions, actions)
+			break
+		}
+	}
+}
+
+func TestRouteResolverError(t *testing.T) {
+	server := newTodoServer(t, "Write a book")
+	errNoShard := errors.New("no shard")
+	client := NewClient()
+	client.RouteResolver = func(Model, Action) (string, error) {
+		return "", errNoShard
+	}
+	if err := client.Read("1", &testTodo{}); err != errNoShard {
+		t.Errorf("Expected the error of the RouteResolver but got %v", err)
+	}
+	if err := client.ReadAll(&[]*testTodo{}); err != errNoShard {
+		t.Errorf("Expected the error of the RouteResolver but got %v", err)
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("Expected no requests to be sent but got %d", n)
+	}
+}
+
+func TestRouteRootURL(t *testing.T) {
+	tests := []struct {
+		rootURL, baseURL, want string
+	}{
+		{"http://example.com/api/todos", "https://shard1.example.com", "https://shard1.example.com/api/todos"},
+		{"/todos", "https://shard1.example.com/v2/", "https://shard1.example.com/v2/todos"},
+		{"http://example.com", "https://shard1.example.com", "https://shard1.example.com"},
+		{"http://example.com/todos", "", "http://example.com/todos"},
+	}
+	for _, test := range tests {
+		client := NewClient()
+		baseURL := test.baseURL
+		client.RouteResolver = func(Model, Action) (string, error) { return baseURL, nil }
+		got, err := client.routeRootURL(&staticRootModel{rootURL: test.rootURL}, ActionRead)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", test.rootURL, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Expected %s for %q and %q but got %s", test.want, test.rootURL, test.baseURL, got)
+		}
+	}
+}
+
+// staticRootModel is a model with a fixed RootURL.
+type staticRootModel struct {
+	DefaultId
+	rootURL string
+}
+
+func (m *staticRootModel) RootURL() string { return m.rootURL }
@@ -0,0 +1,62 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RPCError is returned by Call when the server responds with a Twirp-style
+// error, i.e. a non-2xx status code and a body of the form:
+//
+//	{"code": "not_found", "msg": "todo not found", "meta": {"id": "7"}}
+type RPCError struct {
+	// Code is the error code, e.g. "not_found" or "invalid_argument"
+	Code string `json:"code"`
+	// Msg is a human-readable description of the error
+	Msg string `json:"msg"`
+	// Meta holds additional information about the error
+	Meta map[string]string `json:"meta"`
+	// StatusCode is the http status code of the response
+	StatusCode int `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e RPCError) Error() string {
+	return fmt.Sprintf("rest: rpc error %s: %s", e.Code, e.Msg)
+}
+
+// Call calls a method of an RPC service exposed over HTTP in the style of
+// Twirp or grpc-gateway: req is encoded as JSON and sent in a POST request to
+// c.RPCURL + "/" + service + "/" + method, and the JSON response is decoded
+// into resp. The request goes through the same machinery as any other request
+// sent by the client (default headers, template variables, retries, events,
+// etc.), so a single client can talk to services which mix REST and RPC
+// endpoints. resp may be nil if the response should be discarded. If the
+// server responds with an error in the Twirp format, an RPCError is
+// returned.
+func (c *Client) Call(service string, method string, req interface{}, resp interface{}, opts ...RequestOption) error {
+	if c.RPCURL == "" {
+		return errors.New("rest: Client.RPCURL must be set to use Call")
+	}
+	url := strings.TrimSuffix(c.RPCURL, "/") + "/" + service + "/" + method
+	rb := c.NewRequestBuilder("POST", url).
+		Body(req).
+		ContentType(ContentJSON).
+		Encoder(json.Marshal).
+		Into(resp)
+	err := rb.execute(newRequestOptions(opts))
+	if httpErr, ok := err.(HTTPError); ok {
+		rpcErr := RPCError{}
+		if json.Unmarshal(httpErr.Body, &rpcErr) == nil && rpcErr.Code != "" {
+			rpcErr.StatusCode = httpErr.StatusCode
+			return rpcErr
+		}
+	}
+	return err
+}
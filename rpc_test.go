@@ -0,0 +1,65 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	server := newEchoServer(t, http.StatusOK, `{"count": 2}`)
+	client := NewClient()
+	client.RPCURL = testRootURL + "/twirp/"
+	req := struct {
+		Owner string `json:"owner"`
+	}{Owner: "alex"}
+	resp := struct {
+		Count int `json:"count"`
+	}{}
+	if err := client.Call("todos.TodoService", "CountTodos", req, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 2 {
+		t.Errorf("Expected the response to be decoded but got %+v", resp)
+	}
+	last := server.last()
+	if last.Method != "POST" || last.URL.Path != "/twirp/todos.TodoService/CountTodos" {
+		t.Errorf("Unexpected request: %s %s", last.Method, last.URL)
+	}
+	if body := server.lastBody(); body != `{"owner":"alex"}` || last.Header.Get("Content-Type") != string(ContentJSON) {
+		t.Errorf("Expected a JSON body but got %q", body)
+	}
+	if err := client.Call("todos.TodoService", "Ping", req, nil); err != nil {
+		t.Errorf("Expected a nil resp to discard the response but got %v", err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	newEchoServer(t, http.StatusNotFound, `{"code": "not_found", "msg": "todo not found", "meta": {"id": "7"}}`)
+	client := NewClient()
+	if err := client.Call("todos.TodoService", "GetTodo", nil, nil); err == nil {
+		t.Error("Expected an error when RPCURL is not set")
+	}
+	client.RPCURL = testRootURL
+	err := client.Call("todos.TodoService", "GetTodo", nil, nil)
+	expected := RPCError{
+		Code:       "not_found",
+		Msg:        "todo not found",
+		Meta:       map[string]string{"id": "7"},
+		StatusCode: http.StatusNotFound,
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Expected %#v but got %#v", expected, err)
+	}
+
+	newEchoServer(t, http.StatusBadGateway, "<html>Bad Gateway</html>")
+	client.RPCURL = testRootURL
+	err = client.Call("todos.TodoService", "GetTodo", nil, nil)
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected an HTTPError for a response which is not a Twirp error but got %v", err)
+	}
+}
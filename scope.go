@@ -0,0 +1,194 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware wraps an http.RoundTripper, e.g. to add tracing or to sign
+// requests. See ScopeMiddleware.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// ScopeOption customizes a child client returned by Client.Scope.
+type ScopeOption func(*Client)
+
+// ScopeMiddleware returns a ScopeOption which wraps the transport of the child
+// client with each of the given middleware, in addition to whatever the
+// transport of the parent does. The first middleware is the outermost one, so
+// it sees each request first.
+func ScopeMiddleware(middleware ...Middleware) ScopeOption {
+	return func(c *Client) {
+		transport := c.transport()
+		for i := len(middleware) - 1; i >= 0; i-- {
+			transport = middleware[i](transport)
+		}
+		if c.Transport != nil {
+			c.Transport = TransportFunc(transport.RoundTrip)
+			return
+		}
+		httpClient := http.Client{}
+		if c.HTTPClient != nil {
+			httpClient = *c.HTTPClient
+		}
+		httpClient.Transport = transport
+		c.HTTPClient = &httpClient
+	}
+}
+
+// ScopeHeader returns a ScopeOption which sets the header with the given key
+// to value on every request sent by the child client.
+func ScopeHeader(key string, value string) ScopeOption {
+	return func(c *Client) {
+		if c.Header == nil {
+			c.Header = http.Header{}
+		}
+		c.Header.Set(key, value)
+	}
+}
+
+// ScopeVar returns a ScopeOption which sets the template variable with the
+// given name to value for the child client only.
+func ScopeVar(name string, value string) ScopeOption {
+	return func(c *Client) {
+		c.SetVar(name, value)
+	}
+}
+
+// Scope returns a child client which inherits the settings, template
+// variables, headers, auth, and transport of c, and then applies each of the
+// given options in order. Any setting of the child can also be overridden by
+// assigning to its fields directly. The child gets its own copy of
+// everything, so changes made to c after Scope returns do not affect the
+// child and vice versa. The exception is Cache, which is shared between the
+// two. The child also starts with its own concurrency limits, events, health
+// and failover state, discovered capabilities, quota usage, and endpoint
+// statistics.
+//
+// Scope can be used to e.g. give each tenant of an application a client with
+// its own headers without repeating the common setup:
+//
+//	tenantClient := client.Scope(rest.ScopeHeader("X-Tenant", tenant))
+func (c *Client) Scope(opts ...ScopeOption) *Client {
+	child := c.copySettings()
+	c.mut.RLock()
+	for name, value := range c.vars {
+		if child.vars == nil {
+			child.vars = map[string]string{}
+		}
+		child.vars[name] = value
+	}
+	c.mut.RUnlock()
+	for _, opt := range opts {
+		opt(child)
+	}
+	return child
+}
+
+// copySettings returns a new client with a deep copy of the exported fields
+// of c. Unexported state is left empty.
+func (c *Client) copySettings() *Client {
+	child := &Client{
+		ContentType:                  c.ContentType,
+		Transport:                    c.Transport,
+		UseNumber:                    c.UseNumber,
+		FieldMatching:                c.FieldMatching,
+		WarnFloatMoney:               c.WarnFloatMoney,
+		BodyDigest:                   c.BodyDigest,
+		DigestHeader:                 c.DigestHeader,
+		VerifyResponseDigest:         c.VerifyResponseDigest,
+		Cache:                        c.Cache,
+		CacheTTL:                     c.CacheTTL,
+		CacheRefreshAhead:            c.CacheRefreshAhead,
+		OnDeprecation:                c.OnDeprecation,
+		LogDeprecations:              c.LogDeprecations,
+		AllowedMethods:               copyStrings(c.AllowedMethods),
+		CacheVary:                    copyStrings(c.CacheVary),
+		MaxConcurrentRequests:        c.MaxConcurrentRequests,
+		MaxConcurrentRequestsPerHost: c.MaxConcurrentRequestsPerHost,
+		PatchMode:                    c.PatchMode,
+		RPCURL:                       c.RPCURL,
+		OnTiming:                     c.OnTiming,
+		OnDecode:                     c.OnDecode,
+		OnError:                      c.OnError,
+		Authorize:                    c.Authorize,
+		FieldsParam:                  c.FieldsParam,
+		CompositeKeyPattern:          c.CompositeKeyPattern,
+		URLConvention:                c.URLConvention,
+		RouteResolver:                c.RouteResolver,
+		BaseURL:                      c.BaseURL,
+		Timeout:                      c.Timeout,
+		RecordsKey:                   c.RecordsKey,
+		MetaKey:                      c.MetaKey,
+		ReadByMode:                   c.ReadByMode,
+		MultiGet:                     c.MultiGet,
+		MultiGetParam:                c.MultiGetParam,
+		MultiGetConcurrency:          c.MultiGetConcurrency,
+		IncludeMode:                  c.IncludeMode,
+		IncludeParam:                 c.IncludeParam,
+		CapabilitiesHeader:           c.CapabilitiesHeader,
+		CapabilitiesTTL:              c.CapabilitiesTTL,
+		RememberForbidden:            c.RememberForbidden,
+		JSON:                         c.JSON,
+		LatencySmoothing:             c.LatencySmoothing,
+		SlowLatency:                  c.SlowLatency,
+		SlowErrorRate:                c.SlowErrorRate,
+		ImpersonationHeader:          c.ImpersonationHeader,
+		AutoRegister:                 c.AutoRegister,
+	}
+	if c.Header != nil {
+		child.Header = c.Header.Clone()
+	}
+	if c.CacheTTLs != nil {
+		child.CacheTTLs = map[string]time.Duration{}
+		for root, ttl := range c.CacheTTLs {
+			child.CacheTTLs[root] = ttl
+		}
+	}
+	if c.ResourceHeaders != nil {
+		child.ResourceHeaders = map[string]http.Header{}
+		for root, header := range c.ResourceHeaders {
+			child.ResourceHeaders[root] = header.Clone()
+		}
+	}
+	if c.HTTPClient != nil {
+		httpClient := *c.HTTPClient
+		child.HTTPClient = &httpClient
+	}
+	if c.Retry != nil {
+		retry := *c.Retry
+		retry.StatusCodes = append([]int(nil), c.Retry.StatusCodes...)
+		child.Retry = &retry
+	}
+	if c.Mirror != nil {
+		mirror := *c.Mirror
+		child.Mirror = &mirror
+	}
+	if c.PII != nil {
+		pii := *c.PII
+		pii.Fields = copyStrings(c.PII.Fields)
+		pii.Production = copyStrings(c.PII.Production)
+		child.PII = &pii
+	}
+	if c.Quota != nil {
+		quota := *c.Quota
+		child.Quota = &quota
+	}
+	if c.Failover != nil {
+		failover := *c.Failover
+		failover.Endpoints = copyStrings(c.Failover.Endpoints)
+		child.Failover = &failover
+	}
+	return child
+}
+
+// copyStrings returns a copy of s, or nil if s is nil.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
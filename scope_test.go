@@ -0,0 +1,99 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// tagMiddleware returns Middleware which appends name to *order for each
+// request it sees.
+func tagMiddleware(name string, order *[]string, mut *sync.Mutex) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mut.Lock()
+			*order = append(*order, name)
+			mut.Unlock()
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestScope(t *testing.T) {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	var order []string
+	var mut sync.Mutex
+	parent := FromRoundTripper(tagMiddleware("parent", &order, &mut)(http.DefaultTransport))
+	parent.Header = http.Header{"X-App": {"test"}}
+	parent.SetVar("tenant", "acme")
+	parent.Cache = NewMemoryCache()
+
+	child := parent.Scope(
+		ScopeHeader("X-Team", "a"),
+		ScopeVar("tenant", "globex"),
+		ScopeMiddleware(tagMiddleware("outer", &order, &mut), tagMiddleware("inner", &order, &mut)),
+	)
+	if err := child.Read("1", &tenantTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	req := requests.last()
+	if req.URL.Path != "/t/globex/todos/1" || req.Header.Get("X-App") != "test" || req.Header.Get("X-Team") != "a" {
+		t.Errorf("Expected the settings of the parent and the child to be combined but got %s %v", req.URL.Path, req.Header)
+	}
+	if !reflect.DeepEqual(order, []string{"outer", "inner", "parent"}) {
+		t.Errorf("Expected the middleware of the child to wrap the transport of the parent but got %v", order)
+	}
+
+	order = nil
+	if err := parent.Read("1", &tenantTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	req = requests.last()
+	if req.URL.Path != "/t/acme/todos/1" || req.Header.Get("X-Team") != "" {
+		t.Errorf("Expected the parent not to be affected by the child but got %s %v", req.URL.Path, req.Header)
+	}
+	if !reflect.DeepEqual(order, []string{"parent"}) {
+		t.Errorf("Expected the parent not to use the middleware of the child but got %v", order)
+	}
+
+	parent.Header.Set("X-App", "changed")
+	parent.SetVar("tenant", "initech")
+	child.Header.Set("X-Team", "b")
+	if parent.Header.Get("X-Team") != "" || child.Header.Get("X-App") != "test" || child.Var("tenant") != "globex" {
+		t.Error("Expected changes after Scope not to be shared between the parent and the child")
+	}
+	if child.Cache != parent.Cache {
+		t.Error("Expected the cache to be shared")
+	}
+}
+
+func TestScopeTransport(t *testing.T) {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	var order []string
+	var mut sync.Mutex
+	parent := NewClient()
+	parent.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "transport")
+		return http.DefaultClient.Do(req)
+	})
+	child := parent.Scope(ScopeMiddleware(tagMiddleware("child", &order, &mut)))
+	if err := child.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"child", "transport"}) {
+		t.Errorf("Expected the middleware to wrap the Transport of the parent but got %v", order)
+	}
+}
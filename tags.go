@@ -0,0 +1,56 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagOptions is the comma-separated list of options that follows the name in
+// a `rest:"..."` struct tag.
+type tagOptions string
+
+// parseTag splits the rest struct tag of field into its name and options.
+func parseTag(field reflect.StructField) (string, tagOptions) {
+	return splitTag(field.Tag.Get("rest"))
+}
+
+// splitTag splits a struct tag value of the form "name,opt1,opt2" into its
+// name and options.
+func splitTag(tag string) (string, tagOptions) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, tagOptions("")
+}
+
+// Contains returns true iff opts contains the option with the given name.
+func (opts tagOptions) Contains(name string) bool {
+	_, ok := opts.Get(name)
+	return ok
+}
+
+// Get returns the value of the option with the given name. Options may be
+// either flags (e.g. "money") or key-value pairs (e.g. "header=ETag"). For
+// flags, the returned value is always an empty string.
+func (opts tagOptions) Get(name string) (string, bool) {
+	s := string(opts)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i != -1 {
+			s, next = s[:i], s[i+1:]
+		}
+		key, value := s, ""
+		if i := strings.Index(s, "="); i != -1 {
+			key, value = s[:i], s[i+1:]
+		}
+		if key == name {
+			return value, true
+		}
+		s = next
+	}
+	return "", false
+}
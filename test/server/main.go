@@ -64,7 +64,7 @@ func main() {
 
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AddAllowHeaders("Content-Type")
-	corsConfig.AddAllowMethods("GET", "POST", "DELETE", "PATCH", "OPTIONS")
+	corsConfig.AddAllowMethods("GET", "POST", "DELETE", "PATCH", "PUT", "OPTIONS")
 	corsConfig.AllowAllOrigins = true
 	r.Use(cors.New(corsConfig))
 
@@ -72,6 +72,7 @@ func main() {
 	r.POST("/todos", todosController.Create)
 	r.GET("/todos/:id", todosController.Show)
 	r.PATCH("/todos/:id", todosController.Update)
+	r.PUT("/todos/:id", todosController.Replace)
 	r.DELETE("/todos/:id", todosController.Delete)
 
 	r.Run(":3000")
@@ -164,6 +165,39 @@ func (todosControllerType) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, todoCopy)
 }
 
+// Replace accepts form data for creating or replacing the todo with the given id.
+// Unlike Update, all the fields are required. Any non-negative id is accepted, so
+// Replace can be used to simulate creating a todo with a client-chosen id. It
+// returns the todo that would be stored as a json object.
+func (todosControllerType) Replace(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id < 0 {
+		c.JSON(statusUnprocessableEntity, map[string]string{
+			"error": fmt.Sprintf(`Invalid id paramater "%s"`, idStr),
+		})
+		return
+	}
+	// Parse data and do validations
+	todoData, err := forms.Parse(c.Request)
+	if err != nil {
+		panic(err)
+	}
+	val := todoData.Validator()
+	val.Require("Title")
+	val.Require("IsCompleted")
+	val.TypeBool("IsCompleted")
+	if val.HasErrors() {
+		c.JSON(statusUnprocessableEntity, val.ErrorMap())
+		return
+	}
+	c.JSON(http.StatusOK, todo{
+		Id:          id,
+		Title:       todoData.Get("Title"),
+		IsCompleted: todoData.GetBool("IsCompleted"),
+	})
+}
+
 func (todosControllerType) Delete(c *gin.Context) {
 	// Get the id from the url parameters
 	if _, err := parseId(c); err != nil {
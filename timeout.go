@@ -0,0 +1,124 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// TimeoutError is returned when a request does not complete within the
+// timeout given by Client.Timeout or WithTimeout. It satisfies net.Error, and
+// its Timeout method always returns true, so it can be detected the same way
+// as timeouts of the underlying transport:
+//
+//	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+//		// Handle timeout
+//	}
+type TimeoutError struct {
+	// Method is the http method of the request
+	Method string
+	// URL is the url the request was sent to
+	URL string
+	// Duration is the timeout which was exceeded
+	Duration time.Duration
+}
+
+// Error satisfies the error interface
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("rest: %s request to %s timed out after %s", e.Method, e.URL, e.Duration)
+}
+
+// Timeout returns true. It satisfies net.Error.
+func (e TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary returns true, since the request may succeed if it is sent
+// again. It satisfies net.Error.
+func (e TimeoutError) Temporary() bool {
+	return true
+}
+
+// WithTimeout returns a RequestOption which overrides Client.Timeout for a
+// single request. A timeout of zero means the request has no timeout, other
+// than the deadline of its context, if any.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(opts *requestOptions) {
+		opts.timeout = &timeout
+	}
+}
+
+// timeoutKey is the context key for the timeout given by WithTimeout.
+type timeoutKey struct{}
+
+// withRequestTimeout returns a copy of ctx which carries timeout.
+func withRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// timeoutFor returns the timeout for req: the one given by WithTimeout, if
+// any, or else c.Timeout.
+func (c *Client) timeoutFor(req *http.Request) time.Duration {
+	if timeout, ok := req.Context().Value(timeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return c.Timeout
+}
+
+// doWithTimeout is like doWith but aborts req if it does not complete within
+// timeout, including all retries and reading the response body, and returns a
+// TimeoutError in that case.
+func (c *Client) doWithTimeout(req *http.Request, send func(*http.Request) (*http.Response, error), timeout time.Duration) (*http.Response, error) {
+	parent := req.Context()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	// The timeout has been applied, so doWith must not apply it again
+	req = req.WithContext(withRequestTimeout(ctx, 0))
+	timeoutErr := TimeoutError{Method: req.Method, URL: req.URL.String(), Duration: timeout}
+	res, err := c.doWith(req, send)
+	if err != nil {
+		cancel()
+		if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			return nil, timeoutErr
+		}
+		return nil, err
+	}
+	res.Body = timeoutBody{
+		ReadCloser: res.Body,
+		ctx:        ctx,
+		parent:     parent,
+		cancel:     cancel,
+		err:        timeoutErr,
+	}
+	return res, nil
+}
+
+// timeoutBody is a response body which returns err instead of the error of
+// the underlying body if the timeout of the request passed while reading it,
+// and which releases the resources of the timeout when it is closed.
+type timeoutBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	parent context.Context
+	cancel context.CancelFunc
+	err    TimeoutError
+}
+
+func (b timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.ctx.Err() == context.DeadlineExceeded && b.parent.Err() == nil {
+		err = b.err
+	}
+	return n, err
+}
+
+func (b timeoutBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newSlowServer starts a server for testTodo which waits for delay before
+// sending the response headers, and for bodyDelay before sending the body.
+func newSlowServer(t *testing.T, delay time.Duration, bodyDelay time.Duration) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(bodyDelay)
+		w.Write([]byte(`{"Id": "1", "Title": "Write a book"}`))
+	})
+}
+
+// expectTimeoutError fails the test unless err is a TimeoutError for a GET
+// request with the given duration.
+func expectTimeoutError(t *testing.T, err error, duration time.Duration) {
+	t.Helper()
+	timeoutErr, ok := err.(TimeoutError)
+	if !ok {
+		t.Fatalf("Expected a TimeoutError but got %T: %v", err, err)
+	}
+	if timeoutErr.Method != "GET" || timeoutErr.URL != testRootURL+"/todos/1" || timeoutErr.Duration != duration {
+		t.Errorf("Expected a TimeoutError for GET %s after %s but got %+v", testRootURL+"/todos/1", duration, timeoutErr)
+	}
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Error("Expected the TimeoutError to be a net.Error whose Timeout method returns true")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	newSlowServer(t, 200*time.Millisecond, 0)
+	client := NewClient()
+	client.Timeout = 20 * time.Millisecond
+	start := time.Now()
+	err := client.Read("1", &testTodo{})
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected the request to be aborted after the timeout but it took %s", elapsed)
+	}
+	expectTimeoutError(t, err, 20*time.Millisecond)
+}
+
+func TestClientTimeoutWhileReadingBody(t *testing.T) {
+	newSlowServer(t, 0, 200*time.Millisecond)
+	client := NewClient()
+	client.Timeout = 50 * time.Millisecond
+	expectTimeoutError(t, client.Read("1", &testTodo{}), 50*time.Millisecond)
+}
+
+func TestWithTimeout(t *testing.T) {
+	newSlowServer(t, 50*time.Millisecond, 0)
+	client := NewClient()
+	err := client.Read("1", &testTodo{}, WithTimeout(10*time.Millisecond))
+	expectTimeoutError(t, err, 10*time.Millisecond)
+
+	client.Timeout = 10 * time.Millisecond
+	todo := &testTodo{}
+	if err := client.Read("1", todo, WithTimeout(0)); err != nil {
+		t.Fatalf("Expected WithTimeout(0) to disable the timeout of the client but got %s", err)
+	}
+	if todo.Title != "Write a book" {
+		t.Errorf("Expected the todo to be read but got %+v", todo)
+	}
+	if err := client.Read("1", &testTodo{}, WithTimeout(time.Second)); err != nil {
+		t.Errorf("Expected WithTimeout to override the timeout of the client but got %s", err)
+	}
+}
+
+func TestTimeoutWithCanceledContext(t *testing.T) {
+	newSlowServer(t, 200*time.Millisecond, 0)
+	client := NewClient()
+	client.Timeout = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := client.Read("1", &testTodo{}, WithContext(ctx))
+	if _, ok := err.(TimeoutError); ok {
+		t.Errorf("Expected the error of the context rather than a TimeoutError but got %v", err)
+	}
+	if err == nil {
+		t.Error("Expected an error when the context passes its deadline")
+	}
+}
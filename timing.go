@@ -0,0 +1,87 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timing is a breakdown of the time taken by a request. Phases which did not
+// happen (e.g. DNS lookup and connecting when an existing connection was
+// reused) or which could not be measured are zero. When compiled with gopherjs,
+// the phases are taken from the browser's Resource Timing API, which only
+// reports them for cross-origin requests if the server sends a
+// Timing-Allow-Origin header.
+type Timing struct {
+	// DNS is the time taken to look up the host.
+	DNS time.Duration
+	// Connect is the time taken to establish a TCP connection.
+	Connect time.Duration
+	// TLS is the time taken by the TLS handshake.
+	TLS time.Duration
+	// TTFB (time to first byte) is the time between starting the final
+	// attempt at sending the request and receiving the first byte of the
+	// response.
+	TTFB time.Duration
+	// Total is the time between starting the first attempt and receiving the
+	// headers of the response, including any retries and time spent waiting
+	// for the client's concurrency limits.
+	Total time.Duration
+	// Attempts is the number of times the request was attempted.
+	Attempts int
+}
+
+// WithTiming returns a RequestOption which causes timing to be filled in with a
+// breakdown of the time taken by the request once the response has been
+// received.
+func WithTiming(timing *Timing) RequestOption {
+	return func(opts *requestOptions) {
+		opts.timing = timing
+	}
+}
+
+// timingKey is the context key for the *Timing of a request.
+type timingKey struct{}
+
+// withTiming returns a copy of ctx which carries timing.
+func withTiming(ctx context.Context, timing *Timing) context.Context {
+	return context.WithValue(ctx, timingKey{}, timing)
+}
+
+// timingFrom returns the *Timing carried by ctx, or nil if there is none.
+func timingFrom(ctx context.Context) *Timing {
+	timing, _ := ctx.Value(timingKey{}).(*Timing)
+	return timing
+}
+
+// startTiming prepares req for measuring its timing. If the timing of req
+// should be measured, it returns the *Timing along with a copy of req which
+// is used to send the request. Otherwise the *Timing is nil.
+func (c *Client) startTiming(req *http.Request) (*Timing, *http.Request) {
+	timing := timingFrom(req.Context())
+	if timing == nil {
+		if c.OnTiming == nil {
+			return nil, req
+		}
+		timing = &Timing{}
+		req = req.WithContext(withTiming(req.Context(), timing))
+	}
+	*timing = Timing{}
+	return timing, req
+}
+
+// finishTiming records the total time taken by req, which was started at
+// start, and passes the timing to c.OnTiming.
+func (c *Client) finishTiming(req *http.Request, timing *Timing, start time.Time) {
+	if timing == nil {
+		return
+	}
+	timing.Total = time.Since(start)
+	if c.OnTiming != nil {
+		c.OnTiming(req.Method, req.URL.String(), *timing)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+//go:build js
+// +build js
+
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gopherjs/gopherjs/js"
+)
+
+// traceAttempt returns req unchanged, along with a function which reads the
+// phases of the attempt from the browser's Resource Timing API into timing.
+func traceAttempt(req *http.Request, timing *Timing) (*http.Request, func()) {
+	return req, func() {
+		performance := js.Global.Get("performance")
+		if performance == js.Undefined || performance.Get("getEntriesByName") == js.Undefined {
+			return
+		}
+		entries := performance.Call("getEntriesByName", req.URL.String(), "resource")
+		if entries.Length() == 0 {
+			return
+		}
+		entry := entries.Index(entries.Length() - 1)
+		timing.DNS = between(entry, "domainLookupStart", "domainLookupEnd")
+		timing.Connect = between(entry, "connectStart", "connectEnd")
+		if entry.Get("secureConnectionStart").Float() > 0 {
+			timing.TLS = between(entry, "secureConnectionStart", "connectEnd")
+		}
+		timing.TTFB = between(entry, "requestStart", "responseStart")
+	}
+}
+
+// between returns the duration between two timestamps of a PerformanceEntry,
+// which are in milliseconds.
+func between(entry *js.Object, start string, end string) time.Duration {
+	startMs, endMs := entry.Get(start).Float(), entry.Get(end).Float()
+	if startMs <= 0 || endMs < startMs {
+		return 0
+	}
+	return time.Duration((endMs - startMs) * float64(time.Millisecond))
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithTiming(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{"Id": "1"}`))
+	}))
+	defer server.Close()
+	previous := testRootURL
+	testRootURL = server.URL
+	defer func() { testRootURL = previous }()
+	client := FromRoundTripper(server.Client().Transport)
+	timing := Timing{Attempts: 7}
+	if err := client.Read("1", &testTodo{}, WithTiming(&timing)); err != nil {
+		t.Fatal(err)
+	}
+	if timing.Attempts != 1 || timing.Connect <= 0 || timing.TLS <= 0 {
+		t.Errorf("Expected a single attempt over a new TLS connection but got %+v", timing)
+	}
+	if timing.TTFB < 10*time.Millisecond || timing.Total < timing.TTFB {
+		t.Errorf("Expected the time to first byte to include the server delay but got %+v", timing)
+	}
+
+	if err := client.Read("1", &testTodo{}, WithTiming(&timing)); err != nil {
+		t.Fatal(err)
+	}
+	if timing.Connect != 0 || timing.TLS != 0 || timing.TTFB <= 0 {
+		t.Errorf("Expected no connection phases when the connection is reused but got %+v", timing)
+	}
+}
+
+func TestOnTiming(t *testing.T) {
+	newFlakyServer(t, http.StatusServiceUnavailable, 1)
+	client := NewClient(WithRetry(&RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}))
+	timings := []Timing{}
+	urls := []string{}
+	client.OnTiming = func(method string, url string, timing Timing) {
+		timings = append(timings, timing)
+		urls = append(urls, method+" "+url)
+	}
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(timings) != 1 || timings[0].Attempts != 2 || timings[0].Total <= 0 {
+		t.Fatalf("Expected one timing with 2 attempts but got %+v", timings)
+	}
+	if urls[0] != "GET "+testRootURL+"/todos/1" {
+		t.Errorf("Unexpected request: %s", urls[0])
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+//go:build !js
+// +build !js
+
+package rest
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// attemptTrace records the phases of a single attempt using httptrace.
+type attemptTrace struct {
+	start     time.Time
+	dnsStart  time.Time
+	dns       time.Duration
+	connStart time.Time
+	connect   time.Duration
+	tlsStart  time.Time
+	tls       time.Duration
+	firstByte time.Duration
+	mut       sync.Mutex
+}
+
+// traceAttempt returns a copy of req which records the phases of the attempt
+// into timing once finishAttempt is called.
+func traceAttempt(req *http.Request, timing *Timing) (*http.Request, func()) {
+	trace := &attemptTrace{start: time.Now()}
+	clientTrace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			trace.set(func() { trace.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			trace.set(func() { trace.dns = time.Since(trace.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			trace.set(func() { trace.connStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			trace.set(func() { trace.connect = time.Since(trace.connStart) })
+		},
+		TLSHandshakeStart: func() {
+			trace.set(func() { trace.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			trace.set(func() { trace.tls = time.Since(trace.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			trace.set(func() { trace.firstByte = time.Since(trace.start) })
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), clientTrace))
+	return req, func() {
+		trace.mut.Lock()
+		defer trace.mut.Unlock()
+		timing.DNS = trace.dns
+		timing.Connect = trace.connect
+		timing.TLS = trace.tls
+		timing.TTFB = trace.firstByte
+	}
+}
+
+// set calls f while holding the lock of the trace. The hooks of an
+// httptrace.ClientTrace may be called from different goroutines.
+func (trace *attemptTrace) set(f func()) {
+	trace.mut.Lock()
+	defer trace.mut.Unlock()
+	f()
+}
@@ -0,0 +1,231 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Operation is a single operation in a Transaction.
+type Operation struct {
+	// Method is the http method that would be used to perform the operation
+	// on its own, e.g. "POST" for a create.
+	Method string
+	// URL is the url that would be used to perform the operation on its own.
+	URL string
+	// Model is the model the operation applies to.
+	Model Model
+}
+
+// OperationResult is the result of a single operation in a Transaction.
+type OperationResult struct {
+	// StatusCode is the http status code for the operation.
+	StatusCode int
+	// Body is the JSON response for the operation. For operations other than
+	// deletes, it should contain the data for the model.
+	Body json.RawMessage
+}
+
+// Envelope determines the format used to send a Transaction to the server and
+// to interpret the response.
+type Envelope interface {
+	// Encode returns the JSON body of the batch request for ops.
+	Encode(ops []Operation) ([]byte, error)
+	// Decode converts the body of the batch response into one OperationResult
+	// for each operation, in the same order as the operations.
+	Decode(body []byte) ([]OperationResult, error)
+}
+
+// DefaultEnvelope is the Envelope used by a Transaction unless a different one
+// is provided. Requests look like this:
+//
+//	{"operations": [{"method": "POST", "url": "/todos", "body": {...}}, ...]}
+//
+// And responses are expected to look like this:
+//
+//	{"results": [{"status": 201, "body": {...}}, ...]}
+var DefaultEnvelope Envelope = defaultEnvelope{}
+
+type defaultEnvelope struct{}
+
+type defaultEnvelopeOperation struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+type defaultEnvelopeResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+func (defaultEnvelope) Encode(ops []Operation) ([]byte, error) {
+	envelope := struct {
+		Operations []defaultEnvelopeOperation `json:"operations"`
+	}{}
+	for _, op := range ops {
+		envOp := defaultEnvelopeOperation{
+			Method: op.Method,
+			URL:    op.URL,
+		}
+		if op.Method != "DELETE" {
+			envOp.Body = op.Model
+		}
+		envelope.Operations = append(envelope.Operations, envOp)
+	}
+	return json.Marshal(envelope)
+}
+
+func (defaultEnvelope) Decode(body []byte) ([]OperationResult, error) {
+	envelope := struct {
+		Results []defaultEnvelopeResult `json:"results"`
+	}{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	results := make([]OperationResult, len(envelope.Results))
+	for i, result := range envelope.Results {
+		results[i] = OperationResult{
+			StatusCode: result.Status,
+			Body:       result.Body,
+		}
+	}
+	return results, nil
+}
+
+// Transaction is a group of operations which are sent to the server in a
+// single request and applied atomically. Use Client.NewTransaction to create
+// a Transaction, then add operations with Create, Update, and Delete and send
+// them with Commit.
+type Transaction struct {
+	// Envelope is used to encode the operations and decode the response. It
+	// is DefaultEnvelope unless changed.
+	Envelope Envelope
+	client   *Client
+	url      string
+	ops      []Operation
+	// err is the first error encountered while adding operations. It is
+	// returned by Commit.
+	err error
+}
+
+// NewTransaction returns a new, empty Transaction which will be sent to the
+// batch endpoint at url when committed.
+func (c *Client) NewTransaction(url string) *Transaction {
+	return &Transaction{
+		Envelope: DefaultEnvelope,
+		client:   c,
+		url:      url,
+	}
+}
+
+// Create adds an operation to the transaction which creates model. See
+// Client.Create.
+func (tx *Transaction) Create(model Model) {
+	url, err := normalizeRootURL(model.RootURL())
+	if err != nil {
+		if tx.err == nil {
+			tx.err = err
+		}
+		return
+	}
+	tx.ops = append(tx.ops, Operation{
+		Method: "POST",
+		URL:    url,
+		Model:  model,
+	})
+}
+
+// Update adds an operation to the transaction which updates model. See
+// Client.Update.
+func (tx *Transaction) Update(model Model) {
+	url, err := tx.client.urlForModel(model)
+	if err != nil {
+		if tx.err == nil {
+			tx.err = err
+		}
+		return
+	}
+	tx.ops = append(tx.ops, Operation{
+		Method: "PATCH",
+		URL:    url,
+		Model:  model,
+	})
+}
+
+// Delete adds an operation to the transaction which deletes model. See
+// Client.Delete.
+func (tx *Transaction) Delete(model Model) {
+	url, err := tx.client.urlForModel(model)
+	if err != nil {
+		if tx.err == nil {
+			tx.err = err
+		}
+		return
+	}
+	tx.ops = append(tx.ops, Operation{
+		Method: "DELETE",
+		URL:    url,
+		Model:  model,
+	})
+}
+
+// Operations returns the operations that have been added to the transaction.
+func (tx *Transaction) Operations() []Operation {
+	return tx.ops
+}
+
+// Commit sends all the operations in the transaction to the server in a single
+// POST request with a JSON body. opts are applied to the batch request. If the
+// request was successful, the result of each create or update operation is
+// used to set the fields of the corresponding model, just as if the operation
+// had been sent on its own. If the server reports a non-2xx status code for
+// any operation, Commit returns an HTTPError for the first such operation and
+// no models are mutated. Otherwise the cached responses for the models and
+// their collections are invalidated, as they would be by Create, Update, and
+// Delete. If a model with a malformed RootURL was added to the transaction,
+// Commit returns the corresponding URLError without sending anything.
+func (tx *Transaction) Commit(opts ...RequestOption) error {
+	if tx.err != nil {
+		return tx.err
+	}
+	data, err := tx.Envelope.Encode(tx.ops)
+	if err != nil {
+		return fmt.Errorf("rest: error encoding transaction: %s", err.Error())
+	}
+	var body json.RawMessage
+	if err := tx.client.sendRequestAndUnmarshal("POST", tx.url, ContentJSON, string(data), &body, newRequestOptions(opts)); err != nil {
+		return err
+	}
+	results, err := tx.Envelope.Decode(body)
+	if err != nil {
+		return fmt.Errorf("rest: error decoding transaction response from %s: %s", tx.url, err.Error())
+	}
+	if len(results) != len(tx.ops) {
+		return fmt.Errorf("rest: transaction sent to %s had %d operations but the response had %d results", tx.url, len(tx.ops), len(results))
+	}
+	for i, result := range results {
+		if result.StatusCode != 0 && result.StatusCode/100 != 2 {
+			return HTTPError{
+				URL:        tx.ops[i].URL,
+				Body:       result.Body,
+				StatusCode: result.StatusCode,
+			}
+		}
+	}
+	for i, result := range results {
+		if tx.ops[i].Method == "DELETE" || len(result.Body) == 0 {
+			continue
+		}
+		if err := tx.client.unmarshal(result.Body, tx.ops[i].Model); err != nil {
+			return err
+		}
+	}
+	for _, op := range tx.ops {
+		tx.client.invalidateCache(op.Model)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// batchServer responds to a batch request in the format of DefaultEnvelope
+// with results, and records the operations it received.
+func batchServer(t *testing.T, results string) *[]map[string]interface{} {
+	received := &[]map[string]interface{}{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		envelope := struct {
+			Operations []map[string]interface{} `json:"operations"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+			t.Errorf("Could not decode batch request: %s", err)
+		}
+		*received = envelope.Operations
+		w.Write([]byte(results))
+	})
+	return received
+}
+
+func TestTransactionCommit(t *testing.T) {
+	received := batchServer(t, `{"results": [
+		{"status": 201, "body": {"Id": "3", "Title": "created"}},
+		{"status": 200, "body": {"Id": "1", "Title": "updated", "IsCompleted": true}},
+		{"status": 204}
+	]}`)
+	created := &testTodo{Title: "created"}
+	updated := &testTodo{DefaultId: DefaultId{Id: "1"}, Title: "updated"}
+	deleted := &testTodo{DefaultId: DefaultId{Id: "2"}}
+	tx := NewClient().NewTransaction(testRootURL + "/batch")
+	tx.Create(created)
+	tx.Update(updated)
+	tx.Delete(deleted)
+	if got := len(tx.Operations()); got != 3 {
+		t.Fatalf("Expected 3 operations but got %d", got)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ method, url string }{
+		{"POST", testRootURL + "/todos"},
+		{"PATCH", testRootURL + "/todos/1"},
+		{"DELETE", testRootURL + "/todos/2"},
+	}
+	for i, op := range *received {
+		if op["method"] != expected[i].method || op["url"] != expected[i].url {
+			t.Errorf("Expected operation %d to be %v but got %v", i, expected[i], op)
+		}
+	}
+	if _, found := (*received)[2]["body"]; found {
+		t.Error("Expected the delete operation to have no body")
+	}
+	if created.Id != "3" || !updated.IsCompleted {
+		t.Errorf("Expected the models to be updated with the results but got %+v and %+v", created, updated)
+	}
+}
+
+func TestTransactionFailedOperation(t *testing.T) {
+	batchServer(t, `{"results": [
+		{"status": 201, "body": {"Id": "3", "Title": "changed"}},
+		{"status": 409, "body": {"error": "conflict"}}
+	]}`)
+	created := &testTodo{Title: "created"}
+	tx := NewClient().NewTransaction(testRootURL + "/batch")
+	tx.Create(created)
+	tx.Update(&testTodo{DefaultId: DefaultId{Id: "1"}})
+	err := tx.Commit()
+	httpErr, ok := err.(HTTPError)
+	if !ok || httpErr.StatusCode != http.StatusConflict || httpErr.URL != testRootURL+"/todos/1" {
+		t.Fatalf("Expected an HTTPError for the second operation but got %v", err)
+	}
+	if created.Id != "" || created.Title != "created" {
+		t.Errorf("Expected no models to be changed but got %+v", created)
+	}
+}
+
+func TestTransactionResultCountMismatch(t *testing.T) {
+	batchServer(t, `{"results": []}`)
+	tx := NewClient().NewTransaction(testRootURL + "/batch")
+	tx.Create(&testTodo{})
+	if err := tx.Commit(); err == nil {
+		t.Error("Expected an error when the number of results does not match")
+	}
+}
+
+// badURLModel is a model with a malformed RootURL.
+type badURLModel struct {
+	DefaultId
+}
+
+func (*badURLModel) RootURL() string { return "http://[::1/todos" }
+
+func TestTransactionMalformedRootURL(t *testing.T) {
+	received := batchServer(t, `{"results": []}`)
+	tx := NewClient().NewTransaction(testRootURL + "/batch")
+	tx.Create(&badURLModel{})
+	tx.Create(&testTodo{})
+	if _, ok := tx.Commit().(URLError); !ok {
+		t.Error("Expected a URLError for a malformed RootURL")
+	}
+	if len(*received) != 0 {
+		t.Errorf("Expected nothing to be sent but got %v", *received)
+	}
+}
+
+func TestTransactionInvalidatesCache(t *testing.T) {
+	reads := 0
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			reads++
+			w.Write([]byte(`[{"Id": "1", "Title": "a"}]`))
+			return
+		}
+		w.Write([]byte(`{"results": [{"status": 201, "body": {"Id": "2", "Title": "b"}}]}`))
+	})
+	client := NewClient()
+	client.Cache = NewMemoryCache()
+	todos := []*testTodo{}
+	for i := 0; i < 2; i++ {
+		if err := client.ReadAll(&todos); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reads != 1 {
+		t.Fatalf("Expected the second ReadAll to be served from the cache but got %d reads", reads)
+	}
+	tx := client.NewTransaction(testRootURL + "/batch")
+	tx.Create(&testTodo{Title: "b"})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReadAll(&todos); err != nil {
+		t.Fatal(err)
+	}
+	if reads != 2 {
+		t.Errorf("Expected ReadAll to be sent again after the transaction was committed but got %d reads", reads)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+)
+
+// Transport performs the http requests of a client. Do sends req and returns
+// the response, or an error if no response was received; like http.Client.Do,
+// it must not return an error for non-2xx status codes. A Transport is used
+// for every attempt of every request, so the features of the client, such as
+// retries, caching, and events, work with any implementation.
+//
+// *http.Client satisfies Transport and is what the client uses by default
+// (see Client.HTTPClient). When compiled with gopherjs, XHRTransport and
+// FetchTransport send requests with the browser APIs directly. Tests can use
+// TransportFunc to swap in a fake:
+//
+//	client.Transport = rest.TransportFunc(func(req *http.Request) (*http.Response, error) {
+//		return &http.Response{
+//			StatusCode: 200,
+//			Body:       ioutil.NopCloser(strings.NewReader(`{"Id": "1"}`)),
+//			Request:    req,
+//		}, nil
+//	})
+type Transport interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// TransportFunc is an adapter which allows the use of an ordinary function as
+// a Transport.
+type TransportFunc func(req *http.Request) (*http.Response, error)
+
+// Do satisfies Transport by calling f.
+func (f TransportFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// sender returns the Transport used to send requests: c.Transport if it is
+// set, or else the client's http.Client.
+func (c *Client) sender() Transport {
+	if c.Transport != nil {
+		return c.Transport
+	}
+	return c.httpClient()
+}
+
+// transportRoundTripper adapts a Transport to http.RoundTripper.
+type transportRoundTripper struct {
+	transport Transport
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (rt transportRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.transport.Do(req)
+}
@@ -0,0 +1,176 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+//go:build js
+// +build js
+
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"strconv"
+
+	"github.com/gopherjs/gopherjs/js"
+)
+
+// XHRTransport is a Transport which sends requests with the browser's
+// XMLHttpRequest API directly, bypassing the net/http shim of gopherjs. It
+// works in all browsers supported by rest.
+type XHRTransport struct {
+	// WithCredentials sets the withCredentials property of each
+	// XMLHttpRequest, which causes cookies to be sent with cross-origin
+	// requests.
+	WithCredentials bool
+}
+
+// Do satisfies Transport.
+func (t XHRTransport) Do(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	xhr := js.Global.Get("XMLHttpRequest").New()
+	results := make(chan jsResult, 1)
+	xhr.Call("addEventListener", "load", func(*js.Object) {
+		header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader([]byte(xhr.Call("getAllResponseHeaders").String() + "\r\n")))).ReadMIMEHeader()
+		if err != nil {
+			results <- jsResult{err: err}
+			return
+		}
+		data := js.Global.Get("Uint8Array").New(xhr.Get("response")).Interface().([]byte)
+		results <- jsResult{res: newJSResponse(req, xhr.Get("status").Int(), xhr.Get("statusText").String(), http.Header(header), data)}
+	})
+	xhr.Call("addEventListener", "error", func(*js.Object) {
+		results <- jsResult{err: errors.New("rest: XMLHttpRequest failed")}
+	})
+	xhr.Call("addEventListener", "abort", func(*js.Object) {
+		results <- jsResult{err: errors.New("rest: XMLHttpRequest aborted")}
+	})
+	xhr.Call("open", req.Method, req.URL.String())
+	xhr.Set("responseType", "arraybuffer")
+	xhr.Set("withCredentials", t.WithCredentials)
+	for key, values := range req.Header {
+		for _, value := range values {
+			xhr.Call("setRequestHeader", key, value)
+		}
+	}
+	if body == nil {
+		xhr.Call("send")
+	} else {
+		xhr.Call("send", body)
+	}
+	select {
+	case result := <-results:
+		return result.res, result.err
+	case <-req.Context().Done():
+		xhr.Call("abort")
+		return nil, req.Context().Err()
+	}
+}
+
+// FetchTransport is a Transport which sends requests with the browser's
+// Fetch API directly, bypassing the net/http shim of gopherjs. Requests are
+// aborted when their context is done if the browser supports
+// AbortController.
+type FetchTransport struct {
+	// Credentials is the credentials mode of the requests: "omit",
+	// "same-origin", or "include". The default is "same-origin".
+	Credentials string
+	// Mode is the mode of the requests, e.g. "cors" or "same-origin". The
+	// default is the default of the browser.
+	Mode string
+}
+
+// Do satisfies Transport.
+func (t FetchTransport) Do(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	headers := js.Global.Get("Headers").New()
+	for key, values := range req.Header {
+		for _, value := range values {
+			headers.Call("append", key, value)
+		}
+	}
+	options := js.M{
+		"method":      req.Method,
+		"headers":     headers,
+		"credentials": "same-origin",
+	}
+	if t.Credentials != "" {
+		options["credentials"] = t.Credentials
+	}
+	if t.Mode != "" {
+		options["mode"] = t.Mode
+	}
+	if body != nil {
+		options["body"] = body
+	}
+	var controller *js.Object
+	if abortController := js.Global.Get("AbortController"); abortController != js.Undefined {
+		controller = abortController.New()
+		options["signal"] = controller.Get("signal")
+	}
+	results := make(chan jsResult, 1)
+	onError := func(err *js.Object) {
+		results <- jsResult{err: errors.New("rest: fetch failed: " + err.Call("toString").String())}
+	}
+	js.Global.Call("fetch", req.URL.String(), options).Call("then", func(res *js.Object) {
+		header := http.Header{}
+		res.Get("headers").Call("forEach", func(value string, key string) {
+			header.Add(key, value)
+		})
+		res.Call("arrayBuffer").Call("then", func(buf *js.Object) {
+			data := js.Global.Get("Uint8Array").New(buf).Interface().([]byte)
+			results <- jsResult{res: newJSResponse(req, res.Get("status").Int(), res.Get("statusText").String(), header, data)}
+		}, onError)
+	}, onError)
+	select {
+	case result := <-results:
+		return result.res, result.err
+	case <-req.Context().Done():
+		if controller != nil {
+			controller.Call("abort")
+		}
+		return nil, req.Context().Err()
+	}
+}
+
+// jsResult is the outcome of a request sent with a browser API.
+type jsResult struct {
+	res *http.Response
+	err error
+}
+
+// readRequestBody reads and closes the body of req. It returns nil if req has
+// no body.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
+
+// newJSResponse returns the response to req with the given status, header,
+// and body, as received from a browser API.
+func newJSResponse(req *http.Request, statusCode int, statusText string, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + statusText,
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeTransport returns a Transport which records the requests it receives in
+// sent and responds to all of them with the given status code and body.
+func fakeTransport(sent *[]string, status int, body string) TransportFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*sent = append(*sent, req.Method+" "+req.URL.Path)
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+}
+
+// useFakeRootURL points testTodo at http://example.com for the rest of the
+// test, for tests whose requests never reach a server.
+func useFakeRootURL(t *testing.T) {
+	previous := testRootURL
+	testRootURL = "http://example.com"
+	t.Cleanup(func() {
+		testRootURL = previous
+	})
+}
+
+// failingHTTPClient returns an http.Client which fails the test if it is used.
+func failingHTTPClient(t *testing.T) *http.Client {
+	return &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			t.Errorf("Expected the HTTPClient not to be used but it sent %s %s", req.Method, req.URL)
+			return nil, errors.New("unexpected request")
+		}),
+	}
+}
+
+func TestTransport(t *testing.T) {
+	useFakeRootURL(t)
+	var sent []string
+	client := NewClient()
+	client.HTTPClient = failingHTTPClient(t)
+	client.Transport = fakeTransport(&sent, http.StatusOK, `{"Id": "1", "Title": "Write a book"}`)
+
+	todo := &testTodo{}
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "Write a book" {
+		t.Errorf("Expected the todo to be decoded from the response of the Transport but got %+v", todo)
+	}
+	if err := client.Delete(todo); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || sent[0] != "GET /todos/1" || sent[1] != "DELETE /todos/1" {
+		t.Errorf("Expected the requests to be sent with the Transport but got %v", sent)
+	}
+
+	res, err := (&http.Client{Transport: client.AsRoundTripper()}).Get("http://example.com/todos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(sent) != 3 || sent[2] != "GET /todos" {
+		t.Errorf("Expected AsRoundTripper to send requests with the Transport but got %v", sent)
+	}
+}
+
+func TestTransportErrorStatus(t *testing.T) {
+	useFakeRootURL(t)
+	var sent []string
+	client := NewClient()
+	client.Transport = fakeTransport(&sent, http.StatusNotFound, `{"error": "not found"}`)
+	err := client.Read("1", &testTodo{})
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected an HTTPError with status 404 but got %v", err)
+	}
+
+	errOffline := errors.New("offline")
+	client.Transport = TransportFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errOffline
+	})
+	if err := client.Read("1", &testTodo{}); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("Expected the error of the Transport but got %v", err)
+	}
+}
+
+func TestTransportWithScopeMiddleware(t *testing.T) {
+	useFakeRootURL(t)
+	var sent []string
+	client := NewClient()
+	client.HTTPClient = failingHTTPClient(t)
+	client.Transport = fakeTransport(&sent, http.StatusOK, `{"Id": "1"}`)
+	var wrapped []string
+	child := client.Scope(ScopeMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			wrapped = append(wrapped, req.URL.Path)
+			return next.RoundTrip(req)
+		})
+	}))
+	if err := child.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped) != 1 || len(sent) != 1 {
+		t.Errorf("Expected the middleware to wrap the Transport but the middleware saw %v and the Transport %v", wrapped, sent)
+	}
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped) != 1 {
+		t.Errorf("Expected the middleware not to affect the parent but it saw %v", wrapped)
+	}
+}
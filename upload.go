@@ -0,0 +1,172 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// statusResumeIncomplete is sent by some servers in response to each chunk of
+// a resumable upload except the last.
+const statusResumeIncomplete = 308
+
+// UploadError is returned by Upload when a chunked upload fails part-way
+// through. It can be used to resume the upload with the WithResumeFrom option.
+type UploadError struct {
+	// URL is the url that the upload was sent to
+	URL string
+	// Offset is the number of bytes which were successfully uploaded before
+	// the error occurred.
+	Offset int64
+	// Err is the error that caused the upload to fail
+	Err error
+}
+
+// Error satisfies the error interface
+func (e UploadError) Error() string {
+	return fmt.Sprintf("rest: upload to %s failed at offset %d: %s", e.URL, e.Offset, e.Err.Error())
+}
+
+// Upload sends the contents of r to url in the body of a POST request with the
+// given Content-Type, without buffering it in memory. size is the number of
+// bytes that will be read from r, or -1 if it is not known, in which case the
+// request is sent using chunked transfer encoding. If result is not nil, the
+// JSON response from the server is unmarshaled into it.
+//
+// If the WithChunkSize option is provided and size is known, the upload is
+// instead split into chunks which are sent in consecutive PUT requests with a
+// Content-Range header (e.g. "bytes 0-1048575/5242880"). The server should
+// respond to each chunk except the last with a 2xx or 308 status code. If a
+// chunk fails, Upload returns an UploadError describing how much of the data
+// was uploaded. The WithProgress option can be used to track the progress of
+// the upload.
+func (c *Client) Upload(url string, r io.Reader, size int64, contentType string, result interface{}, opts ...RequestOption) error {
+	reqOpts := newRequestOptions(opts)
+	if reqOpts.chunkSize > 0 && size >= 0 {
+		return c.uploadChunks(url, r, size, contentType, result, reqOpts)
+	}
+	body := newProgressReader(r, 0, size, reqOpts)
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return fmt.Errorf("Something went wrong building POST request to %s: %s", url, err.Error())
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	res, err := c.do(req.WithContext(reqOpts.context()))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return c.unmarshalUploadResponse(res, result)
+}
+
+// uploadChunks uploads the contents of r in chunks of reqOpts.chunkSize bytes.
+func (c *Client) uploadChunks(url string, r io.Reader, size int64, contentType string, result interface{}, reqOpts *requestOptions) error {
+	offset := reqOpts.resumeFrom
+	if offset < 0 || offset > size || (offset == size && size > 0) {
+		// There would be no chunk to send, so the response which holds the
+		// result would never be received
+		return UploadError{URL: url, Offset: offset, Err: fmt.Errorf("rest: cannot resume an upload of %d bytes from offset %d", size, offset)}
+	}
+	if offset > 0 {
+		if seeker, ok := r.(io.Seeker); ok {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return UploadError{URL: url, Offset: offset, Err: err}
+			}
+		} else if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+			// The reader cannot seek, so skip the bytes which were already
+			// uploaded by reading them
+			return UploadError{URL: url, Offset: offset, Err: fmt.Errorf("rest: could not skip to offset %d: %s", offset, err.Error())}
+		}
+	}
+	for offset < size || size == 0 {
+		chunkLen := reqOpts.chunkSize
+		if remaining := size - offset; remaining < chunkLen {
+			chunkLen = remaining
+		}
+		body := newProgressReader(io.LimitReader(r, chunkLen), offset, size, reqOpts)
+		req, err := http.NewRequest("PUT", url, body)
+		if err != nil {
+			return fmt.Errorf("Something went wrong building PUT request to %s: %s", url, err.Error())
+		}
+		req.ContentLength = chunkLen
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", "application/json")
+		if chunkLen > 0 {
+			req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+chunkLen-1, size))
+		} else {
+			req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		}
+		res, err := c.do(req.WithContext(reqOpts.context()))
+		if err != nil {
+			return UploadError{URL: url, Offset: offset, Err: err}
+		}
+		if offset+chunkLen == size {
+			// This was the last chunk.
+			defer res.Body.Close()
+			if err := c.unmarshalUploadResponse(res, result); err != nil {
+				return UploadError{URL: url, Offset: offset, Err: err}
+			}
+			return nil
+		}
+		if res.StatusCode/100 != 2 && res.StatusCode != statusResumeIncomplete {
+			err := newHTTPError(res)
+			res.Body.Close()
+			return UploadError{URL: url, Offset: offset, Err: err}
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		offset += chunkLen
+	}
+	return nil
+}
+
+// unmarshalUploadResponse unmarshals the response to an upload into result.
+// If result is nil, only the status code is checked.
+func (c *Client) unmarshalUploadResponse(res *http.Response, result interface{}) error {
+	if result == nil {
+		if res.StatusCode/100 != 2 {
+			return newHTTPError(res)
+		}
+		return nil
+	}
+	return c.unmarshalResponse(res, result)
+}
+
+// newProgressReader wraps r so that the progress callback in reqOpts (if any)
+// is called as r is read. offset is the number of bytes which have already been
+// uploaded and total is the size of the entire upload.
+func newProgressReader(r io.Reader, offset int64, total int64, reqOpts *requestOptions) io.Reader {
+	if reqOpts.progress == nil {
+		return r
+	}
+	return &progressReader{
+		r:        r,
+		read:     offset,
+		total:    total,
+		progress: reqOpts.progress,
+	}
+}
+
+// progressReader is an io.Reader which calls progress after each read from r.
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.read += int64(n)
+		pr.progress(pr.read, pr.total)
+	}
+	return n, err
+}
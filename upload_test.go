@@ -0,0 +1,148 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// chunkRecorder is a server for chunked uploads which records the
+// Content-Range and body of each chunk.
+type chunkRecorder struct {
+	ranges []string
+	bodies []string
+	// failAt is the index of a chunk which fails with a 500, or -1
+	failAt int
+	mut    sync.Mutex
+}
+
+func (rec *chunkRecorder) handle(w http.ResponseWriter, r *http.Request) {
+	rec.mut.Lock()
+	defer rec.mut.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	if len(rec.ranges) == rec.failAt {
+		rec.failAt = -1
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rec.ranges = append(rec.ranges, r.Header.Get("Content-Range"))
+	rec.bodies = append(rec.bodies, string(body))
+	w.Write([]byte(`{"Id": "upload"}`))
+}
+
+func TestUploadChunks(t *testing.T) {
+	rec := &chunkRecorder{failAt: -1}
+	srv := newTestServer(t, rec.handle)
+	result := &testTodo{}
+	progress := []int64{}
+	data := "abcdefghij"
+	err := NewClient().Upload(srv.URL+"/files", strings.NewReader(data), int64(len(data)), "text/plain", result, WithChunkSize(4), WithProgress(func(written, total int64) {
+		progress = append(progress, written)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRanges := []string{"bytes 0-3/10", "bytes 4-7/10", "bytes 8-9/10"}
+	if strings.Join(rec.ranges, ",") != strings.Join(expectedRanges, ",") {
+		t.Errorf("Expected ranges %v but got %v", expectedRanges, rec.ranges)
+	}
+	if got := strings.Join(rec.bodies, ""); got != data {
+		t.Errorf("Expected the chunks to add up to %q but got %q", data, got)
+	}
+	if result.Id != "upload" {
+		t.Errorf("Expected the response to be decoded into result but got %+v", result)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 10 {
+		t.Errorf("Expected progress to end at 10 but got %v", progress)
+	}
+}
+
+func TestUploadChunkFailure(t *testing.T) {
+	rec := &chunkRecorder{failAt: 1}
+	srv := newTestServer(t, rec.handle)
+	data := "abcdefghij"
+	err := NewClient().Upload(srv.URL+"/files", strings.NewReader(data), int64(len(data)), "text/plain", nil, WithChunkSize(4))
+	uploadErr, ok := err.(UploadError)
+	if !ok {
+		t.Fatalf("Expected an UploadError but got %T: %v", err, err)
+	}
+	if uploadErr.Offset != 4 {
+		t.Errorf("Expected the upload to fail at offset 4 but got %d", uploadErr.Offset)
+	}
+}
+
+func TestUploadResume(t *testing.T) {
+	data := "abcdefghij"
+	readers := map[string]func() io.Reader{
+		// bytes.Reader can seek
+		"seeker": func() io.Reader { return bytes.NewReader([]byte(data)) },
+		// embedding hides the Seek method of strings.Reader
+		"non-seeker": func() io.Reader { return struct{ io.Reader }{strings.NewReader(data)} },
+	}
+	for name, newReader := range readers {
+		rec := &chunkRecorder{failAt: -1}
+		srv := newTestServer(t, rec.handle)
+		err := NewClient().Upload(srv.URL+"/files", newReader(), int64(len(data)), "text/plain", nil, WithChunkSize(4), WithResumeFrom(4))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if got := strings.Join(rec.ranges, ","); got != "bytes 4-7/10,bytes 8-9/10" {
+			t.Errorf("%s: expected the upload to resume at offset 4 but got ranges %s", name, got)
+		}
+		if got := strings.Join(rec.bodies, ""); got != data[4:] {
+			t.Errorf("%s: expected the chunks to add up to %q but got %q", name, data[4:], got)
+		}
+	}
+}
+
+func TestUploadResumeBeyondEnd(t *testing.T) {
+	rec := &chunkRecorder{failAt: -1}
+	srv := newTestServer(t, rec.handle)
+	r := struct{ io.Reader }{strings.NewReader("abc")}
+	err := NewClient().Upload(srv.URL+"/files", r, 10, "text/plain", nil, WithChunkSize(4), WithResumeFrom(4))
+	if _, ok := err.(UploadError); !ok {
+		t.Fatalf("Expected an UploadError when the reader is shorter than the offset but got %T: %v", err, err)
+	}
+	if len(rec.ranges) != 0 {
+		t.Errorf("Expected no chunks to be sent but got %v", rec.ranges)
+	}
+}
+
+func TestUploadResumeOutOfRange(t *testing.T) {
+	rec := &chunkRecorder{failAt: -1}
+	srv := newTestServer(t, rec.handle)
+	for _, offset := range []int64{-1, 10, 11} {
+		result := map[string]interface{}{}
+		err := NewClient().Upload(srv.URL+"/files", strings.NewReader("abcdefghij"), 10, "text/plain", &result, WithChunkSize(4), WithResumeFrom(offset))
+		if uploadErr, ok := err.(UploadError); !ok || uploadErr.Offset != offset {
+			t.Errorf("Expected an UploadError at offset %d but got %T: %v", offset, err, err)
+		}
+	}
+	if len(rec.ranges) != 0 {
+		t.Errorf("Expected no chunks to be sent but got %v", rec.ranges)
+	}
+}
+
+func TestUploadStreaming(t *testing.T) {
+	var got string
+	var contentType string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got, contentType = string(body), r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusCreated)
+	})
+	if err := NewClient().Upload(srv.URL+"/files", strings.NewReader("hello"), -1, "text/plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != "hello" || contentType != "text/plain" {
+		t.Errorf("Expected body %q with type text/plain but got %q with type %q", "hello", got, contentType)
+	}
+}
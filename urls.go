@@ -0,0 +1,217 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URLError is returned when the RootURL of a model is malformed.
+type URLError struct {
+	// URL is the offending url
+	URL string
+	// Reason describes what is wrong with it
+	Reason string
+}
+
+// Error satisfies the error interface
+func (e URLError) Error() string {
+	return fmt.Sprintf("rest: invalid RootURL %q: %s", e.URL, e.Reason)
+}
+
+// normalizeRootURL validates rootURL and returns it in a normalized form:
+// surrounding whitespace, duplicate slashes in the path, and trailing slashes
+// are removed. Relative urls (e.g. "/todos") are allowed, but absolute urls
+// must use http or https and have a host. Template variables (see SetVar) are
+// left as is.
+func normalizeRootURL(rootURL string) (string, error) {
+	trimmed := strings.TrimSpace(rootURL)
+	if trimmed == "" {
+		return "", URLError{URL: rootURL, Reason: "it is empty"}
+	}
+	if strings.ContainsAny(trimmed, " \t\r\n") {
+		return "", URLError{URL: rootURL, Reason: "it contains whitespace"}
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil {
+		return "", URLError{URL: rootURL, Reason: err.Error()}
+	}
+	if parsed.Scheme != "" {
+		if scheme := strings.ToLower(parsed.Scheme); scheme != "http" && scheme != "https" {
+			return "", URLError{URL: rootURL, Reason: fmt.Sprintf("unsupported scheme %q", parsed.Scheme)}
+		}
+		if parsed.Host == "" {
+			return "", URLError{URL: rootURL, Reason: "it has no host"}
+		}
+	}
+	// Split the url into the scheme and host, the path, and the query so that
+	// only the path is changed
+	prefix, path, suffix := "", trimmed, ""
+	if i := strings.IndexAny(path, "?#"); i != -1 {
+		path, suffix = path[:i], path[i:]
+	}
+	if i := strings.Index(path, "://"); i != -1 {
+		if j := strings.Index(path[i+3:], "/"); j != -1 {
+			prefix, path = path[:i+3+j], path[i+3+j:]
+		} else {
+			prefix, path = path, ""
+		}
+	} else if strings.HasPrefix(path, "//") {
+		// Protocol-relative url
+		if j := strings.Index(path[2:], "/"); j != -1 {
+			prefix, path = path[:2+j], path[2+j:]
+		} else {
+			prefix, path = path, ""
+		}
+	}
+	for strings.Contains(path, "//") {
+		path = strings.Replace(path, "//", "/", -1)
+	}
+	path = strings.TrimSuffix(path, "/")
+	return prefix + path + suffix, nil
+}
+
+// modelURL returns the url of the model with the given id, i.e. the
+// normalized rootURL followed by a slash and the escaped id. Any query in
+// rootURL is kept at the end.
+func modelURL(rootURL string, id string) (string, error) {
+	return joinURL(rootURL, escapeId(id))
+}
+
+// joinURL returns the normalized rootURL followed by a slash and idPath, which
+// must already be escaped.
+func joinURL(rootURL string, idPath string) (string, error) {
+	normalized, err := normalizeRootURL(rootURL)
+	if err != nil {
+		return "", err
+	}
+	path, suffix := normalized, ""
+	if i := strings.IndexAny(path, "?#"); i != -1 {
+		path, suffix = path[:i], path[i:]
+	}
+	return path + "/" + idPath + suffix, nil
+}
+
+// escapeId escapes id so that it can be used as a single path segment.
+// Template variables (e.g. "{id}") are left as is.
+func escapeId(id string) string {
+	escaped := url.PathEscape(id)
+	if strings.Contains(id, "{") {
+		escaped = strings.Replace(strings.Replace(escaped, "%7B", "{", -1), "%7D", "}", -1)
+	}
+	return escaped
+}
+
+// CompositeModel is an optional interface for models which are identified by
+// more than one key, e.g. an order line which is identified by the id of the
+// order and its line number. If a model implements CompositeModel, its url is
+// built from the escaped ids returned by ModelIds, joined according to the
+// CompositeKeyPattern of the client, instead of from ModelId.
+type CompositeModel interface {
+	Model
+	// ModelIds returns the keys which identify the model, in order.
+	ModelIds() []string
+}
+
+// URLConvention determines where the id of a model goes in its url. See
+// Client.URLConvention.
+type URLConvention struct {
+	// idParam is the name of the query parameter which holds the id, or ""
+	// if the id is part of the path.
+	idParam string
+}
+
+// IdInPath is the default URLConvention. The url of a model is its RootURL
+// followed by a slash and its id, e.g. "/todos/3".
+var IdInPath = URLConvention{}
+
+// IdInQuery returns a URLConvention for APIs which address a model with a
+// query parameter holding its id, e.g. "/todos?id=3" for IdInQuery("id").
+func IdInQuery(param string) URLConvention {
+	return URLConvention{idParam: param}
+}
+
+// idURL returns the url of the model with the given id and root url
+// according to c.URLConvention.
+func (c *Client) idURL(rootURL string, id string) (string, error) {
+	if c.URLConvention.idParam == "" {
+		return modelURL(rootURL, id)
+	}
+	normalized, err := normalizeRootURL(rootURL)
+	if err != nil || id == "" {
+		return normalized, err
+	}
+	return appendQuery(normalized, Query{c.URLConvention.idParam: {id}}), nil
+}
+
+// urlForModel returns the url of model, i.e. model.RootURL() followed by its
+// escaped id or ids, or with its id in the query if c.URLConvention says so.
+// Composite models always have their ids in the path.
+func (c *Client) urlForModel(model Model) (string, error) {
+	return c.urlForModelAt(model.RootURL(), model)
+}
+
+// urlForModelAt is like urlForModel but uses rootURL instead of
+// model.RootURL().
+func (c *Client) urlForModelAt(rootURL string, model Model) (string, error) {
+	composite, ok := model.(CompositeModel)
+	if !ok {
+		return c.idURL(rootURL, model.ModelId())
+	}
+	return joinURL(rootURL, joinIds(c.CompositeKeyPattern, composite.ModelIds()))
+}
+
+// joinIds escapes each of ids and joins them according to pattern. See
+// Client.CompositeKeyPattern.
+func joinIds(pattern string, ids []string) string {
+	escaped := make([]string, len(ids))
+	for i, id := range ids {
+		escaped[i] = escapeId(id)
+	}
+	if pattern == "" {
+		return strings.Join(escaped, "/")
+	}
+	replacements := make([]string, 0, 2*len(escaped))
+	for i, id := range escaped {
+		replacements = append(replacements, "{"+strconv.Itoa(i)+"}", id)
+	}
+	return strings.NewReplacer(replacements...).Replace(pattern)
+}
+
+// resolveBaseURL prepends c.BaseURL to the url of req if it is relative, e.g.
+// "/todos" becomes "https://api.example.com/v1/todos" with a BaseURL of
+// "https://api.example.com/v1". Absolute urls are left alone.
+func (c *Client) resolveBaseURL(req *http.Request) error {
+	if c.BaseURL == "" || req.URL.IsAbs() || req.URL.Host != "" {
+		return nil
+	}
+	base, err := url.Parse(c.expandVars(c.BaseURL))
+	if err != nil || !base.IsAbs() || base.Host == "" {
+		return fmt.Errorf("rest: invalid BaseURL %q: it must be an absolute url", c.BaseURL)
+	}
+	resolved := *req.URL
+	resolved.Scheme = base.Scheme
+	resolved.User = base.User
+	resolved.Host = base.Host
+	resolved.Path = joinPath(base.Path, req.URL.Path)
+	if req.URL.RawPath != "" {
+		resolved.RawPath = joinPath(base.EscapedPath(), req.URL.RawPath)
+	}
+	req.URL = &resolved
+	req.Host = resolved.Host
+	return nil
+}
+
+// joinPath joins prefix and path with exactly one slash.
+func joinPath(prefix string, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,252 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestNormalizeRootURL(t *testing.T) {
+	tests := map[string]string{
+		"http://example.com/todos":               "http://example.com/todos",
+		"  https://example.com//api///todos/  ":  "https://example.com/api/todos",
+		"http://example.com":                     "http://example.com",
+		"http://example.com/todos/?page=1#top":   "http://example.com/todos?page=1#top",
+		"/api//todos/":                           "/api/todos",
+		"//example.com//todos":                   "//example.com/todos",
+		"http://example.com/{tenant}//todos":     "http://example.com/{tenant}/todos",
+		"http://example.com/todos?redirect=a//b": "http://example.com/todos?redirect=a//b",
+	}
+	for rootURL, expected := range tests {
+		normalized, err := normalizeRootURL(rootURL)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", rootURL, err)
+		} else if normalized != expected {
+			t.Errorf("Expected %q for %q but got %q", expected, rootURL, normalized)
+		}
+	}
+	for _, rootURL := range []string{"", "  ", "http://example.com/my todos", "ftp://example.com/todos", "http:///todos", "http://[::1/todos"} {
+		if _, err := normalizeRootURL(rootURL); err == nil {
+			t.Errorf("Expected an error for %q", rootURL)
+		} else if _, ok := err.(URLError); !ok {
+			t.Errorf("Expected a URLError for %q but got %T", rootURL, err)
+		}
+	}
+}
+
+func TestModelURL(t *testing.T) {
+	tests := []struct {
+		rootURL  string
+		id       string
+		expected string
+	}{
+		{rootURL: "http://example.com/todos/", id: "1", expected: "http://example.com/todos/1"},
+		{rootURL: "http://example.com/todos", id: "a/b c", expected: "http://example.com/todos/a%2Fb%20c"},
+		{rootURL: "http://example.com/todos", id: "ü", expected: "http://example.com/todos/%C3%BC"},
+		{rootURL: "http://example.com/todos?v=2", id: "1", expected: "http://example.com/todos/1?v=2"},
+		{rootURL: "http://example.com/todos", id: "{id}", expected: "http://example.com/todos/{id}"},
+	}
+	for _, test := range tests {
+		url, err := modelURL(test.rootURL, test.id)
+		if err != nil {
+			t.Errorf("Unexpected error for %q and %q: %s", test.rootURL, test.id, err)
+		} else if url != test.expected {
+			t.Errorf("Expected %q for %q and %q but got %q", test.expected, test.rootURL, test.id, url)
+		}
+	}
+}
+
+func TestEscapedIdRequest(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte("{}"))
+	})
+	if err := NewClient().Read("a/b c", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if path := log.last().URL.EscapedPath(); path != "/todos/a%2Fb%20c" {
+		t.Errorf("Expected the id to be sent as a single escaped segment but got %s", path)
+	}
+	if err := NewClient().Read("1", &badURLModel{}); err == nil {
+		t.Error("Expected an error for a malformed RootURL")
+	} else if _, ok := err.(URLError); !ok {
+		t.Errorf("Expected a URLError but got %T: %v", err, err)
+	}
+}
+
+// orderLine is a model identified by the id of its order and its line number.
+type orderLine struct {
+	OrderId string
+	Line    int
+	Product string
+}
+
+func (*orderLine) RootURL() string { return testRootURL + "/orders" }
+func (*orderLine) ModelId() string { return "" }
+func (l *orderLine) ModelIds() []string {
+	return []string{l.OrderId, strconv.Itoa(l.Line)}
+}
+
+func TestCompositeModel(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte(`{"Product": "tea"}`))
+	})
+	client := NewClient()
+	line := &orderLine{OrderId: "A/1", Line: 2}
+	if err := client.Read("", line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Product != "tea" {
+		t.Errorf("Expected the line to be read but got %+v", line)
+	}
+	if err := client.Update(line); err != nil {
+		t.Fatal(err)
+	}
+	if path := log.last().URL.EscapedPath(); path != "/orders/A%2F1/2" {
+		t.Errorf("Expected the ids to be joined with slashes but got %s", path)
+	}
+	client.CompositeKeyPattern = "{0}/lines/{1}"
+	if err := client.Delete(line); err != nil {
+		t.Fatal(err)
+	}
+	if path := log.last().URL.EscapedPath(); path != "/orders/A%2F1/lines/2" {
+		t.Errorf("Expected the ids to be joined according to the pattern but got %s", path)
+	}
+}
+
+func TestJoinIds(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "", expected: "a/b%20c"},
+		{pattern: "{0};{1}", expected: "a;b%20c"},
+		{pattern: "{1}-{0}-{1}", expected: "b%20c-a-b%20c"},
+	}
+	for _, test := range tests {
+		if joined := joinIds(test.pattern, []string{"a", "b c"}); joined != test.expected {
+			t.Errorf("Expected %q for %q but got %q", test.expected, test.pattern, joined)
+		}
+	}
+}
+
+func TestIdInQuery(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte(`{"Id": "a/b c"}`))
+	})
+	client := NewClient()
+	client.URLConvention = IdInQuery("id")
+	todo := &testTodo{}
+	if err := client.Read("a/b c", todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Update(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete(todo); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range log.all() {
+		if uri := req.URL.RequestURI(); uri != "/todos?id=a%2Fb+c" {
+			t.Errorf("Expected the %s request to have the id in the query but got %s", req.Method, uri)
+		}
+	}
+
+	if err := client.Update(&orderLine{OrderId: "A/1", Line: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if uri := log.last().URL.RequestURI(); uri != "/orders/A%2F1/2" {
+		t.Errorf("Expected composite models to keep their ids in the path but got %s", uri)
+	}
+
+	client.URLConvention = IdInPath
+	if err := client.Read("1", todo); err != nil {
+		t.Fatal(err)
+	}
+	if uri := log.last().URL.RequestURI(); uri != "/todos/1" {
+		t.Errorf("Expected IdInPath to put the id in the path but got %s", uri)
+	}
+}
+
+func TestIdURL(t *testing.T) {
+	client := NewClient()
+	client.URLConvention = IdInQuery("key")
+	tests := []struct {
+		rootURL, id, want string
+	}{
+		{"http://example.com/todos/", "3", "http://example.com/todos?key=3"},
+		{"http://example.com/todos?v=2", "3", "http://example.com/todos?v=2&key=3"},
+		{"http://example.com/todos", "", "http://example.com/todos"},
+	}
+	for _, test := range tests {
+		got, err := client.idURL(test.rootURL, test.id)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", test.rootURL, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Expected %s for %q and %q but got %s", test.want, test.rootURL, test.id, got)
+		}
+	}
+	if _, err := client.idURL("http://[::1", "3"); err == nil {
+		t.Error("Expected an error for a malformed root url")
+	}
+}
+
+// relativeTodo is a model with a relative RootURL.
+type relativeTodo struct {
+	DefaultId
+	Title string
+}
+
+func (*relativeTodo) RootURL() string { return "/todos" }
+
+func TestBaseURL(t *testing.T) {
+	log := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.Write([]byte(`{"Id": "1", "Title": "a"}`))
+	})
+	client := NewClient()
+	client.BaseURL = testRootURL + "/api/"
+	todo := &relativeTodo{}
+	if err := client.Read("a/b", todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "a" {
+		t.Errorf("Expected the todo to be read but got %+v", todo)
+	}
+	if path := log.last().URL.EscapedPath(); path != "/api/todos/a%2Fb" {
+		t.Errorf("Expected the relative url to be resolved against the BaseURL but got %s", path)
+	}
+
+	if err := client.Read("1", &testTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if path := log.last().URL.Path; path != "/todos/1" {
+		t.Errorf("Expected absolute urls to be sent as is but got %s", path)
+	}
+
+	client.BaseURL = testRootURL + "/{version}"
+	client.SetVar("version", "v2")
+	if err := client.Read("1", &relativeTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if path := log.last().URL.Path; path != "/v2/todos/1" {
+		t.Errorf("Expected the template variables of the BaseURL to be expanded but got %s", path)
+	}
+
+	client.BaseURL = "example.com/api"
+	if err := client.Read("1", &relativeTodo{}); err == nil {
+		t.Error("Expected an error for a BaseURL which is not absolute")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID is a universally unique identifier as described in RFC 4122. It
+// implements encoding.TextMarshaler and encoding.TextUnmarshaler, so it is
+// encoded in its canonical string form, e.g.
+// "6ba7b810-9dad-11d1-80b4-00c04fd430c8", in both JSON and url-encoded
+// requests.
+type UUID [16]byte
+
+// NewUUID returns a new random (version 4) UUID, which can be used to create
+// models with client-chosen ids (see Client.Put).
+func NewUUID() (UUID, error) {
+	var uuid UUID
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return UUID{}, fmt.Errorf("rest: error generating UUID: %s", err.Error())
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return uuid, nil
+}
+
+// ParseUUID parses s, which must be in the canonical form
+// "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx". Upper case hex digits and
+// surrounding braces are also accepted.
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	str := s
+	if len(str) == 38 && str[0] == '{' && str[37] == '}' {
+		str = str[1:37]
+	}
+	if len(str) != 36 || str[8] != '-' || str[13] != '-' || str[18] != '-' || str[23] != '-' {
+		return UUID{}, fmt.Errorf("rest: invalid UUID %q", s)
+	}
+	digits := str[0:8] + str[9:13] + str[14:18] + str[19:23] + str[24:36]
+	if _, err := hex.Decode(uuid[:], []byte(digits)); err != nil {
+		return UUID{}, fmt.Errorf("rest: invalid UUID %q", s)
+	}
+	return uuid, nil
+}
+
+// IsZero returns true iff uuid is the zero UUID.
+func (uuid UUID) IsZero() bool {
+	return uuid == UUID{}
+}
+
+// String returns the canonical form of uuid.
+func (uuid UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf)
+}
+
+// MarshalText satisfies encoding.TextMarshaler. The zero UUID is encoded as
+// an empty string.
+func (uuid UUID) MarshalText() ([]byte, error) {
+	if uuid.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(uuid.String()), nil
+}
+
+// UnmarshalText satisfies encoding.TextUnmarshaler. An empty string decodes
+// to the zero UUID.
+func (uuid *UUID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*uuid = UUID{}
+		return nil
+	}
+	parsed, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*uuid = parsed
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypeError is returned when a value passed to the client does not have the
+// shape a method expects, e.g. when a struct value is passed where a pointer
+// to a model is needed. It is returned instead of letting the reflection
+// involved panic.
+type TypeError struct {
+	// Type is the type of the offending value. It is nil if the value was nil.
+	Type reflect.Type
+	// Expected describes the shape that was expected, e.g.
+	// "a pointer to a slice of models".
+	Expected string
+	// Hint suggests how to fix the problem, e.g. "did you mean *[]*Todo?". It
+	// may be empty.
+	Hint string
+}
+
+// Error satisfies the error interface
+func (e TypeError) Error() string {
+	got := "nil"
+	if e.Type != nil {
+		got = e.Type.String()
+	}
+	msg := fmt.Sprintf("rest: expected %s but got %s", e.Expected, got)
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg
+}
+
+// checkModelPointer returns an error if model cannot be mutated by method,
+// i.e. if it is nil or not a non-nil pointer. Without this check, passing a
+// struct value would silently discard the response from the server.
+func checkModelPointer(method string, model Model) error {
+	expected := method + " to be called with a non-nil pointer to a model"
+	if model == nil {
+		return TypeError{Expected: expected}
+	}
+	val := reflect.ValueOf(model)
+	if val.Kind() != reflect.Ptr {
+		return TypeError{
+			Type:     val.Type(),
+			Expected: expected,
+			Hint:     fmt.Sprintf("the model would not be updated with the response; did you mean &model, i.e. a *%s?", val.Type()),
+		}
+	}
+	if val.IsNil() {
+		return TypeError{Type: val.Type(), Expected: expected, Hint: "the pointer was nil"}
+	}
+	return nil
+}
+
+// checkModelsType returns an error if models is not a pointer to a container
+// whose elements implement Model and are structs or pointers to structs. If
+// allowMap is true, the container may be a map with string keys instead of a
+// slice. Interface element types (e.g. []rest.Model) are rejected since there
+// would be no way to instantiate the elements.
+func checkModelsType(models interface{}, allowMap bool) error {
+	expected := "a pointer to a slice of models"
+	if allowMap {
+		expected = "a pointer to a slice or map of models"
+	}
+	typ := reflect.TypeOf(models)
+	if typ == nil {
+		return TypeError{Expected: expected}
+	}
+	if typ.Kind() != reflect.Ptr {
+		hint := ""
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+			hint = fmt.Sprintf("did you mean *%s?", typ)
+		}
+		return TypeError{Type: typ, Expected: expected, Hint: hint}
+	}
+	if reflect.ValueOf(models).IsNil() {
+		return TypeError{Type: typ, Expected: expected, Hint: "the pointer was nil"}
+	}
+	container := typ.Elem()
+	switch {
+	case container.Kind() == reflect.Slice:
+	case container.Kind() == reflect.Map && allowMap:
+		if container.Key().Kind() != reflect.String {
+			return TypeError{Type: typ, Expected: expected, Hint: fmt.Sprintf("map keys must be strings, did you mean *map[string]%s?", container.Elem())}
+		}
+	default:
+		hint := ""
+		if container.Implements(modelType) || reflect.PtrTo(container).Implements(modelType) {
+			hint = fmt.Sprintf("to read a single model use Read; to read many, did you mean *[]*%s?", derefType(container))
+		}
+		return TypeError{Type: typ, Expected: expected, Hint: hint}
+	}
+	elemType := container.Elem()
+	if elemType.Kind() == reflect.Interface {
+		return TypeError{Type: typ, Expected: expected, Hint: fmt.Sprintf("the elements must have a concrete type, not the interface type %s", elemType)}
+	}
+	if elemType.Implements(modelType) {
+		if derefType(elemType).Kind() != reflect.Struct {
+			return TypeError{Type: typ, Expected: expected, Hint: fmt.Sprintf("%s is not a struct or a pointer to a struct", elemType)}
+		}
+		return nil
+	}
+	hint := fmt.Sprintf("%s does not implement Model", elemType)
+	if reflect.PtrTo(elemType).Implements(modelType) {
+		// The methods of Model have pointer receivers
+		suggestion := reflect.PtrTo(reflect.SliceOf(reflect.PtrTo(elemType)))
+		if container.Kind() == reflect.Map {
+			suggestion = reflect.PtrTo(reflect.MapOf(container.Key(), reflect.PtrTo(elemType)))
+		}
+		hint = fmt.Sprintf("%s does not implement Model but *%s does; did you mean %s?", elemType, elemType, suggestion)
+	}
+	return TypeError{Type: typ, Expected: expected, Hint: hint}
+}
+
+// maxIndirections is the maximum number of pointers which are followed to
+// reach a value or type. It guards against recursive pointer types such as
+// `type P *P`, which could otherwise be followed forever.
+const maxIndirections = 64
+
+// derefType returns the type typ points to, following any number of pointers
+// up to maxIndirections. If there are more, it returns a pointer type.
+func derefType(typ reflect.Type) reflect.Type {
+	for i := 0; typ.Kind() == reflect.Ptr && i < maxIndirections; i++ {
+		typ = typ.Elem()
+	}
+	return typ
+}
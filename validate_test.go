@@ -0,0 +1,144 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// stringModel is a Model which is not a struct.
+type stringModel string
+
+func (m stringModel) ModelId() string { return string(m) }
+func (m stringModel) RootURL() string { return testRootURL + "/strings" }
+
+func TestCheckModelsType(t *testing.T) {
+	if err := checkModelsType(&[]*testTodo{}, false); err != nil {
+		t.Errorf("Expected a slice to be valid but got %v", err)
+	}
+	if err := checkModelsType(&map[string]*testTodo{}, true); err != nil {
+		t.Errorf("Expected a map to be valid when allowed but got %v", err)
+	}
+	var nilPointer *[]*testTodo
+	invalid := []interface{}{
+		nil,
+		nilPointer,
+		[]*testTodo{},
+		&testTodo{},
+		&[]testTodo{},
+		&[]Model{},
+		&[]interface{}{},
+		&[]string{},
+		&[]stringModel{},
+		&map[string]*testTodo{},
+		&map[int]*testTodo{},
+	}
+	for _, models := range invalid {
+		if err := checkModelsType(models, false); err == nil {
+			t.Errorf("Expected an error for %T", models)
+		} else if _, ok := err.(TypeError); !ok {
+			t.Errorf("Expected a TypeError for %T but got %T", models, err)
+		}
+	}
+}
+
+func TestInterfaceModelsRejected(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	calls := map[string]func(models interface{}) error{
+		"ReadAll": func(models interface{}) error {
+			return client.ReadAll(models)
+		},
+		"ReadAll with WithMerge": func(models interface{}) error {
+			return client.ReadAll(models, WithMerge(false))
+		},
+		"ReadAll with WithCSV": func(models interface{}) error {
+			return client.ReadAll(models, WithCSV())
+		},
+		"ReadAllComplete": func(models interface{}) error {
+			return client.ReadAllComplete(models, 10)
+		},
+		"ReadMany": func(models interface{}) error {
+			return client.ReadMany([]string{"1"}, models)
+		},
+		"Export": func(models interface{}) error {
+			_, err := client.Export(models, ioutil.Discard)
+			return err
+		},
+		"ExportCSV": func(models interface{}) error {
+			_, err := client.ExportCSV(models, ioutil.Discard)
+			return err
+		},
+		"Import": func(models interface{}) error {
+			_, err := client.Import(models, strings.NewReader(`{"Title": "b"}`), 10)
+			return err
+		},
+		"DecodeCSV": func(models interface{}) error {
+			return DecodeCSV([]byte("Id,Title\n1,a\n"), models)
+		},
+	}
+	for name, call := range calls {
+		for _, models := range []interface{}{&[]Model{}, &[]stringModel{}} {
+			if _, ok := call(models).(TypeError); !ok {
+				t.Errorf("Expected %s to return a TypeError for %T", name, models)
+			}
+		}
+	}
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests but got %v", requests)
+	}
+	var buf bytes.Buffer
+	if _, err := client.Export(&[]*testTodo{}, &buf); err != nil || !strings.Contains(buf.String(), `"a"`) {
+		t.Errorf("Expected Export to work with a valid type but got %v and %q", err, buf.String())
+	}
+}
+
+func TestNonPointerModelsRejected(t *testing.T) {
+	server := newTodoServer(t, "a")
+	client := NewClient()
+	calls := map[string]func(model Model) error{
+		"Create":        func(model Model) error { return client.Create(model) },
+		"Read":          func(model Model) error { return client.Read("1", model) },
+		"Update":        func(model Model) error { return client.Update(model) },
+		"Put":           func(model Model) error { return client.Put(model) },
+		"ReadBy":        func(model Model) error { return client.ReadBy(model, "Title", "a") },
+		"FirstOrCreate": func(model Model) error { return client.FirstOrCreate(model, Query{"Title": {"a"}}) },
+		"Hydrate":       func(model Model) error { return client.Hydrate(model) },
+	}
+	var nilTodo *testTodo
+	for name, call := range calls {
+		for _, model := range []Model{nil, nilTodo, stringModel("1")} {
+			err := call(model)
+			typeErr, ok := err.(TypeError)
+			if !ok {
+				t.Errorf("Expected %s to return a TypeError for %T but got %v", name, model, err)
+				continue
+			}
+			if !strings.Contains(typeErr.Error(), name) {
+				t.Errorf("Expected the error to name %s but got %q", name, typeErr.Error())
+			}
+		}
+	}
+	if err := client.Read("1", stringModel("1")); !strings.Contains(err.Error(), "did you mean &model") {
+		t.Errorf("Expected a hint for a model which is not a pointer but got %q", err.Error())
+	}
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests but got %v", requests)
+	}
+}
+
+func TestReadAllStructSliceRejected(t *testing.T) {
+	server := newTodoServer(t, "a")
+	err := NewClient().ReadAll(&[]testTodo{})
+	if err == nil || !strings.Contains(err.Error(), "*[]*rest.testTodo") {
+		t.Errorf("Expected an error suggesting *[]*rest.testTodo but got %v", err)
+	}
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests but got %v", requests)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SetVar sets the template variable with the given name to value. Template
+// variables are substituted into urls and header values at request time
+// wherever "{name}" appears. For example, after calling
+// client.SetVar("tenant", "acme"), a model with a RootURL of "/t/{tenant}/todos"
+// will send requests to "/t/acme/todos". Setting a variable to an empty string
+// removes it. SetVar is safe to call concurrently with requests.
+func (c *Client) SetVar(name string, value string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if value == "" {
+		delete(c.vars, name)
+		return
+	}
+	if c.vars == nil {
+		c.vars = map[string]string{}
+	}
+	c.vars[name] = value
+}
+
+// Var returns the value of the template variable with the given name, or an
+// empty string if it has not been set.
+func (c *Client) Var(name string) string {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.vars[name]
+}
+
+// expandVars replaces each occurrence of "{name}" in s with the value of the
+// corresponding template variable. Occurrences of unknown variables are left
+// as is.
+func (c *Client) expandVars(s string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	for name, value := range c.vars {
+		s = strings.Replace(s, "{"+name+"}", value, -1)
+	}
+	return s
+}
+
+// resourceHeadersFor returns the ResourceHeaders whose root url matches u,
+// ordered from the longest root url to the shortest. Root urls which start
+// with a single slash are matched against the path of u, so that they apply
+// regardless of the host.
+func (c *Client) resourceHeadersFor(u *url.URL) []http.Header {
+	if len(c.ResourceHeaders) == 0 {
+		return nil
+	}
+	expandedURL := *u
+	c.expandURLVars(&expandedURL)
+	full, path := expandedURL.String(), expandedURL.RequestURI()
+	rootURLs := []string{}
+	matches := map[string]http.Header{}
+	for rootURL, header := range c.ResourceHeaders {
+		expanded := strings.TrimSuffix(c.expandVars(rootURL), "/")
+		target := full
+		if strings.HasPrefix(expanded, "/") && !strings.HasPrefix(expanded, "//") {
+			target = path
+		}
+		if hasURLPrefix(target, expanded) {
+			rootURLs = append(rootURLs, expanded)
+			matches[expanded] = header
+		}
+	}
+	sort.Slice(rootURLs, func(i, j int) bool {
+		return len(rootURLs[i]) > len(rootURLs[j])
+	})
+	headers := make([]http.Header, len(rootURLs))
+	for i, rootURL := range rootURLs {
+		headers[i] = matches[rootURL]
+	}
+	return headers
+}
+
+// addDefaultHeaders adds the headers of policy, which may be nil, the
+// ResourceHeaders which match u, and the client's Header to header, in that
+// order of precedence. Headers which are already in header are left as is.
+func (c *Client) addDefaultHeaders(header http.Header, u *url.URL, policy *Policy) {
+	sources := []http.Header{}
+	if policy != nil {
+		sources = append(sources, policy.Headers)
+	}
+	sources = append(sources, c.resourceHeadersFor(u)...)
+	sources = append(sources, c.Header)
+	for _, source := range sources {
+		for key, values := range source {
+			if _, found := header[key]; !found {
+				header[key] = append([]string{}, values...)
+			}
+		}
+	}
+}
+
+// applyHeadersAndVars adds the headers of the model policy, the client's
+// ResourceHeaders, and the client's default headers to req, in that order of
+// precedence, and substitutes template variables into the url and header
+// values of req.
+func (c *Client) applyHeadersAndVars(req *http.Request) {
+	c.addDefaultHeaders(req.Header, req.URL, policyFrom(req.Context()))
+	for _, values := range req.Header {
+		for i, value := range values {
+			values[i] = c.expandVars(value)
+		}
+	}
+	c.expandURLVars(req.URL)
+}
+
+// expandURLVars substitutes template variables into the path and query of u.
+func (c *Client) expandURLVars(u *url.URL) {
+	if u.RawPath != "" {
+		// The path contains escaped characters (e.g. an id with a slash in
+		// it) which must survive the expansion.
+		if rawPath := c.expandVars(u.RawPath); rawPath != u.RawPath {
+			if path, err := url.PathUnescape(rawPath); err == nil {
+				u.Path, u.RawPath = path, rawPath
+			}
+		}
+	} else if path := c.expandVars(u.Path); path != u.Path {
+		u.Path = path
+		u.RawPath = ""
+	}
+	u.RawQuery = c.expandVars(u.RawQuery)
+}
@@ -0,0 +1,110 @@
+// Copyright 2015 Alex Browne.  All rights reserved.
+// Use of this source code is governed by the MIT
+// license, which can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// tenantTodo is a model whose RootURL contains a template variable.
+type tenantTodo struct {
+	DefaultId
+	Title string
+}
+
+func (*tenantTodo) RootURL() string { return testRootURL + "/t/{tenant}/todos" }
+
+func TestTemplateVars(t *testing.T) {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	client := NewClient()
+	client.Header = http.Header{"X-Tenant": {"{tenant}"}, "X-Unknown": {"{unknown}"}}
+	client.SetVar("tenant", "acme")
+	if client.Var("tenant") != "acme" {
+		t.Errorf("Expected Var to return acme but got %q", client.Var("tenant"))
+	}
+	if err := client.Read("1", &tenantTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	req := requests.last()
+	if req.URL.Path != "/t/acme/todos/1" {
+		t.Errorf("Expected the variable to be substituted into the url but got %s", req.URL.Path)
+	}
+	if req.Header.Get("X-Tenant") != "acme" || req.Header.Get("X-Unknown") != "{unknown}" {
+		t.Errorf("Expected only known variables to be substituted into headers but got %v", req.Header)
+	}
+	if client.Header.Get("X-Tenant") != "{tenant}" {
+		t.Error("Expected the client's default headers to be left unchanged")
+	}
+
+	client.SetVar("tenant", "")
+	if client.Var("tenant") != "" {
+		t.Error("Expected setting an empty value to remove the variable")
+	}
+}
+
+func TestTemplateVarsInEscapedPath(t *testing.T) {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		w.Write([]byte(`{}`))
+	})
+	client := NewClient()
+	client.SetVar("tenant", "acme")
+	if err := client.Read("a/b", &tenantTodo{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.last().URL.EscapedPath(); got != "/t/acme/todos/a%2Fb" {
+		t.Errorf("Expected the escaped id to survive the substitution but got %s", got)
+	}
+}
+
+func TestResourceHeaders(t *testing.T) {
+	requests := &requestLog{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.add(r)
+		if r.URL.Path == "/todos" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`{"Id": "1"}`))
+	})
+	client := NewClient()
+	client.SetVar("tenant", "acme")
+	client.Header = http.Header{"X-Feature": {"stable"}, "X-Client": {"test"}}
+	client.ResourceHeaders = map[string]http.Header{
+		"/todos":                     {"X-Feature": {"beta"}},
+		testRootURL + "/todos/1":     {"X-Feature": {"alpha"}},
+		"/todo":                      {"X-Prefix": {"yes"}},
+		testRootURL + "/t/{tenant}/": {"X-Tenant": {"{tenant}"}},
+	}
+	expected := []struct {
+		read    func() error
+		feature string
+		tenant  string
+	}{
+		{func() error { return client.Read("2", &testTodo{}) }, "beta", ""},
+		{func() error { return client.Read("1", &testTodo{}) }, "alpha", ""},
+		{func() error { return client.ReadAll(&[]*testTodo{}, WithQuery(Query{"Title": {"a"}})) }, "beta", ""},
+		{func() error { return client.Read("1", &tenantTodo{}) }, "stable", "acme"},
+		{func() error { return client.Read("10", &testTodo{}) }, "beta", ""},
+	}
+	for i, e := range expected {
+		if err := e.read(); err != nil {
+			t.Fatal(err)
+		}
+		header := requests.last().Header
+		if header.Get("X-Feature") != e.feature || header.Get("X-Tenant") != e.tenant || header.Get("X-Client") != "test" {
+			t.Errorf("Expected the headers for %s to be X-Feature %q and X-Tenant %q but got %v", requests.last().URL, e.feature, e.tenant, header)
+		}
+		if header.Get("X-Prefix") != "" {
+			t.Errorf("Expected the headers for /todo not to match request %d", i)
+		}
+	}
+}